@@ -0,0 +1,78 @@
+package main
+
+import (
+	"clean-architecture-gochat/infrastructure/mysql"
+	redisInfra "clean-architecture-gochat/infrastructure/redis"
+	"clean-architecture-gochat/internal/config"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+)
+
+// 匯入外部聊天記錄，格式說明見 docs/IMPORT_FORMAT.md
+//
+//	go run ./cmd/import -file export.json -dry-run
+//	go run ./cmd/import -file export.json
+func main() {
+	path := flag.String("file", "", "匯入檔路徑")
+	dryRun := flag.Bool("dry-run", false, "只檢查資料並輸出報告，不寫入任何資料")
+	flag.Parse()
+	if *path == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	config.LoadConfig("internal/config")
+
+	f, err := os.Open(*path)
+	if err != nil {
+		log.Fatalf("❌ 開啟匯入檔失敗: %v", err)
+	}
+	var file entities.ImportFile
+	err = json.NewDecoder(f).Decode(&file)
+	f.Close()
+	if err != nil {
+		log.Fatalf("❌ 解析匯入檔失敗: %v", err)
+	}
+
+	db := mysql.Connect()
+	if db == nil {
+		log.Fatal("Failed to initialize database connection.")
+	}
+	redisClient, err := redisInfra.Connect()
+	if err != nil {
+		log.Printf("Redis 初始化失敗，改由資料庫配發訊息序號: %v", err)
+	}
+
+	// 匯入指令與服務分屬不同程序，搜尋索引固定使用 MySQL FULLTEXT
+	sequencer := chat.NewSequencer(redisInfra.NewSequenceCache(redisClient), repositories.NewSequenceRepository(db))
+	importUseCase := chat.NewImportUseCase(
+		repositories.NewImportRepository(db),
+		repositories.NewMessageRepository(db),
+		redisInfra.NewMessageCacheRepository(redisClient),
+		repositories.NewGroupRepository(db),
+		repositories.NewConversationRepository(db),
+		redisInfra.NewUnreadCounterCache(redisClient),
+		mysql.NewMessageSearchIndex(db),
+		sequencer,
+	)
+
+	report, err := importUseCase.Import(context.Background(), &file, *dryRun)
+	if err != nil {
+		log.Fatalf("❌ 匯入失敗，修正後重新執行會略過已匯入的資料: %v", err)
+	}
+
+	output, _ := json.MarshalIndent(report, "", "  ")
+	fmt.Println(string(output))
+	if *dryRun {
+		fmt.Println("🔍 試跑完成，未寫入任何資料")
+	} else {
+		fmt.Println("✅ 匯入完成！")
+	}
+}
@@ -24,10 +24,36 @@ func main() {
 		&entities.User{},
 		&entities.Message{},
 		&entities.Group{},
+		&entities.Conversation{},
+		&entities.MessageRevision{},
+		&entities.MessageReaction{},
+		&entities.MessagePin{},
+		&entities.ScheduledMessage{},
+		&entities.DisappearingSetting{},
+		&entities.Draft{},
+		&entities.ConversationSequence{},
+		&entities.Poll{},
+		&entities.PollVote{},
+		&entities.MessageStar{},
+		&entities.ExportJob{},
+		&entities.ImportMapping{},
+		&entities.ModerationRule{},
+		&entities.HeldMessage{},
+		&entities.ModerationLog{},
 	)
 	if err != nil {
 		log.Fatalf("❌ 數據庫遷移失敗: %v", err)
 	}
 
+	// 建立訊息搜尋使用的 FULLTEXT 索引
+	if err := mysql.EnsureMessageFullTextIndex(db); err != nil {
+		log.Fatalf("❌ 建立訊息搜尋索引失敗: %v", err)
+	}
+
+	// 為既有訊息補上對話序號
+	if err := mysql.BackfillMessageSequences(db); err != nil {
+		log.Fatalf("❌ 補齊訊息序號失敗: %v", err)
+	}
+
 	fmt.Println("✅ 數據庫遷移成功！")
 }
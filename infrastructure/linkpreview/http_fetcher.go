@@ -0,0 +1,297 @@
+package linkpreview
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/linkpreview"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	defaultTimeout      = 5 * time.Second
+	defaultMaxBodySize  = 512 * 1024
+	defaultMaxRedirects = 3
+	userAgent           = "GoChatLinkPreview/1.0"
+
+	// 預覽欄位的最大字數
+	maxTitleLength       = 200
+	maxDescriptionLength = 500
+	maxURLLength         = 2048
+)
+
+var (
+	// ErrBlockedAddress 網址指向內部網路或保留位址
+	ErrBlockedAddress = errors.New("link preview: address is not allowed")
+	// ErrUnsupportedURL 網址不是 http 或 https
+	ErrUnsupportedURL = errors.New("link preview: unsupported url")
+	// ErrNotHTML 回應不是 HTML 頁面
+	ErrNotHTML = errors.New("link preview: response is not html")
+)
+
+// reservedNetworks net.IP 方法未涵蓋、但同樣不可從外部存取的網段
+var reservedNetworks = mustParseCIDRs(
+	"0.0.0.0/8",       // 本網路
+	"100.64.0.0/10",   // 電信級 NAT
+	"192.0.0.0/24",    // IETF 協定分配
+	"192.0.2.0/24",    // 文件範例
+	"198.18.0.0/15",   // 效能測試
+	"198.51.100.0/24", // 文件範例
+	"203.0.113.0/24",  // 文件範例
+	"240.0.0.0/4",     // 保留與廣播
+	"64:ff9b::/96",    // NAT64，可能對應到內部 IPv4 位址
+	"2001:db8::/32",   // 文件範例
+)
+
+// Options HTTP 讀取器的限制，零值欄位使用預設值
+type Options struct {
+	Timeout      time.Duration // 單一網址的讀取時間上限，包含重新導向
+	MaxBodySize  int64         // 最多讀取的回應大小
+	MaxRedirects int           // 最多跟隨的重新導向次數
+}
+
+// httpFetcher 以 HTTP 讀取網頁並解析 OpenGraph 與 Twitter Card 標籤。
+// 連線時檢查實際連線的 IP，重新導向與 DNS 解析都無法繞過內部網路的限制
+type httpFetcher struct {
+	client      *http.Client
+	maxBodySize int64
+}
+
+// NewHTTPFetcher 創建只允許連線到公開網路的連結預覽讀取器
+func NewHTTPFetcher(opts Options) linkpreview.Fetcher {
+	return newHTTPFetcher(opts, isPublicIP)
+}
+
+// newHTTPFetcher 以 allowIP 判斷可連線的位址，測試時可允許本機的測試伺服器
+func newHTTPFetcher(opts Options, allowIP func(net.IP) bool) *httpFetcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxBodySize <= 0 {
+		opts.MaxBodySize = defaultMaxBodySize
+	}
+	if opts.MaxRedirects <= 0 {
+		opts.MaxRedirects = defaultMaxRedirects
+	}
+
+	dialer := &net.Dialer{
+		Timeout: opts.Timeout,
+		// Control 在 DNS 解析後、建立連線前執行，檢查的是實際連線的位址
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || !allowIP(ip) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		Proxy:                 nil, // 不經過代理，避免代理代為連線到內部網路
+		DialContext:           dialer.DialContext,
+		TLSHandshakeTimeout:   opts.Timeout,
+		ResponseHeaderTimeout: opts.Timeout,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       30 * time.Second,
+	}
+
+	return &httpFetcher{
+		client: &http.Client{
+			Transport: transport,
+			Timeout:   opts.Timeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > opts.MaxRedirects {
+					return fmt.Errorf("link preview: stopped after %d redirects", opts.MaxRedirects)
+				}
+				if err := checkURL(req.URL); err != nil {
+					return err
+				}
+				return nil
+			},
+		},
+		maxBodySize: opts.MaxBodySize,
+	}
+}
+
+func (f *httpFetcher) Fetch(ctx context.Context, rawURL string) (*entities.LinkPreview, error) {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrUnsupportedURL
+	}
+	if err := checkURL(target); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("link preview: unexpected status %d", resp.StatusCode)
+	}
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return nil, ErrNotHTML
+	}
+
+	preview := parsePreview(io.LimitReader(resp.Body, f.maxBodySize), resp.Request.URL)
+	preview.URL = rawURL
+	return preview, nil
+}
+
+// checkURL 只允許 http 與 https 網址，且不可包含帳號密碼
+func checkURL(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ErrUnsupportedURL
+	}
+	if u.Hostname() == "" || u.User != nil || len(u.String()) > maxURLLength {
+		return ErrUnsupportedURL
+	}
+	return nil
+}
+
+// parsePreview 解析 head 中的 meta 標籤，讀到 body 或超過大小上限即停止
+func parsePreview(body io.Reader, base *url.URL) *entities.LinkPreview {
+	meta := make(map[string]string)
+	var title string
+	inTitle := false
+
+	tokenizer := html.NewTokenizer(body)
+parse:
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			break parse
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "body":
+				break parse
+			case "title":
+				inTitle = true
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				var key, content string
+				for {
+					attr, value, more := tokenizer.TagAttr()
+					switch strings.ToLower(string(attr)) {
+					case "property", "name":
+						key = strings.ToLower(strings.TrimSpace(string(value)))
+					case "content":
+						content = string(value)
+					}
+					if !more {
+						break
+					}
+				}
+				if _, exists := meta[key]; key != "" && !exists {
+					meta[key] = content
+				}
+			}
+		case html.TextToken:
+			if inTitle && title == "" {
+				title = string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "title":
+				inTitle = false
+			case "head":
+				break parse
+			}
+		}
+	}
+
+	preview := &entities.LinkPreview{
+		Title:       clean(firstOf(meta["og:title"], meta["twitter:title"], title), maxTitleLength),
+		Description: clean(firstOf(meta["og:description"], meta["twitter:description"], meta["description"]), maxDescriptionLength),
+		SiteName:    clean(meta["og:site_name"], maxTitleLength),
+	}
+	if image := firstOf(meta["og:image:secure_url"], meta["og:image"], meta["twitter:image"], meta["twitter:image:src"]); image != "" {
+		preview.Image = resolveImage(base, image)
+	}
+	return preview
+}
+
+// resolveImage 將圖片網址轉為絕對網址，非 http 或 https 的網址不採用
+func resolveImage(base *url.URL, image string) string {
+	ref, err := url.Parse(strings.TrimSpace(image))
+	if err != nil {
+		return ""
+	}
+	resolved := base.ResolveReference(ref)
+	if checkURL(resolved) != nil {
+		return ""
+	}
+	return resolved.String()
+}
+
+// clean 移除多餘空白與無效字元，並截斷到 limit 個字
+func clean(value string, limit int) string {
+	value = strings.Join(strings.Fields(strings.ToValidUTF8(value, "")), " ")
+	if utf8.RuneCountInString(value) <= limit {
+		return value
+	}
+	return string([]rune(value)[:limit]) + "…"
+}
+
+func firstOf(values ...string) string {
+	for _, value := range values {
+		if strings.TrimSpace(value) != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// isPublicIP 判斷位址是否可從公開網路存取
+func isPublicIP(ip net.IP) bool {
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() || ip.IsMulticast() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() {
+		return false
+	}
+	for _, network := range reservedNetworks {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func mustParseCIDRs(cidrs ...string) []*net.IPNet {
+	networks := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic(err)
+		}
+		networks[i] = network
+	}
+	return networks
+}
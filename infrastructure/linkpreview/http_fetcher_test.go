@@ -0,0 +1,132 @@
+package linkpreview
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func allowAll(net.IP) bool { return true }
+
+const samplePage = `<!DOCTYPE html>
+<html><head>
+<title>Fallback title</title>
+<meta property="og:title" content="  Example   Article ">
+<meta name="twitter:title" content="Twitter title">
+<meta name="description" content="A short description">
+<meta property="og:image" content="/images/cover.png">
+<meta property="og:site_name" content="Example">
+</head><body><meta property="og:title" content="ignored"></body></html>`
+
+func TestHTTPFetcher_ParsesOpenGraph(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(samplePage))
+	}))
+	defer server.Close()
+
+	preview, err := newHTTPFetcher(Options{}, allowAll).Fetch(context.Background(), server.URL+"/post")
+
+	assert.NoError(t, err)
+	assert.Equal(t, server.URL+"/post", preview.URL)
+	assert.Equal(t, "Example Article", preview.Title)
+	assert.Equal(t, "A short description", preview.Description)
+	assert.Equal(t, server.URL+"/images/cover.png", preview.Image)
+	assert.Equal(t, "Example", preview.SiteName)
+}
+
+func TestHTTPFetcher_FallsBackToTitle(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Plain page</title><meta property="og:image" content="javascript:alert(1)"></head></html>`))
+	}))
+	defer server.Close()
+
+	preview, err := newHTTPFetcher(Options{}, allowAll).Fetch(context.Background(), server.URL)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Plain page", preview.Title)
+	assert.Empty(t, preview.Image)
+}
+
+func TestHTTPFetcher_BlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(samplePage))
+	}))
+	defer server.Close()
+
+	_, err := NewHTTPFetcher(Options{}).Fetch(context.Background(), server.URL)
+	assert.True(t, errors.Is(err, ErrBlockedAddress), "%v", err)
+
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "172.16.0.1", "192.168.1.1", "169.254.169.254", "100.64.0.1", "0.0.0.0", "::1", "fd00::1", "fe80::1"} {
+		assert.False(t, isPublicIP(net.ParseIP(ip)), ip)
+	}
+	assert.True(t, isPublicIP(net.ParseIP("93.184.216.34")))
+}
+
+func TestHTTPFetcher_BlocksRedirectToPrivateAddress(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/secret" {
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(samplePage))
+			return
+		}
+		// 以另一個迴路位址代表內部網路，連線檢查發生在 DNS 解析與重新導向之後
+		_, port, _ := net.SplitHostPort(r.Host)
+		http.Redirect(w, r, "http://127.0.0.2:"+port+"/secret", http.StatusFound)
+	}))
+	defer server.Close()
+
+	fetcher := newHTTPFetcher(Options{}, func(ip net.IP) bool { return ip.Equal(net.IPv4(127, 0, 0, 1)) })
+
+	_, err := fetcher.Fetch(context.Background(), server.URL)
+	assert.True(t, errors.Is(err, ErrBlockedAddress), "%v", err)
+
+	_, err = fetcher.Fetch(context.Background(), "file:///etc/passwd")
+	assert.Equal(t, ErrUnsupportedURL, err)
+}
+
+func TestHTTPFetcher_Limits(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/json":
+			w.Header().Set("Content-Type", "application/json")
+			w.Write([]byte(`{}`))
+		case "/large":
+			// meta 標籤在大小上限之後，不會被讀到
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte("<html><head>" + strings.Repeat("<!-- padding -->", 1024) + `<meta property="og:title" content="late">`))
+		case "/slow":
+			time.Sleep(500 * time.Millisecond)
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(samplePage))
+		case "/loop":
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	fetcher := newHTTPFetcher(Options{Timeout: 100 * time.Millisecond, MaxBodySize: 1024}, allowAll)
+	ctx := context.Background()
+
+	_, err := fetcher.Fetch(ctx, server.URL+"/json")
+	assert.Equal(t, ErrNotHTML, err)
+
+	preview, err := fetcher.Fetch(ctx, server.URL+"/large")
+	assert.NoError(t, err)
+	assert.True(t, preview.IsEmpty())
+
+	_, err = fetcher.Fetch(ctx, server.URL+"/slow")
+	assert.Error(t, err)
+
+	_, err = fetcher.Fetch(ctx, server.URL+"/loop")
+	assert.ErrorContains(t, err, "redirects")
+}
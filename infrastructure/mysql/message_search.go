@@ -0,0 +1,121 @@
+package mysql
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/search"
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	// messageFullTextIndex 訊息內容與富文本純文字的 FULLTEXT 索引名稱
+	messageFullTextIndex = "idx_messages_text_fulltext"
+	// legacyFullTextIndex 只涵蓋 content 欄位的舊索引，MATCH 的欄位需與索引一致
+	legacyFullTextIndex = "idx_messages_content_fulltext"
+)
+
+// EnsureMessageFullTextIndex 建立訊息內容的 FULLTEXT 索引，並移除只涵蓋 content 的舊索引。
+// 優先使用支援中日韓文字的 ngram 解析器，資料庫不支援時（如 MariaDB）退回預設解析器
+func EnsureMessageFullTextIndex(db *gorm.DB) error {
+	if db.Migrator().HasIndex(&entities.Message{}, legacyFullTextIndex) {
+		if err := db.Migrator().DropIndex(&entities.Message{}, legacyFullTextIndex); err != nil {
+			return err
+		}
+	}
+	if db.Migrator().HasIndex(&entities.Message{}, messageFullTextIndex) {
+		return nil
+	}
+	err := db.Exec("CREATE FULLTEXT INDEX " + messageFullTextIndex + " ON messages (content, plain_text) WITH PARSER ngram").Error
+	if err == nil {
+		return nil
+	}
+	return db.Exec("CREATE FULLTEXT INDEX " + messageFullTextIndex + " ON messages (content, plain_text)").Error
+}
+
+// messageSearchIndex 以 MySQL FULLTEXT 索引搜尋 messages 表，索引由資料庫維護。
+// 已到自動消失時間但尚未被清除的訊息以 expires_at 過濾
+type messageSearchIndex struct {
+	db *gorm.DB
+}
+
+// NewMessageSearchIndex 創建以 MySQL FULLTEXT 實作的訊息搜尋
+func NewMessageSearchIndex(db *gorm.DB) search.MessageIndex {
+	return &messageSearchIndex{db: db}
+}
+
+// Index 資料庫在寫入時自動更新 FULLTEXT 索引，不需額外處理
+func (s *messageSearchIndex) Index(ctx context.Context, message *entities.Message) error {
+	return nil
+}
+
+// Remove 撤回的訊息以 recalled_at 過濾，不需額外處理
+func (s *messageSearchIndex) Remove(ctx context.Context, messageID uint) error {
+	return nil
+}
+
+func (s *messageSearchIndex) Search(ctx context.Context, query search.Query) (*search.Result, error) {
+	query = query.Normalize()
+	result := &search.Result{Hits: []*search.Hit{}}
+	if len(query.Terms) == 0 {
+		return result, nil
+	}
+
+	db := s.db.WithContext(ctx).
+		Where("MATCH(content, plain_text) AGAINST(? IN BOOLEAN MODE)", booleanQuery(query.Terms)).
+		Where("recalled_at IS NULL").
+		Where("(expires_at IS NULL OR expires_at > ?)", time.Now())
+
+	// 可見範圍：自己的私聊與所屬群組
+	if len(query.RoomIDs) > 0 {
+		db = db.Where("(type = ? AND (user_id = ? OR target_id = ?)) OR (type = ? AND room_id IN ?)",
+			entities.MessageTypePrivate, query.ViewerID, query.ViewerID, entities.MessageTypeGroup, query.RoomIDs)
+	} else {
+		db = db.Where("type = ? AND (user_id = ? OR target_id = ?)", entities.MessageTypePrivate, query.ViewerID, query.ViewerID)
+	}
+
+	switch query.ConversationType {
+	case entities.ConversationTypePrivate:
+		db = db.Where("type = ? AND ((user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?))",
+			entities.MessageTypePrivate, query.ViewerID, query.TargetID, query.TargetID, query.ViewerID)
+	case entities.ConversationTypeGroup:
+		db = db.Where("type = ? AND room_id = ?", entities.MessageTypeGroup, query.TargetID)
+	}
+	if query.SenderID != 0 {
+		db = db.Where("user_id = ?", query.SenderID)
+	}
+	if !query.From.IsZero() {
+		db = db.Where("created_at >= ?", query.From)
+	}
+	if !query.To.IsZero() {
+		db = db.Where("created_at < ?", query.To)
+	}
+	if query.Media != 0 {
+		db = db.Where("media = ?", query.Media)
+	}
+
+	var messages []*entities.Message
+	if err := db.Order("id DESC").Offset(query.Offset).Limit(query.Limit + 1).Find(&messages).Error; err != nil {
+		return nil, err
+	}
+
+	result.HasMore = len(messages) > query.Limit
+	if result.HasMore {
+		messages = messages[:query.Limit]
+	}
+	for _, message := range messages {
+		result.Hits = append(result.Hits, &search.Hit{Message: message, Snippet: search.Snippet(message.DisplayText(), query.Terms)})
+	}
+	return result, nil
+}
+
+// booleanQuery 將搜尋詞組成 BOOLEAN MODE 查詢，每個詞都必須出現
+func booleanQuery(terms []string) string {
+	parts := make([]string, len(terms))
+	for i, term := range terms {
+		parts[i] = `+"` + strings.ReplaceAll(term, `"`, "") + `"`
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,87 @@
+package mysql
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/search"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	gormMySQL "gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock, func()) {
+	conn, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+
+	db, err := gorm.Open(gormMySQL.New(gormMySQL.Config{Conn: conn, SkipInitializeWithVersion: true}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening gorm", err)
+	}
+
+	return db, mock, func() {
+		conn.Close()
+	}
+}
+
+// expiryFilter 模擬資料庫套用 expires_at 條件：以查詢帶入的截止時間過濾 messages 表中的資料列
+type expiryFilter struct {
+	messages []*entities.Message
+	rows     *sqlmock.Rows
+}
+
+func (f *expiryFilter) Match(value driver.Value) bool {
+	cutoff, ok := value.(time.Time)
+	if !ok {
+		return false
+	}
+	for _, message := range f.messages {
+		if message.ExpiresAt != nil && !message.ExpiresAt.After(cutoff) {
+			continue
+		}
+		var expiresAt driver.Value
+		if message.ExpiresAt != nil {
+			expiresAt = *message.ExpiresAt
+		}
+		f.rows.AddRow(message.ID, message.UserId, message.TargetId, message.Type, message.Content, expiresAt)
+	}
+	return true
+}
+
+// 測試搜尋結果不包含已到自動消失時間、尚未被背景工作刪除的訊息
+func TestMessageSearchIndex_SkipsExpiredMessages(t *testing.T) {
+	db, mock, cleanup := setupTestDB(t)
+	defer cleanup()
+
+	expired := time.Now().Add(-time.Minute)
+	later := time.Now().Add(time.Hour)
+	filter := &expiryFilter{
+		messages: []*entities.Message{
+			{ID: 3, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Content: "hello again", ExpiresAt: &later},
+			{ID: 2, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Content: "hello secret", ExpiresAt: &expired},
+			{ID: 1, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Content: "hello"},
+		},
+		rows: sqlmock.NewRows([]string{"id", "user_id", "target_id", "type", "content", "expires_at"}),
+	}
+	mock.ExpectQuery(`\(expires_at IS NULL OR expires_at > \?\)`).
+		WithArgs(`+"hello"`, filter, entities.MessageTypePrivate, uint(1), uint(1), 21).
+		WillReturnRows(filter.rows)
+
+	index := NewMessageSearchIndex(db)
+	result, err := index.Search(context.Background(), search.Query{Terms: []string{"hello"}, ViewerID: 1})
+
+	assert.NoError(t, err)
+	ids := make([]uint, 0, len(result.Hits))
+	for _, hit := range result.Hits {
+		ids = append(ids, hit.Message.ID)
+	}
+	assert.Equal(t, []uint{3, 1}, ids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
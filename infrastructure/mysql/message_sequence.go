@@ -0,0 +1,65 @@
+package mysql
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+
+	"gorm.io/gorm"
+)
+
+// backfillGroupSequences 依 ID 順序為群組中尚未配發序號的訊息補上序號，接在對話既有的最大序號之後
+const backfillGroupSequences = `
+UPDATE messages m
+JOIN (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY room_id ORDER BY id) AS rn
+	FROM messages
+	WHERE seq = 0 AND thread_root_id IS NULL AND room_id <> 0 AND type IN (?, ?)
+) pending ON pending.id = m.id
+LEFT JOIN (
+	SELECT room_id, MAX(seq) AS base
+	FROM messages
+	WHERE room_id <> 0 AND type IN (?, ?)
+	GROUP BY room_id
+) latest ON latest.room_id = m.room_id
+SET m.seq = COALESCE(latest.base, 0) + pending.rn`
+
+// backfillPrivateSequences 同上，私聊雙方兩個方向的訊息共用同一組序號
+const backfillPrivateSequences = `
+UPDATE messages m
+JOIN (
+	SELECT id, ROW_NUMBER() OVER (PARTITION BY LEAST(user_id, target_id), GREATEST(user_id, target_id) ORDER BY id) AS rn
+	FROM messages
+	WHERE seq = 0 AND thread_root_id IS NULL AND (type = ? OR (type = ? AND room_id = 0))
+) pending ON pending.id = m.id
+LEFT JOIN (
+	SELECT LEAST(user_id, target_id) AS low, GREATEST(user_id, target_id) AS high, MAX(seq) AS base
+	FROM messages
+	WHERE type = ? OR (type = ? AND room_id = 0)
+	GROUP BY low, high
+) latest ON latest.low = LEAST(m.user_id, m.target_id) AND latest.high = GREATEST(m.user_id, m.target_id)
+SET m.seq = COALESCE(latest.base, 0) + pending.rn`
+
+// backfillSequenceScopes 為已有序號的訊息補上所屬對話，使其受 (seq_scope, seq) 唯一索引保護；
+// 加入唯一索引前已重複的序號以 IGNORE 略過，保留為 NULL
+const backfillSequenceScopes = `
+UPDATE IGNORE messages
+SET seq_scope = CASE
+	WHEN type = ? OR (type = ? AND room_id <> 0) THEN CONCAT('room:', room_id)
+	ELSE CONCAT('private:', LEAST(user_id, target_id), ':', GREATEST(user_id, target_id))
+END
+WHERE seq > 0 AND seq_scope IS NULL`
+
+// BackfillMessageSequences 為加入對話序號前的既有訊息補上序號。
+// 需在新版本開始配發序號前執行，否則舊訊息的序號會排在新訊息之後；重複執行時只處理尚未配發序號或尚未記錄所屬對話的訊息
+func BackfillMessageSequences(db *gorm.DB) error {
+	group, system := entities.MessageTypeGroup, entities.MessageTypeSystem
+	if err := db.Exec(backfillGroupSequences, group, system, group, system).Error; err != nil {
+		return err
+	}
+
+	private := entities.MessageTypePrivate
+	if err := db.Exec(backfillPrivateSequences, private, system, private, system).Error; err != nil {
+		return err
+	}
+
+	return db.Exec(backfillSequenceScopes, group, system).Error
+}
@@ -0,0 +1,215 @@
+package redis
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	draftKeyFormat   = "chat:drafts:%d"    // chat:drafts:userId，欄位為 type:targetId
+	draftDirtyKey    = "chat:drafts:dirty" // 待寫回資料庫的草稿，成員為 userId:type:targetId，分數為標記時間
+	draftDirtyFormat = "%d:%d:%d"
+
+	// draftLoadedField 標記用戶的草稿已從資料庫載入，沒有草稿的用戶也不必重複查詢
+	draftLoadedField = "_"
+	// 用戶的草稿保留 7 天，過期後從資料庫重新載入
+	draftTTL = 7 * 24 * time.Hour
+)
+
+// saveDraftScript 只在用戶的草稿已載入時更新，避免部分載入的快取遮蔽資料庫中的其他草稿
+var saveDraftScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if ARGV[2] == '' then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+else
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+end
+redis.call('EXPIRE', KEYS[1], ARGV[5])
+redis.call('ZADD', KEYS[2], ARGV[3], ARGV[4])
+return 1
+`)
+
+// loadDraftsScript 在用戶的草稿尚未載入時寫入載入標記與所有草稿
+var loadDraftsScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 0
+end
+redis.call('HSET', KEYS[1], '` + draftLoadedField + `', '1')
+for i = 2, #ARGV, 2 do
+	redis.call('HSET', KEYS[1], ARGV[i], ARGV[i + 1])
+end
+redis.call('EXPIRE', KEYS[1], ARGV[1])
+return 1
+`)
+
+// takeDirtyScript 以 ZREM 的結果認領待寫回的草稿，認領成功才讀取最新內容，已刪除的草稿返回空字串。
+// 草稿快取已過期時無法判斷內容，不返回
+var takeDirtyScript = redis.NewScript(`
+if redis.call('ZREM', KEYS[1], ARGV[1]) == 0 then
+	return false
+end
+if redis.call('EXISTS', KEYS[2]) == 0 then
+	return false
+end
+return redis.call('HGET', KEYS[2], ARGV[2]) or ''
+`)
+
+// DraftCacheRepository 以每個用戶一個 Redis Hash 保存草稿
+type DraftCacheRepository struct {
+	client *redis.Client
+}
+
+// NewDraftCache 創建新的草稿快取
+func NewDraftCache(client *redis.Client) cache.DraftCache {
+	return &DraftCacheRepository{
+		client: client,
+	}
+}
+
+func (r *DraftCacheRepository) GetDrafts(ctx context.Context, userID uint) ([]*entities.Draft, bool, error) {
+	key := fmt.Sprintf(draftKeyFormat, userID)
+
+	fields, err := r.client.HGetAll(ctx, key).Result()
+	if err != nil {
+		return nil, false, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "HGETALL",
+			"key":       key,
+		})
+	}
+	if len(fields) == 0 {
+		return nil, false, nil
+	}
+
+	drafts := make([]*entities.Draft, 0, len(fields)-1)
+	for field, data := range fields {
+		if field == draftLoadedField {
+			continue
+		}
+		var draft entities.Draft
+		if err := json.Unmarshal([]byte(data), &draft); err != nil {
+			continue
+		}
+		drafts = append(drafts, &draft)
+	}
+	return drafts, true, nil
+}
+
+func (r *DraftCacheRepository) LoadDrafts(ctx context.Context, userID uint, drafts []*entities.Draft) error {
+	key := fmt.Sprintf(draftKeyFormat, userID)
+
+	args := []interface{}{int(draftTTL / time.Second)}
+	for _, draft := range drafts {
+		data, err := json.Marshal(draft)
+		if err != nil {
+			return appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+				"message": "序列化草稿失敗",
+			})
+		}
+		args = append(args, draft.ConversationKey(), data)
+	}
+
+	if err := loadDraftsScript.Run(ctx, r.client, []string{key}, args...).Err(); err != nil && err != redis.Nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "EVALSHA",
+			"key":       key,
+		})
+	}
+	return nil
+}
+
+func (r *DraftCacheRepository) SaveDraft(ctx context.Context, draft *entities.Draft) (bool, error) {
+	key := fmt.Sprintf(draftKeyFormat, draft.UserID)
+
+	data := ""
+	if draft.Content != "" {
+		encoded, err := json.Marshal(draft)
+		if err != nil {
+			return false, appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+				"message": "序列化草稿失敗",
+			})
+		}
+		data = string(encoded)
+	}
+
+	saved, err := saveDraftScript.Run(ctx, r.client, []string{key, draftDirtyKey},
+		draft.ConversationKey(), data, draft.UpdatedAt.UnixMilli(), dirtyMember(draft), int(draftTTL/time.Second)).Int()
+	if err != nil {
+		return false, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "EVALSHA",
+			"key":       key,
+		})
+	}
+	return saved == 1, nil
+}
+
+func (r *DraftCacheRepository) TakeDirty(ctx context.Context, before time.Time, limit int) ([]*entities.Draft, error) {
+	members, err := r.client.ZRangeByScore(ctx, draftDirtyKey, &redis.ZRangeBy{
+		Min:   "-inf",
+		Max:   strconv.FormatInt(before.UnixMilli(), 10),
+		Count: int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "ZRANGEBYSCORE",
+			"key":       draftDirtyKey,
+		})
+	}
+
+	var drafts []*entities.Draft
+	for _, member := range members {
+		draft := &entities.Draft{}
+		if _, err := fmt.Sscanf(member, draftDirtyFormat, &draft.UserID, &draft.Type, &draft.TargetID); err != nil {
+			r.client.ZRem(ctx, draftDirtyKey, member)
+			continue
+		}
+
+		key := fmt.Sprintf(draftKeyFormat, draft.UserID)
+		data, err := takeDirtyScript.Run(ctx, r.client, []string{draftDirtyKey, key}, member, draft.ConversationKey()).Text()
+		if err == redis.Nil {
+			// 已被其他程序取出，或草稿快取已過期
+			continue
+		}
+		if err != nil {
+			return drafts, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+				"operation": "EVALSHA",
+				"key":       key,
+			})
+		}
+		if data != "" {
+			if err := json.Unmarshal([]byte(data), draft); err != nil {
+				continue
+			}
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+func (r *DraftCacheRepository) MarkDirty(ctx context.Context, draft *entities.Draft) error {
+	err := r.client.ZAdd(ctx, draftDirtyKey, redis.Z{
+		Score:  float64(time.Now().UnixMilli()),
+		Member: dirtyMember(draft),
+	}).Err()
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "ZADD",
+			"key":       draftDirtyKey,
+		})
+	}
+	return nil
+}
+
+func dirtyMember(draft *entities.Draft) string {
+	return fmt.Sprintf(draftDirtyFormat, draft.UserID, draft.Type, draft.TargetID)
+}
@@ -0,0 +1,83 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDraftCache_SaveRequiresLoadedDrafts(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewDraftCache(client)
+	ctx := context.Background()
+	draft := &entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, Content: "hi", UpdatedAt: time.Now()}
+
+	// 尚未載入時不寫入
+	saved, err := cache.SaveDraft(ctx, draft)
+	assert.NoError(t, err)
+	assert.False(t, saved)
+	_, found, err := cache.GetDrafts(ctx, 1)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// 沒有草稿的用戶也會被標記為已載入
+	assert.NoError(t, cache.LoadDrafts(ctx, 1, nil))
+	drafts, found, err := cache.GetDrafts(ctx, 1)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, drafts)
+
+	saved, err = cache.SaveDraft(ctx, draft)
+	assert.NoError(t, err)
+	assert.True(t, saved)
+
+	// 已載入時不被資料庫的舊資料覆寫
+	assert.NoError(t, cache.LoadDrafts(ctx, 1, []*entities.Draft{{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, Content: "old"}}))
+	drafts, _, err = cache.GetDrafts(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, drafts, 1) {
+		assert.Equal(t, "hi", drafts[0].Content)
+	}
+}
+
+func TestDraftCache_TakeDirty(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewDraftCache(client)
+	ctx := context.Background()
+	now := time.Now()
+	assert.NoError(t, cache.LoadDrafts(ctx, 1, nil))
+
+	_, err := cache.SaveDraft(ctx, &entities.Draft{UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, Content: "typing", UpdatedAt: now.Add(-time.Minute)})
+	assert.NoError(t, err)
+	_, err = cache.SaveDraft(ctx, &entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, Content: "x", UpdatedAt: now.Add(-time.Minute)})
+	assert.NoError(t, err)
+	_, err = cache.SaveDraft(ctx, &entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, UpdatedAt: now.Add(-time.Minute)})
+	assert.NoError(t, err)
+	_, err = cache.SaveDraft(ctx, &entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 3, Content: "recent", UpdatedAt: now})
+	assert.NoError(t, err)
+
+	drafts, err := cache.TakeDirty(ctx, now.Add(-time.Second), 10)
+	assert.NoError(t, err)
+	contents := map[uint]string{}
+	for _, draft := range drafts {
+		contents[draft.TargetID] = draft.Content
+	}
+	// 已清除的草稿以空內容返回，最近變更的草稿尚未取出
+	assert.Equal(t, map[uint]string{5: "typing", 2: ""}, contents)
+
+	// 每筆草稿只會被取出一次
+	drafts, err = cache.TakeDirty(ctx, now.Add(time.Second), 10)
+	assert.NoError(t, err)
+	if assert.Len(t, drafts, 1) {
+		assert.Equal(t, "recent", drafts[0].Content)
+		assert.Equal(t, entities.ConversationTypePrivate, drafts[0].Type)
+	}
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	linkPreviewKeyFormat = "chat:preview:%x" // chat:preview:sha1(url)
+
+	// 預覽保留 1 天，讀取失敗的結果保留 1 小時，避免反覆請求無法讀取的網址
+	linkPreviewTTL         = 24 * time.Hour
+	linkPreviewFailureTTL  = time.Hour
+	linkPreviewFailureMark = "null"
+)
+
+// LinkPreviewCacheRepository 以 Redis 字串保存網址的連結預覽
+type LinkPreviewCacheRepository struct {
+	client *redis.Client
+}
+
+// NewLinkPreviewCache 創建新的連結預覽快取
+func NewLinkPreviewCache(client *redis.Client) cache.LinkPreviewCache {
+	return &LinkPreviewCacheRepository{
+		client: client,
+	}
+}
+
+func linkPreviewKey(url string) string {
+	return fmt.Sprintf(linkPreviewKeyFormat, sha1.Sum([]byte(url)))
+}
+
+func (r *LinkPreviewCacheRepository) GetLinkPreview(ctx context.Context, url string) (*entities.LinkPreview, bool, error) {
+	key := linkPreviewKey(url)
+
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "GET",
+			"key":       key,
+		})
+	}
+	if string(data) == linkPreviewFailureMark {
+		return nil, true, nil
+	}
+
+	var preview entities.LinkPreview
+	if err := json.Unmarshal(data, &preview); err != nil {
+		return nil, false, appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+			"message": "解析連結預覽快取失敗",
+			"key":     key,
+		})
+	}
+	return &preview, true, nil
+}
+
+func (r *LinkPreviewCacheRepository) StoreLinkPreview(ctx context.Context, url string, preview *entities.LinkPreview) error {
+	key := linkPreviewKey(url)
+
+	data, ttl := []byte(linkPreviewFailureMark), linkPreviewFailureTTL
+	if preview != nil {
+		encoded, err := json.Marshal(preview)
+		if err != nil {
+			return appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+				"message": "序列化連結預覽失敗",
+			})
+		}
+		data, ttl = encoded, linkPreviewTTL
+	}
+
+	if err := r.client.Set(ctx, key, data, ttl).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "SET",
+			"key":       key,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkPreviewCache_StoreAndGet(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewLinkPreviewCache(client)
+	ctx := context.Background()
+
+	_, found, err := cache.GetLinkPreview(ctx, "https://example.com")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	preview := &entities.LinkPreview{URL: "https://example.com", Title: "Example", Image: "https://example.com/a.png"}
+	assert.NoError(t, cache.StoreLinkPreview(ctx, "https://example.com", preview))
+	cached, found, err := cache.GetLinkPreview(ctx, "https://example.com")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, preview, cached)
+	assert.Equal(t, linkPreviewTTL, client.TTL(ctx, linkPreviewKey("https://example.com")).Val())
+
+	// 讀取失敗的結果以較短的時間快取
+	assert.NoError(t, cache.StoreLinkPreview(ctx, "https://down.example", nil))
+	cached, found, err = cache.GetLinkPreview(ctx, "https://down.example")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Nil(t, cached)
+	assert.Equal(t, linkPreviewFailureTTL, client.TTL(ctx, linkPreviewKey("https://down.example")).Val())
+}
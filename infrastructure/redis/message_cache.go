@@ -8,6 +8,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -15,20 +16,28 @@ import (
 
 const (
 	// 訊息快取的 key 格式
-	messageKeyFormat  = "chat:msg:%d:%d"  // chat:msg:fromUserId:toUserId
-	roomKeyFormat     = "chat:room:%d"    // chat:room:roomId
-	userMsgListFormat = "chat:msglist:%d" // chat:msglist:userId
-	messagePattern    = "chat:msg:*:*"    // 用於搜尋所有私人訊息
-	roomPattern       = "chat:room:*"     // 用於搜尋所有群組訊息
-	userListPattern   = "chat:msglist:*"  // 用於搜尋所有用戶列表
+	messageKeyFormat  = "chat:msg:%d:%d"   // chat:msg:smallerUserId:largerUserId，雙方共用同一列表
+	roomKeyFormat     = "chat:room:%d"     // chat:room:roomId
+	userMsgListFormat = "chat:msglist:%d"  // chat:msglist:userId
+	messagePattern    = "chat:msg:*:*"     // 用於搜尋所有私人訊息
+	roomPattern       = "chat:room:*"      // 用於搜尋所有群組訊息
+	userListPattern   = "chat:msglist:*"   // 用於搜尋所有用戶列表
+	dedupKeyFormat    = "chat:dedup:%d:%s" // chat:dedup:userId:clientMsgId
 
 	// 快取過期時間
 	messageTTL     = 24 * time.Hour // 單條訊息快取 24 小時
 	roomTTL        = 48 * time.Hour // 群組訊息快取 48 小時
 	messageListTTL = 72 * time.Hour // 訊息列表快取 72 小時
+	dedupTTL       = 24 * time.Hour // 去重記錄保留 24 小時，超過後由資料庫唯一索引兜底
 
 	// 訊息列表的最大長度
 	maxMessageListLength = 100
+
+	// SCAN 每批次建議返回的鍵數量
+	scanBatchSize = 100
+
+	// WATCH 交易衝突時的最大重試次數
+	maxWatchRetries = 3
 )
 
 // RedisCacheRepository Redis緩存儲存庫，使用新的錯誤處理系統
@@ -43,11 +52,19 @@ func NewMessageCacheRepository(client *redis.Client) cache.MessageCacheRepositor
 	}
 }
 
+// privateMessageKey 產生私聊快取的 key，不論方向都對應到同一個列表
+func privateMessageKey(userID, targetID uint) string {
+	if userID > targetID {
+		userID, targetID = targetID, userID
+	}
+	return fmt.Sprintf(messageKeyFormat, userID, targetID)
+}
+
 func (r *RedisCacheRepository) StorePrivateMessage(ctx context.Context, message *entities.Message) error {
-	key := fmt.Sprintf(messageKeyFormat, message.UserId, message.TargetId)
+	key := privateMessageKey(message.UserId, message.TargetId)
 
 	// 序列化消息
-	data, err := json.Marshal(message)
+	data, err := marshalMessage(message)
 	if err != nil {
 		return appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
 			"message":   "序列化私人訊息失敗",
@@ -92,7 +109,7 @@ func (r *RedisCacheRepository) StoreGroupMessage(ctx context.Context, message *e
 	key := fmt.Sprintf(roomKeyFormat, message.RoomID)
 
 	// 序列化消息
-	data, err := json.Marshal(message)
+	data, err := marshalMessage(message)
 	if err != nil {
 		return appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
 			"message":   "序列化群組訊息失敗",
@@ -133,40 +150,195 @@ func (r *RedisCacheRepository) StoreGroupMessage(ctx context.Context, message *e
 }
 
 func (r *RedisCacheRepository) GetPrivateMessages(ctx context.Context, fromUserID, toUserID uint) ([]*entities.Message, error) {
-	key := fmt.Sprintf(messageKeyFormat, fromUserID, toUserID)
+	key := privateMessageKey(fromUserID, toUserID)
 
-	// 獲取所有消息
-	data, err := r.client.LRange(ctx, key, 0, -1).Result()
-	if err == redis.Nil {
-		return []*entities.Message{}, nil
+	messages, err := r.readMessages(ctx, key)
+	if err != nil {
+		return nil, err
 	}
+
+	return chronological(messages), nil
+}
+
+func (r *RedisCacheRepository) GetGroupMessages(ctx context.Context, roomID uint) ([]*entities.Message, error) {
+	key := fmt.Sprintf(roomKeyFormat, roomID)
+
+	messages, err := r.readMessages(ctx, key)
 	if err != nil {
-		return nil, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
-			"operation": "LRANGE",
+		return nil, err
+	}
+
+	return chronological(messages), nil
+}
+
+func (r *RedisCacheRepository) GetPrivateMessagesPage(ctx context.Context, fromUserID, toUserID uint, query entities.HistoryQuery) (*entities.MessagePage, bool, error) {
+	messages, err := r.readMessages(ctx, privateMessageKey(fromUserID, toUserID))
+	if err != nil {
+		return nil, false, err
+	}
+
+	page, hit := pageFromCache(messages, query)
+	return page, hit, nil
+}
+
+func (r *RedisCacheRepository) GetGroupMessagesPage(ctx context.Context, roomID uint, query entities.HistoryQuery) (*entities.MessagePage, bool, error) {
+	messages, err := r.readMessages(ctx, fmt.Sprintf(roomKeyFormat, roomID))
+	if err != nil {
+		return nil, false, err
+	}
+
+	page, hit := pageFromCache(messages, query)
+	return page, hit, nil
+}
+
+func (r *RedisCacheRepository) WarmPrivateMessages(ctx context.Context, userID, targetID uint, messages []*entities.Message) error {
+	return r.replaceMessages(ctx, privateMessageKey(userID, targetID), messages, messageTTL)
+}
+
+func (r *RedisCacheRepository) WarmGroupMessages(ctx context.Context, roomID uint, messages []*entities.Message) error {
+	return r.replaceMessages(ctx, fmt.Sprintf(roomKeyFormat, roomID), messages, roomTTL)
+}
+
+func (r *RedisCacheRepository) UpdateMessage(ctx context.Context, message *entities.Message) error {
+	key := messageCacheKey(message)
+
+	data, err := marshalMessage(message)
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+			"message":   "序列化訊息失敗",
+			"messageId": message.ID,
+		})
+	}
+
+	// 以 WATCH 保證找到的位置在寫入前沒有因新訊息 LPUSH 而移動，衝突時重試
+	update := func(tx *redis.Tx) error {
+		index, err := findMessageIndex(ctx, tx, key, message.ID)
+		if err != nil || index < 0 {
+			return err
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.LSet(ctx, key, index, data)
+			return nil
+		})
+		return err
+	}
+
+	for i := 0; i < maxWatchRetries; i++ {
+		err = r.client.Watch(ctx, update, key)
+		if err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "LSET",
 			"key":       key,
-			"fromUser":  fromUserID,
-			"toUser":    toUserID,
+			"messageId": message.ID,
 		})
 	}
 
-	messages := make([]*entities.Message, 0, len(data))
-	for _, msgData := range data {
-		var msg entities.Message
-		if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
-			// 記錄解析錯誤但繼續處理其他消息
-			appErrors.WithDevMessage(err, fmt.Sprintf("跳過無法解析的消息: %v", err)).LogError()
+	return nil
+}
+
+func (r *RedisCacheRepository) InvalidateMessages(ctx context.Context, message *entities.Message) error {
+	key := messageCacheKey(message)
+
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "DEL",
+			"key":       key,
+		})
+	}
+	return nil
+}
+
+func (r *RedisCacheRepository) RemoveMessages(ctx context.Context, messages []*entities.Message) error {
+	// 依所在的快取列表分組，每個列表只讀取一次
+	idsByKey := make(map[string]map[uint]bool)
+	for _, message := range messages {
+		key := messageCacheKey(message)
+		if idsByKey[key] == nil {
+			idsByKey[key] = make(map[uint]bool)
+		}
+		idsByKey[key][message.ID] = true
+	}
+
+	for key, ids := range idsByKey {
+		data, err := r.client.LRange(ctx, key, 0, -1).Result()
+		if err != nil && err != redis.Nil {
+			return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+				"operation": "LRANGE",
+				"key":       key,
+			})
+		}
+
+		pipe := r.client.TxPipeline()
+		matched := 0
+		for _, item := range data {
+			var msg struct {
+				ID uint `json:"id"`
+			}
+			if err := json.Unmarshal([]byte(item), &msg); err != nil || !ids[msg.ID] {
+				continue
+			}
+			// 以原始內容比對刪除，不受期間新加入的訊息影響
+			pipe.LRem(ctx, key, 0, item)
+			matched++
+		}
+		if matched == 0 {
 			continue
 		}
-		messages = append(messages, &msg)
+		if _, err := pipe.Exec(ctx); err != nil {
+			return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+				"operation": "LREM",
+				"key":       key,
+			})
+		}
 	}
 
-	return messages, nil
+	return nil
 }
 
-func (r *RedisCacheRepository) GetGroupMessages(ctx context.Context, roomID uint) ([]*entities.Message, error) {
-	key := fmt.Sprintf(roomKeyFormat, roomID)
+// marshalMessage 序列化要寫入快取的訊息，表情回應與引用預覽在查詢時另行附加而不寫入快取
+func marshalMessage(message *entities.Message) ([]byte, error) {
+	cached := *message
+	cached.Reactions = nil
+	cached.ReplyTo = nil
+	return json.Marshal(&cached)
+}
 
-	// 獲取所有消息
+// messageCacheKey 返回訊息所在的快取列表 key
+func messageCacheKey(message *entities.Message) string {
+	if message.IsGroupConversation() {
+		return fmt.Sprintf(roomKeyFormat, message.RoomID)
+	}
+	return privateMessageKey(message.UserId, message.TargetId)
+}
+
+// findMessageIndex 返回訊息在快取列表中的位置，找不到時返回 -1
+func findMessageIndex(ctx context.Context, tx *redis.Tx, key string, messageID uint) (int64, error) {
+	data, err := tx.LRange(ctx, key, 0, -1).Result()
+	if err != nil && err != redis.Nil {
+		return -1, err
+	}
+
+	for i, msgData := range data {
+		var msg struct {
+			ID uint `json:"id"`
+		}
+		if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
+			continue
+		}
+		if msg.ID == messageID {
+			return int64(i), nil
+		}
+	}
+	return -1, nil
+}
+
+// readMessages 讀取快取列表中的所有訊息，依列表順序（由新到舊）返回。
+// 已到自動消失時間、尚未被背景工作移除的訊息不返回
+func (r *RedisCacheRepository) readMessages(ctx context.Context, key string) ([]*entities.Message, error) {
 	data, err := r.client.LRange(ctx, key, 0, -1).Result()
 	if err == redis.Nil {
 		return []*entities.Message{}, nil
@@ -175,16 +347,19 @@ func (r *RedisCacheRepository) GetGroupMessages(ctx context.Context, roomID uint
 		return nil, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
 			"operation": "LRANGE",
 			"key":       key,
-			"roomId":    roomID,
 		})
 	}
 
+	now := time.Now()
 	messages := make([]*entities.Message, 0, len(data))
 	for _, msgData := range data {
 		var msg entities.Message
 		if err := json.Unmarshal([]byte(msgData), &msg); err != nil {
 			// 記錄解析錯誤但繼續處理其他消息
-			appErrors.WithDevMessage(err, fmt.Sprintf("跳過無法解析的群組消息: %v", err)).LogError()
+			appErrors.WithDevMessage(err, fmt.Sprintf("跳過無法解析的消息: %v, key: %s", err, key)).LogError()
+			continue
+		}
+		if msg.IsExpired(now) {
 			continue
 		}
 		messages = append(messages, &msg)
@@ -193,17 +368,155 @@ func (r *RedisCacheRepository) GetGroupMessages(ctx context.Context, roomID uint
 	return messages, nil
 }
 
-func (r *RedisCacheRepository) GetUserMessageList(ctx context.Context, userID uint) ([]*entities.Message, error) {
-	pattern := fmt.Sprintf("chat:msg:%d:*", userID)
-	keys, err := r.client.Keys(ctx, pattern).Result()
-	if err != nil {
-		return nil, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
-			"operation": "KEYS",
-			"pattern":   pattern,
-			"userId":    userID,
+// replaceMessagesScript 以資料庫讀出的訊息重建快取列表，列表中已有比 ARGV[3]、ARGV[4]（序號、ID）更新的訊息時不覆蓋，
+// 避免讀取資料庫後才寫入快取的新訊息被刪除。ARGV[1] 為過期毫秒數，ARGV[2] 為列表最大長度，之後為由舊到新的訊息
+var replaceMessagesScript = redis.NewScript(`
+local newestSeq = tonumber(ARGV[3])
+local newestID = tonumber(ARGV[4])
+for _, item in ipairs(redis.call('LRANGE', KEYS[1], 0, -1)) do
+	local ok, msg = pcall(cjson.decode, item)
+	if ok and type(msg) == 'table' then
+		local seq = tonumber(msg['seq']) or 0
+		local id = tonumber(msg['id']) or 0
+		if seq > newestSeq or (seq == newestSeq and id > newestID) then
+			return 0
+		end
+	end
+end
+redis.call('DEL', KEYS[1])
+if #ARGV > 4 then
+	redis.call('LPUSH', KEYS[1], unpack(ARGV, 5))
+	redis.call('LTRIM', KEYS[1], 0, tonumber(ARGV[2]) - 1)
+	redis.call('PEXPIRE', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// replaceMessages 以給定的訊息重建快取列表，messages 依時間由舊到新排列。
+// 重建在背景執行，期間發送的訊息可能已寫入快取，此時保留現有的列表而不覆蓋
+func (r *RedisCacheRepository) replaceMessages(ctx context.Context, key string, messages []*entities.Message, ttl time.Duration) error {
+	var newest entities.Message
+	args := make([]interface{}, 0, 4+len(messages))
+	args = append(args, ttl.Milliseconds(), maxMessageListLength, 0, 0)
+	for _, message := range messages {
+		data, err := marshalMessage(message)
+		if err != nil {
+			return appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+				"message":   "序列化訊息失敗",
+				"messageId": message.ID,
+			})
+		}
+		// 依序 LPUSH 後，最新的訊息位於列表頭部，與 Store* 的寫入方式一致
+		args = append(args, data)
+		if sequencedBefore(&newest, message) {
+			newest = *message
+		}
+	}
+	args[2], args[3] = newest.Seq, newest.ID
+
+	if err := replaceMessagesScript.Run(ctx, r.client, []string{key}, args...).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "REPLACE",
+			"key":       key,
+			"count":     len(messages),
 		})
 	}
 
+	return nil
+}
+
+// chronological 將快取中的訊息依對話序號由舊到新排列。
+// 快取以 LPUSH 寫入，併發發送時寫入順序可能與序號不同，因此不能只反轉
+func chronological(messages []*entities.Message) []*entities.Message {
+	result := make([]*entities.Message, len(messages))
+	copy(result, messages)
+	sort.SliceStable(result, func(i, j int) bool { return sequencedBefore(result[i], result[j]) })
+	return result
+}
+
+// sequencedBefore 判斷 a 是否排在 b 之前，序號相同（例如序號配發前的舊訊息）時以ID排序
+func sequencedBefore(a, b *entities.Message) bool {
+	if a.Seq != b.Seq {
+		return a.Seq < b.Seq
+	}
+	return a.ID < b.ID
+}
+
+// inQueryDirection 返回判斷訊息是否位於游標查詢方向上的函式，序號游標優先於ID游標
+func inQueryDirection(query entities.HistoryQuery) func(*entities.Message) bool {
+	switch {
+	case query.AfterSeq > 0:
+		return func(msg *entities.Message) bool { return msg.Seq > query.AfterSeq }
+	case query.BeforeSeq > 0:
+		return func(msg *entities.Message) bool { return msg.Seq < query.BeforeSeq }
+	case query.AfterID > 0:
+		return func(msg *entities.Message) bool { return msg.ID > query.AfterID }
+	case query.BeforeID > 0:
+		return func(msg *entities.Message) bool { return msg.ID < query.BeforeID }
+	}
+	return func(*entities.Message) bool { return true }
+}
+
+// pageFromCache 嘗試以快取中的訊息回答分頁查詢。
+// 快取只保存對話中最新的一段連續訊息，因此只有在結果能確定完整時才算命中：
+// 往新翻頁時快取需涵蓋游標位置；往舊翻頁或取最新一頁時需取得多於一頁的訊息。
+func pageFromCache(messages []*entities.Message, query entities.HistoryQuery) (*entities.MessagePage, bool) {
+	query = query.Normalize()
+	if len(messages) == 0 {
+		return nil, false
+	}
+
+	oldestFirst := chronological(messages)
+	matches := inQueryDirection(query)
+
+	if query.IsForward() {
+		if matches(oldestFirst[0]) {
+			return nil, false
+		}
+		var rows []*entities.Message
+		for _, msg := range oldestFirst {
+			if matches(msg) {
+				rows = append(rows, msg)
+				if len(rows) > query.Limit {
+					break
+				}
+			}
+		}
+		return entities.NewMessagePage(rows, query), true
+	}
+
+	var rows []*entities.Message
+	for i := len(oldestFirst) - 1; i >= 0; i-- {
+		if !matches(oldestFirst[i]) {
+			continue
+		}
+		rows = append(rows, oldestFirst[i])
+		if len(rows) > query.Limit {
+			return entities.NewMessagePage(rows, query), true
+		}
+	}
+
+	return nil, false
+}
+
+func (r *RedisCacheRepository) GetUserMessageList(ctx context.Context, userID uint) ([]*entities.Message, error) {
+	// 私聊列表的 key 以較小的用戶ID在前，需同時搜尋兩個位置
+	var keys []string
+	for _, pattern := range []string{fmt.Sprintf("chat:msg:%d:*", userID), fmt.Sprintf("chat:msg:*:%d", userID)} {
+		// 以 SCAN 逐批迭代，避免 KEYS 在鍵數量大時阻塞 Redis
+		iter := r.client.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+		for iter.Next(ctx) {
+			keys = append(keys, iter.Val())
+		}
+		if err := iter.Err(); err != nil {
+			return nil, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+				"operation": "SCAN",
+				"pattern":   pattern,
+				"userId":    userID,
+			})
+		}
+	}
+
 	var allMessages []*entities.Message
 	for _, key := range keys {
 		data, err := r.client.LRange(ctx, key, 0, -1).Result()
@@ -227,6 +540,37 @@ func (r *RedisCacheRepository) GetUserMessageList(ctx context.Context, userID ui
 	return allMessages, nil
 }
 
+func (r *RedisCacheRepository) GetClientMessageID(ctx context.Context, userID uint, clientMsgID string) (uint, error) {
+	key := fmt.Sprintf(dedupKeyFormat, userID, clientMsgID)
+
+	id, err := r.client.Get(ctx, key).Uint64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "GET",
+			"key":       key,
+		})
+	}
+
+	return uint(id), nil
+}
+
+func (r *RedisCacheRepository) StoreClientMessageID(ctx context.Context, userID uint, clientMsgID string, messageID uint) error {
+	key := fmt.Sprintf(dedupKeyFormat, userID, clientMsgID)
+
+	if err := r.client.Set(ctx, key, messageID, dedupTTL).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "SET",
+			"key":       key,
+			"messageId": messageID,
+		})
+	}
+
+	return nil
+}
+
 func (r *RedisCacheRepository) CleanExpiredMessages(ctx context.Context) error {
 	// 清理私人訊息
 	if err := r.cleanExpiredKeys(ctx, messagePattern); err != nil {
@@ -238,19 +582,19 @@ func (r *RedisCacheRepository) CleanExpiredMessages(ctx context.Context) error {
 		return err
 	}
 
+	// 清理釘選列表
+	if err := r.cleanExpiredKeys(ctx, pinPattern); err != nil {
+		return err
+	}
+
 	return nil
 }
 
 func (r *RedisCacheRepository) cleanExpiredKeys(ctx context.Context, pattern string) error {
-	keys, err := r.client.Keys(ctx, pattern).Result()
-	if err != nil {
-		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
-			"operation": "KEYS",
-			"pattern":   pattern,
-		})
-	}
-
-	for _, key := range keys {
+	// 以 SCAN 逐批迭代，避免 KEYS 在鍵數量大時阻塞 Redis
+	iter := r.client.Scan(ctx, 0, pattern, scanBatchSize).Iterator()
+	for iter.Next(ctx) {
+		key := iter.Val()
 		if err := r.client.Del(ctx, key).Err(); err != nil {
 			return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
 				"operation": "DEL",
@@ -258,6 +602,12 @@ func (r *RedisCacheRepository) cleanExpiredKeys(ctx context.Context, pattern str
 			})
 		}
 	}
+	if err := iter.Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "SCAN",
+			"pattern":   pattern,
+		})
+	}
 
 	return nil
 }
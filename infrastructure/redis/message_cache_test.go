@@ -135,3 +135,233 @@ func TestMessageCache_GetUserMessageList(t *testing.T) {
 		assert.Equal(t, message.Content, messages[0].Content)
 	}
 }
+
+func TestMessageCache_GetGroupMessagesPage(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	for i := uint(1); i <= 5; i++ {
+		err := cache.StoreGroupMessage(ctx, &entities.Message{
+			ID:        i,
+			UserId:    1,
+			RoomID:    1,
+			Content:   "msg",
+			Type:      entities.MessageTypeGroup,
+			CreatedAt: time.Now(),
+		})
+		assert.NoError(t, err)
+	}
+
+	// 快取足以回答的最新一頁
+	page, hit, err := cache.GetGroupMessagesPage(ctx, 1, entities.HistoryQuery{Limit: 2})
+	assert.NoError(t, err)
+	if assert.True(t, hit) && assert.Len(t, page.Messages, 2) {
+		assert.Equal(t, uint(4), page.Messages[0].ID)
+		assert.Equal(t, uint(5), page.Messages[1].ID)
+		assert.True(t, page.HasMore)
+	}
+
+	// 往前翻頁，快取不足以判斷是否還有更舊的訊息時應回源資料庫
+	_, hit, err = cache.GetGroupMessagesPage(ctx, 1, entities.HistoryQuery{BeforeID: 3, Limit: 2})
+	assert.NoError(t, err)
+	assert.False(t, hit)
+}
+
+func TestMessageCache_PageOrderedBySeq(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	// 併發發送時寫入快取的順序可能與序號不同
+	for _, seq := range []uint64{1, 2, 4, 3, 5} {
+		err := cache.StoreGroupMessage(ctx, &entities.Message{ID: uint(seq), RoomID: 1, Seq: seq, Type: entities.MessageTypeGroup, Content: "msg"})
+		assert.NoError(t, err)
+	}
+
+	page, hit, err := cache.GetGroupMessagesPage(ctx, 1, entities.HistoryQuery{Limit: 3})
+	assert.NoError(t, err)
+	if assert.True(t, hit) && assert.Len(t, page.Messages, 3) {
+		assert.Equal(t, []uint64{3, 4, 5}, []uint64{page.Messages[0].Seq, page.Messages[1].Seq, page.Messages[2].Seq})
+	}
+
+	// 以序號補齊漏收的訊息
+	page, hit, err = cache.GetGroupMessagesPage(ctx, 1, entities.HistoryQuery{AfterSeq: 2, Limit: 10})
+	assert.NoError(t, err)
+	if assert.True(t, hit) && assert.Len(t, page.Messages, 3) {
+		assert.Equal(t, uint64(3), page.Messages[0].Seq)
+		assert.False(t, page.HasMore)
+	}
+
+	// 往前翻頁，快取不足以判斷是否還有更舊的訊息時應回源資料庫
+	_, hit, err = cache.GetGroupMessagesPage(ctx, 1, entities.HistoryQuery{BeforeSeq: 2, Limit: 10})
+	assert.NoError(t, err)
+	assert.False(t, hit)
+}
+
+func TestMessageCache_UpdateMessage(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	for i := uint(1); i <= 3; i++ {
+		err := cache.StorePrivateMessage(ctx, &entities.Message{ID: i, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Content: "msg"})
+		assert.NoError(t, err)
+	}
+
+	err := cache.UpdateMessage(ctx, &entities.Message{ID: 2, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Content: "edited"})
+	assert.NoError(t, err)
+
+	// 不在快取中的訊息不會被寫入
+	err = cache.UpdateMessage(ctx, &entities.Message{ID: 9, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Content: "missing"})
+	assert.NoError(t, err)
+
+	messages, err := cache.GetPrivateMessages(ctx, 1, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 3) {
+		assert.Equal(t, "msg", messages[0].Content)
+		assert.Equal(t, "edited", messages[1].Content)
+		assert.Equal(t, "msg", messages[2].Content)
+	}
+}
+
+func TestMessageCache_RemoveMessages(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	var private []*entities.Message
+	for i := uint(1); i <= 3; i++ {
+		message := &entities.Message{ID: i, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Content: "msg"}
+		assert.NoError(t, cache.StorePrivateMessage(ctx, message))
+		private = append(private, message)
+	}
+	group := &entities.Message{ID: 4, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup, Content: "group"}
+	assert.NoError(t, cache.StoreGroupMessage(ctx, group))
+
+	err := cache.RemoveMessages(ctx, []*entities.Message{private[0], private[2], group})
+	assert.NoError(t, err)
+
+	messages, err := cache.GetPrivateMessages(ctx, 1, 2)
+	assert.NoError(t, err)
+	if assert.Len(t, messages, 1) {
+		assert.Equal(t, uint(2), messages[0].ID)
+	}
+
+	groupMessages, err := cache.GetGroupMessages(ctx, 5)
+	assert.NoError(t, err)
+	assert.Empty(t, groupMessages)
+}
+
+// 測試清除對話的快取列表後，查詢最新一頁不再命中快取，其他對話不受影響
+func TestMessageCache_InvalidateMessages(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	private := &entities.Message{ID: 1, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Content: "msg"}
+	assert.NoError(t, cache.StorePrivateMessage(ctx, private))
+	group := &entities.Message{ID: 2, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup, Content: "group"}
+	assert.NoError(t, cache.StoreGroupMessage(ctx, group))
+
+	assert.NoError(t, cache.InvalidateMessages(ctx, &entities.Message{UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate}))
+
+	_, hit, err := cache.GetPrivateMessagesPage(ctx, 1, 2, entities.HistoryQuery{Limit: 20})
+	assert.NoError(t, err)
+	assert.False(t, hit)
+
+	groupMessages, err := cache.GetGroupMessages(ctx, 5)
+	assert.NoError(t, err)
+	assert.Len(t, groupMessages, 1)
+}
+
+func TestMessageCache_WarmGroupMessages(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	rows := func(seqs ...uint64) []*entities.Message {
+		var messages []*entities.Message
+		for _, seq := range seqs {
+			messages = append(messages, &entities.Message{ID: uint(seq), RoomID: 1, Seq: seq, Type: entities.MessageTypeGroup, Content: "msg"})
+		}
+		return messages
+	}
+
+	// 讀取資料庫後才發送的訊息已寫入快取，不可被較舊的結果覆蓋
+	assert.NoError(t, cache.StoreGroupMessage(ctx, rows(5)[0]))
+	assert.NoError(t, cache.WarmGroupMessages(ctx, 1, rows(1, 2, 3, 4)))
+	messages, err := cache.GetGroupMessages(ctx, 1)
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+
+	// 資料庫的結果已包含快取中的訊息時重建列表，多取一筆的最新一頁可直接命中
+	assert.NoError(t, cache.WarmGroupMessages(ctx, 1, rows(1, 2, 3, 4, 5)))
+	page, hit, err := cache.GetGroupMessagesPage(ctx, 1, entities.HistoryQuery{Limit: 4})
+	assert.NoError(t, err)
+	if assert.True(t, hit) && assert.Len(t, page.Messages, 4) {
+		assert.Equal(t, uint64(2), page.Messages[0].Seq)
+		assert.True(t, page.HasMore)
+	}
+	assert.True(t, client.TTL(ctx, "chat:room:1").Val() > 0)
+}
+
+func TestMessageCache_CleanExpiredMessages(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	// 超過一個 SCAN 批次的鍵也要全部清除
+	for i := uint(1); i <= scanBatchSize+10; i++ {
+		assert.NoError(t, cache.StoreGroupMessage(ctx, &entities.Message{ID: i, RoomID: i, Type: entities.MessageTypeGroup, Content: "msg"}))
+	}
+	assert.NoError(t, cache.StorePrivateMessage(ctx, &entities.Message{ID: 1, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Content: "msg"}))
+	client.Set(ctx, "chat:pins:room:1", "[]", 0)
+	client.Set(ctx, "chat:dedup:1:abc", 1, 0)
+
+	assert.NoError(t, cache.CleanExpiredMessages(ctx))
+
+	keys, err := client.Keys(ctx, "*").Result()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"chat:dedup:1:abc"}, keys)
+}
+
+func TestMessageCache_SkipsExpiredMessages(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewMessageCacheRepository(client)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-time.Minute)
+	pending := time.Now().Add(time.Hour)
+	for seq := uint64(1); seq <= 4; seq++ {
+		msg := &entities.Message{ID: uint(seq), RoomID: 1, Seq: seq, Type: entities.MessageTypeGroup, Content: "msg", ExpiresAt: &pending}
+		if seq == 3 {
+			msg.ExpiresAt = &expired
+		}
+		assert.NoError(t, cache.StoreGroupMessage(ctx, msg))
+	}
+
+	// 背景工作尚未移除的過期訊息不會出現在快取的結果中
+	page, hit, err := cache.GetGroupMessagesPage(ctx, 1, entities.HistoryQuery{Limit: 2})
+	assert.NoError(t, err)
+	if assert.True(t, hit) && assert.Len(t, page.Messages, 2) {
+		assert.Equal(t, uint(2), page.Messages[0].ID)
+		assert.Equal(t, uint(4), page.Messages[1].ID)
+	}
+}
@@ -0,0 +1,124 @@
+package redis
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	offlineKeyFormat = "chat:offline:%d" // chat:offline:userId
+
+	// 離線事件保留 7 天，每個用戶最多保留 500 筆
+	offlineTTL            = 7 * 24 * time.Hour
+	maxOfflineQueueLength = 500
+)
+
+// OfflineEventRepository 以 Redis 列表暫存離線事件
+type OfflineEventRepository struct {
+	client *redis.Client
+}
+
+// NewOfflineEventCache 創建新的離線事件快取
+func NewOfflineEventCache(client *redis.Client) cache.OfflineEventCache {
+	return &OfflineEventRepository{
+		client: client,
+	}
+}
+
+func (r *OfflineEventRepository) PushOfflineEvent(ctx context.Context, userID uint, payload []byte) error {
+	key := fmt.Sprintf(offlineKeyFormat, userID)
+
+	pipe := r.client.TxPipeline()
+	pipe.RPush(ctx, key, payload)
+	// 只保留最新的事件，避免長期離線的用戶佇列無限增長
+	pipe.LTrim(ctx, key, -maxOfflineQueueLength, -1)
+	pipe.Expire(ctx, key, offlineTTL)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "RPUSH",
+			"key":       key,
+			"userId":    userID,
+		})
+	}
+
+	return nil
+}
+
+func (r *OfflineEventRepository) PopOfflineEvents(ctx context.Context, userID uint) ([][]byte, error) {
+	key := fmt.Sprintf(offlineKeyFormat, userID)
+
+	pipe := r.client.TxPipeline()
+	rangeCmd := pipe.LRange(ctx, key, 0, -1)
+	pipe.Del(ctx, key)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "LRANGE",
+			"key":       key,
+			"userId":    userID,
+		})
+	}
+
+	data := rangeCmd.Val()
+	events := make([][]byte, 0, len(data))
+	for _, item := range data {
+		events = append(events, []byte(item))
+	}
+
+	return events, nil
+}
+
+func (r *OfflineEventRepository) RemoveMessageEvents(ctx context.Context, userID uint, messageIDs ...uint) error {
+	key := fmt.Sprintf(offlineKeyFormat, userID)
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	removed := make(map[uint]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		removed[id] = true
+	}
+
+	data, err := r.client.LRange(ctx, key, 0, -1).Result()
+	if err != nil && err != redis.Nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "LRANGE",
+			"key":       key,
+			"userId":    userID,
+		})
+	}
+
+	pipe := r.client.TxPipeline()
+	matched := 0
+	for _, item := range data {
+		var event struct {
+			Data struct {
+				ID uint `json:"id"`
+			} `json:"data"`
+		}
+		if err := json.Unmarshal([]byte(item), &event); err != nil || !removed[event.Data.ID] {
+			continue
+		}
+		// 以原始內容比對刪除，不受期間新加入或被取出的事件影響
+		pipe.LRem(ctx, key, 0, item)
+		matched++
+	}
+	if matched == 0 {
+		return nil
+	}
+
+	if _, err := pipe.Exec(ctx); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "LREM",
+			"key":       key,
+			"userId":    userID,
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOfflineEventCache_PushAndPop(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewOfflineEventCache(client)
+	ctx := context.Background()
+
+	assert.NoError(t, cache.PushOfflineEvent(ctx, 1, []byte(`{"event":"a"}`)))
+	assert.NoError(t, cache.PushOfflineEvent(ctx, 1, []byte(`{"event":"b"}`)))
+
+	// 依加入順序取出
+	events, err := cache.PopOfflineEvents(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, events, 2) {
+		assert.Equal(t, `{"event":"a"}`, string(events[0]))
+		assert.Equal(t, `{"event":"b"}`, string(events[1]))
+	}
+
+	// 取出後佇列應被清空
+	events, err = cache.PopOfflineEvents(ctx, 1)
+	assert.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestOfflineEventCache_RemoveMessageEvents(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewOfflineEventCache(client)
+	ctx := context.Background()
+
+	events := []string{
+		`{"event":"message.new","data":{"id":1,"content":"a"}}`,
+		`{"event":"message.new","data":{"id":2,"content":"secret"}}`,
+		`{"event":"message.edited","data":{"id":2,"content":"secret!"}}`,
+	}
+	for _, event := range events {
+		assert.NoError(t, cache.PushOfflineEvent(ctx, 1, []byte(event)))
+	}
+
+	assert.NoError(t, cache.RemoveMessageEvents(ctx, 1, 2))
+
+	remaining, err := cache.PopOfflineEvents(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, remaining, 1) {
+		assert.Equal(t, events[0], string(remaining[0]))
+	}
+}
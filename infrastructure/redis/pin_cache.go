@@ -0,0 +1,84 @@
+package redis
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// 釘選列表與訊息列表使用相同的過期時間，並在清理訊息快取時一併刪除。
+	// key 不放在 chat:msg: 與 chat:room: 之下，避免被當作訊息列表掃描
+	pinKeyFormat = "chat:pins:%s" // chat:pins:scope
+	pinPattern   = "chat:pins:*"
+)
+
+func (r *RedisCacheRepository) GetPins(ctx context.Context, scope string) ([]*entities.PinnedMessage, bool, error) {
+	key := fmt.Sprintf(pinKeyFormat, scope)
+
+	data, err := r.client.Get(ctx, key).Bytes()
+	if err == redis.Nil {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "GET",
+			"key":       key,
+		})
+	}
+
+	var pins []*entities.PinnedMessage
+	if err := json.Unmarshal(data, &pins); err != nil {
+		return nil, false, appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+			"message": "解析釘選快取失敗",
+			"key":     key,
+		})
+	}
+	return pins, true, nil
+}
+
+func (r *RedisCacheRepository) StorePins(ctx context.Context, scope string, pins []*entities.PinnedMessage) error {
+	key := fmt.Sprintf(pinKeyFormat, scope)
+
+	// 與 marshalMessage 相同，不快取查詢時才附加的欄位
+	cached := make([]*entities.PinnedMessage, len(pins))
+	for i, pin := range pins {
+		message := *pin.Message
+		message.Reactions = nil
+		message.ReplyTo = nil
+		cached[i] = &entities.PinnedMessage{Pin: pin.Pin, Message: &message}
+	}
+
+	data, err := json.Marshal(cached)
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+			"message": "序列化釘選列表失敗",
+			"scope":   scope,
+		})
+	}
+
+	if err := r.client.Set(ctx, key, data, roomTTL).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "SET",
+			"key":       key,
+		})
+	}
+	return nil
+}
+
+func (r *RedisCacheRepository) InvalidatePins(ctx context.Context, scope string) error {
+	key := fmt.Sprintf(pinKeyFormat, scope)
+
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "DEL",
+			"key":       key,
+		})
+	}
+	return nil
+}
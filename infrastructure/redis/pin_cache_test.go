@@ -0,0 +1,41 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMessageCache_Pins(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	repo := NewMessageCacheRepository(client)
+	ctx := context.Background()
+	scope := entities.GroupPinScope(5)
+
+	_, hit, err := repo.GetPins(ctx, scope)
+	assert.NoError(t, err)
+	assert.False(t, hit)
+
+	message := &entities.Message{ID: 7, RoomID: 5, Content: "公告", Reactions: []entities.ReactionCount{{Emoji: "👍", Count: 1}}}
+	pins := []*entities.PinnedMessage{{Pin: &entities.MessagePin{ID: 1, Scope: scope, MessageID: 7}, Message: message}}
+	assert.NoError(t, repo.StorePins(ctx, scope, pins))
+
+	cached, hit, err := repo.GetPins(ctx, scope)
+	assert.NoError(t, err)
+	assert.True(t, hit)
+	if assert.Len(t, cached, 1) {
+		assert.Equal(t, "公告", cached[0].Message.Content)
+		assert.Nil(t, cached[0].Message.Reactions)
+	}
+	assert.NotNil(t, message.Reactions)
+
+	assert.NoError(t, repo.InvalidatePins(ctx, scope))
+	_, hit, err = repo.GetPins(ctx, scope)
+	assert.NoError(t, err)
+	assert.False(t, hit)
+}
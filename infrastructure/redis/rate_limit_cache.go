@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// chat:ratelimit:<key>，桶補滿後即過期
+const rateLimitKeyFormat = "chat:ratelimit:%s"
+
+// takeTokensScript 先補充所有桶的令牌，全部足夠時才一起扣除，否則返回最長的等待毫秒數。
+// ARGV[1] 為目前時間（毫秒），之後每個桶依序為容量、補充一個令牌的毫秒數、取出數量
+var takeTokensScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local wait = 0
+local state = {}
+for i, key in ipairs(KEYS) do
+	local burst = tonumber(ARGV[i * 3 - 1])
+	local interval = tonumber(ARGV[i * 3])
+	local cost = tonumber(ARGV[i * 3 + 1])
+	local bucket = redis.call('HMGET', key, 'tokens', 'ts')
+	local tokens = tonumber(bucket[1]) or burst
+	local ts = tonumber(bucket[2]) or now
+	if now > ts then
+		tokens = tokens + (now - ts) / interval
+		ts = now
+	end
+	tokens = math.min(burst, tokens)
+	state[i] = {tokens, ts}
+	if tokens < cost then
+		wait = math.max(wait, math.ceil((cost - tokens) * interval))
+	end
+end
+if wait > 0 then
+	return wait
+end
+for i, key in ipairs(KEYS) do
+	local burst = tonumber(ARGV[i * 3 - 1])
+	local interval = tonumber(ARGV[i * 3])
+	local cost = tonumber(ARGV[i * 3 + 1])
+	redis.call('HSET', key, 'tokens', tostring(state[i][1] - cost), 'ts', state[i][2])
+	redis.call('PEXPIRE', key, math.ceil(burst * interval) + 1000)
+end
+return 0
+`)
+
+// RateLimitCacheRepository 以 Redis 令牌桶限制發送頻率
+type RateLimitCacheRepository struct {
+	client *redis.Client
+}
+
+// NewRateLimitCache 創建新的發送頻率限制快取
+func NewRateLimitCache(client *redis.Client) cache.RateLimitCache {
+	return &RateLimitCacheRepository{
+		client: client,
+	}
+}
+
+func rateLimitKey(key string) string {
+	return fmt.Sprintf(rateLimitKeyFormat, key)
+}
+
+func (r *RateLimitCacheRepository) Take(ctx context.Context, buckets []cache.TokenBucket, now time.Time) (time.Duration, error) {
+	if len(buckets) == 0 {
+		return 0, nil
+	}
+
+	keys := make([]string, len(buckets))
+	args := make([]interface{}, 0, 1+len(buckets)*3)
+	args = append(args, now.UnixMilli())
+	for i, bucket := range buckets {
+		keys[i] = rateLimitKey(bucket.Key)
+		interval := bucket.Interval.Milliseconds()
+		if interval < 1 {
+			interval = 1
+		}
+		args = append(args, bucket.Burst, interval, bucket.Cost)
+	}
+
+	wait, err := takeTokensScript.Run(ctx, r.client, keys, args...).Int64()
+	if err != nil {
+		return 0, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "EVALSHA",
+			"keys":      keys,
+		})
+	}
+	return time.Duration(wait) * time.Millisecond, nil
+}
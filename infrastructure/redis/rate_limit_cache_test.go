@@ -0,0 +1,54 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/cache"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitCache_Take(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	limiter := NewRateLimitCache(client)
+	ctx := context.Background()
+	now := time.Unix(1700000000, 0)
+	user := cache.TokenBucket{Key: "text:user:1", Burst: 3, Interval: time.Second, Cost: 1}
+	room := cache.TokenBucket{Key: "text:room:5", Burst: 10, Interval: 100 * time.Millisecond, Cost: 1}
+
+	for i := 0; i < 3; i++ {
+		wait, err := limiter.Take(ctx, []cache.TokenBucket{user, room}, now)
+		assert.NoError(t, err)
+		assert.Zero(t, wait)
+	}
+
+	// 用戶的桶已空，對話的桶也不會被扣除
+	wait, err := limiter.Take(ctx, []cache.TokenBucket{user, room}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+	assert.Equal(t, "7", client.HGet(ctx, rateLimitKey(room.Key), "tokens").Val())
+	assert.True(t, client.PTTL(ctx, rateLimitKey(user.Key)).Val() > 0)
+
+	// 經過半個補充間隔仍不足一個令牌，需再等待剩下的時間
+	wait, err = limiter.Take(ctx, []cache.TokenBucket{user}, now.Add(500*time.Millisecond))
+	assert.NoError(t, err)
+	assert.Equal(t, 500*time.Millisecond, wait)
+
+	wait, err = limiter.Take(ctx, []cache.TokenBucket{user}, now.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+
+	// 閒置很久也不會累積超過容量
+	many := user
+	many.Cost = 3
+	wait, err = limiter.Take(ctx, []cache.TokenBucket{many}, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Zero(t, wait)
+	wait, err = limiter.Take(ctx, []cache.TokenBucket{user}, now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Second, wait)
+}
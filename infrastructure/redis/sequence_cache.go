@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// chat:seq:<scope>，不設過期時間，遺失時會由資料庫中的最大序號重新初始化
+const sequenceKeyFormat = "chat:seq:%s"
+
+// nextSeqScript 只遞增已初始化的計數器，未初始化時返回 0，
+// 避免從 1 開始配發而與資料庫中既有的序號重複
+var nextSeqScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+return redis.call('INCR', KEYS[1])
+`)
+
+// ensureSeqScript 只在計數器小於 ARGV[1] 時提高，計數器不會倒退
+var ensureSeqScript = redis.NewScript(`
+local current = tonumber(redis.call('GET', KEYS[1]) or '0')
+if current < tonumber(ARGV[1]) then
+	redis.call('SET', KEYS[1], ARGV[1])
+end
+return 1
+`)
+
+// SequenceCacheRepository 以 Redis 計數器配發對話的訊息序號
+type SequenceCacheRepository struct {
+	client *redis.Client
+}
+
+// NewSequenceCache 創建新的序號快取
+func NewSequenceCache(client *redis.Client) cache.SequenceCache {
+	return &SequenceCacheRepository{
+		client: client,
+	}
+}
+
+func sequenceKey(scope string) string {
+	return fmt.Sprintf(sequenceKeyFormat, scope)
+}
+
+func (r *SequenceCacheRepository) NextSeq(ctx context.Context, scope string) (uint64, bool, error) {
+	key := sequenceKey(scope)
+
+	seq, err := nextSeqScript.Run(ctx, r.client, []string{key}).Uint64()
+	if err != nil {
+		return 0, false, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "INCR",
+			"key":       key,
+		})
+	}
+	return seq, seq > 0, nil
+}
+
+func (r *SequenceCacheRepository) EnsureSeq(ctx context.Context, scope string, seq uint64) error {
+	key := sequenceKey(scope)
+
+	if err := ensureSeqScript.Run(ctx, r.client, []string{key}, seq).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "SET",
+			"key":       key,
+		})
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSequenceCache_NextAndEnsure(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	cache := NewSequenceCache(client)
+	ctx := context.Background()
+
+	// 未初始化的計數器不會從 1 開始配發
+	_, ok, err := cache.NextSeq(ctx, "room:1")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), client.Exists(ctx, sequenceKey("room:1")).Val())
+
+	assert.NoError(t, cache.EnsureSeq(ctx, "room:1", 10))
+	seq, ok, err := cache.NextSeq(ctx, "room:1")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(11), seq)
+
+	// 計數器不會倒退
+	assert.NoError(t, cache.EnsureSeq(ctx, "room:1", 5))
+	seq, _, err = cache.NextSeq(ctx, "room:1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12), seq)
+
+	assert.NoError(t, cache.EnsureSeq(ctx, "room:1", 20))
+	seq, _, err = cache.NextSeq(ctx, "room:1")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(21), seq)
+}
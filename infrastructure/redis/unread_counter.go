@@ -0,0 +1,169 @@
+package redis
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	unreadKeyFormat        = "chat:unread:%d:%d:%d"     // chat:unread:userId:type:targetId
+	unreadVersionKeyFormat = "chat:unread:%d:%d:%d:ver" // 載入計數器期間的版本，有新訊息時遞增
+
+	// 計數器保留 1 天，過期後從資料庫重新載入，限制與資料庫不一致的時間
+	unreadTTL = 24 * time.Hour
+	// 載入版本保留的時間，需長於一次從資料庫載入的時間
+	unreadVersionTTL = 10 * time.Second
+	// 每次腳本呼叫處理的接收者數量上限
+	unreadBatchSize = 500
+)
+
+// recordUnreadScript 只更新已載入的計數器：KEYS 依序為計數器與其載入版本，第一組為發送者，歸零；其餘為接收者，加一。
+// 未載入的計數器若在此建立，會從 0 開始計數而與資料庫不一致；正在載入時改為遞增版本，讓載入者重新讀取資料庫
+var recordUnreadScript = redis.NewScript(`
+for i = 1, #KEYS, 2 do
+	if redis.call('EXISTS', KEYS[i]) == 1 then
+		if i == 1 and ARGV[1] == '1' then
+			redis.call('SET', KEYS[i], 0, 'KEEPTTL')
+		else
+			redis.call('INCR', KEYS[i])
+		end
+	elseif redis.call('EXISTS', KEYS[i + 1]) == 1 then
+		redis.call('INCR', KEYS[i + 1])
+	end
+end
+return 0
+`)
+
+// prepareLoadScript 開始載入計數器並返回目前的載入版本，版本以目前時間起算，過期後重建不會與先前的版本相同
+var prepareLoadScript = redis.NewScript(`
+redis.call('SET', KEYS[1], ARGV[1], 'NX', 'PX', ARGV[2])
+return redis.call('GET', KEYS[1])
+`)
+
+// loadUnreadScript 只在載入版本未變更時寫入計數器，計數器已存在時不覆寫
+var loadUnreadScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 1 then
+	return 1
+end
+if redis.call('GET', KEYS[2]) ~= ARGV[1] then
+	return 0
+end
+redis.call('SET', KEYS[1], ARGV[2], 'PX', ARGV[3])
+return 1
+`)
+
+// UnreadCounterRepository 以 Redis 字串計數器保存每個會話的未讀數
+type UnreadCounterRepository struct {
+	client *redis.Client
+}
+
+// NewUnreadCounterCache 創建新的未讀數計數器快取
+func NewUnreadCounterCache(client *redis.Client) cache.UnreadCounterCache {
+	return &UnreadCounterRepository{
+		client: client,
+	}
+}
+
+func unreadKey(userID uint, convType entities.ConversationType, targetID uint) string {
+	return fmt.Sprintf(unreadKeyFormat, userID, convType, targetID)
+}
+
+func unreadVersionKey(userID uint, convType entities.ConversationType, targetID uint) string {
+	return fmt.Sprintf(unreadVersionKeyFormat, userID, convType, targetID)
+}
+
+func (r *UnreadCounterRepository) RecordMessage(ctx context.Context, message *entities.Message, recipients []uint) error {
+	convType, targetID := entities.ConversationOf(message, message.UserId)
+	keys := []string{unreadKey(message.UserId, convType, targetID), unreadVersionKey(message.UserId, convType, targetID)}
+	senderFlag := "1"
+
+	for _, userID := range recipients {
+		if userID == message.UserId {
+			continue
+		}
+		convType, targetID := entities.ConversationOf(message, userID)
+		keys = append(keys, unreadKey(userID, convType, targetID), unreadVersionKey(userID, convType, targetID))
+
+		if len(keys) >= unreadBatchSize*2 {
+			if err := r.runRecord(ctx, message, keys, senderFlag); err != nil {
+				return err
+			}
+			keys, senderFlag = keys[:0], "0"
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.runRecord(ctx, message, keys, senderFlag)
+}
+
+func (r *UnreadCounterRepository) runRecord(ctx context.Context, message *entities.Message, keys []string, senderFlag string) error {
+	if err := recordUnreadScript.Run(ctx, r.client, keys, senderFlag).Err(); err != nil && err != redis.Nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "EVALSHA",
+			"messageId": message.ID,
+		})
+	}
+	return nil
+}
+
+func (r *UnreadCounterRepository) GetUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (int, bool, error) {
+	key := unreadKey(userID, convType, targetID)
+
+	count, err := r.client.Get(ctx, key).Int()
+	if err == redis.Nil {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "GET",
+			"key":       key,
+		})
+	}
+	return count, true, nil
+}
+
+func (r *UnreadCounterRepository) PrepareLoad(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (int64, error) {
+	key := unreadVersionKey(userID, convType, targetID)
+
+	version, err := prepareLoadScript.Run(ctx, r.client, []string{key}, time.Now().UnixMicro(), unreadVersionTTL.Milliseconds()).Int64()
+	if err != nil {
+		return 0, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "EVALSHA",
+			"key":       key,
+		})
+	}
+	return version, nil
+}
+
+func (r *UnreadCounterRepository) LoadUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, count int, version int64) (bool, error) {
+	keys := []string{unreadKey(userID, convType, targetID), unreadVersionKey(userID, convType, targetID)}
+
+	loaded, err := loadUnreadScript.Run(ctx, r.client, keys, version, count, unreadTTL.Milliseconds()).Int()
+	if err != nil {
+		return false, appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "EVALSHA",
+			"key":       keys[0],
+		})
+	}
+	return loaded == 1, nil
+}
+
+func (r *UnreadCounterRepository) InvalidateUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	key := unreadKey(userID, convType, targetID)
+
+	if err := r.client.Del(ctx, key).Err(); err != nil {
+		return appErrors.Wrap(err, enum.ErrRedisOperationFailed, map[string]interface{}{
+			"operation": "DEL",
+			"key":       key,
+		})
+	}
+	return nil
+}
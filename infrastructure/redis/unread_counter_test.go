@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/entities"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnreadCounter_RecordMessageOnlyUpdatesLoadedCounters(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	counter := NewUnreadCounterCache(client)
+	ctx := context.Background()
+	group := entities.ConversationTypeGroup
+
+	load := func(userID uint, count int) {
+		version, err := counter.PrepareLoad(ctx, userID, group, 5)
+		assert.NoError(t, err)
+		loaded, err := counter.LoadUnread(ctx, userID, group, 5, count, version)
+		assert.NoError(t, err)
+		assert.True(t, loaded)
+	}
+	load(1, 4)
+	load(2, 3)
+	// 已存在的計數器不會被覆寫
+	load(2, 9)
+
+	message := &entities.Message{ID: 10, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup}
+	assert.NoError(t, counter.RecordMessage(ctx, message, []uint{1, 2, 3}))
+
+	count, found, err := counter.GetUnread(ctx, 1, group, 5)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 0, count)
+
+	count, found, err = counter.GetUnread(ctx, 2, group, 5)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, 4, count)
+
+	_, found, err = counter.GetUnread(ctx, 3, group, 5)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.NoError(t, counter.InvalidateUnread(ctx, 2, group, 5))
+	_, found, err = counter.GetUnread(ctx, 2, group, 5)
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestUnreadCounter_LoadUnreadRejectsMessagesDuringLoad(t *testing.T) {
+	client, cleanup := setupTestRedis(t)
+	defer cleanup()
+
+	counter := NewUnreadCounterCache(client)
+	ctx := context.Background()
+	group := entities.ConversationTypeGroup
+
+	version, err := counter.PrepareLoad(ctx, 1, group, 5)
+	assert.NoError(t, err)
+	// 同時載入的其他請求取得相同的版本
+	other, err := counter.PrepareLoad(ctx, 1, group, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, version, other)
+
+	// 讀取資料庫後、寫入計數器前送出的訊息會改變載入版本
+	message := &entities.Message{ID: 10, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup}
+	assert.NoError(t, counter.RecordMessage(ctx, message, []uint{1, 2, 3}))
+
+	loaded, err := counter.LoadUnread(ctx, 1, group, 5, 3, version)
+	assert.NoError(t, err)
+	assert.False(t, loaded)
+	_, found, err := counter.GetUnread(ctx, 1, group, 5)
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// 重新讀取後以新的版本載入
+	version, err = counter.PrepareLoad(ctx, 1, group, 5)
+	assert.NoError(t, err)
+	loaded, err = counter.LoadUnread(ctx, 1, group, 5, 4, version)
+	assert.NoError(t, err)
+	assert.True(t, loaded)
+	count, _, err := counter.GetUnread(ctx, 1, group, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.True(t, client.TTL(ctx, unreadKey(1, group, 5)).Val() > 0)
+
+	// 沒有人在載入的計數器不會產生載入版本
+	assert.Zero(t, client.Exists(ctx, unreadVersionKey(3, group, 5)).Val())
+}
@@ -0,0 +1,173 @@
+package search
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/search"
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// memoryIndex 嵌入式的記憶體倒排索引，供本機開發使用。
+// 索引只包含啟動後寫入的訊息，重啟後需重新建立
+type memoryIndex struct {
+	mu       sync.RWMutex
+	messages map[uint]*entities.Message
+	postings map[string]map[uint]struct{} // 搜尋詞 -> 訊息ID
+}
+
+// NewMemoryIndex 創建記憶體倒排索引
+func NewMemoryIndex() search.MessageIndex {
+	return &memoryIndex{
+		messages: make(map[uint]*entities.Message),
+		postings: make(map[string]map[uint]struct{}),
+	}
+}
+
+func (idx *memoryIndex) Index(ctx context.Context, message *entities.Message) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(message.ID)
+	if message.IsRecalled() || message.IsSystem() {
+		return nil
+	}
+
+	copied := *message
+	copied.Reactions = nil
+	copied.ReplyTo = nil
+	idx.messages[message.ID] = &copied
+	for _, term := range search.Tokenize(message.DisplayText()) {
+		if idx.postings[term] == nil {
+			idx.postings[term] = make(map[uint]struct{})
+		}
+		idx.postings[term][message.ID] = struct{}{}
+	}
+	return nil
+}
+
+func (idx *memoryIndex) Remove(ctx context.Context, messageID uint) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(messageID)
+	return nil
+}
+
+// remove 移除訊息與其所有倒排記錄，呼叫者需持有寫鎖
+func (idx *memoryIndex) remove(messageID uint) {
+	message, ok := idx.messages[messageID]
+	if !ok {
+		return
+	}
+	for _, term := range search.Tokenize(message.DisplayText()) {
+		delete(idx.postings[term], messageID)
+		if len(idx.postings[term]) == 0 {
+			delete(idx.postings, term)
+		}
+	}
+	delete(idx.messages, messageID)
+}
+
+func (idx *memoryIndex) Search(ctx context.Context, query search.Query) (*search.Result, error) {
+	query = query.Normalize()
+	result := &search.Result{Hits: []*search.Hit{}}
+	if len(query.Terms) == 0 {
+		return result, nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	// 從最短的倒排列表開始取交集
+	lists := make([]map[uint]struct{}, len(query.Terms))
+	for i, term := range query.Terms {
+		lists[i] = idx.postings[term]
+		if len(lists[i]) == 0 {
+			return result, nil
+		}
+	}
+	sort.Slice(lists, func(i, j int) bool { return len(lists[i]) < len(lists[j]) })
+
+	now := time.Now()
+	var ids []uint
+	for id := range lists[0] {
+		matched := true
+		for _, list := range lists[1:] {
+			if _, ok := list[id]; !ok {
+				matched = false
+				break
+			}
+		}
+		if matched && !idx.messages[id].IsExpired(now) && visible(idx.messages[id], query) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] > ids[j] })
+
+	if query.Offset >= len(ids) {
+		return result, nil
+	}
+	ids = ids[query.Offset:]
+	result.HasMore = len(ids) > query.Limit
+	if result.HasMore {
+		ids = ids[:query.Limit]
+	}
+	for _, id := range ids {
+		copied := *idx.messages[id]
+		result.Hits = append(result.Hits, &search.Hit{Message: &copied, Snippet: search.Snippet(copied.DisplayText(), query.Terms)})
+	}
+	return result, nil
+}
+
+// visible 判斷訊息是否在用戶的可見範圍內並符合過濾條件
+func visible(message *entities.Message, query search.Query) bool {
+	switch message.Type {
+	case entities.MessageTypePrivate:
+		if message.UserId != query.ViewerID && message.TargetId != query.ViewerID {
+			return false
+		}
+	case entities.MessageTypeGroup:
+		if !containsID(query.RoomIDs, message.RoomID) {
+			return false
+		}
+	default:
+		return false
+	}
+
+	switch query.ConversationType {
+	case entities.ConversationTypePrivate:
+		if message.Type != entities.MessageTypePrivate ||
+			(message.UserId != query.TargetID && message.TargetId != query.TargetID) {
+			return false
+		}
+	case entities.ConversationTypeGroup:
+		if message.Type != entities.MessageTypeGroup || message.RoomID != query.TargetID {
+			return false
+		}
+	}
+
+	if query.SenderID != 0 && message.UserId != query.SenderID {
+		return false
+	}
+	if !query.From.IsZero() && message.CreatedAt.Before(query.From) {
+		return false
+	}
+	if !query.To.IsZero() && !message.CreatedAt.Before(query.To) {
+		return false
+	}
+	if query.Media != 0 && message.Media != query.Media {
+		return false
+	}
+	return true
+}
+
+func containsID(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
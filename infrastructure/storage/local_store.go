@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"clean-architecture-gochat/internal/domain/storage"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errInvalidName 表示檔名包含路徑或為特殊名稱
+var errInvalidName = errors.New("invalid file name")
+
+type localExportStore struct {
+	dir string
+}
+
+// NewLocalExportStore 創建保存在本機目錄的匯出儲存區，目錄不存在時於寫入時建立
+func NewLocalExportStore(dir string) storage.ExportStore {
+	return &localExportStore{dir: dir}
+}
+
+func (s *localExportStore) Create(name string) (io.WriteCloser, error) {
+	if !isPlainName(name) {
+		return nil, fmt.Errorf("%w: %q", errInvalidName, name)
+	}
+	if err := os.MkdirAll(s.dir, 0o750); err != nil {
+		return nil, err
+	}
+	return os.OpenFile(filepath.Join(s.dir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o640)
+}
+
+func (s *localExportStore) Open(name string) (io.ReadCloser, error) {
+	if !isPlainName(name) {
+		return nil, storage.ErrNotFound
+	}
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, storage.ErrNotFound
+	}
+	return file, err
+}
+
+func (s *localExportStore) Remove(name string) error {
+	if !isPlainName(name) {
+		return nil
+	}
+	err := os.Remove(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+type localAttachmentStore struct {
+	dir       string
+	urlPrefix string
+}
+
+// NewLocalAttachmentStore 創建讀取本機上傳檔案的附件來源，urlPrefix 為上傳檔案對外的網址前綴，對應到 dir 目錄
+func NewLocalAttachmentStore(dir, urlPrefix string) storage.AttachmentStore {
+	return &localAttachmentStore{dir: dir, urlPrefix: urlPrefix}
+}
+
+func (s *localAttachmentStore) Lookup(url string) (string, bool) {
+	name, ok := s.resolve(url)
+	if !ok {
+		return "", false
+	}
+	// 只接受一般檔案，避免透過符號連結讀取上傳目錄以外的檔案
+	info, err := os.Lstat(filepath.Join(s.dir, name))
+	if err != nil || !info.Mode().IsRegular() {
+		return "", false
+	}
+	return name, true
+}
+
+func (s *localAttachmentStore) Open(url string) (io.ReadCloser, error) {
+	name, ok := s.Lookup(url)
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	file, err := os.Open(filepath.Join(s.dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, storage.ErrNotFound
+	}
+	return file, err
+}
+
+// resolve 將附件網址轉為上傳目錄中的檔名，只接受直接位於目錄下的檔案
+func (s *localAttachmentStore) resolve(url string) (string, bool) {
+	if !strings.HasPrefix(url, s.urlPrefix) {
+		return "", false
+	}
+	name := strings.TrimPrefix(url, s.urlPrefix)
+	if i := strings.IndexAny(name, "?#"); i >= 0 {
+		name = name[:i]
+	}
+	return name, isPlainName(name)
+}
+
+// isPlainName 判斷檔名不包含路徑分隔符號且不是目前或上層目錄
+func isPlainName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`) && !strings.ContainsRune(name, 0)
+}
@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/storage"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 測試匯出檔的建立、讀取與刪除，並拒絕包含路徑的檔名
+func TestLocalExportStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "exports")
+	store := NewLocalExportStore(dir)
+
+	file, err := store.Create("export_1.json")
+	assert.NoError(t, err)
+	_, err = io.WriteString(file, `{"messages":[]}`)
+	assert.NoError(t, err)
+	assert.NoError(t, file.Close())
+
+	// 同名檔案不會被覆寫
+	_, err = store.Create("export_1.json")
+	assert.Error(t, err)
+
+	reader, err := store.Open("export_1.json")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, `{"messages":[]}`, string(data))
+
+	for _, name := range []string{"", ".", "..", "../escape.json", "sub/export.json", `..\escape.json`} {
+		_, err := store.Create(name)
+		assert.Error(t, err, name)
+		_, err = store.Open(name)
+		assert.ErrorIs(t, err, storage.ErrNotFound, name)
+	}
+
+	assert.NoError(t, store.Remove("export_1.json"))
+	assert.NoError(t, store.Remove("export_1.json"))
+	_, err = store.Open("export_1.json")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+}
+
+// 測試附件只能讀取上傳目錄中的一般檔案
+func TestLocalAttachmentStore(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "files")
+	assert.NoError(t, os.MkdirAll(dir, 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "file_1.jpg"), []byte("JPEG"), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "secret.txt"), []byte("secret"), 0o644))
+	assert.NoError(t, os.Symlink(filepath.Join(root, "secret.txt"), filepath.Join(dir, "link.txt")))
+	store := NewLocalAttachmentStore(dir, "/web/asset/files/")
+
+	name, ok := store.Lookup("/web/asset/files/file_1.jpg?v=2")
+	assert.True(t, ok)
+	assert.Equal(t, "file_1.jpg", name)
+	reader, err := store.Open("/web/asset/files/file_1.jpg")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "JPEG", string(data))
+
+	for _, url := range []string{
+		"/web/asset/files/../secret.txt",
+		"/web/asset/files/link.txt",
+		"/web/asset/files/missing.jpg",
+		"/web/asset/avatars/file_1.jpg",
+		"https://example.com/web/asset/files/file_1.jpg",
+		"/web/asset/files/",
+	} {
+		_, ok := store.Lookup(url)
+		assert.False(t, ok, url)
+		_, err := store.Open(url)
+		assert.ErrorIs(t, err, storage.ErrNotFound, url)
+	}
+}
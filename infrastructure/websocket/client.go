@@ -7,6 +7,9 @@ import (
 	"github.com/gorilla/websocket"
 )
 
+// maxMessageSize 客戶端單則訊息的大小上限，需容納最長的草稿
+const maxMessageSize = 16 * 1024
+
 // Client 代表一個 WebSocket 連線的客戶端
 type Client struct {
 	Conn          *websocket.Conn
@@ -24,7 +27,7 @@ func (c *Client) ReadPump() {
 		c.Conn.Close()
 	}()
 
-	c.Conn.SetReadLimit(512)
+	c.Conn.SetReadLimit(maxMessageSize)
 	c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
 	c.Conn.SetPongHandler(func(string) error {
 		c.Conn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -39,7 +42,8 @@ func (c *Client) ReadPump() {
 			}
 			break
 		}
-		c.Hub.Broadcast <- message
+		// 只處理已註冊類型的訊息，其他訊息直接丟棄，不轉發給其他用戶
+		c.Hub.dispatch(c, message)
 	}
 }
 
@@ -90,9 +94,19 @@ func (c *Client) HandleMessage(msg map[string]interface{}) {
 	// TODO: 根據實際需求處理不同類型的訊息
 }
 
-// 發送訊息給特定客戶端
-func (c *Client) SendMessage(msg []byte) {
-	if !c.IsClosed {
-		c.Send <- msg
+// 發送訊息給特定客戶端，返回是否成功放入發送佇列。
+// 與 SendToUser 相同在讀取鎖內以非阻塞方式發送：連線已關閉或發送緩衝已滿時丟棄訊息，
+// 避免寫入已關閉的通道，也不會讓 ReadPump 卡在回覆上
+func (c *Client) SendMessage(msg []byte) bool {
+	c.Hub.lock.RLock()
+	defer c.Hub.lock.RUnlock()
+	if c.IsClosed {
+		return false
+	}
+	select {
+	case c.Send <- msg:
+		return true
+	default:
+		return false
 	}
 }
@@ -1,54 +1,110 @@
 package websocket
 
 import (
+	"encoding/json"
 	"sync"
 )
 
-// Hub 負責管理所有 WebSocket 連線
+// InboundHandler 處理客戶端送來的特定類型訊息，data 為訊息中的 data 欄位
+type InboundHandler func(client *Client, data json.RawMessage)
+
+// Hub 負責管理所有 WebSocket 連線，同一用戶可同時有多個裝置連線
 type Hub struct {
-	Clients    map[string]*Client
+	Clients    map[string]map[*Client]bool // 用戶ID -> 該用戶已連線的裝置
 	Register   chan *Client
 	Unregister chan *Client
 	Broadcast  chan []byte
 	lock       sync.RWMutex
+	handlers   map[string]InboundHandler // 訊息類型 -> 處理函式
 }
 
 // NewHub 創建一個新的 Hub 實例
 func NewHub() *Hub {
 	return &Hub{
-		Clients:    make(map[string]*Client),
+		Clients:    make(map[string]map[*Client]bool),
 		Register:   make(chan *Client),
 		Unregister: make(chan *Client),
 		Broadcast:  make(chan []byte),
+		handlers:   make(map[string]InboundHandler),
 	}
 }
 
+// Handle 註冊客戶端訊息的處理函式，type 欄位符合的訊息交由 handler 處理
+func (h *Hub) Handle(msgType string, handler InboundHandler) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.handlers[msgType] = handler
+}
+
+// dispatch 將客戶端訊息交給已註冊的處理函式，沒有對應的處理函式時返回 false
+func (h *Hub) dispatch(client *Client, message []byte) bool {
+	var envelope struct {
+		Type string          `json:"type"`
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(message, &envelope); err != nil || envelope.Type == "" {
+		return false
+	}
+
+	h.lock.RLock()
+	handler, ok := h.handlers[envelope.Type]
+	h.lock.RUnlock()
+	if !ok {
+		return false
+	}
+	handler(client, envelope.Data)
+	return true
+}
+
 // Run 啟動 WebSocket Hub
 func (h *Hub) Run() {
 	for {
 		select {
 		case client := <-h.Register:
-			h.lock.Lock()
-			h.Clients[client.UserID] = client
-			h.lock.Unlock()
+			h.register(client)
 		case client := <-h.Unregister:
 			h.lock.Lock()
-			if _, ok := h.Clients[client.UserID]; ok {
-				delete(h.Clients, client.UserID)
-				close(client.Send)
-			}
+			h.remove(client)
 			h.lock.Unlock()
 		case message := <-h.Broadcast:
-			h.lock.RLock()
-			for _, client := range h.Clients {
-				select {
-				case client.Send <- message:
-				default:
-					close(client.Send)
-					delete(h.Clients, client.UserID)
+			h.lock.Lock()
+			for _, devices := range h.Clients {
+				for client := range devices {
+					select {
+					case client.Send <- message:
+					default:
+						h.remove(client)
+					}
 				}
 			}
-			h.lock.RUnlock()
+			h.lock.Unlock()
 		}
 	}
 }
+
+// register 將裝置加入用戶的連線列表，返回後即可收到推送給該用戶的訊息
+func (h *Hub) register(client *Client) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	devices, ok := h.Clients[client.UserID]
+	if !ok {
+		devices = make(map[*Client]bool)
+		h.Clients[client.UserID] = devices
+	}
+	devices[client] = true
+}
+
+// remove 移除單一裝置的連線並關閉其發送通道，呼叫者需持有寫入鎖
+func (h *Hub) remove(client *Client) {
+	devices, ok := h.Clients[client.UserID]
+	if !ok || !devices[client] {
+		return
+	}
+
+	delete(devices, client)
+	if len(devices) == 0 {
+		delete(h.Clients, client.UserID)
+	}
+	client.IsClosed = true
+	close(client.Send)
+}
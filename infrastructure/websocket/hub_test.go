@@ -0,0 +1,68 @@
+package websocket
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// dialTestServer 啟動以 handler 處理升級後連線的測試伺服器，返回客戶端連線
+func dialTestServer(t *testing.T, handler func(conn *websocket.Conn)) (*websocket.Conn, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Errorf("upgrade failed: %v", err)
+			return
+		}
+		handler(conn)
+	}))
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		server.Close()
+		t.Fatalf("an error '%s' was not expected when dialing the test server", err)
+	}
+	return conn, func() {
+		conn.Close()
+		server.Close()
+	}
+}
+
+// 測試連線建立後立即推送的訊息即可送達，不會因 Hub 尚未登記而被視為離線
+func TestUpgradeConnection_RegistersBeforeReturning(t *testing.T) {
+	delivered := make(chan int, 1)
+	conn, cleanup := dialTestServer(t, func(conn *websocket.Conn) {
+		UpgradeConnection(conn, "ws-test-1")
+		delivered <- SendToUser("ws-test-1", []byte("pending"))
+	})
+	defer cleanup()
+
+	assert.Equal(t, 1, <-delivered)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, message, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, "pending", string(message))
+}
+
+// 測試回覆單一裝置時不會阻塞或寫入已關閉的通道：緩衝已滿或連線已移除時丟棄訊息
+func TestClient_SendMessageDropsWhenFullOrClosed(t *testing.T) {
+	hub := NewHub()
+	client := &Client{UserID: "ws-test-2", Send: make(chan []byte, 1), Hub: hub}
+	hub.register(client)
+
+	assert.True(t, client.SendMessage([]byte("ack")))
+	assert.False(t, client.SendMessage([]byte("ack")))
+	assert.Equal(t, "ack", string(<-client.Send))
+
+	hub.lock.Lock()
+	hub.remove(client)
+	hub.lock.Unlock()
+	assert.NotPanics(t, func() {
+		assert.False(t, client.SendMessage([]byte("ack")))
+	})
+}
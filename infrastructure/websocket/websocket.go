@@ -43,19 +43,41 @@ func UpgradeConnection(conn *websocket.Conn, userID string) *Client {
 		IsClosed:      false,
 	}
 
-	GetHub().Register <- client
+	// 同步加入連線列表，呼叫者返回後補發的離線訊息才能送達此裝置，不會被退回離線佇列
+	client.Hub.register(client)
 	go client.ReadPump()
 	go client.WritePump()
 	return client
 }
 
-// 透過用戶ID取得 WebSocket 連線的客戶端
-func GetClient(userID string) (*Client, bool) {
+// 透過用戶ID取得該用戶所有已連線裝置的客戶端
+func GetClients(userID string) []*Client {
 	hub := GetHub()
 	hub.lock.RLock()
 	defer hub.lock.RUnlock()
-	client, exists := hub.Clients[userID]
-	return client, exists
+	clients := make([]*Client, 0, len(hub.Clients[userID]))
+	for client := range hub.Clients[userID] {
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+// 發送訊息給用戶所有已連線的裝置，返回成功放入發送佇列的裝置數。
+// 在讀取鎖內發送，避免裝置同時斷線時寫入已關閉的通道
+func SendToUser(userID string, msg []byte) int {
+	hub := GetHub()
+	hub.lock.RLock()
+	defer hub.lock.RUnlock()
+	delivered := 0
+	for client := range hub.Clients[userID] {
+		select {
+		case client.Send <- msg:
+			delivered++
+		default:
+			// 此裝置的發送緩衝已滿，略過
+		}
+	}
+	return delivered
 }
 
 // 廣播訊息給所有連線的客戶端
@@ -1,12 +1,16 @@
 package controllers
 
 import (
+	websocketInfra "clean-architecture-gochat/infrastructure/websocket"
 	"clean-architecture-gochat/internal/domain/entities"
-	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
 	"clean-architecture-gochat/internal/usecases/chat"
 	ws "clean-architecture-gochat/internal/usecases/websocket"
 
 	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"net/http"
@@ -21,20 +25,20 @@ type ChatController struct {
 	privateChatService chat.Service
 	groupChatService   chat.GroupChatService
 	connectionService  ws.ConnectionService
-	messageService     repositories.MessageService
+	messageUseCase     chat.MessageUseCase
 }
 
 func NewChatController(
 	privateChatService chat.Service,
 	groupChatService chat.GroupChatService,
 	connectionService ws.ConnectionService,
-	messageService repositories.MessageService,
+	messageUseCase chat.MessageUseCase,
 ) *ChatController {
 	return &ChatController{
 		privateChatService: privateChatService,
 		groupChatService:   groupChatService,
 		connectionService:  connectionService,
-		messageService:     messageService,
+		messageUseCase:     messageUseCase,
 	}
 }
 
@@ -75,6 +79,11 @@ func (cc *ChatController) HandleWebSocket(c *gin.Context) {
 		return
 	}
 
+	// 補發離線期間的訊息
+	if err := cc.messageUseCase.DeliverPendingMessages(c.Request.Context(), uint(userID)); err != nil {
+		log.Printf("Failed to deliver pending messages: %v", err)
+	}
+
 	// 連接成功，返回 200 狀態碼
 	c.Status(http.StatusOK)
 }
@@ -82,11 +91,14 @@ func (cc *ChatController) HandleWebSocket(c *gin.Context) {
 // 發送私人訊息
 func (cc *ChatController) SendPrivateMessage(c *gin.Context) {
 	var req struct {
-		FromUserID uint   `json:"fromUserId"`
-		ToUserID   uint   `json:"toUserId"`
-		Content    string `json:"content"`
-		Type       int    `json:"type"`
-		Media      int    `json:"media"`
+		FromUserID   uint   `json:"fromUserId"`
+		ToUserID     uint   `json:"toUserId"`
+		Content      string `json:"content"`
+		Type         int    `json:"type"`
+		Media        int    `json:"media"`
+		ClientMsgID  string `json:"clientMsgId"`  // 客戶端訊息ID，重試時沿用以避免重複發送
+		ReplyToID    uint   `json:"replyToId"`    // 引用回覆的訊息ID
+		ThreadRootID uint   `json:"threadRootId"` // 在討論串中回覆時的根訊息ID
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -95,20 +107,23 @@ func (cc *ChatController) SendPrivateMessage(c *gin.Context) {
 	}
 
 	message := &entities.Message{
-		UserId:    req.FromUserID,
-		TargetId:  req.ToUserID,
-		Content:   req.Content,
-		Type:      entities.MessageType(req.Type),
-		Media:     entities.MediaType(req.Media),
-		CreatedAt: time.Now(),
+		UserId:       req.FromUserID,
+		TargetId:     req.ToUserID,
+		ClientMsgID:  optionalString(req.ClientMsgID),
+		ReplyToID:    optionalID(req.ReplyToID),
+		ThreadRootID: optionalID(req.ThreadRootID),
+		Content:      req.Content,
+		Type:         entities.MessageType(req.Type),
+		Media:        entities.MediaType(req.Media),
+		CreatedAt:    time.Now(),
 	}
 
-	if err := cc.messageService.SendPrivateMessage(c.Request.Context(), message); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": err.Error()})
+	if err := cc.messageUseCase.SendPrivateMessage(c.Request.Context(), message); err != nil {
+		respondSendError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "訊息發送成功"})
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "訊息發送成功", "data": message})
 }
 
 // 獲取私人聊天歷史
@@ -125,13 +140,19 @@ func (cc *ChatController) GetPrivateHistory(c *gin.Context) {
 		return
 	}
 
-	messages, err := cc.messageService.GetPrivateHistory(c.Request.Context(), uint(fromUserID), uint(toUserID))
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	page, err := cc.messageUseCase.GetPrivateMessageHistory(c.Request.Context(), uint(fromUserID), uint(toUserID), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取聊天歷史成功", "data": messages})
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取聊天歷史成功", "data": page.Messages, "has_more": page.HasMore})
 }
 
 // 創建群組
@@ -317,6 +338,33 @@ func (cc *ChatController) AddGroupMember(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
 }
 
+// 設定群組成員角色
+func (cc *ChatController) SetGroupMemberRole(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "invalid group id"})
+		return
+	}
+
+	var req struct {
+		OperatorID uint   `json:"operator_id" binding:"required"`
+		UserID     uint   `json:"user_id" binding:"required"`
+		Role       string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	if err := cc.groupChatService.SetMemberRole(c.Request.Context(), uint(groupID), req.OperatorID, req.UserID, entities.GroupRole(req.Role)); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "success"})
+}
+
 // 移除群組成員
 func (cc *ChatController) RemoveGroupMember(c *gin.Context) {
 	groupIDStr := c.Param("id")
@@ -351,11 +399,14 @@ func (cc *ChatController) RemoveGroupMember(c *gin.Context) {
 // 發送群組消息
 func (cc *ChatController) SendGroupMessage(c *gin.Context) {
 	var req struct {
-		UserID  uint   `json:"userId"`
-		RoomID  uint   `json:"roomId"`
-		Content string `json:"content"`
-		Type    int    `json:"type"`
-		Media   int    `json:"media"`
+		UserID       uint   `json:"userId"`
+		RoomID       uint   `json:"roomId"`
+		Content      string `json:"content"`
+		Type         int    `json:"type"`
+		Media        int    `json:"media"`
+		ClientMsgID  string `json:"clientMsgId"`  // 客戶端訊息ID，重試時沿用以避免重複發送
+		ReplyToID    uint   `json:"replyToId"`    // 引用回覆的訊息ID
+		ThreadRootID uint   `json:"threadRootId"` // 在討論串中回覆時的根訊息ID
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -364,20 +415,23 @@ func (cc *ChatController) SendGroupMessage(c *gin.Context) {
 	}
 
 	message := &entities.Message{
-		UserId:    req.UserID,
-		RoomID:    req.RoomID,
-		Content:   req.Content,
-		Type:      entities.MessageType(req.Type),
-		Media:     entities.MediaType(req.Media),
-		CreatedAt: time.Now(),
+		UserId:       req.UserID,
+		RoomID:       req.RoomID,
+		ClientMsgID:  optionalString(req.ClientMsgID),
+		ReplyToID:    optionalID(req.ReplyToID),
+		ThreadRootID: optionalID(req.ThreadRootID),
+		Content:      req.Content,
+		Type:         entities.MessageType(req.Type),
+		Media:        entities.MediaType(req.Media),
+		CreatedAt:    time.Now(),
 	}
 
-	if err := cc.messageService.SendGroupMessage(c.Request.Context(), message); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": err.Error()})
+	if err := cc.messageUseCase.SendGroupMessage(c.Request.Context(), message); err != nil {
+		respondSendError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "群組訊息發送成功"})
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "群組訊息發送成功", "data": message})
 }
 
 // 獲取群組聊天歷史
@@ -388,13 +442,19 @@ func (cc *ChatController) GetGroupHistory(c *gin.Context) {
 		return
 	}
 
-	messages, err := cc.messageService.GetGroupHistory(c.Request.Context(), uint(roomID))
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	page, err := cc.messageUseCase.GetGroupMessageHistory(c.Request.Context(), uint(roomID), query)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取群組聊天歷史成功", "data": messages})
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取群組聊天歷史成功", "data": page.Messages, "has_more": page.HasMore})
 }
 
 // CreateCustomGroup 處理前端自定義格式的群組創建請求
@@ -528,19 +588,139 @@ func (cc *ChatController) JoinGroup(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "加入群組成功"})
 }
 
-// GetRecentMessages 獲取最近的訊息列表
-func (cc *ChatController) GetRecentMessages(c *gin.Context) {
-	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+// socketSendRequest 客戶端透過 WebSocket 發送訊息的內容，roomId 不為 0 時發送到群組，否則發送給 toUserId
+type socketSendRequest struct {
+	RoomID       uint   `json:"roomId"`
+	ToUserID     uint   `json:"toUserId"`
+	Content      string `json:"content"`
+	Media        int    `json:"media"`
+	ClientMsgID  string `json:"clientMsgId"`
+	ReplyToID    uint   `json:"replyToId"`
+	ThreadRootID uint   `json:"threadRootId"`
+}
+
+// HandleSendMessage 處理客戶端透過 WebSocket 送出的訊息，與 HTTP 發送走相同的流程與頻率限制。
+// 發送者以連線的用戶為準，結果以 message.ack 或 message.failed 回覆給送出的裝置
+func (cc *ChatController) HandleSendMessage(client *websocketInfra.Client, data json.RawMessage) {
+	userID, err := strconv.ParseUint(client.UserID, 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
 		return
 	}
 
-	messages, err := cc.messageService.GetRecentMessages(c.Request.Context(), uint(userID))
+	var req socketSendRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		replySocket(client, chat.EventMessageFailed, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	message := &entities.Message{
+		UserId:       uint(userID),
+		ClientMsgID:  optionalString(req.ClientMsgID),
+		ReplyToID:    optionalID(req.ReplyToID),
+		ThreadRootID: optionalID(req.ThreadRootID),
+		Content:      req.Content,
+		Media:        entities.MediaType(req.Media),
+		CreatedAt:    time.Now(),
+	}
+	ctx := context.Background()
+	if req.RoomID != 0 {
+		message.Type, message.RoomID = entities.MessageTypeGroup, req.RoomID
+		err = cc.messageUseCase.SendGroupMessage(ctx, message)
+	} else {
+		message.Type, message.TargetId = entities.MessageTypePrivate, req.ToUserID
+		err = cc.messageUseCase.SendPrivateMessage(ctx, message)
+	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"code": -1, "message": err.Error()})
+		_, body := appErrors.ToResponse(err)
+		response := body.(map[string]interface{})
+		response["clientMsgId"] = req.ClientMsgID
+		replySocket(client, chat.EventMessageFailed, response)
+		return
+	}
+	replySocket(client, chat.EventMessageAck, gin.H{"clientMsgId": req.ClientMsgID, "message": message})
+}
+
+// replySocket 只回覆給送出請求的裝置，不經由事件推送也不暫存到離線佇列；
+// 裝置已斷線或發送緩衝已滿時丟棄回覆；訊息本身不受影響，以相同的 clientMsgId 重送時會返回原訊息
+func replySocket(client *websocketInfra.Client, eventType chat.EventType, data interface{}) {
+	payload, err := json.Marshal(&chat.Event{Type: eventType, Data: data})
+	if err != nil {
+		log.Printf("Failed to encode socket reply: %v", err)
 		return
 	}
+	if !client.SendMessage(payload) {
+		log.Printf("Dropped socket reply: user=%s, event=%s", client.UserID, eventType)
+	}
+}
+
+// respondSendError 寫入發送失敗的錯誤回應，超過發送頻率限制時附上 Retry-After 標頭
+func respondSendError(c *gin.Context, err error) {
+	if seconds, ok := chat.RetryAfter(err); ok {
+		c.Header("Retry-After", strconv.Itoa(seconds))
+	}
+	c.JSON(appErrors.ToResponse(err))
+}
+
+// optionalString 將空字串轉為 nil，用於可選的請求欄位
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// optionalID 將 0 轉換為 nil，其餘返回指標
+func optionalID(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
+
+// parseHistoryQuery 解析歷史訊息的游標分頁參數：before、after 為訊息ID，
+// beforeSeq、afterSeq 為對話序號（優先於訊息ID），limit 為每頁數量
+func parseHistoryQuery(c *gin.Context) (entities.HistoryQuery, error) {
+	var query entities.HistoryQuery
+
+	if v := c.Query("before"); v != "" {
+		before, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return query, errors.New("無效的 before 游標")
+		}
+		query.BeforeID = uint(before)
+	}
+
+	if v := c.Query("after"); v != "" {
+		after, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			return query, errors.New("無效的 after 游標")
+		}
+		query.AfterID = uint(after)
+	}
+
+	if v := c.Query("beforeSeq"); v != "" {
+		before, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return query, errors.New("無效的 beforeSeq 游標")
+		}
+		query.BeforeSeq = before
+	}
+
+	if v := c.Query("afterSeq"); v != "" {
+		after, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return query, errors.New("無效的 afterSeq 游標")
+		}
+		query.AfterSeq = after
+	}
+
+	if v := c.Query("limit"); v != "" {
+		limit, err := strconv.Atoi(v)
+		if err != nil {
+			return query, errors.New("無效的 limit")
+		}
+		query.Limit = limit
+	}
 
-	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取最近訊息成功", "data": messages})
+	return query.Normalize(), nil
 }
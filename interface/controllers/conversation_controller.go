@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ConversationController struct {
+	conversationUseCase chat.ConversationUseCase
+}
+
+func NewConversationController(conversationUseCase chat.ConversationUseCase) *ConversationController {
+	return &ConversationController{conversationUseCase: conversationUseCase}
+}
+
+// conversationRequest 定位單一會話的請求欄位
+type conversationRequest struct {
+	UserID   uint `json:"userId"`
+	Type     int  `json:"type"`     // 1-私聊，2-群聊
+	TargetID uint `json:"targetId"` // 私聊為對方用戶ID，群聊為群組ID
+}
+
+// GetConversations 分頁獲取用戶的會話列表
+func (cc *ConversationController) GetConversations(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	var query entities.ConversationQuery
+	if v := c.Query("offset"); v != "" {
+		if query.Offset, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的 offset"})
+			return
+		}
+	}
+	if v := c.Query("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的 limit"})
+			return
+		}
+	}
+
+	page, err := cc.conversationUseCase.ListConversations(c.Request.Context(), uint(userID), query)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取會話列表成功", "data": page.Conversations, "has_more": page.HasMore})
+}
+
+// GetUnreadCount 獲取單一會話的未讀數
+func (cc *ConversationController) GetUnreadCount(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+	convType, err := strconv.Atoi(c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的會話類型"})
+		return
+	}
+	targetID, err := strconv.ParseUint(c.Query("targetId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的會話對象ID"})
+		return
+	}
+
+	count, err := cc.conversationUseCase.GetUnreadCount(c.Request.Context(), uint(userID), entities.ConversationType(convType), uint(targetID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取未讀數成功", "data": gin.H{"unread_count": count}})
+}
+
+// MarkRead 推進會話的已讀位置，並同步到用戶的其他裝置
+func (cc *ConversationController) MarkRead(c *gin.Context) {
+	var req struct {
+		conversationRequest
+		MessageID uint `json:"messageId"` // 已讀到的訊息ID，為 0 時表示全部已讀
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	conversation, err := cc.conversationUseCase.MarkRead(c.Request.Context(), req.UserID, entities.ConversationType(req.Type), req.TargetID, req.MessageID)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "已標記為已讀", "data": conversation})
+}
+
+// SetMuted 設定會話免打擾
+func (cc *ConversationController) SetMuted(c *gin.Context) {
+	var req struct {
+		conversationRequest
+		Muted bool `json:"muted"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	if err := cc.conversationUseCase.SetMuted(c.Request.Context(), req.UserID, entities.ConversationType(req.Type), req.TargetID, req.Muted); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "設定免打擾成功"})
+}
+
+// SetPinned 設定會話置頂
+func (cc *ConversationController) SetPinned(c *gin.Context) {
+	var req struct {
+		conversationRequest
+		Pinned bool `json:"pinned"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	if err := cc.conversationUseCase.SetPinned(c.Request.Context(), req.UserID, entities.ConversationType(req.Type), req.TargetID, req.Pinned); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "設定置頂成功"})
+}
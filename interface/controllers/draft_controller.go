@@ -0,0 +1,92 @@
+package controllers
+
+import (
+	websocketInfra "clean-architecture-gochat/infrastructure/websocket"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type DraftController struct {
+	draftUseCase chat.DraftUseCase
+	debouncer    *chat.DraftDebouncer
+}
+
+func NewDraftController(draftUseCase chat.DraftUseCase, debouncer *chat.DraftDebouncer) *DraftController {
+	return &DraftController{draftUseCase: draftUseCase, debouncer: debouncer}
+}
+
+// draftRequest 草稿更新的內容，HTTP 與 WebSocket 共用
+type draftRequest struct {
+	UserID   uint   `json:"userId"`
+	Type     int    `json:"type"`     // 1-私聊，2-群聊
+	TargetID uint   `json:"targetId"` // 私聊為對方用戶ID，群聊為群組ID
+	Content  string `json:"content"`  // 空字串表示清除草稿
+}
+
+func (r draftRequest) draft() *entities.Draft {
+	return &entities.Draft{
+		UserID:   r.UserID,
+		Type:     entities.ConversationType(r.Type),
+		TargetID: r.TargetID,
+		Content:  r.Content,
+	}
+}
+
+// ListDrafts 列出用戶所有的草稿
+func (dc *DraftController) ListDrafts(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	drafts, err := dc.draftUseCase.ListDrafts(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取草稿成功", "data": drafts})
+}
+
+// SaveDraft 立即儲存會話草稿，內容為空時清除草稿
+func (dc *DraftController) SaveDraft(c *gin.Context) {
+	var req draftRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	draft := req.draft()
+	if err := dc.draftUseCase.SaveDraft(c.Request.Context(), draft); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "儲存草稿成功", "data": draft})
+}
+
+// HandleDraftUpdate 處理客戶端透過 WebSocket 送出的草稿更新，合併頻繁的更新後再儲存。
+// 用戶ID以連線的用戶為準，忽略訊息中的 userId
+func (dc *DraftController) HandleDraftUpdate(client *websocketInfra.Client, data json.RawMessage) {
+	userID, err := strconv.ParseUint(client.UserID, 10, 32)
+	if err != nil {
+		return
+	}
+
+	var req draftRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		log.Printf("Invalid draft update from user %d: %v", userID, err)
+		return
+	}
+	req.UserID = uint(userID)
+	dc.debouncer.Update(req.draft())
+}
@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// exportContentTypes 各匯出檔副檔名下載時的 Content-Type
+var exportContentTypes = map[string]string{
+	"json": "application/json; charset=utf-8",
+	"html": "text/html; charset=utf-8",
+	"csv":  "text/csv; charset=utf-8",
+	"zip":  "application/zip",
+}
+
+type ExportController struct {
+	exportUseCase chat.ExportUseCase
+}
+
+func NewExportController(exportUseCase chat.ExportUseCase) *ExportController {
+	return &ExportController{exportUseCase: exportUseCase}
+}
+
+// CreateExport 建立對話匯出工作
+func (ec *ExportController) CreateExport(c *gin.Context) {
+	var req struct {
+		UserID            uint   `json:"userId"`
+		Type              int    `json:"type"`     // 1-私聊，2-群聊
+		TargetID          uint   `json:"targetId"` // 私聊為對方用戶ID，群聊為群組ID
+		Format            string `json:"format"`   // json、html 或 csv
+		BundleAttachments bool   `json:"bundleAttachments"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	job := &entities.ExportJob{
+		UserID:            req.UserID,
+		Type:              entities.ConversationType(req.Type),
+		TargetID:          req.TargetID,
+		Format:            entities.ExportFormat(strings.ToLower(req.Format)),
+		BundleAttachments: req.BundleAttachments,
+	}
+	if err := ec.exportUseCase.CreateExport(c.Request.Context(), job); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "匯出工作已建立", "data": job})
+}
+
+// ListExports 列出用戶最近的匯出工作
+func (ec *ExportController) ListExports(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	jobs, err := ec.exportUseCase.ListExports(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取匯出工作成功", "data": jobs})
+}
+
+// GetExport 獲取匯出工作的狀態與進度
+func (ec *ExportController) GetExport(c *gin.Context) {
+	id, userID, ok := parseExportRequest(c)
+	if !ok {
+		return
+	}
+
+	job, err := ec.exportUseCase.GetExport(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取匯出工作成功", "data": job})
+}
+
+// DownloadExport 下載已完成的匯出檔
+func (ec *ExportController) DownloadExport(c *gin.Context) {
+	id, userID, ok := parseExportRequest(c)
+	if !ok {
+		return
+	}
+
+	job, file, err := ec.exportUseCase.OpenExport(c.Request.Context(), userID, id)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+	defer file.Close()
+
+	name := job.DownloadName()
+	contentType := exportContentTypes[name[strings.LastIndex(name, ".")+1:]]
+	// 一律以附件下載，避免 HTML 匯出檔在本站網域下被瀏覽器直接開啟
+	c.DataFromReader(http.StatusOK, -1, contentType, file, map[string]string{
+		"Content-Disposition":    fmt.Sprintf(`attachment; filename="%s"`, name),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// parseExportRequest 解析路徑中的匯出工作ID與查詢參數中的用戶ID，失敗時已寫入錯誤回應
+func parseExportRequest(c *gin.Context) (id, userID uint, ok bool) {
+	exportID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的匯出工作ID"})
+		return 0, 0, false
+	}
+	uid, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return 0, 0, false
+	}
+	return uint(exportID), uint(uid), true
+}
@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+	"crypto/subtle"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportBodySize 匯入檔的大小上限，更大的檔案請使用 cmd/import 指令
+const maxImportBodySize = 64 << 20
+
+type ImportController struct {
+	importUseCase chat.ImportUseCase
+	token         string
+}
+
+// NewImportController 創建匯入控制器，token 為空時停用匯入 API
+func NewImportController(importUseCase chat.ImportUseCase, token string) *ImportController {
+	return &ImportController{importUseCase: importUseCase, token: token}
+}
+
+// ImportHistory 匯入外部聊天記錄，需在 X-Import-Token 標頭提供設定的匯入權杖，
+// dryRun=true 時只返回檢查報告，不寫入任何資料
+func (ic *ImportController) ImportHistory(c *gin.Context) {
+	provided := c.GetHeader("X-Import-Token")
+	if ic.token == "" || subtle.ConstantTimeCompare([]byte(provided), []byte(ic.token)) != 1 {
+		c.JSON(appErrors.ToResponse(appErrors.New(enum.ErrAccessDenied, "無效的匯入權杖")))
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dryRun"); value != "" {
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的 dryRun 參數"})
+			return
+		}
+		dryRun = parsed
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportBodySize)
+	var file entities.ImportFile
+	if err := c.ShouldBindJSON(&file); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的匯入檔格式"})
+		return
+	}
+
+	report, err := ic.importUseCase.Import(c.Request.Context(), &file, dryRun)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	message := "匯入完成"
+	if dryRun {
+		message = "試跑完成，未寫入任何資料"
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": message, "data": report})
+}
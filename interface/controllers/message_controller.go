@@ -0,0 +1,479 @@
+package controllers
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MessageController struct {
+	messageUseCase chat.MessageUseCase
+}
+
+func NewMessageController(messageUseCase chat.MessageUseCase) *MessageController {
+	return &MessageController{messageUseCase: messageUseCase}
+}
+
+// EditMessage 編輯自己發送的文字訊息
+func (mc *MessageController) EditMessage(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	var req struct {
+		UserID  uint   `json:"userId"`
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	message, err := mc.messageUseCase.EditMessage(c.Request.Context(), req.UserID, uint(messageID), req.Content)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "訊息編輯成功", "data": message})
+}
+
+// ForwardMessages 將多則訊息轉發到多個私聊或群組
+func (mc *MessageController) ForwardMessages(c *gin.Context) {
+	var req struct {
+		UserID     uint                 `json:"userId"`
+		MessageIDs []uint               `json:"messageIds"`
+		Targets    []chat.ForwardTarget `json:"targets"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	messages, err := mc.messageUseCase.ForwardMessages(c.Request.Context(), req.UserID, req.MessageIDs, req.Targets)
+	if err != nil {
+		respondSendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "訊息轉發成功", "data": messages})
+}
+
+// RecallMessage 撤回訊息
+func (mc *MessageController) RecallMessage(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"userId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	message, err := mc.messageUseCase.RecallMessage(c.Request.Context(), req.UserID, uint(messageID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "訊息已撤回", "data": message})
+}
+
+// AddReaction 對訊息新增表情回應
+func (mc *MessageController) AddReaction(c *gin.Context) {
+	mc.changeReaction(c, mc.messageUseCase.AddReaction)
+}
+
+// RemoveReaction 移除對訊息的表情回應
+func (mc *MessageController) RemoveReaction(c *gin.Context) {
+	mc.changeReaction(c, mc.messageUseCase.RemoveReaction)
+}
+
+// changeReaction 解析表情回應請求並呼叫對應的用例
+func (mc *MessageController) changeReaction(c *gin.Context, change func(ctx context.Context, userID, messageID uint, emoji string) ([]entities.ReactionCount, error)) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	var req struct {
+		UserID uint   `json:"userId"`
+		Emoji  string `json:"emoji"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	reactions, err := change(c.Request.Context(), req.UserID, uint(messageID), req.Emoji)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "更新表情回應成功", "data": reactions})
+}
+
+// GetRevisions 獲取訊息的編輯歷史
+func (mc *MessageController) GetRevisions(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	revisions, err := mc.messageUseCase.GetMessageRevisions(c.Request.Context(), uint(userID), uint(messageID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取編輯歷史成功", "data": revisions})
+}
+
+// GetThread 以游標分頁獲取討論串的根訊息與回覆
+func (mc *MessageController) GetThread(c *gin.Context) {
+	rootID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	query, err := parseHistoryQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	thread, err := mc.messageUseCase.GetThread(c.Request.Context(), uint(userID), uint(rootID), query)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取討論串成功", "data": thread})
+}
+
+// PinMessage 釘選訊息
+func (mc *MessageController) PinMessage(c *gin.Context) {
+	messageID, userID, ok := parsePinRequest(c)
+	if !ok {
+		return
+	}
+
+	pin, err := mc.messageUseCase.PinMessage(c.Request.Context(), userID, messageID)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "訊息已釘選", "data": pin})
+}
+
+// UnpinMessage 取消釘選訊息
+func (mc *MessageController) UnpinMessage(c *gin.Context) {
+	messageID, userID, ok := parsePinRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := mc.messageUseCase.UnpinMessage(c.Request.Context(), userID, messageID); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "已取消釘選"})
+}
+
+// ListPinnedMessages 列出對話中的釘選訊息
+func (mc *MessageController) ListPinnedMessages(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+	convType, err := strconv.Atoi(c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的會話類型"})
+		return
+	}
+	targetID, err := strconv.ParseUint(c.Query("targetId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的會話對象ID"})
+		return
+	}
+
+	pins, err := mc.messageUseCase.ListPinnedMessages(c.Request.Context(), uint(userID), entities.ConversationType(convType), uint(targetID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取釘選訊息成功", "data": pins})
+}
+
+// SetDisappearing 設定對話的訊息自動消失時間
+func (mc *MessageController) SetDisappearing(c *gin.Context) {
+	var req struct {
+		UserID   uint                      `json:"userId"`
+		Type     entities.ConversationType `json:"type"`
+		TargetID uint                      `json:"targetId"`
+		TTL      int                       `json:"ttl"` // 單位秒，0 表示關閉
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	ttl := time.Duration(req.TTL) * time.Second
+	if err := mc.messageUseCase.SetDisappearing(c.Request.Context(), req.UserID, req.Type, req.TargetID, ttl); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "設定訊息自動消失成功", "data": gin.H{"ttl": req.TTL}})
+}
+
+// GetDisappearing 獲取對話的訊息自動消失時間
+func (mc *MessageController) GetDisappearing(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+	convType, err := strconv.Atoi(c.Query("type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的會話類型"})
+		return
+	}
+	targetID, err := strconv.ParseUint(c.Query("targetId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的會話對象ID"})
+		return
+	}
+
+	ttl, err := mc.messageUseCase.GetDisappearing(c.Request.Context(), uint(userID), entities.ConversationType(convType), uint(targetID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取訊息自動消失設定成功", "data": gin.H{"ttl": int(ttl / time.Second)}})
+}
+
+// parsePinRequest 解析只帶有用戶ID的訊息操作請求，用於釘選、截止投票與取消收藏，失敗時已寫入錯誤回應
+func parsePinRequest(c *gin.Context) (messageID, userID uint, ok bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return 0, 0, false
+	}
+
+	var req struct {
+		UserID uint `json:"userId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return 0, 0, false
+	}
+	return uint(id), req.UserID, true
+}
+
+// CreatePoll 在群組中建立投票
+func (mc *MessageController) CreatePoll(c *gin.Context) {
+	var req struct {
+		UserID         uint      `json:"userId"`
+		RoomID         uint      `json:"roomId"`
+		Question       string    `json:"question"`
+		Options        []string  `json:"options"`
+		MultipleChoice bool      `json:"multipleChoice"`
+		Anonymous      bool      `json:"anonymous"`
+		ClosesAt       time.Time `json:"closesAt"` // RFC3339 格式
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	message, err := mc.messageUseCase.CreatePoll(c.Request.Context(), &entities.Poll{
+		CreatorID:      req.UserID,
+		RoomID:         req.RoomID,
+		Question:       req.Question,
+		Options:        req.Options,
+		MultipleChoice: req.MultipleChoice,
+		Anonymous:      req.Anonymous,
+		ClosesAt:       req.ClosesAt,
+	})
+	if err != nil {
+		respondSendError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "投票建立成功", "data": message})
+}
+
+// GetPoll 獲取投票目前的結果與用戶選擇的選項
+func (mc *MessageController) GetPoll(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	result, err := mc.messageUseCase.GetPoll(c.Request.Context(), uint(userID), uint(messageID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取投票成功", "data": result})
+}
+
+// VotePoll 投票，以本次選擇的選項取代之前的選擇，選項為空時撤回投票
+func (mc *MessageController) VotePoll(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	var req struct {
+		UserID  uint  `json:"userId"`
+		Options []int `json:"options"` // 選項索引，從 0 開始
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	result, err := mc.messageUseCase.VotePoll(c.Request.Context(), req.UserID, uint(messageID), req.Options)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "投票成功", "data": result})
+}
+
+// ClosePoll 提前截止投票
+func (mc *MessageController) ClosePoll(c *gin.Context) {
+	messageID, userID, ok := parsePinRequest(c)
+	if !ok {
+		return
+	}
+
+	result, err := mc.messageUseCase.ClosePoll(c.Request.Context(), userID, messageID)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "投票已截止", "data": result})
+}
+
+// StarMessage 收藏訊息，已收藏時更新備註
+func (mc *MessageController) StarMessage(c *gin.Context) {
+	messageID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的訊息ID"})
+		return
+	}
+
+	var req struct {
+		UserID uint   `json:"userId"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	star, err := mc.messageUseCase.StarMessage(c.Request.Context(), req.UserID, uint(messageID), req.Note)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "訊息已收藏", "data": star})
+}
+
+// UnstarMessage 取消收藏訊息
+func (mc *MessageController) UnstarMessage(c *gin.Context) {
+	messageID, userID, ok := parsePinRequest(c)
+	if !ok {
+		return
+	}
+
+	if err := mc.messageUseCase.UnstarMessage(c.Request.Context(), userID, messageID); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "已取消收藏"})
+}
+
+// ListStarredMessages 分頁列出用戶跨對話的收藏
+func (mc *MessageController) ListStarredMessages(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	var query entities.StarQuery
+	if v := c.Query("beforeId"); v != "" {
+		beforeID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的 beforeId"})
+			return
+		}
+		query.BeforeID = uint(beforeID)
+	}
+	if v := c.Query("limit"); v != "" {
+		if query.Limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的 limit"})
+			return
+		}
+	}
+
+	page, err := mc.messageUseCase.ListStarredMessages(c.Request.Context(), uint(userID), query)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取收藏成功", "data": page})
+}
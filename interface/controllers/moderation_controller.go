@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ModerationController struct {
+	moderationUseCase chat.ModerationUseCase
+}
+
+func NewModerationController(moderationUseCase chat.ModerationUseCase) *ModerationController {
+	return &ModerationController{moderationUseCase: moderationUseCase}
+}
+
+// ListRules 列出群組的審核規則
+func (mc *ModerationController) ListRules(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的群組ID"})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	rules, err := mc.moderationUseCase.ListRules(c.Request.Context(), uint(userID), uint(groupID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取審核規則成功", "data": rules})
+}
+
+// CreateRule 新增群組的審核規則
+func (mc *ModerationController) CreateRule(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的群組ID"})
+		return
+	}
+	var req struct {
+		UserID  uint   `json:"userId"`
+		Pattern string `json:"pattern"`
+		Regex   bool   `json:"regex"`  // false 時為不分大小寫的關鍵字
+		Action  string `json:"action"` // mask、hold 或 reject
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	rule := &entities.ModerationRule{
+		GroupID:   uint(groupID),
+		Pattern:   req.Pattern,
+		IsRegex:   req.Regex,
+		Action:    entities.ModerationAction(strings.ToLower(req.Action)),
+		CreatedBy: req.UserID,
+	}
+	if err := mc.moderationUseCase.CreateRule(c.Request.Context(), rule); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "審核規則已新增", "data": rule})
+}
+
+// DeleteRule 刪除群組的審核規則
+func (mc *ModerationController) DeleteRule(c *gin.Context) {
+	groupID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的群組ID"})
+		return
+	}
+	ruleID, err := strconv.ParseUint(c.Param("ruleId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的規則ID"})
+		return
+	}
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	if err := mc.moderationUseCase.DeleteRule(c.Request.Context(), uint(userID), uint(groupID), uint(ruleID)); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "審核規則已刪除"})
+}
+
+// ListHeld 分頁列出審核佇列，可用 groupId 限定群組、status 篩選審核狀態
+func (mc *ModerationController) ListHeld(c *gin.Context) {
+	userID, beforeID, limit, ok := parseModerationPaging(c)
+	if !ok {
+		return
+	}
+	query := entities.HeldQuery{
+		Status:   entities.HeldStatus(c.Query("status")),
+		BeforeID: beforeID,
+		Limit:    limit,
+	}
+	if v := c.Query("groupId"); v != "" {
+		groupID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的群組ID"})
+			return
+		}
+		query.GroupID = uint(groupID)
+	}
+
+	page, err := mc.moderationUseCase.ListHeld(c.Request.Context(), userID, query)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取審核佇列成功", "data": page})
+}
+
+// ApproveHeld 核准待審核訊息並以原內容發送
+func (mc *ModerationController) ApproveHeld(c *gin.Context) {
+	mc.reviewHeld(c, true)
+}
+
+// RejectHeld 駁回待審核訊息
+func (mc *ModerationController) RejectHeld(c *gin.Context) {
+	mc.reviewHeld(c, false)
+}
+
+func (mc *ModerationController) reviewHeld(c *gin.Context, approve bool) {
+	heldID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的待審核訊息ID"})
+		return
+	}
+	var req struct {
+		UserID uint   `json:"userId"`
+		Note   string `json:"note"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	held, err := mc.moderationUseCase.ReviewHeld(c.Request.Context(), req.UserID, uint(heldID), approve, req.Note)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	message := "訊息已駁回"
+	if approve {
+		message = "訊息已核准並發送"
+	}
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": message, "data": held})
+}
+
+// ListLogs 分頁列出審核的稽核日誌，可用 type 與 targetId 限定對話
+func (mc *ModerationController) ListLogs(c *gin.Context) {
+	userID, beforeID, limit, ok := parseModerationPaging(c)
+	if !ok {
+		return
+	}
+	query := entities.ModerationLogQuery{BeforeID: beforeID, Limit: limit}
+	if v := c.Query("targetId"); v != "" {
+		convType, err := strconv.Atoi(c.Query("type"))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的對話類型"})
+			return
+		}
+		targetID, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的對話ID"})
+			return
+		}
+		query.Type = entities.ConversationType(convType)
+		query.TargetID = uint(targetID)
+	}
+
+	page, err := mc.moderationUseCase.ListLogs(c.Request.Context(), userID, query)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取稽核日誌成功", "data": page})
+}
+
+// parseModerationPaging 解析查詢參數中的用戶ID與分頁參數，失敗時已寫入錯誤回應
+func parseModerationPaging(c *gin.Context) (userID, beforeID uint, limit int, ok bool) {
+	id, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return 0, 0, 0, false
+	}
+	if v := c.Query("beforeId"); v != "" {
+		before, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的 beforeId"})
+			return 0, 0, 0, false
+		}
+		beforeID = uint(before)
+	}
+	if v := c.Query("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的 limit"})
+			return 0, 0, 0, false
+		}
+	}
+	return uint(id), beforeID, limit, true
+}
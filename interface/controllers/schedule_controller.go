@@ -0,0 +1,122 @@
+package controllers
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ScheduleController struct {
+	scheduleUseCase chat.ScheduleUseCase
+}
+
+func NewScheduleController(scheduleUseCase chat.ScheduleUseCase) *ScheduleController {
+	return &ScheduleController{scheduleUseCase: scheduleUseCase}
+}
+
+// ScheduleMessage 建立在指定時間發送的訊息
+func (sc *ScheduleController) ScheduleMessage(c *gin.Context) {
+	var req struct {
+		UserID   uint          `json:"userId"`
+		Type     int           `json:"type"`     // 1-私聊，2-群聊
+		TargetID uint          `json:"targetId"` // 私聊為接收者ID，群聊為群組ID
+		Media    int           `json:"media"`
+		Content  string        `json:"content"`
+		Metadata entities.JSON `json:"metadata"`
+		SendAt   time.Time     `json:"sendAt"` // RFC3339 格式
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	scheduled := &entities.ScheduledMessage{
+		UserID:   req.UserID,
+		Type:     entities.MessageType(req.Type),
+		TargetID: req.TargetID,
+		Media:    entities.MediaType(req.Media),
+		Content:  req.Content,
+		Metadata: req.Metadata,
+		SendAt:   req.SendAt,
+	}
+	if err := sc.scheduleUseCase.ScheduleMessage(c.Request.Context(), scheduled); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "排程訊息建立成功", "data": scheduled})
+}
+
+// ListScheduled 列出用戶待發送的排程訊息
+func (sc *ScheduleController) ListScheduled(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	scheduled, err := sc.scheduleUseCase.ListScheduled(c.Request.Context(), uint(userID))
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "獲取排程訊息成功", "data": scheduled})
+}
+
+// UpdateScheduled 修改待發送排程的內容與發送時間
+func (sc *ScheduleController) UpdateScheduled(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的排程ID"})
+		return
+	}
+
+	var req struct {
+		UserID  uint      `json:"userId"`
+		Content string    `json:"content"`
+		SendAt  time.Time `json:"sendAt"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	scheduled, err := sc.scheduleUseCase.UpdateScheduled(c.Request.Context(), req.UserID, uint(id), req.Content, req.SendAt)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "排程訊息已更新", "data": scheduled})
+}
+
+// CancelScheduled 取消待發送的排程
+func (sc *ScheduleController) CancelScheduled(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的排程ID"})
+		return
+	}
+
+	var req struct {
+		UserID uint `json:"userId"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的請求格式"})
+		return
+	}
+
+	if err := sc.scheduleUseCase.CancelScheduled(c.Request.Context(), req.UserID, uint(id)); err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "排程訊息已取消"})
+}
@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SearchController struct {
+	searchUseCase chat.SearchUseCase
+}
+
+func NewSearchController(searchUseCase chat.SearchUseCase) *SearchController {
+	return &SearchController{searchUseCase: searchUseCase}
+}
+
+// SearchMessages 搜尋用戶可見的訊息
+// 參數：userId、q 為必填；type 與 targetId 限定對話；senderId、media 過濾發送者與媒體類型；
+// from、to 為 RFC3339 時間或 YYYY-MM-DD 日期（to 為日期時包含當天）；offset、limit 分頁
+func (sc *SearchController) SearchMessages(c *gin.Context) {
+	userID, err := strconv.ParseUint(c.Query("userId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": "無效的用戶ID"})
+		return
+	}
+
+	query, err := parseSearchQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"code": -1, "message": err.Error()})
+		return
+	}
+
+	result, err := sc.searchUseCase.SearchMessages(c.Request.Context(), uint(userID), query)
+	if err != nil {
+		c.JSON(appErrors.ToResponse(err))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"code": 0, "message": "搜尋成功", "data": result.Hits, "has_more": result.HasMore})
+}
+
+// parseSearchQuery 解析搜尋條件
+func parseSearchQuery(c *gin.Context) (chat.SearchQuery, error) {
+	query := chat.SearchQuery{Text: c.Query("q")}
+
+	uintParams := []struct {
+		name   string
+		target *uint
+	}{
+		{"targetId", &query.TargetID},
+		{"senderId", &query.SenderID},
+	}
+	for _, p := range uintParams {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				return query, fmt.Errorf("無效的 %s", p.name)
+			}
+			*p.target = uint(n)
+		}
+	}
+
+	intParams := []struct {
+		name   string
+		target *int
+	}{
+		{"type", (*int)(&query.ConversationType)},
+		{"media", (*int)(&query.Media)},
+		{"offset", &query.Offset},
+		{"limit", &query.Limit},
+	}
+	for _, p := range intParams {
+		if v := c.Query(p.name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return query, fmt.Errorf("無效的 %s", p.name)
+			}
+			*p.target = n
+		}
+	}
+
+	var err error
+	if query.From, err = parseSearchTime(c.Query("from"), false); err != nil {
+		return query, fmt.Errorf("無效的 from")
+	}
+	if query.To, err = parseSearchTime(c.Query("to"), true); err != nil {
+		return query, fmt.Errorf("無效的 to")
+	}
+	return query, nil
+}
+
+// parseSearchTime 解析 RFC3339 時間或 YYYY-MM-DD 日期，endOfDay 時日期會換算為隔天零點
+func parseSearchTime(v string, endOfDay bool) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", v, time.Local)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
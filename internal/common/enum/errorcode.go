@@ -26,10 +26,26 @@ const (
 	ErrUserDeleteFailed  ErrorCode = 3003
 
 	// 聊天錯誤 (4xxx)
-	ErrMessageSendFailed ErrorCode = 4000
-	ErrMessageInvalid    ErrorCode = 4001
-	ErrReceiverNotFound  ErrorCode = 4002
-	ErrHistoryLoadFailed ErrorCode = 4003
+	ErrMessageSendFailed    ErrorCode = 4000
+	ErrMessageInvalid       ErrorCode = 4001
+	ErrReceiverNotFound     ErrorCode = 4002
+	ErrHistoryLoadFailed    ErrorCode = 4003
+	ErrConversationNotFound ErrorCode = 4004
+	ErrMessageNotFound      ErrorCode = 4005
+	ErrMessageEditExpired   ErrorCode = 4006
+	ErrMessageRecallExpired ErrorCode = 4007
+	ErrReactionNotAllowed   ErrorCode = 4008
+	ErrPinLimitReached      ErrorCode = 4009
+	ErrScheduleNotFound     ErrorCode = 4010
+	ErrScheduleNotPending   ErrorCode = 4011
+	ErrPollClosed           ErrorCode = 4012
+	ErrExportNotFound       ErrorCode = 4013
+	ErrExportNotReady       ErrorCode = 4014
+	ErrMessageHeld          ErrorCode = 4015
+	ErrMessageRejected      ErrorCode = 4016
+	ErrHeldMessageNotFound  ErrorCode = 4017
+	ErrHeldMessageReviewed  ErrorCode = 4018
+	ErrRateLimited          ErrorCode = 4019
 
 	// 群組錯誤 (5xxx)
 	ErrGroupNotFound     ErrorCode = 5000
@@ -81,10 +97,26 @@ var ErrorCodeDetails = map[ErrorCode]struct {
 	ErrUserUpdateFailed:  {"USER_UPDATE_FAILED", "用戶更新失敗"},
 	ErrUserDeleteFailed:  {"USER_DELETE_FAILED", "用戶刪除失敗"},
 
-	ErrMessageSendFailed: {"MESSAGE_SEND_FAILED", "消息發送失敗"},
-	ErrMessageInvalid:    {"MESSAGE_INVALID", "無效的消息"},
-	ErrReceiverNotFound:  {"RECEIVER_NOT_FOUND", "接收者不存在"},
-	ErrHistoryLoadFailed: {"HISTORY_LOAD_FAILED", "歷史記錄加載失敗"},
+	ErrMessageSendFailed:    {"MESSAGE_SEND_FAILED", "消息發送失敗"},
+	ErrMessageInvalid:       {"MESSAGE_INVALID", "無效的消息"},
+	ErrReceiverNotFound:     {"RECEIVER_NOT_FOUND", "接收者不存在"},
+	ErrHistoryLoadFailed:    {"HISTORY_LOAD_FAILED", "歷史記錄加載失敗"},
+	ErrConversationNotFound: {"CONVERSATION_NOT_FOUND", "會話不存在"},
+	ErrMessageNotFound:      {"MESSAGE_NOT_FOUND", "消息不存在"},
+	ErrMessageEditExpired:   {"MESSAGE_EDIT_EXPIRED", "已超過可編輯時間"},
+	ErrMessageRecallExpired: {"MESSAGE_RECALL_EXPIRED", "已超過可撤回時間"},
+	ErrReactionNotAllowed:   {"REACTION_NOT_ALLOWED", "此群組不允許使用該表情"},
+	ErrPinLimitReached:      {"PIN_LIMIT_REACHED", "此對話的釘選訊息已達上限"},
+	ErrScheduleNotFound:     {"SCHEDULE_NOT_FOUND", "排程訊息不存在"},
+	ErrScheduleNotPending:   {"SCHEDULE_NOT_PENDING", "排程訊息已發送或已取消"},
+	ErrPollClosed:           {"POLL_CLOSED", "投票已截止"},
+	ErrExportNotFound:       {"EXPORT_NOT_FOUND", "匯出工作不存在"},
+	ErrExportNotReady:       {"EXPORT_NOT_READY", "匯出尚未完成"},
+	ErrMessageHeld:          {"MESSAGE_HELD", "訊息需經管理員審核後才會發送"},
+	ErrMessageRejected:      {"MESSAGE_REJECTED", "訊息含有不允許的內容"},
+	ErrHeldMessageNotFound:  {"HELD_MESSAGE_NOT_FOUND", "待審核訊息不存在"},
+	ErrHeldMessageReviewed:  {"HELD_MESSAGE_REVIEWED", "訊息已審核"},
+	ErrRateLimited:          {"RATE_LIMITED", "發送過於頻繁，請稍後再試"},
 
 	ErrGroupNotFound:     {"GROUP_NOT_FOUND", "群組不存在"},
 	ErrGroupCreateFailed: {"GROUP_CREATE_FAILED", "創建群組失敗"},
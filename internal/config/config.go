@@ -30,6 +30,56 @@ type AppConfig struct {
 		Server string
 		UDP    int
 	}
+	Chat struct {
+		EditWindow         int // 訊息發送後可編輯的時間 單位秒
+		RecallWindow       int // 訊息發送後發送者可撤回的時間 單位秒
+		PinLimit           int // 每個對話最多可釘選的訊息數
+		ScheduleInterval   int // 檢查到期排程訊息的間隔 單位秒
+		PurgeInterval      int // 清理自動消失訊息的間隔 單位秒
+		DraftDebounce      int // WebSocket 草稿更新的合併時間 單位毫秒
+		DraftFlushInterval int // 將草稿寫回資料庫的間隔 單位秒
+		PollCloseInterval  int // 檢查到期投票的間隔 單位秒
+	}
+	LinkPreview struct {
+		Timeout     int // 讀取單一網址的時間上限 單位秒
+		MaxBodySize int // 讀取網頁內容的大小上限 單位 KB
+	}
+	Search struct {
+		Driver string // 訊息搜尋索引：mysql（FULLTEXT）或 memory（本機開發用的記憶體索引）
+	}
+	Export struct {
+		Dir      string // 保存對話匯出檔的目錄，不可位於公開的靜態檔案目錄下
+		Interval int    // 檢查待處理匯出的間隔 單位秒
+	}
+	Import struct {
+		Token string // 呼叫匯入 API 需在 X-Import-Token 標頭提供的權杖，空白表示停用匯入 API
+	}
+	Moderation struct {
+		Rules []struct {
+			Pattern string // 關鍵字或正規表達式
+			Regex   bool   // false 時為不分大小寫的關鍵字
+			Action  string // mask、hold 或 reject
+		}
+		Reviewers []uint // 可審核所有對話的全域審核者用戶ID，私聊訊息被暫扣時只會通知他們
+	}
+	RateLimit struct {
+		NewAccountAge int           // 註冊未滿此時間的帳號套用 newUser 限制 單位秒，0 表示不區分
+		Text          RateLimitTier // 文字訊息
+		Media         RateLimitTier // 圖片、語音、檔案、投票與轉發
+	}
+}
+
+// RateLimitTier 一類訊息的發送頻率限制
+type RateLimitTier struct {
+	User         RateLimit // 每個用戶在所有對話中合計
+	NewUser      RateLimit // 新註冊帳號的用戶限制
+	Conversation RateLimit // 每個對話中所有成員合計
+}
+
+// RateLimit 令牌桶的限制：最多可連續發送 Burst 則，之後每 Interval 毫秒可再發送一則，Burst 為 0 表示不限制
+type RateLimit struct {
+	Burst    int
+	Interval int
 }
 
 var Config *AppConfig
@@ -0,0 +1,27 @@
+package cache
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"time"
+)
+
+// DraftCache 定義草稿快取的介面。草稿以快取為準即時更新，變更過的草稿會被標記，
+// 由背景工作稍後寫回資料庫，連續編輯時只寫入最後的內容
+type DraftCache interface {
+	// GetDrafts 獲取用戶所有的草稿，用戶的草稿尚未載入時 found 為 false
+	GetDrafts(ctx context.Context, userID uint) (drafts []*entities.Draft, found bool, err error)
+
+	// LoadDrafts 以資料庫的草稿載入用戶的快取，已載入時不覆寫
+	LoadDrafts(ctx context.Context, userID uint, drafts []*entities.Draft) error
+
+	// SaveDraft 更新草稿並標記為待寫回，內容為空時刪除草稿。用戶的草稿尚未載入時不寫入並返回 false
+	SaveDraft(ctx context.Context, draft *entities.Draft) (bool, error)
+
+	// TakeDirty 取出在 before 之前標記的待寫回草稿，已刪除的草稿以空內容返回。
+	// 每筆草稿只會被一個呼叫者取出，可在多個程序同時執行
+	TakeDirty(ctx context.Context, before time.Time, limit int) ([]*entities.Draft, error)
+
+	// MarkDirty 重新標記草稿為待寫回，用於寫回資料庫失敗時重試
+	MarkDirty(ctx context.Context, draft *entities.Draft) error
+}
@@ -0,0 +1,15 @@
+package cache
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+)
+
+// LinkPreviewCache 定義以網址為鍵的連結預覽快取，同一網址在不同訊息中只需讀取一次
+type LinkPreviewCache interface {
+	// GetLinkPreview 讀取網址的預覽，未快取時 found 為 false，快取了讀取失敗的結果時 preview 為 nil
+	GetLinkPreview(ctx context.Context, url string) (preview *entities.LinkPreview, found bool, err error)
+
+	// StoreLinkPreview 快取網址的預覽，preview 為 nil 時快取讀取失敗的結果，以較短的時間過期
+	StoreLinkPreview(ctx context.Context, url string, preview *entities.LinkPreview) error
+}
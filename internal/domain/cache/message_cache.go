@@ -14,17 +14,54 @@ type MessageCacheRepository interface {
 	// StoreGroupMessage 儲存群組訊息到快取
 	StoreGroupMessage(ctx context.Context, message *entities.Message) error
 
-	// GetPrivateMessages 獲取私人訊息
+	// GetPrivateMessages 獲取私人訊息，依時間由舊到新排列
 	GetPrivateMessages(ctx context.Context, fromUserID, toUserID uint) ([]*entities.Message, error)
 
-	// GetGroupMessages 獲取群組訊息
+	// GetGroupMessages 獲取群組訊息，依時間由舊到新排列
 	GetGroupMessages(ctx context.Context, roomID uint) ([]*entities.Message, error)
 
+	// GetPrivateMessagesPage 從快取取出一頁私人訊息，hit 為 false 表示快取無法完整回答此查詢
+	GetPrivateMessagesPage(ctx context.Context, fromUserID, toUserID uint, query entities.HistoryQuery) (page *entities.MessagePage, hit bool, err error)
+
+	// GetGroupMessagesPage 從快取取出一頁群組訊息，hit 為 false 表示快取無法完整回答此查詢
+	GetGroupMessagesPage(ctx context.Context, roomID uint, query entities.HistoryQuery) (page *entities.MessagePage, hit bool, err error)
+
+	// WarmPrivateMessages 以資料庫中最新的一段訊息重建私聊快取，messages 依時間由舊到新排列
+	WarmPrivateMessages(ctx context.Context, userID, targetID uint, messages []*entities.Message) error
+
+	// WarmGroupMessages 以資料庫中最新的一段訊息重建群組快取，messages 依時間由舊到新排列
+	WarmGroupMessages(ctx context.Context, roomID uint, messages []*entities.Message) error
+
+	// UpdateMessage 以新內容取代快取列表中相同ID的訊息，訊息不在快取中時不做任何事
+	UpdateMessage(ctx context.Context, message *entities.Message) error
+
+	// GetPins 獲取對話的釘選列表快取，hit 為 false 表示未快取
+	GetPins(ctx context.Context, scope string) (pins []*entities.PinnedMessage, hit bool, err error)
+
+	// StorePins 快取對話的釘選列表
+	StorePins(ctx context.Context, scope string, pins []*entities.PinnedMessage) error
+
+	// InvalidatePins 刪除對話的釘選列表快取，於釘選變動或被釘選訊息更新時呼叫
+	InvalidatePins(ctx context.Context, scope string) error
+
 	// GetUserMessageList 獲取用戶的訊息列表
 	GetUserMessageList(ctx context.Context, userID uint) ([]*entities.Message, error)
 
+	// InvalidateMessages 刪除訊息所屬對話的快取列表，下次查詢時由資料庫重建。
+	// 用於無法依序追加到快取的寫入，例如匯入歷史訊息
+	InvalidateMessages(ctx context.Context, message *entities.Message) error
+
+	// RemoveMessages 從私聊與群組的快取列表中移除訊息
+	RemoveMessages(ctx context.Context, messages []*entities.Message) error
+
 	// CleanExpiredMessages 清理過期的訊息
 	CleanExpiredMessages(ctx context.Context) error
+
+	// GetClientMessageID 查詢 client_msg_id 對應的已儲存訊息ID，不存在時返回 0
+	GetClientMessageID(ctx context.Context, userID uint, clientMsgID string) (uint, error)
+
+	// StoreClientMessageID 記錄 client_msg_id 與已儲存訊息ID的對應，用於重試去重
+	StoreClientMessageID(ctx context.Context, userID uint, clientMsgID string, messageID uint) error
 }
 
 // MessageCache 定義訊息快取的介面
@@ -0,0 +1,15 @@
+package cache
+
+import "context"
+
+// OfflineEventCache 定義離線事件暫存的介面，用戶上線後再補發
+type OfflineEventCache interface {
+	// PushOfflineEvent 將事件加入用戶的離線佇列
+	PushOfflineEvent(ctx context.Context, userID uint, payload []byte) error
+
+	// PopOfflineEvents 取出並清空用戶的離線佇列，依加入順序返回
+	PopOfflineEvents(ctx context.Context, userID uint) ([][]byte, error)
+
+	// RemoveMessageEvents 移除佇列中 data.id 為任一 messageIDs 的事件，用於撤回或刪除尚未送達的訊息內容
+	RemoveMessageEvents(ctx context.Context, userID uint, messageIDs ...uint) error
+}
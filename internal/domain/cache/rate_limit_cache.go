@@ -0,0 +1,21 @@
+package cache
+
+import (
+	"context"
+	"time"
+)
+
+// TokenBucket 一次取用的令牌桶：容量為 Burst，每經過 Interval 補充一個令牌，本次取出 Cost 個
+type TokenBucket struct {
+	Key      string
+	Burst    int
+	Interval time.Duration
+	Cost     int
+}
+
+// RateLimitCache 以 Redis 令牌桶限制發送頻率，多個副本共用同一份狀態
+type RateLimitCache interface {
+	// Take 原子地從所有令牌桶各取出 Cost 個令牌，任一桶的令牌不足時都不取出，
+	// 並返回令牌足夠前需等待的時間；retryAfter 為 0 表示已取出
+	Take(ctx context.Context, buckets []TokenBucket, now time.Time) (retryAfter time.Duration, err error)
+}
@@ -0,0 +1,12 @@
+package cache
+
+import "context"
+
+// SequenceCache 以 Redis 計數器配發對話的訊息序號，scope 格式與 entities.PinScopeOf 相同
+type SequenceCache interface {
+	// NextSeq 原子地遞增並返回對話的序號，計數器尚未初始化時 ok 為 false 且不會建立計數器
+	NextSeq(ctx context.Context, scope string) (seq uint64, ok bool, err error)
+
+	// EnsureSeq 將對話的計數器提高到至少 seq，用於初始化計數器或在資料庫接手配發後同步
+	EnsureSeq(ctx context.Context, scope string, seq uint64) error
+}
@@ -0,0 +1,26 @@
+package cache
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+)
+
+// UnreadCounterCache 定義會話未讀數計數器的介面，讓讀取未讀數不需查詢資料庫。
+// 計數器只在已載入時隨新訊息增加，尚未載入或已失效時由呼叫者從資料庫重新載入
+type UnreadCounterCache interface {
+	// RecordMessage 新訊息送出後將接收者的計數器加一、發送者的計數器歸零，不存在的計數器維持不存在
+	RecordMessage(ctx context.Context, message *entities.Message, recipients []uint) error
+
+	// GetUnread 讀取會話的未讀數，計數器不存在時 found 為 false
+	GetUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (count int, found bool, err error)
+
+	// PrepareLoad 在從資料庫讀取未讀數前呼叫，返回目前的載入版本；載入期間有新訊息時版本會改變
+	PrepareLoad(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (version int64, err error)
+
+	// LoadUnread 以資料庫的未讀數載入計數器，計數器已存在時不覆寫。
+	// 載入版本與 PrepareLoad 返回的不同時不寫入並返回 loaded = false，呼叫者需重新讀取資料庫
+	LoadUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, count int, version int64) (loaded bool, err error)
+
+	// InvalidateUnread 已讀位置變更後移除計數器，下次讀取時再從資料庫載入
+	InvalidateUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error
+}
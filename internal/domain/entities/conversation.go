@@ -0,0 +1,107 @@
+package entities
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// ConversationType 定義會話類型
+type ConversationType int
+
+const (
+	ConversationTypePrivate ConversationType = 1 // 私聊，TargetID 為對方用戶ID
+	ConversationTypeGroup   ConversationType = 2 // 群聊，TargetID 為群組ID
+)
+
+const (
+	// DefaultConversationLimit 未指定數量時每頁返回的會話數
+	DefaultConversationLimit = 20
+	// MaxConversationLimit 每頁最多返回的會話數
+	MaxConversationLimit = 100
+	// maxPreviewLength 最後一則訊息預覽的最大字數
+	maxPreviewLength = 100
+)
+
+// Conversation 用戶的會話，每個用戶對每個私聊對象或群組各有一筆，於發送與已讀時增量更新
+type Conversation struct {
+	ID                 uint             `json:"id" gorm:"primaryKey"`
+	UserID             uint             `json:"user_id" gorm:"not null;uniqueIndex:idx_conversations_owner_target,priority:1;index:idx_conversations_inbox,priority:1"`
+	Type               ConversationType `json:"type" gorm:"not null;uniqueIndex:idx_conversations_owner_target,priority:2"`
+	TargetID           uint             `json:"target_id" gorm:"not null;uniqueIndex:idx_conversations_owner_target,priority:3"`
+	LastMessageID      uint             `json:"last_message_id" gorm:"index"`
+	LastSenderID       uint             `json:"last_sender_id"`
+	LastMessagePreview string           `json:"last_message_preview" gorm:"size:400"`
+	LastReadMessageID  uint             `json:"last_read_message_id"`
+	UnreadCount        int              `json:"unread_count" gorm:"not null;default:0"`
+	MentionCount       int              `json:"mention_count" gorm:"not null;default:0"` // 未讀訊息中提及此用戶的數量
+	Muted              bool             `json:"muted" gorm:"not null;default:false"`
+	Pinned             bool             `json:"pinned" gorm:"not null;default:false;index:idx_conversations_inbox,priority:2"`
+	CreatedAt          time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time        `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP;index:idx_conversations_inbox,priority:3"`
+	Draft              *Draft           `json:"draft,omitempty" gorm:"-"` // 用戶尚未送出的草稿，查詢會話列表時附加
+}
+
+// TableName 指定表名
+func (Conversation) TableName() string {
+	return "conversations"
+}
+
+// ConversationQuery 會話列表的分頁條件
+type ConversationQuery struct {
+	Offset int // 略過的會話數
+	Limit  int // 每頁數量
+}
+
+// Normalize 修正超出範圍的分頁參數
+func (q ConversationQuery) Normalize() ConversationQuery {
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultConversationLimit
+	}
+	if q.Limit > MaxConversationLimit {
+		q.Limit = MaxConversationLimit
+	}
+	return q
+}
+
+// ConversationPage 一頁會話，置頂會話在前，其餘依最後更新時間由新到舊排列
+type ConversationPage struct {
+	Conversations []*Conversation `json:"conversations"`
+	HasMore       bool            `json:"has_more"`
+}
+
+// ConversationOf 返回訊息在指定參與者視角下所屬的會話類型與對象
+func ConversationOf(message *Message, userID uint) (ConversationType, uint) {
+	if message.IsGroupConversation() {
+		return ConversationTypeGroup, message.RoomID
+	}
+	if message.UserId == userID {
+		return ConversationTypePrivate, message.TargetId
+	}
+	return ConversationTypePrivate, message.UserId
+}
+
+// MessagePreview 產生會話列表中顯示的訊息預覽，非文字訊息以類型標示代替
+func MessagePreview(message *Message) string {
+	switch message.Media {
+	case MediaTypeImage:
+		return "[圖片]"
+	case MediaTypeVoice:
+		return "[語音]"
+	case MediaTypeVideo:
+		return "[影片]"
+	case MediaTypeFile:
+		return "[檔案]"
+	}
+
+	text := message.DisplayText()
+	if message.Media == MediaTypePoll {
+		text = "[投票] " + text
+	}
+	if utf8.RuneCountInString(text) <= maxPreviewLength {
+		return text
+	}
+	return string([]rune(text)[:maxPreviewLength]) + "…"
+}
@@ -0,0 +1,54 @@
+package entities
+
+import "time"
+
+// DisappearingTTLs 可選的訊息自動消失時間
+var DisappearingTTLs = []time.Duration{
+	time.Hour,
+	24 * time.Hour,
+	7 * 24 * time.Hour,
+}
+
+// DisappearingSetting 對話的訊息自動消失設定，私聊雙方或群組成員共用
+type DisappearingSetting struct {
+	Scope     string    `json:"scope" gorm:"primaryKey;size:64"` // 所屬對話，格式與 PinScopeOf 相同
+	TTL       int       `json:"ttl" gorm:"not null;default:0"`   // 新訊息發送後保留的秒數，0 表示關閉
+	UpdatedBy uint      `json:"updated_by"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (DisappearingSetting) TableName() string {
+	return "disappearing_settings"
+}
+
+// IsValidDisappearingTTL 判斷是否為可選的自動消失時間，0 表示關閉
+func IsValidDisappearingTTL(ttl time.Duration) bool {
+	if ttl == 0 {
+		return true
+	}
+	for _, allowed := range DisappearingTTLs {
+		if ttl == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// DisappearingLabel 返回自動消失時間在系統通知中顯示的文字
+func DisappearingLabel(ttl time.Duration) string {
+	switch ttl {
+	case time.Hour:
+		return "1 小時"
+	case 24 * time.Hour:
+		return "1 天"
+	case 7 * 24 * time.Hour:
+		return "1 週"
+	}
+	return ttl.String()
+}
+
+// IsExpired 判斷訊息在 now 時是否已超過自動消失時間
+func (m *Message) IsExpired(now time.Time) bool {
+	return m.ExpiresAt != nil && !m.ExpiresAt.After(now)
+}
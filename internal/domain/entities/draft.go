@@ -0,0 +1,34 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// MaxDraftLength 草稿內容的最大字數
+const MaxDraftLength = 4000
+
+// Draft 用戶在會話中尚未送出的草稿，每個用戶對每個會話最多一筆，讓其他裝置可以接續編輯
+type Draft struct {
+	ID        uint             `json:"-" gorm:"primaryKey"`
+	UserID    uint             `json:"user_id" gorm:"not null;uniqueIndex:idx_drafts_owner_target,priority:1"`
+	Type      ConversationType `json:"type" gorm:"not null;uniqueIndex:idx_drafts_owner_target,priority:2"`
+	TargetID  uint             `json:"target_id" gorm:"not null;uniqueIndex:idx_drafts_owner_target,priority:3"`
+	Content   string           `json:"content" gorm:"type:text"` // 空字串表示草稿已清除
+	UpdatedAt time.Time        `json:"updated_at"`
+}
+
+// TableName 指定表名
+func (Draft) TableName() string {
+	return "drafts"
+}
+
+// ConversationKey 返回草稿在用戶範圍內的會話識別，格式為 type:targetId
+func (d *Draft) ConversationKey() string {
+	return DraftConversationKey(d.Type, d.TargetID)
+}
+
+// DraftConversationKey 返回會話在用戶範圍內的草稿識別，格式為 type:targetId
+func DraftConversationKey(convType ConversationType, targetID uint) string {
+	return fmt.Sprintf("%d:%d", convType, targetID)
+}
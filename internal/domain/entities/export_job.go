@@ -0,0 +1,94 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// ExportFormat 對話匯出的檔案格式
+type ExportFormat string
+
+const (
+	ExportFormatJSON ExportFormat = "json" // 完整欄位的 JSON，適合程式處理
+	ExportFormatHTML ExportFormat = "html" // 可直接以瀏覽器開啟的單一 HTML 檔
+	ExportFormatCSV  ExportFormat = "csv"  // 每則訊息一列，適合試算表
+)
+
+// IsValid 判斷是否為支援的匯出格式
+func (f ExportFormat) IsValid() bool {
+	switch f {
+	case ExportFormatJSON, ExportFormatHTML, ExportFormatCSV:
+		return true
+	}
+	return false
+}
+
+// ExportStatus 匯出工作的狀態
+type ExportStatus string
+
+const (
+	ExportPending   ExportStatus = "pending"   // 等待背景工作處理
+	ExportRunning   ExportStatus = "running"   // 已被背景工作認領，正在產生檔案
+	ExportCompleted ExportStatus = "completed" // 檔案已產生，可以下載
+	ExportFailed    ExportStatus = "failed"    // 多次嘗試失敗後放棄
+)
+
+// MaxExportAttempts 每個匯出工作最多嘗試的次數
+const MaxExportAttempts = 3
+
+// ExportJob 將一個對話的完整歷史匯出為檔案的背景工作，只有建立者可以查詢與下載
+type ExportJob struct {
+	ID                uint             `json:"id" gorm:"primaryKey"`
+	UserID            uint             `json:"user_id" gorm:"not null;index:idx_export_jobs_owner,priority:1"`
+	Type              ConversationType `json:"type" gorm:"not null"`      // 私聊或群聊
+	TargetID          uint             `json:"target_id" gorm:"not null"` // 私聊為對方用戶ID，群聊為群組ID
+	Format            ExportFormat     `json:"format" gorm:"size:8;not null"`
+	BundleAttachments bool             `json:"bundle_attachments" gorm:"not null;default:false"` // 將本機附件與訊息檔一起打包為 zip
+	Status            ExportStatus     `json:"status" gorm:"size:16;not null;index:idx_export_jobs_status"`
+	Processed         int              `json:"processed"` // 已寫入的訊息數
+	Total             int              `json:"total"`     // 開始匯出時對話的訊息總數，匯出期間的新訊息可能使 Processed 超過此值
+	Attempts          int              `json:"attempts" gorm:"not null;default:0"`
+	FileName          string           `json:"-" gorm:"size:128"` // 已完成匯出在儲存區中的檔名
+	LastError         string           `json:"last_error,omitempty" gorm:"size:255"`
+	ClaimToken        string           `json:"-" gorm:"size:32;index"`
+	ClaimedUntil      *time.Time       `json:"-"` // 認領的有效期限，逾期未完成時可被其他程序重新認領
+	CompletedAt       *time.Time       `json:"completed_at,omitempty"`
+	CreatedAt         time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP;index:idx_export_jobs_owner,priority:2"`
+	UpdatedAt         time.Time        `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (ExportJob) TableName() string {
+	return "export_jobs"
+}
+
+// IsGroup 判斷是否為群聊匯出
+func (j *ExportJob) IsGroup() bool {
+	return j.Type == ConversationTypeGroup
+}
+
+// OutputName 返回此次認領產生的檔名，包含認領標記，避免認領逾期後重複執行的程序寫入同一個檔案
+func (j *ExportJob) OutputName() string {
+	ext := string(j.Format)
+	if j.BundleAttachments {
+		ext = "zip"
+	}
+	token := j.ClaimToken
+	if len(token) > 8 {
+		token = token[:8]
+	}
+	return fmt.Sprintf("export_%d_%s.%s", j.ID, token, ext)
+}
+
+// DownloadName 返回下載時建議的檔名
+func (j *ExportJob) DownloadName() string {
+	ext := string(j.Format)
+	if j.BundleAttachments {
+		ext = "zip"
+	}
+	kind := "private"
+	if j.IsGroup() {
+		kind = "group"
+	}
+	return fmt.Sprintf("chat_%s_%d_%s.%s", kind, j.TargetID, j.CreatedAt.Format("20060102"), ext)
+}
@@ -2,9 +2,23 @@ package entities
 
 import "time"
 
+// GroupRole 定義群組成員的角色，群主由 Group.OwnerId 決定
+type GroupRole string
+
+const (
+	GroupRoleMember GroupRole = "member" // 一般成員
+	GroupRoleAdmin  GroupRole = "admin"  // 管理員
+)
+
+// IsValid 判斷是否為可指派的角色
+func (r GroupRole) IsValid() bool {
+	return r == GroupRoleMember || r == GroupRoleAdmin
+}
+
 type GroupMember struct {
 	GroupID   uint      `json:"group_id" gorm:"primaryKey"`
 	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	Role      GroupRole `json:"role" gorm:"size:16;not null;default:member"`
 	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
 }
 
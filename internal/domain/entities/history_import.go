@@ -0,0 +1,131 @@
+package entities
+
+import (
+	"strings"
+	"time"
+)
+
+const (
+	// MaxImportSourceLength 匯入來源名稱的長度上限
+	MaxImportSourceLength = 64
+	// MaxImportExternalIDLength 外部ID的長度上限
+	MaxImportExternalIDLength = 128
+	// MaxImportTextLength 單則訊息內容的位元組上限，與 MySQL TEXT 欄位相同
+	MaxImportTextLength = 65535
+	// MaxImportIssues 匯入報告中列出的問題數上限，超過的只計入 IssueCount
+	MaxImportIssues = 200
+)
+
+// ImportFormat 外部訊息的內容格式
+type ImportFormat string
+
+const (
+	ImportFormatText     ImportFormat = "text"     // 純文字，預設值
+	ImportFormatMarkdown ImportFormat = "markdown" // 以富文本保存
+)
+
+// ImportFile 外部聊天記錄的匯入檔，格式說明見 docs/IMPORT_FORMAT.md。
+// 所有ID都是來源系統中的ID，同一 Source 重複匯入時以ID判斷是否已匯入
+type ImportFile struct {
+	Source   string          `json:"source"` // 來源名稱，例如 slack-acme，區分不同來源的外部ID
+	Users    []ImportUser    `json:"users"`
+	Rooms    []ImportRoom    `json:"rooms"`
+	Messages []ImportMessage `json:"messages"`
+}
+
+// ImportUser 外部用戶，電子郵件與既有帳號相同時對應到既有帳號，否則建立新帳號
+type ImportUser struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Email  string `json:"email"`
+	Phone  string `json:"phone"`
+	Avatar string `json:"avatar"`
+}
+
+// ImportRoom 外部聊天室，對應到群組
+type ImportRoom struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Owner       string   `json:"owner"`   // 群主的外部用戶ID
+	Members     []string `json:"members"` // 成員的外部用戶ID，群主可省略
+	Admins      []string `json:"admins"`  // 管理員的外部用戶ID，需同時為成員
+}
+
+// ImportMessage 外部訊息，Room 與 To 擇一：Room 為群組訊息，To 為私聊訊息
+type ImportMessage struct {
+	ID       string       `json:"id"`
+	Room     string       `json:"room"`
+	To       string       `json:"to"`
+	From     string       `json:"from"`
+	Text     string       `json:"text"`
+	Format   ImportFormat `json:"format"`
+	SentAt   time.Time    `json:"sentAt"`
+	EditedAt *time.Time   `json:"editedAt"`
+	ReplyTo  string       `json:"replyTo"` // 引用回覆的外部訊息ID
+	Thread   string       `json:"thread"`  // 所屬討論串根訊息的外部訊息ID
+}
+
+// NormalizedSource 返回去除前後空白的來源名稱
+func (f *ImportFile) NormalizedSource() string {
+	return strings.TrimSpace(f.Source)
+}
+
+// ImportKind 匯入對應的資料類型
+type ImportKind string
+
+const (
+	ImportKindUser    ImportKind = "user"
+	ImportKindRoom    ImportKind = "room"
+	ImportKindMessage ImportKind = "message"
+)
+
+// ImportMapping 記錄外部ID對應的本地ID，重複匯入時據此略過已匯入的資料
+type ImportMapping struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	Source     string     `json:"source" gorm:"size:64;not null;uniqueIndex:idx_import_mappings_key,priority:1"`
+	Kind       ImportKind `json:"kind" gorm:"size:16;not null;uniqueIndex:idx_import_mappings_key,priority:2"`
+	ExternalID string     `json:"external_id" gorm:"size:128;not null;uniqueIndex:idx_import_mappings_key,priority:3"`
+	LocalID    uint       `json:"local_id" gorm:"not null"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (ImportMapping) TableName() string {
+	return "import_mappings"
+}
+
+// ImportCounts 單一類型資料的匯入統計
+type ImportCounts struct {
+	Created  int `json:"created"`          // 新建立的數量，試跑時為將建立的數量
+	Existing int `json:"existing"`         // 先前已匯入而略過的數量
+	Linked   int `json:"linked,omitempty"` // 以電子郵件對應到既有帳號的用戶數
+	Skipped  int `json:"skipped"`          // 因資料有誤而略過的數量，原因見 Issues
+}
+
+// ImportIssue 匯入時略過的單筆資料與原因
+type ImportIssue struct {
+	Kind       ImportKind `json:"kind"`
+	ExternalID string     `json:"externalId"`
+	Reason     string     `json:"reason"`
+}
+
+// ImportReport 匯入結果，試跑時不寫入任何資料，統計的是實際匯入時的預期結果
+type ImportReport struct {
+	Source       string        `json:"source"`
+	DryRun       bool          `json:"dryRun"`
+	Users        ImportCounts  `json:"users"`
+	Rooms        ImportCounts  `json:"rooms"`
+	MembersAdded int           `json:"membersAdded"` // 新加入群組的成員數
+	Messages     ImportCounts  `json:"messages"`
+	Issues       []ImportIssue `json:"issues"`
+	IssueCount   int           `json:"issueCount"`
+}
+
+// AddIssue 記錄略過的資料，列出的問題數超過上限後只累計數量
+func (r *ImportReport) AddIssue(kind ImportKind, externalID, reason string) {
+	r.IssueCount++
+	if len(r.Issues) < MaxImportIssues {
+		r.Issues = append(r.Issues, ImportIssue{Kind: kind, ExternalID: externalID, Reason: reason})
+	}
+}
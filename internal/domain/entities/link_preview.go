@@ -0,0 +1,72 @@
+package entities
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	// metadataLinkPreviewsKey 連結預覽在訊息附加資料中的鍵
+	metadataLinkPreviewsKey = "link_previews"
+	// MaxLinkPreviews 每則訊息最多產生預覽的網址數
+	MaxLinkPreviews = 3
+)
+
+// urlPattern 比對訊息內容中的 http 與 https 網址，只包含網址允許的 ASCII 字元，
+// 中文內容與全形標點不會被併入網址
+var urlPattern = regexp.MustCompile(`https?://[A-Za-z0-9\-._~:/?#\[\]@!$&()*+,;=%]+`)
+
+// LinkPreview 網址的 OpenGraph 或 Twitter Card 摘要
+type LinkPreview struct {
+	URL         string `json:"url"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Image       string `json:"image,omitempty"`
+	SiteName    string `json:"site_name,omitempty"`
+}
+
+// IsEmpty 判斷預覽是否沒有可顯示的內容
+func (p *LinkPreview) IsEmpty() bool {
+	return p.Title == "" && p.Description == "" && p.Image == ""
+}
+
+// LinkPreviews 解析訊息附加資料中的連結預覽
+func (m *Message) LinkPreviews() []LinkPreview {
+	raw, ok := m.metadataFields()[metadataLinkPreviewsKey]
+	if !ok {
+		return nil
+	}
+	var previews []LinkPreview
+	if err := json.Unmarshal(raw, &previews); err != nil {
+		return nil
+	}
+	return previews
+}
+
+// SetLinkPreviews 寫入訊息的連結預覽，previews 為空時移除
+func (m *Message) SetLinkPreviews(previews []LinkPreview) error {
+	if len(previews) == 0 {
+		return m.setMetadataField(metadataLinkPreviewsKey, nil)
+	}
+	return m.setMetadataField(metadataLinkPreviewsKey, previews)
+}
+
+// ExtractURLs 依出現順序擷取內容中不重複的網址，最多 MaxLinkPreviews 個，
+// 並去除句尾常見的標點符號
+func ExtractURLs(content string) []string {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, match := range urlPattern.FindAllString(content, -1) {
+		url := strings.TrimRight(match, ".,;:!?)]")
+		if len(url) <= len("https://") || seen[url] {
+			continue
+		}
+		seen[url] = true
+		urls = append(urls, url)
+		if len(urls) == MaxLinkPreviews {
+			break
+		}
+	}
+	return urls
+}
@@ -0,0 +1,103 @@
+package entities
+
+import (
+	"encoding/json"
+	"strconv"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// MentionAllKeyword 提及全體成員的關鍵字，即內容中的 @all
+	MentionAllKeyword = "all"
+	// MaxMentions 單則訊息最多解析的提及數量
+	MaxMentions = 50
+	// metadataMentionsKey 提及列表在訊息附加資料中的鍵
+	metadataMentionsKey = "mentions"
+)
+
+// Mention 訊息內容中的一個提及，Offset 與 Length 以字元（rune）計算
+type Mention struct {
+	UserID uint `json:"user_id,omitempty"` // 被提及的用戶ID，提及全體時為 0
+	All    bool `json:"all,omitempty"`     // 是否為 @all
+	Offset int  `json:"offset"`            // 提及在內容中的起始位置
+	Length int  `json:"length"`            // 提及文字的長度，包含 @
+}
+
+// ParseMentions 解析內容中的 @用戶ID 與 @all，@ 必須位於開頭或空白之後。
+// 同一用戶只保留第一次出現的位置，超過 MaxMentions 的部分忽略
+func ParseMentions(content string) []Mention {
+	var mentions []Mention
+	seen := make(map[uint]bool)
+	seenAll := false
+
+	runes := []rune(content)
+	for i := 0; i < len(runes) && len(mentions) < MaxMentions; i++ {
+		if runes[i] != '@' || (i > 0 && !unicode.IsSpace(runes[i-1])) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isMentionRune(runes[end]) {
+			end++
+		}
+		token := string(runes[i+1 : end])
+		mention := Mention{Offset: i, Length: end - i}
+
+		if token == MentionAllKeyword {
+			if seenAll {
+				continue
+			}
+			seenAll = true
+			mention.All = true
+			mentions = append(mentions, mention)
+			continue
+		}
+
+		id, err := strconv.ParseUint(token, 10, 32)
+		if err != nil || id == 0 || seen[uint(id)] {
+			continue
+		}
+		seen[uint(id)] = true
+		mention.UserID = uint(id)
+		mentions = append(mentions, mention)
+	}
+	return mentions
+}
+
+// isMentionRune 判斷字元是否屬於提及的名稱部分
+func isMentionRune(r rune) bool {
+	return r < utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// Mentions 返回訊息附加資料中記錄的提及
+func (m *Message) Mentions() []Mention {
+	fields := m.metadataFields()
+	raw, ok := fields[metadataMentionsKey]
+	if !ok {
+		return nil
+	}
+	var mentions []Mention
+	if err := json.Unmarshal(raw, &mentions); err != nil {
+		return nil
+	}
+	return mentions
+}
+
+// SetMentions 以提及列表覆寫附加資料中的 mentions，保留其他欄位
+func (m *Message) SetMentions(mentions []Mention) error {
+	if len(mentions) == 0 {
+		return m.setMetadataField(metadataMentionsKey, nil)
+	}
+	return m.setMetadataField(metadataMentionsKey, mentions)
+}
+
+// MentionsAll 判斷訊息是否提及全體成員
+func (m *Message) MentionsAll() bool {
+	for _, mention := range m.Mentions() {
+		if mention.All {
+			return true
+		}
+	}
+	return false
+}
@@ -15,29 +15,150 @@ const (
 	MessageTypeHeartbeat MessageType = 4
 )
 
+// RecalledMessageContent 撤回後的墓碑訊息內容
+const RecalledMessageContent = "此訊息已撤回"
+
 // MediaType 定義不同的媒體類型
 type MediaType int
 
 const (
-	MediaTypeText  MediaType = 1
-	MediaTypeImage MediaType = 2
-	MediaTypeVoice MediaType = 3
-	MediaTypeVideo MediaType = 4
-	MediaTypeFile  MediaType = 5
+	MediaTypeText     MediaType = 1
+	MediaTypeImage    MediaType = 2
+	MediaTypeVoice    MediaType = 3
+	MediaTypeVideo    MediaType = 4
+	MediaTypeFile     MediaType = 5
+	MediaTypeRichText MediaType = 6 // 內容為 Markdown 子集的富文本
+	MediaTypePoll     MediaType = 7 // 群組投票，內容為投票問題，選項與結果見 Poll
 )
 
 // Message 實體
 type Message struct {
-	ID        uint        `json:"id" gorm:"primaryKey"`
-	UserId    uint        `json:"user_id" gorm:"not null"`
-	TargetId  uint        `json:"target_id" gorm:"not null"`
-	RoomID    uint        `json:"room_id" gorm:"index"` // 聊天室ID
-	Type      MessageType `json:"type" gorm:"not null"`
-	Media     MediaType   `json:"media" gorm:"not null"`
-	Content   string      `json:"content" gorm:"type:text"`
-	Metadata  JSON        `json:"metadata" gorm:"type:json"`
-	CreatedAt time.Time   `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
-	UpdatedAt time.Time   `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+	ID                 uint            `json:"id" gorm:"primaryKey;index:idx_messages_room_history,priority:2;index:idx_messages_private_history,priority:3;index:idx_messages_thread,priority:2"`
+	UserId             uint            `json:"user_id" gorm:"not null;uniqueIndex:idx_messages_sender_client_msg,priority:1;index:idx_messages_private_history,priority:1;index:idx_messages_private_seq,priority:1"`
+	TargetId           uint            `json:"target_id" gorm:"not null;index:idx_messages_private_history,priority:2;index:idx_messages_private_seq,priority:2"`
+	RoomID             uint            `json:"room_id" gorm:"index:idx_messages_room_history,priority:1;index:idx_messages_room_seq,priority:1"`                                                                       // 聊天室ID
+	ReplyToID          *uint           `json:"reply_to_id,omitempty" gorm:"index"`                                                                                                                                     // 引用回覆的訊息ID
+	ThreadRootID       *uint           `json:"thread_root_id,omitempty" gorm:"index:idx_messages_thread,priority:1"`                                                                                                   // 所屬討論串的根訊息ID，討論串回覆不會出現在主對話歷史中
+	Seq                uint64          `json:"seq,omitempty" gorm:"not null;default:0;index:idx_messages_room_seq,priority:2;index:idx_messages_private_seq,priority:3;uniqueIndex:idx_messages_scope_seq,priority:2"` // 對話內嚴格遞增的序號，客戶端可據此排序與偵測漏收，討論串回覆為 0
+	SeqScope           *string         `json:"-" gorm:"size:64;uniqueIndex:idx_messages_scope_seq,priority:1"`                                                                                                         // 配發序號的對話，格式與 PinScopeOf 相同，與序號組成唯一索引；沒有序號時為 NULL，不受唯一索引限制
+	ClientMsgID        *string         `json:"client_msg_id,omitempty" gorm:"size:64;uniqueIndex:idx_messages_sender_client_msg,priority:2"`                                                                           // 客戶端產生的訊息ID，同一發送者內唯一，用於重試去重
+	Type               MessageType     `json:"type" gorm:"not null"`
+	Media              MediaType       `json:"media" gorm:"not null"`
+	Content            string          `json:"content" gorm:"type:text"`
+	PlainText          string          `json:"plain_text,omitempty" gorm:"type:text"` // 富文本去除格式後的純文字，用於搜尋與通知
+	Metadata           JSON            `json:"metadata" gorm:"type:json"`
+	EditedAt           *time.Time      `json:"edited_at,omitempty"`                                    // 最後一次編輯時間，未編輯過為 nil
+	RecalledAt         *time.Time      `json:"recalled_at,omitempty"`                                  // 撤回時間，撤回後訊息只保留為墓碑
+	RecalledBy         uint            `json:"recalled_by,omitempty"`                                  // 執行撤回的用戶ID
+	ThreadReplyCount   int             `json:"thread_reply_count,omitempty" gorm:"not null;default:0"` // 討論串回覆數，只記錄在根訊息上
+	ThreadParticipants UintList        `json:"thread_participants,omitempty" gorm:"type:json"`         // 曾在討論串回覆的用戶ID
+	ThreadLastReplyAt  *time.Time      `json:"thread_last_reply_at,omitempty"`                         // 討論串最後回覆時間
+	ExpiresAt          *time.Time      `json:"expires_at,omitempty" gorm:"index"`                      // 自動消失的時間，對話未開啟自動消失時為 nil
+	Reactions          []ReactionCount `json:"reactions,omitempty" gorm:"-"`                           // 表情回應統計，查詢歷史時附加，不寫入快取
+	ReplyTo            *QuotedMessage  `json:"reply_to,omitempty" gorm:"-"`                            // 被引用訊息的預覽，查詢歷史時附加，不寫入快取
+	Poll               *PollResult     `json:"poll,omitempty" gorm:"-"`                                // 投票的選項與目前結果，查詢歷史與推送時附加，不寫入快取
+	CreatedAt          time.Time       `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt          time.Time       `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// HasClientMsgID 判斷訊息是否帶有客戶端訊息ID
+func (m *Message) HasClientMsgID() bool {
+	return m.ClientMsgID != nil && *m.ClientMsgID != ""
+}
+
+// IsEdited 判斷訊息是否被編輯過
+func (m *Message) IsEdited() bool {
+	return m.EditedAt != nil
+}
+
+// IsRecalled 判斷訊息是否已被撤回
+func (m *Message) IsRecalled() bool {
+	return m.RecalledAt != nil
+}
+
+// Recall 將訊息轉為撤回後的墓碑：清除原始內容與附加資料，只保留撤回提示
+func (m *Message) Recall(userID uint, at time.Time) {
+	m.Content = RecalledMessageContent
+	m.Media = MediaTypeText
+	m.PlainText = ""
+	m.Metadata = nil
+	m.RecalledAt = &at
+	m.RecalledBy = userID
+	m.UpdatedAt = at
+}
+
+// IsSystem 判斷訊息是否為系統通知
+func (m *Message) IsSystem() bool {
+	return m.Type == MessageTypeSystem
+}
+
+// IsGroupConversation 判斷訊息是否屬於群組對話，包含發在群組中的系統通知
+func (m *Message) IsGroupConversation() bool {
+	return m.Type == MessageTypeGroup || (m.Type == MessageTypeSystem && m.RoomID != 0)
+}
+
+// IsThreadReply 判斷訊息是否為討論串中的回覆
+func (m *Message) IsThreadReply() bool {
+	return m.ThreadRootID != nil
+}
+
+// SameConversation 判斷兩則訊息是否屬於同一個私聊或群組對話
+func (m *Message) SameConversation(other *Message) bool {
+	if m.IsGroupConversation() || other.IsGroupConversation() {
+		return m.IsGroupConversation() && other.IsGroupConversation() && m.RoomID == other.RoomID
+	}
+	return (m.UserId == other.UserId && m.TargetId == other.TargetId) ||
+		(m.UserId == other.TargetId && m.TargetId == other.UserId)
+}
+
+// QuotedMessage 被引用訊息在回覆中顯示的預覽資料
+type QuotedMessage struct {
+	ID       uint      `json:"id"`
+	UserID   uint      `json:"user_id"`
+	Media    MediaType `json:"media"`
+	Preview  string    `json:"preview"`
+	Recalled bool      `json:"recalled,omitempty"`
+}
+
+// NewQuotedMessage 由原訊息建立引用預覽
+func NewQuotedMessage(message *Message) *QuotedMessage {
+	return &QuotedMessage{
+		ID:       message.ID,
+		UserID:   message.UserId,
+		Media:    message.Media,
+		Preview:  MessagePreview(message),
+		Recalled: message.IsRecalled(),
+	}
+}
+
+// UintList 以 JSON 陣列存儲的ID列表
+type UintList []uint
+
+// Contains 判斷列表中是否包含 id
+func (l UintList) Contains(id uint) bool {
+	for _, v := range l {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// 實現 GORM 的 Scanner 和 Valuer 接口
+func (l *UintList) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok || len(bytes) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+func (l UintList) Value() (interface{}, error) {
+	if len(l) == 0 {
+		return nil, nil
+	}
+	return json.Marshal([]uint(l))
 }
 
 // JSON 類型用於存儲 JSON 數據
@@ -62,6 +183,24 @@ func (j JSON) Value() (interface{}, error) {
 	return json.RawMessage(j).MarshalJSON()
 }
 
+// MarshalJSON 以原始 JSON 輸出，而非 []byte 預設的 base64 字串
+func (j JSON) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return json.RawMessage(j).MarshalJSON()
+}
+
+// UnmarshalJSON 保存原始 JSON，null 視為空
+func (j *JSON) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		*j = nil
+		return nil
+	}
+	*j = append((*j)[:0], data...)
+	return nil
+}
+
 // RoomType 定義聊天室類型
 type RoomType int
 
@@ -80,3 +219,39 @@ type ChatRoom struct {
 	CreatedAt    time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
 	UpdatedAt    time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
 }
+
+// metadataFields 將附加資料解析為欄位表，非 JSON 物件時視為空
+func (m *Message) metadataFields() map[string]json.RawMessage {
+	fields := make(map[string]json.RawMessage)
+	if len(m.Metadata) > 0 {
+		if err := json.Unmarshal(m.Metadata, &fields); err != nil {
+			return make(map[string]json.RawMessage)
+		}
+	}
+	return fields
+}
+
+// setMetadataField 寫入附加資料中的單一欄位並保留其他欄位，value 為 nil 時移除該欄位
+func (m *Message) setMetadataField(key string, value interface{}) error {
+	fields := m.metadataFields()
+	if value == nil {
+		delete(fields, key)
+	} else {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		fields[key] = raw
+	}
+
+	if len(fields) == 0 {
+		m.Metadata = nil
+		return nil
+	}
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	m.Metadata = JSON(data)
+	return nil
+}
@@ -0,0 +1,67 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// metadataForwardKey 轉發來源在訊息附加資料中的鍵
+const metadataForwardKey = "forward"
+
+// ForwardOrigin 轉發副本的來源，記錄在副本的附加資料中
+type ForwardOrigin struct {
+	MessageID uint        `json:"message_id"`          // 原始訊息ID
+	SenderID  uint        `json:"sender_id"`           // 原始發送者ID
+	Type      MessageType `json:"type"`                // 原始對話類型
+	RoomID    uint        `json:"room_id,omitempty"`   // 原始群組ID
+	TargetID  uint        `json:"target_id,omitempty"` // 原始私聊的接收者ID
+	SentAt    time.Time   `json:"sent_at"`             // 原始訊息的發送時間
+}
+
+// ForwardOrigin 解析訊息的轉發來源，不是轉發副本時返回 nil
+func (m *Message) ForwardOrigin() *ForwardOrigin {
+	raw, ok := m.metadataFields()[metadataForwardKey]
+	if !ok {
+		return nil
+	}
+	var origin ForwardOrigin
+	if err := json.Unmarshal(raw, &origin); err != nil {
+		return nil
+	}
+	return &origin
+}
+
+// ForwardCopy 以 senderID 的身份建立訊息的轉發副本，複製內容、媒體類型與附加資料，
+// 提及不會帶到新的對話。轉發已轉發過的訊息時沿用最初的來源，
+// 副本的對話類型與接收對象由呼叫者設定
+func (m *Message) ForwardCopy(senderID uint) (*Message, error) {
+	origin := m.ForwardOrigin()
+	if origin == nil {
+		origin = &ForwardOrigin{
+			MessageID: m.ID,
+			SenderID:  m.UserId,
+			Type:      m.Type,
+			SentAt:    m.CreatedAt,
+		}
+		if m.IsGroupConversation() {
+			origin.RoomID = m.RoomID
+		} else {
+			origin.TargetID = m.TargetId
+		}
+	}
+
+	forwarded := &Message{
+		UserId:    senderID,
+		Media:     m.Media,
+		Content:   m.Content,
+		PlainText: m.PlainText,
+		Metadata:  append(JSON(nil), m.Metadata...),
+	}
+	if err := forwarded.setMetadataField(metadataMentionsKey, nil); err != nil {
+		return nil, err
+	}
+	if err := forwarded.setMetadataField(metadataForwardKey, origin); err != nil {
+		return nil, err
+	}
+	return forwarded, nil
+}
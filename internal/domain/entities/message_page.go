@@ -0,0 +1,77 @@
+package entities
+
+const (
+	// DefaultHistoryLimit 未指定數量時每頁返回的訊息數
+	DefaultHistoryLimit = 50
+	// MaxHistoryLimit 每頁最多返回的訊息數
+	MaxHistoryLimit = 200
+)
+
+// HistoryQuery 訊息歷史的游標分頁條件，未指定任何游標時返回最新一頁。
+// 對話歷史依序號排序，以 BeforeSeq/AfterSeq 為游標，傳入 ID 游標時換算為該訊息的序號；
+// 討論串回覆沒有序號，只使用 ID 游標
+type HistoryQuery struct {
+	BeforeID  uint   // 取 ID 小於此值的訊息（往舊翻頁）
+	AfterID   uint   // 取 ID 大於此值的訊息（往新翻頁），優先於 BeforeID
+	BeforeSeq uint64 // 取序號小於此值的訊息（往舊翻頁），優先於 ID 游標
+	AfterSeq  uint64 // 取序號大於此值的訊息（往新翻頁），優先於其他游標，可用於補齊漏收的訊息
+	Limit     int    // 每頁數量
+}
+
+// IsLatest 判斷查詢是否為最新一頁
+func (q HistoryQuery) IsLatest() bool {
+	return q.BeforeID == 0 && q.AfterID == 0 && q.BeforeSeq == 0 && q.AfterSeq == 0
+}
+
+// IsForward 判斷查詢是否往新翻頁
+func (q HistoryQuery) IsForward() bool {
+	if q.AfterSeq > 0 {
+		return true
+	}
+	if q.BeforeSeq > 0 {
+		return false
+	}
+	return q.AfterID > 0
+}
+
+// Normalize 修正超出範圍的每頁數量
+func (q HistoryQuery) Normalize() HistoryQuery {
+	if q.Limit <= 0 {
+		q.Limit = DefaultHistoryLimit
+	}
+	if q.Limit > MaxHistoryLimit {
+		q.Limit = MaxHistoryLimit
+	}
+	return q
+}
+
+// MessagePage 一頁訊息，Messages 依時間由舊到新排列
+type MessagePage struct {
+	Messages []*Message `json:"messages"`
+	HasMore  bool       `json:"has_more"` // 查詢方向上是否還有更多訊息
+}
+
+// NewMessagePage 由多取一筆的查詢結果建立分頁，rows 需依查詢方向排列
+// （往新翻頁為由舊到新，其餘為由新到舊）
+func NewMessagePage(rows []*Message, query HistoryQuery) *MessagePage {
+	hasMore := len(rows) > query.Limit
+	if hasMore {
+		rows = rows[:query.Limit]
+	}
+
+	if !query.IsForward() {
+		// 由新到舊的結果反轉為由舊到新
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	return &MessagePage{Messages: rows, HasMore: hasMore}
+}
+
+// ThreadPage 討論串的一頁回覆，Root 為根訊息，Replies 依時間由舊到新排列
+type ThreadPage struct {
+	Root    *Message   `json:"root"`
+	Replies []*Message `json:"replies"`
+	HasMore bool       `json:"has_more"` // 查詢方向上是否還有更多回覆
+}
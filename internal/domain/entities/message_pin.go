@@ -0,0 +1,47 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// MessagePin 對話中被釘選的訊息，每則訊息最多只會被釘選一次
+type MessagePin struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Scope     string    `json:"scope" gorm:"size:64;not null;index:idx_message_pins_scope,priority:1"` // 所屬對話，見 PinScopeOf
+	MessageID uint      `json:"message_id" gorm:"not null;uniqueIndex"`
+	PinnedBy  uint      `json:"pinned_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP;index:idx_message_pins_scope,priority:2"`
+}
+
+// TableName 指定表名
+func (MessagePin) TableName() string {
+	return "message_pins"
+}
+
+// PinnedMessage 釘選記錄與被釘選的訊息
+type PinnedMessage struct {
+	Pin     *MessagePin `json:"pin"`
+	Message *Message    `json:"message"`
+}
+
+// GroupPinScope 返回群組的釘選範圍
+func GroupPinScope(roomID uint) string {
+	return fmt.Sprintf("room:%d", roomID)
+}
+
+// PrivatePinScope 返回私聊雙方共用的釘選範圍，與參數順序無關
+func PrivatePinScope(userID, targetID uint) string {
+	if userID > targetID {
+		userID, targetID = targetID, userID
+	}
+	return fmt.Sprintf("private:%d:%d", userID, targetID)
+}
+
+// PinScopeOf 返回訊息所屬對話的釘選範圍
+func PinScopeOf(message *Message) string {
+	if message.IsGroupConversation() {
+		return GroupPinScope(message.RoomID)
+	}
+	return PrivatePinScope(message.UserId, message.TargetId)
+}
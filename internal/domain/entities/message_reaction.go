@@ -0,0 +1,25 @@
+package entities
+
+import "time"
+
+// MaxReactionLength 表情回應的最大位元組數
+const MaxReactionLength = 32
+
+// MessageReaction 用戶對訊息的表情回應，同一用戶對同一訊息的同一表情只會有一筆
+type MessageReaction struct {
+	MessageID uint      `json:"message_id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey"`
+	Emoji     string    `json:"emoji" gorm:"primaryKey;size:32"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (MessageReaction) TableName() string {
+	return "message_reactions"
+}
+
+// ReactionCount 訊息上某個表情回應的統計
+type ReactionCount struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
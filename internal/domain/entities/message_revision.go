@@ -0,0 +1,17 @@
+package entities
+
+import "time"
+
+// MessageRevision 訊息被編輯前的內容，每次編輯保存一筆
+type MessageRevision struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	MessageID uint      `json:"message_id" gorm:"not null;index"`
+	Content   string    `json:"content" gorm:"type:text"` // 編輯前的內容
+	EditedBy  uint      `json:"edited_by" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"` // 此版本被取代的時間
+}
+
+// TableName 指定表名
+func (MessageRevision) TableName() string {
+	return "message_revisions"
+}
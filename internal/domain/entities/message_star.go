@@ -0,0 +1,57 @@
+package entities
+
+import "time"
+
+const (
+	// MaxStarNoteLength 收藏備註的最大字數
+	MaxStarNoteLength = 500
+	// DefaultStarLimit 未指定數量時每頁返回的收藏數
+	DefaultStarLimit = 20
+	// MaxStarLimit 每頁最多返回的收藏數
+	MaxStarLimit = 100
+)
+
+// MessageStar 用戶收藏的訊息，只有收藏者本人可見，每個用戶對每則訊息最多一筆
+type MessageStar struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_message_stars_owner_message,priority:1"`
+	MessageID uint      `json:"message_id" gorm:"not null;uniqueIndex:idx_message_stars_owner_message,priority:2;index"`
+	Note      string    `json:"note" gorm:"size:500"` // 收藏者的個人備註
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (MessageStar) TableName() string {
+	return "message_stars"
+}
+
+// StarredMessage 收藏記錄與被收藏的訊息
+type StarredMessage struct {
+	Star    *MessageStar `json:"star"`
+	Message *Message     `json:"message"`
+}
+
+// StarQuery 收藏列表的游標分頁條件，以收藏ID由新到舊排列
+type StarQuery struct {
+	BeforeID uint // 只返回收藏ID小於此值的收藏，0 表示從最新開始
+	Limit    int  // 每頁數量
+}
+
+// Normalize 修正超出範圍的分頁參數
+func (q StarQuery) Normalize() StarQuery {
+	if q.Limit <= 0 {
+		q.Limit = DefaultStarLimit
+	}
+	if q.Limit > MaxStarLimit {
+		q.Limit = MaxStarLimit
+	}
+	return q
+}
+
+// StarPage 一頁收藏，依收藏時間由新到舊排列
+type StarPage struct {
+	Items      []*StarredMessage `json:"items"`
+	HasMore    bool              `json:"has_more"`
+	NextCursor uint              `json:"next_cursor,omitempty"` // 下一頁的 beforeId
+}
@@ -0,0 +1,196 @@
+package entities
+
+import "time"
+
+const (
+	// MaxModerationPatternLength 審核規則關鍵字或正規表達式的長度上限
+	MaxModerationPatternLength = 200
+	// MaxModerationRulesPerGroup 每個群組可設定的審核規則數上限
+	MaxModerationRulesPerGroup = 200
+	// DefaultModerationLimit 列出待審核訊息與稽核日誌時預設返回的數量
+	DefaultModerationLimit = 20
+	// MaxModerationLimit 列出待審核訊息與稽核日誌時每頁的數量上限
+	MaxModerationLimit = 100
+	// MaxReviewNoteLength 審核備註的長度上限
+	MaxReviewNoteLength = 500
+)
+
+// ModerationAction 審核對訊息的處理方式，依嚴重程度由低到高排列
+type ModerationAction string
+
+const (
+	ModerationAllow  ModerationAction = "allow"  // 直接發送
+	ModerationMask   ModerationAction = "mask"   // 將命中的內容遮蔽後發送
+	ModerationHold   ModerationAction = "hold"   // 暫不發送，交由管理員審核
+	ModerationReject ModerationAction = "reject" // 拒絕發送
+)
+
+// severity 返回處理方式的嚴重程度，用於合併多條規則的結果
+func (a ModerationAction) severity() int {
+	switch a {
+	case ModerationMask:
+		return 1
+	case ModerationHold:
+		return 2
+	case ModerationReject:
+		return 3
+	}
+	return 0
+}
+
+// IsValid 判斷是否為規則可設定的處理方式
+func (a ModerationAction) IsValid() bool {
+	return a == ModerationMask || a == ModerationHold || a == ModerationReject
+}
+
+// Stricter 返回兩者中較嚴格的處理方式
+func (a ModerationAction) Stricter(other ModerationAction) ModerationAction {
+	if other.severity() > a.severity() {
+		return other
+	}
+	return a
+}
+
+// ModerationRule 內容審核規則，GroupID 為 0 的全域規則來自設定檔，群組規則由群主與管理員維護
+type ModerationRule struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	GroupID   uint             `json:"group_id" gorm:"not null;index"`
+	Pattern   string           `json:"pattern" gorm:"size:200;not null"`
+	IsRegex   bool             `json:"is_regex" gorm:"not null;default:false"` // false 時為不分大小寫的關鍵字
+	Action    ModerationAction `json:"action" gorm:"size:16;not null"`
+	CreatedBy uint             `json:"created_by"`
+	CreatedAt time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (ModerationRule) TableName() string {
+	return "moderation_rules"
+}
+
+// ModerationDecision 審核結果，Content 為遮蔽後的內容，只在 Action 為 mask 時有意義
+type ModerationDecision struct {
+	Action  ModerationAction `json:"action"`
+	Matches []string         `json:"matches,omitempty"` // 命中的規則，格式為 規則來源:規則內容
+	Content string           `json:"-"`
+}
+
+// HeldStatus 待審核訊息的狀態
+type HeldStatus string
+
+const (
+	HeldPending  HeldStatus = "pending"  // 等待審核
+	HeldApproved HeldStatus = "approved" // 已核准並發送
+	HeldRejected HeldStatus = "rejected" // 已駁回
+)
+
+// HeldMessage 被審核規則暫扣的訊息，核准後以原內容發送
+type HeldMessage struct {
+	ID         uint             `json:"id" gorm:"primaryKey"`
+	UserID     uint             `json:"user_id" gorm:"not null;index"`
+	Type       ConversationType `json:"type" gorm:"not null;index:idx_held_messages_queue,priority:2"`
+	TargetID   uint             `json:"target_id" gorm:"not null;index:idx_held_messages_queue,priority:3"` // 私聊為接收者ID，群聊為群組ID
+	Status     HeldStatus       `json:"status" gorm:"size:16;not null;index:idx_held_messages_queue,priority:1"`
+	Payload    JSON             `json:"payload" gorm:"type:json"` // 發送時的訊息內容
+	Matches    string           `json:"matches" gorm:"size:1000"` // 命中的規則，以換行分隔
+	ReviewerID uint             `json:"reviewer_id,omitempty"`
+	ReviewNote string           `json:"review_note,omitempty" gorm:"size:500"`
+	ReviewedAt *time.Time       `json:"reviewed_at,omitempty"`
+	MessageID  uint             `json:"message_id,omitempty"` // 核准後實際發送的訊息ID
+	CreatedAt  time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt  time.Time        `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (HeldMessage) TableName() string {
+	return "held_messages"
+}
+
+// IsGroup 判斷是否為群組訊息
+func (h *HeldMessage) IsGroup() bool {
+	return h.Type == ConversationTypeGroup
+}
+
+// HeldQuery 列出待審核訊息的條件，GroupID 為 0 表示不限群組
+type HeldQuery struct {
+	GroupID  uint
+	Status   HeldStatus
+	BeforeID uint
+	Limit    int
+}
+
+// Normalize 套用預設值並限制每頁數量
+func (q HeldQuery) Normalize() HeldQuery {
+	if q.Status == "" {
+		q.Status = HeldPending
+	}
+	q.Limit = normalizeModerationLimit(q.Limit)
+	return q
+}
+
+// HeldPage 一頁待審核訊息，依ID由新到舊排列
+type HeldPage struct {
+	Items      []*HeldMessage `json:"items"`
+	HasMore    bool           `json:"has_more"`
+	NextCursor uint           `json:"next_cursor,omitempty"` // 下一頁的 beforeId
+}
+
+// ModerationLog 審核的稽核日誌：自動審核的非放行結果、人工審核與規則變更都會記錄
+type ModerationLog struct {
+	ID        uint             `json:"id" gorm:"primaryKey;index:idx_moderation_logs_scope,priority:3"`
+	Type      ConversationType `json:"type" gorm:"not null;index:idx_moderation_logs_scope,priority:1"`
+	TargetID  uint             `json:"target_id" gorm:"not null;index:idx_moderation_logs_scope,priority:2"`
+	ActorID   uint             `json:"actor_id"` // 人工審核或變更規則的用戶，自動審核為 0
+	UserID    uint             `json:"user_id"`  // 訊息的發送者
+	Event     string           `json:"event" gorm:"size:32;not null"`
+	Action    ModerationAction `json:"action" gorm:"size:16"`
+	MessageID uint             `json:"message_id,omitempty"`
+	HeldID    uint             `json:"held_id,omitempty"`
+	RuleID    uint             `json:"rule_id,omitempty"`
+	Detail    string           `json:"detail" gorm:"size:1000"`
+	CreatedAt time.Time        `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (ModerationLog) TableName() string {
+	return "moderation_logs"
+}
+
+// 稽核日誌的事件類型
+const (
+	ModerationEventScreened    = "screened"     // 自動審核的結果
+	ModerationEventApproved    = "approved"     // 待審核訊息被核准
+	ModerationEventRejected    = "rejected"     // 待審核訊息被駁回
+	ModerationEventRuleCreated = "rule.created" // 新增群組規則
+	ModerationEventRuleDeleted = "rule.deleted" // 刪除群組規則
+)
+
+// ModerationLogQuery 查詢稽核日誌的條件，Type 為 0 表示不限對話
+type ModerationLogQuery struct {
+	Type     ConversationType
+	TargetID uint
+	BeforeID uint
+	Limit    int
+}
+
+// Normalize 限制每頁數量
+func (q ModerationLogQuery) Normalize() ModerationLogQuery {
+	q.Limit = normalizeModerationLimit(q.Limit)
+	return q
+}
+
+// ModerationLogPage 一頁稽核日誌，依ID由新到舊排列
+type ModerationLogPage struct {
+	Items      []*ModerationLog `json:"items"`
+	HasMore    bool             `json:"has_more"`
+	NextCursor uint             `json:"next_cursor,omitempty"` // 下一頁的 beforeId
+}
+
+func normalizeModerationLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultModerationLimit
+	}
+	if limit > MaxModerationLimit {
+		return MaxModerationLimit
+	}
+	return limit
+}
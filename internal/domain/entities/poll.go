@@ -0,0 +1,132 @@
+package entities
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	// MaxPollQuestionLength 投票問題的最大字數
+	MaxPollQuestionLength = 200
+	// MaxPollOptionLength 每個選項的最大字數
+	MaxPollOptionLength = 100
+	// MinPollOptions 投票最少的選項數
+	MinPollOptions = 2
+	// MaxPollOptions 投票最多的選項數
+	MaxPollOptions = 10
+	// MaxPollDuration 投票從建立到截止的最長時間
+	MaxPollDuration = 30 * 24 * time.Hour
+)
+
+// Poll 群組中的投票，與投票訊息一對一，訊息內容為投票問題
+type Poll struct {
+	MessageID      uint       `json:"message_id" gorm:"primaryKey;autoIncrement:false"`
+	RoomID         uint       `json:"room_id" gorm:"not null"`
+	CreatorID      uint       `json:"creator_id" gorm:"not null"`
+	Question       string     `json:"question" gorm:"type:text"`
+	Options        StringList `json:"options" gorm:"type:json"`
+	MultipleChoice bool       `json:"multiple_choice" gorm:"not null;default:false"` // 是否可複選
+	Anonymous      bool       `json:"anonymous" gorm:"not null;default:false"`       // 匿名投票不公開各選項的投票者
+	ClosesAt       time.Time  `json:"closes_at" gorm:"not null;index:idx_polls_due,priority:2"`
+	ClosedAt       *time.Time `json:"closed_at,omitempty" gorm:"index:idx_polls_due,priority:1"` // 實際截止時間，尚未截止為 nil
+	CreatedAt      time.Time  `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (Poll) TableName() string {
+	return "polls"
+}
+
+// IsClosed 判斷投票在 now 時是否已截止
+func (p *Poll) IsClosed(now time.Time) bool {
+	return p.ClosedAt != nil || !p.ClosesAt.After(now)
+}
+
+// PollVote 用戶在投票中選擇的一個選項，複選時每個選項一筆
+type PollVote struct {
+	MessageID uint      `json:"message_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID    uint      `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Option    int       `json:"option" gorm:"primaryKey;autoIncrement:false"` // 選項的索引，從 0 開始
+	CreatedAt time.Time `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (PollVote) TableName() string {
+	return "poll_votes"
+}
+
+// PollOptionResult 單一選項的投票結果
+type PollOptionResult struct {
+	Text   string `json:"text"`
+	Count  int    `json:"count"`
+	Voters []uint `json:"voters,omitempty"` // 投票者ID，匿名投票不公開
+}
+
+// PollResult 投票的選項與目前結果
+type PollResult struct {
+	MessageID      uint               `json:"message_id"`
+	RoomID         uint               `json:"room_id"`
+	Question       string             `json:"question"`
+	Options        []PollOptionResult `json:"options"`
+	MultipleChoice bool               `json:"multiple_choice"`
+	Anonymous      bool               `json:"anonymous"`
+	ClosesAt       time.Time          `json:"closes_at"`
+	ClosedAt       *time.Time         `json:"closed_at,omitempty"`
+	TotalVoters    int                `json:"total_voters"`       // 參與投票的人數，複選時可能小於各選項票數的總和
+	MyVotes        []int              `json:"my_votes,omitempty"` // 查詢者選擇的選項，推送給所有成員的結果不包含
+}
+
+// NewPollResult 由投票與所有選票統計結果，viewerID 不為 0 時附上該用戶選擇的選項
+func NewPollResult(poll *Poll, votes []*PollVote, viewerID uint) *PollResult {
+	result := &PollResult{
+		MessageID:      poll.MessageID,
+		RoomID:         poll.RoomID,
+		Question:       poll.Question,
+		Options:        make([]PollOptionResult, len(poll.Options)),
+		MultipleChoice: poll.MultipleChoice,
+		Anonymous:      poll.Anonymous,
+		ClosesAt:       poll.ClosesAt,
+		ClosedAt:       poll.ClosedAt,
+	}
+	for i, text := range poll.Options {
+		result.Options[i].Text = text
+	}
+
+	voters := make(map[uint]bool)
+	for _, vote := range votes {
+		if vote.MessageID != poll.MessageID || vote.Option < 0 || vote.Option >= len(result.Options) {
+			continue
+		}
+		option := &result.Options[vote.Option]
+		option.Count++
+		if !poll.Anonymous {
+			option.Voters = append(option.Voters, vote.UserID)
+		}
+		voters[vote.UserID] = true
+		if viewerID != 0 && vote.UserID == viewerID {
+			result.MyVotes = append(result.MyVotes, vote.Option)
+		}
+	}
+	result.TotalVoters = len(voters)
+	return result
+}
+
+// StringList 以 JSON 陣列存儲的字串列表
+type StringList []string
+
+// 實現 GORM 的 Scanner 和 Valuer 接口
+func (l *StringList) Scan(value interface{}) error {
+	bytes, ok := value.([]byte)
+	if !ok || len(bytes) == 0 {
+		*l = nil
+		return nil
+	}
+	return json.Unmarshal(bytes, l)
+}
+
+func (l StringList) Value() (interface{}, error) {
+	if len(l) == 0 {
+		return nil, nil
+	}
+	return json.Marshal([]string(l))
+}
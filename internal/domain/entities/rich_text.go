@@ -0,0 +1,46 @@
+package entities
+
+import "encoding/json"
+
+// metadataHTMLKey 富文本經伺服器清理後的 HTML 在訊息附加資料中的鍵
+const metadataHTMLKey = "html"
+
+// IsRichText 判斷訊息是否為富文本
+func (m *Message) IsRichText() bool {
+	return m.Media == MediaTypeRichText
+}
+
+// IsTextual 判斷訊息內容是否為文字，包含純文字與富文本
+func (m *Message) IsTextual() bool {
+	return m.Media == MediaTypeText || m.Media == MediaTypeRichText
+}
+
+// DisplayText 返回去除格式的文字內容，富文本返回純文字版本
+func (m *Message) DisplayText() string {
+	if m.IsRichText() && m.PlainText != "" {
+		return m.PlainText
+	}
+	return m.Content
+}
+
+// RichTextHTML 返回富文本清理後的 HTML，不是富文本時返回空字串
+func (m *Message) RichTextHTML() string {
+	if !m.IsRichText() {
+		return ""
+	}
+	var html string
+	if err := json.Unmarshal(m.metadataFields()[metadataHTMLKey], &html); err != nil {
+		return ""
+	}
+	return html
+}
+
+// SetRichText 寫入富文本的 HTML 與純文字版本，html 為空時移除，
+// 呼叫者需確保 html 已經過清理
+func (m *Message) SetRichText(html, plainText string) error {
+	m.PlainText = plainText
+	if html == "" {
+		return m.setMetadataField(metadataHTMLKey, nil)
+	}
+	return m.setMetadataField(metadataHTMLKey, html)
+}
@@ -0,0 +1,73 @@
+package entities
+
+import (
+	"fmt"
+	"time"
+)
+
+// ScheduleStatus 排程訊息的狀態
+type ScheduleStatus string
+
+const (
+	SchedulePending  ScheduleStatus = "pending"  // 等待發送，可編輯或取消
+	ScheduleSending  ScheduleStatus = "sending"  // 已被發送程序認領
+	ScheduleSent     ScheduleStatus = "sent"     // 已發送
+	ScheduleCanceled ScheduleStatus = "canceled" // 已取消
+	ScheduleFailed   ScheduleStatus = "failed"   // 多次發送失敗後放棄
+)
+
+const (
+	// MaxScheduleAhead 最遠可排程的時間
+	MaxScheduleAhead = 365 * 24 * time.Hour
+	// MaxScheduleAttempts 每則排程訊息最多嘗試發送的次數
+	MaxScheduleAttempts = 3
+)
+
+// ScheduledMessage 預約在指定時間發送的私聊或群聊訊息
+type ScheduledMessage struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	UserID       uint           `json:"user_id" gorm:"not null;index:idx_scheduled_messages_owner,priority:1"`
+	Type         MessageType    `json:"type" gorm:"not null"`      // 私聊或群聊
+	TargetID     uint           `json:"target_id" gorm:"not null"` // 私聊為接收者ID，群聊為群組ID
+	Media        MediaType      `json:"media" gorm:"not null"`
+	Content      string         `json:"content" gorm:"type:text"`
+	Metadata     JSON           `json:"metadata" gorm:"type:json"`
+	SendAt       time.Time      `json:"send_at" gorm:"not null;index:idx_scheduled_messages_due,priority:2;index:idx_scheduled_messages_owner,priority:3"`
+	Status       ScheduleStatus `json:"status" gorm:"size:16;not null;index:idx_scheduled_messages_due,priority:1;index:idx_scheduled_messages_owner,priority:2"`
+	Attempts     int            `json:"attempts" gorm:"not null;default:0"`
+	LastError    string         `json:"last_error,omitempty" gorm:"size:255"`
+	MessageID    uint           `json:"message_id,omitempty"` // 發送後建立的訊息ID
+	ClaimToken   string         `json:"-" gorm:"size:32;index"`
+	ClaimedUntil *time.Time     `json:"-"` // 認領的有效期限，逾期未完成時可被其他程序重新認領
+	CreatedAt    time.Time      `json:"created_at" gorm:"default:CURRENT_TIMESTAMP"`
+	UpdatedAt    time.Time      `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (ScheduledMessage) TableName() string {
+	return "scheduled_messages"
+}
+
+// IsGroup 判斷是否為群聊排程
+func (s *ScheduledMessage) IsGroup() bool {
+	return s.Type == MessageTypeGroup
+}
+
+// ToMessage 建立交給一般發送流程的訊息。以排程ID作為 client_msg_id，
+// 認領逾期後被重新發送時由訊息去重機制略過
+func (s *ScheduledMessage) ToMessage() *Message {
+	clientMsgID := fmt.Sprintf("scheduled:%d", s.ID)
+	message := &Message{
+		UserId:      s.UserID,
+		Media:       s.Media,
+		Content:     s.Content,
+		Metadata:    s.Metadata,
+		ClientMsgID: &clientMsgID,
+	}
+	if s.IsGroup() {
+		message.RoomID = s.TargetID
+	} else {
+		message.TargetId = s.TargetID
+	}
+	return message
+}
@@ -0,0 +1,15 @@
+package entities
+
+import "time"
+
+// ConversationSequence 對話訊息序號的資料庫計數器，Redis 不可用時由資料庫接手配發序號
+type ConversationSequence struct {
+	Scope     string    `json:"scope" gorm:"primaryKey;size:64"` // 所屬對話，格式與 PinScopeOf 相同
+	Seq       uint64    `json:"seq" gorm:"not null;default:0"`   // 資料庫最後配發的序號
+	UpdatedAt time.Time `json:"updated_at" gorm:"default:CURRENT_TIMESTAMP"`
+}
+
+// TableName 指定表名
+func (ConversationSequence) TableName() string {
+	return "conversation_sequences"
+}
@@ -0,0 +1,12 @@
+package linkpreview
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+)
+
+// Fetcher 讀取網頁並解析連結預覽，實作需拒絕指向內部網路的網址
+type Fetcher interface {
+	// Fetch 讀取網址的 OpenGraph 或 Twitter Card 資料，頁面沒有可用資料時返回空的預覽
+	Fetch(ctx context.Context, rawURL string) (*entities.LinkPreview, error)
+}
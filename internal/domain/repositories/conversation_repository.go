@@ -0,0 +1,296 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrConversationNotFound 表示用戶沒有此會話
+var ErrConversationNotFound = errors.New("conversation not found")
+
+// 同一批次寫入的會話數量上限
+const conversationBatchSize = 500
+
+type ConversationRepository interface {
+	// RecordMessage 以新訊息更新發送者與所有接收者的會話，接收者的未讀數加一
+	RecordMessage(ctx context.Context, message *entities.Message, recipients []uint) error
+	// RecordImported 為尚未有此會話的用戶建立以匯入訊息為最後訊息、且已讀到此訊息的會話。
+	// 已存在的會話不變更，避免匯入的舊訊息覆寫預覽，或將會話中真正未讀的訊息標為已讀
+	RecordImported(ctx context.Context, message *entities.Message, userIDs []uint) error
+	// RefreshPreview 訊息內容變更後，更新以此訊息為最後訊息的會話預覽
+	RefreshPreview(ctx context.Context, message *entities.Message) error
+	// RecordMentions 將被提及用戶在此訊息所屬群組會話的提及數加一
+	RecordMentions(ctx context.Context, message *entities.Message, userIDs []uint) error
+	// Find 查詢用戶的單一會話，不存在時返回 ErrConversationNotFound
+	Find(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (*entities.Conversation, error)
+	// ListByUser 分頁查詢用戶的會話列表，置頂在前，其餘依最後更新時間由新到舊
+	ListByUser(ctx context.Context, userID uint, query entities.ConversationQuery) (*entities.ConversationPage, error)
+	// MarkRead 將會話已讀位置推進到 messageID（為 0 時表示全部已讀）並重新計算未讀數，
+	// 提及數不會超過剩餘的未讀數
+	MarkRead(ctx context.Context, userID uint, convType entities.ConversationType, targetID, messageID uint) (*entities.Conversation, error)
+	// SetMuted 設定會話是否免打擾，會話不存在時會建立
+	SetMuted(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, muted bool) error
+	// SetPinned 設定會話是否置頂，會話不存在時會建立
+	SetPinned(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, pinned bool) error
+	// ClearPreview 清除以這些訊息為最後訊息的會話預覽，用於訊息被刪除後
+	ClearPreview(ctx context.Context, messageIDs []uint) error
+	// FindDisappearingTTL 查詢對話的訊息自動消失時間，未設定時返回 0
+	FindDisappearingTTL(ctx context.Context, scope string) (time.Duration, error)
+	// SaveDisappearingSetting 儲存對話的訊息自動消失設定
+	SaveDisappearingSetting(ctx context.Context, setting *entities.DisappearingSetting) error
+}
+
+type conversationRepository struct {
+	db *gorm.DB
+}
+
+func NewConversationRepository(db *gorm.DB) ConversationRepository {
+	return &conversationRepository{db: db}
+}
+
+func (r *conversationRepository) RecordMessage(ctx context.Context, message *entities.Message, recipients []uint) error {
+	preview := entities.MessagePreview(message)
+	newConversation := func(userID uint) *entities.Conversation {
+		convType, targetID := entities.ConversationOf(message, userID)
+		return &entities.Conversation{
+			UserID:             userID,
+			Type:               convType,
+			TargetID:           targetID,
+			LastMessageID:      message.ID,
+			LastSenderID:       message.UserId,
+			LastMessagePreview: preview,
+			UpdatedAt:          message.CreatedAt,
+			CreatedAt:          message.CreatedAt,
+		}
+	}
+
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 1. 發送者的會話：發送即代表已讀到此訊息
+		sender := newConversation(message.UserId)
+		sender.LastReadMessageID = message.ID
+		senderUpdates := append(lastMessageAssignments(),
+			clause.Assignment{Column: clause.Column{Name: "unread_count"}, Value: 0},
+			clause.Assignment{Column: clause.Column{Name: "last_read_message_id"}, Value: gorm.Expr("GREATEST(last_read_message_id, VALUES(last_read_message_id))")},
+			clause.Assignment{Column: clause.Column{Name: "last_message_id"}, Value: gorm.Expr("GREATEST(last_message_id, VALUES(last_message_id))")},
+		)
+		if err := tx.Clauses(conversationUpsert(senderUpdates)).Create(sender).Error; err != nil {
+			return err
+		}
+
+		// 2. 接收者的會話：未讀數加一
+		conversations := make([]*entities.Conversation, 0, len(recipients))
+		for _, userID := range recipients {
+			if userID == message.UserId {
+				continue
+			}
+			recipient := newConversation(userID)
+			recipient.UnreadCount = 1
+			conversations = append(conversations, recipient)
+		}
+		if len(conversations) == 0 {
+			return nil
+		}
+
+		recipientUpdates := append(lastMessageAssignments(),
+			clause.Assignment{Column: clause.Column{Name: "unread_count"}, Value: gorm.Expr("unread_count + 1")},
+			clause.Assignment{Column: clause.Column{Name: "last_message_id"}, Value: gorm.Expr("GREATEST(last_message_id, VALUES(last_message_id))")},
+		)
+		return tx.Clauses(conversationUpsert(recipientUpdates)).CreateInBatches(conversations, conversationBatchSize).Error
+	})
+}
+
+func (r *conversationRepository) RecordImported(ctx context.Context, message *entities.Message, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	preview := entities.MessagePreview(message)
+	conversations := make([]*entities.Conversation, 0, len(userIDs))
+	for _, userID := range userIDs {
+		convType, targetID := entities.ConversationOf(message, userID)
+		conversations = append(conversations, &entities.Conversation{
+			UserID:             userID,
+			Type:               convType,
+			TargetID:           targetID,
+			LastMessageID:      message.ID,
+			LastReadMessageID:  message.ID,
+			LastSenderID:       message.UserId,
+			LastMessagePreview: preview,
+			UpdatedAt:          message.CreatedAt,
+			CreatedAt:          message.CreatedAt,
+		})
+	}
+	return r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "target_id"}}, DoNothing: true}).
+		CreateInBatches(conversations, conversationBatchSize).Error
+}
+
+func (r *conversationRepository) RefreshPreview(ctx context.Context, message *entities.Message) error {
+	return r.db.WithContext(ctx).Model(&entities.Conversation{}).
+		Where("last_message_id = ?", message.ID).
+		UpdateColumn("last_message_preview", entities.MessagePreview(message)).Error
+}
+
+func (r *conversationRepository) RecordMentions(ctx context.Context, message *entities.Message, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&entities.Conversation{}).
+		Where("user_id IN ? AND type = ? AND target_id = ?", userIDs, entities.ConversationTypeGroup, message.RoomID).
+		UpdateColumn("mention_count", gorm.Expr("mention_count + 1")).Error
+}
+
+func (r *conversationRepository) Find(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (*entities.Conversation, error) {
+	var conversation entities.Conversation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND type = ? AND target_id = ?", userID, convType, targetID).
+		First(&conversation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrConversationNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (r *conversationRepository) ListByUser(ctx context.Context, userID uint, query entities.ConversationQuery) (*entities.ConversationPage, error) {
+	query = query.Normalize()
+
+	var conversations []*entities.Conversation
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("pinned DESC, updated_at DESC, id DESC").
+		Offset(query.Offset).
+		Limit(query.Limit + 1).
+		Find(&conversations).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hasMore := len(conversations) > query.Limit
+	if hasMore {
+		conversations = conversations[:query.Limit]
+	}
+
+	return &entities.ConversationPage{Conversations: conversations, HasMore: hasMore}, nil
+}
+
+func (r *conversationRepository) MarkRead(ctx context.Context, userID uint, convType entities.ConversationType, targetID, messageID uint) (*entities.Conversation, error) {
+	var conversation entities.Conversation
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND type = ? AND target_id = ?", userID, convType, targetID).
+			First(&conversation).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrConversationNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		if messageID == 0 || messageID > conversation.LastMessageID {
+			messageID = conversation.LastMessageID
+		}
+		// 已讀位置只會前進
+		if messageID <= conversation.LastReadMessageID && conversation.UnreadCount == 0 && conversation.MentionCount == 0 {
+			return nil
+		}
+		if messageID < conversation.LastReadMessageID {
+			messageID = conversation.LastReadMessageID
+		}
+
+		unread := int64(0)
+		if messageID < conversation.LastMessageID {
+			if err := countUnread(tx, &conversation, messageID).Count(&unread).Error; err != nil {
+				return err
+			}
+		}
+
+		conversation.LastReadMessageID = messageID
+		conversation.UnreadCount = int(unread)
+		if conversation.MentionCount > conversation.UnreadCount {
+			conversation.MentionCount = conversation.UnreadCount
+		}
+		return tx.Model(&conversation).UpdateColumns(map[string]interface{}{
+			"last_read_message_id": conversation.LastReadMessageID,
+			"unread_count":         conversation.UnreadCount,
+			"mention_count":        conversation.MentionCount,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &conversation, nil
+}
+
+func (r *conversationRepository) SetMuted(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, muted bool) error {
+	conversation := &entities.Conversation{UserID: userID, Type: convType, TargetID: targetID, Muted: muted}
+	return r.db.WithContext(ctx).
+		Clauses(conversationUpsert(clause.Set{{Column: clause.Column{Name: "muted"}, Value: muted}})).
+		Create(conversation).Error
+}
+
+func (r *conversationRepository) SetPinned(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, pinned bool) error {
+	conversation := &entities.Conversation{UserID: userID, Type: convType, TargetID: targetID, Pinned: pinned}
+	return r.db.WithContext(ctx).
+		Clauses(conversationUpsert(clause.Set{{Column: clause.Column{Name: "pinned"}, Value: pinned}})).
+		Create(conversation).Error
+}
+
+func (r *conversationRepository) ClearPreview(ctx context.Context, messageIDs []uint) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Model(&entities.Conversation{}).
+		Where("last_message_id IN ?", messageIDs).
+		UpdateColumn("last_message_preview", "").Error
+}
+
+func (r *conversationRepository) FindDisappearingTTL(ctx context.Context, scope string) (time.Duration, error) {
+	var setting entities.DisappearingSetting
+	err := r.db.WithContext(ctx).Where("scope = ?", scope).Limit(1).Find(&setting).Error
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(setting.TTL) * time.Second, nil
+}
+
+func (r *conversationRepository) SaveDisappearingSetting(ctx context.Context, setting *entities.DisappearingSetting) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "scope"}},
+		DoUpdates: clause.AssignmentColumns([]string{"ttl", "updated_by", "updated_at"}),
+	}).Create(setting).Error
+}
+
+// conversationUpsert 以 (user_id, type, target_id) 唯一索引處理會話已存在的情況
+func conversationUpsert(updates clause.Set) clause.OnConflict {
+	return clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "target_id"}},
+		DoUpdates: updates,
+	}
+}
+
+// lastMessageAssignments 只在新訊息比現有的最後訊息更新時覆寫預覽欄位。
+// MySQL 依序套用賦值，因此 last_message_id 必須在這些欄位之後才更新
+func lastMessageAssignments() clause.Set {
+	newer := "VALUES(last_message_id) >= last_message_id"
+	return clause.Set{
+		{Column: clause.Column{Name: "last_sender_id"}, Value: gorm.Expr("IF(" + newer + ", VALUES(last_sender_id), last_sender_id)")},
+		{Column: clause.Column{Name: "last_message_preview"}, Value: gorm.Expr("IF(" + newer + ", VALUES(last_message_preview), last_message_preview)")},
+		{Column: clause.Column{Name: "updated_at"}, Value: gorm.Expr("IF(" + newer + ", VALUES(updated_at), updated_at)")},
+	}
+}
+
+// countUnread 建立計算會話中已讀位置之後、他人所發訊息數量的查詢，討論串回覆不計入
+func countUnread(tx *gorm.DB, conversation *entities.Conversation, readMessageID uint) *gorm.DB {
+	query := tx.Model(&entities.Message{}).Where("id > ? AND user_id <> ? AND thread_root_id IS NULL", readMessageID, conversation.UserID)
+	if conversation.Type == entities.ConversationTypeGroup {
+		return query.Where("room_id = ?", conversation.TargetID)
+	}
+	return query.Where("user_id = ? AND target_id = ?", conversation.TargetID, conversation.UserID)
+}
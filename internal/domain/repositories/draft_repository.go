@@ -0,0 +1,46 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DraftRepository 草稿的持久化儲存，作為 Redis 草稿快取的備份
+type DraftRepository interface {
+	// Save 新增或覆寫用戶在會話中的草稿
+	Save(ctx context.Context, draft *entities.Draft) error
+	// Delete 刪除用戶在會話中的草稿
+	Delete(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error
+	// ListByUser 列出用戶所有的草稿
+	ListByUser(ctx context.Context, userID uint) ([]*entities.Draft, error)
+}
+
+type draftRepository struct {
+	db *gorm.DB
+}
+
+func NewDraftRepository(db *gorm.DB) DraftRepository {
+	return &draftRepository{db: db}
+}
+
+func (r *draftRepository) Save(ctx context.Context, draft *entities.Draft) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "type"}, {Name: "target_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"content", "updated_at"}),
+	}).Create(draft).Error
+}
+
+func (r *draftRepository) Delete(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	return r.db.WithContext(ctx).
+		Where("user_id = ? AND type = ? AND target_id = ?", userID, convType, targetID).
+		Delete(&entities.Draft{}).Error
+}
+
+func (r *draftRepository) ListByUser(ctx context.Context, userID uint) ([]*entities.Draft, error) {
+	var drafts []*entities.Draft
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Find(&drafts).Error
+	return drafts, err
+}
@@ -0,0 +1,155 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrExportJobNotFound 表示匯出工作不存在
+var ErrExportJobNotFound = errors.New("export job not found")
+
+type ExportJobRepository interface {
+	// Create 建立匯出工作
+	Create(ctx context.Context, job *entities.ExportJob) error
+	// FindByID 依ID查詢匯出工作，不存在時返回 ErrExportJobNotFound
+	FindByID(ctx context.Context, id uint) (*entities.ExportJob, error)
+	// ListByUser 列出用戶最近的匯出工作，依建立時間由新到舊排列
+	ListByUser(ctx context.Context, userID uint, limit int) ([]*entities.ExportJob, error)
+	// ClaimNext 認領一個待處理或認領逾期的匯出工作，認領在 lease 後失效，沒有工作時返回 nil。
+	// 以單一 UPDATE 搶佔，多個程序同時執行時每個工作只會被其中一個認領
+	ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*entities.ExportJob, error)
+	// UpdateProgress 記錄進度並將認領延長 lease，認領已失效時返回 held = false
+	UpdateProgress(ctx context.Context, job *entities.ExportJob, lease time.Duration) (held bool, err error)
+	// MarkCompleted 記錄匯出完成，認領已失效時返回 held = false
+	MarkCompleted(ctx context.Context, job *entities.ExportJob, fileName string) (held bool, err error)
+	// MarkFailed 記錄匯出失敗，retry 為 true 時放回待處理狀態，否則標記為失敗；認領已失效時不會更新
+	MarkFailed(ctx context.Context, job *entities.ExportJob, reason string, retry bool) error
+}
+
+type exportJobRepository struct {
+	db *gorm.DB
+}
+
+func NewExportJobRepository(db *gorm.DB) ExportJobRepository {
+	return &exportJobRepository{db: db}
+}
+
+func (r *exportJobRepository) Create(ctx context.Context, job *entities.ExportJob) error {
+	return r.db.WithContext(ctx).Create(job).Error
+}
+
+func (r *exportJobRepository) FindByID(ctx context.Context, id uint) (*entities.ExportJob, error) {
+	var job entities.ExportJob
+	err := r.db.WithContext(ctx).First(&job, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrExportJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *exportJobRepository) ListByUser(ctx context.Context, userID uint, limit int) ([]*entities.ExportJob, error) {
+	var jobs []*entities.ExportJob
+	err := r.db.WithContext(ctx).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+func (r *exportJobRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*entities.ExportJob, error) {
+	token, err := newClaimToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 以認領標記搶佔最早的工作，條件與更新在同一個語句中完成
+	result := r.db.WithContext(ctx).Model(&entities.ExportJob{}).
+		Where("status = ? OR (status = ? AND claimed_until < ?)",
+			entities.ExportPending, entities.ExportRunning, now).
+		Order("id").
+		Limit(1).
+		UpdateColumns(map[string]interface{}{
+			"status":        entities.ExportRunning,
+			"claim_token":   token,
+			"claimed_until": now.Add(lease),
+			"processed":     0,
+			"attempts":      gorm.Expr("attempts + 1"),
+			"updated_at":    now,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	// 2. 取回此次認領的工作
+	var job entities.ExportJob
+	err = r.db.WithContext(ctx).
+		Where("claim_token = ? AND status = ?", token, entities.ExportRunning).
+		First(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *exportJobRepository) UpdateProgress(ctx context.Context, job *entities.ExportJob, lease time.Duration) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).Model(&entities.ExportJob{}).
+		Where("id = ? AND claim_token = ? AND status = ?", job.ID, job.ClaimToken, entities.ExportRunning).
+		UpdateColumns(map[string]interface{}{
+			"processed":     job.Processed,
+			"total":         job.Total,
+			"claimed_until": now.Add(lease),
+			"updated_at":    now,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *exportJobRepository) MarkCompleted(ctx context.Context, job *entities.ExportJob, fileName string) (bool, error) {
+	now := time.Now()
+	result := r.finishClaim(ctx, job, map[string]interface{}{
+		"status":       entities.ExportCompleted,
+		"processed":    job.Processed,
+		"file_name":    fileName,
+		"last_error":   "",
+		"completed_at": now,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *exportJobRepository) MarkFailed(ctx context.Context, job *entities.ExportJob, reason string, retry bool) error {
+	status := entities.ExportFailed
+	if retry {
+		status = entities.ExportPending
+	}
+	if runes := []rune(reason); len(runes) > 255 {
+		reason = string(runes[:255])
+	}
+	return r.finishClaim(ctx, job, map[string]interface{}{
+		"status":     status,
+		"last_error": reason,
+	}).Error
+}
+
+// finishClaim 在仍持有認領時更新匯出工作並釋放認領
+func (r *exportJobRepository) finishClaim(ctx context.Context, job *entities.ExportJob, updates map[string]interface{}) *gorm.DB {
+	updates["claim_token"] = ""
+	updates["claimed_until"] = nil
+	updates["updated_at"] = time.Now()
+	return r.db.WithContext(ctx).Model(&entities.ExportJob{}).
+		Where("id = ? AND claim_token = ?", job.ID, job.ClaimToken).
+		UpdateColumns(updates)
+}
@@ -19,6 +19,11 @@ type GroupRepository interface {
 	RemoveMember(ctx context.Context, groupID, userID uint) error
 	GetMembers(ctx context.Context, groupID uint) ([]uint, error)
 	IsMember(ctx context.Context, groupID, userID uint) (bool, error)
+	// GetMemberRole 返回成員在群組中的角色，非成員時返回空字串
+	GetMemberRole(ctx context.Context, groupID, userID uint) (entities.GroupRole, error)
+	SetMemberRole(ctx context.Context, groupID, userID uint, role entities.GroupRole) error
+	// GetMembersByRole 返回群組中具有此角色的成員
+	GetMembersByRole(ctx context.Context, groupID uint, role entities.GroupRole) ([]uint, error)
 	FindJoinedGroups(ctx context.Context, userID uint) ([]*entities.Group, error)
 }
 
@@ -114,6 +119,33 @@ func (r *groupRepository) IsMember(ctx context.Context, groupID, userID uint) (b
 	return count > 0, nil
 }
 
+func (r *groupRepository) GetMemberRole(ctx context.Context, groupID, userID uint) (entities.GroupRole, error) {
+	var roles []entities.GroupRole
+	err := r.db.WithContext(ctx).Table("group_members").
+		Where("group_id = ? AND user_id = ?", groupID, userID).
+		Limit(1).
+		Pluck("role", &roles).Error
+	if err != nil || len(roles) == 0 {
+		return "", err
+	}
+	return roles[0], nil
+}
+
+func (r *groupRepository) GetMembersByRole(ctx context.Context, groupID uint, role entities.GroupRole) ([]uint, error) {
+	var members []uint
+	err := r.db.WithContext(ctx).Table("group_members").
+		Where("group_id = ? AND role = ?", groupID, role).
+		Pluck("user_id", &members).Error
+	return members, err
+}
+
+func (r *groupRepository) SetMemberRole(ctx context.Context, groupID, userID uint, role entities.GroupRole) error {
+	return r.db.WithContext(ctx).Exec(
+		"UPDATE group_members SET role = ? WHERE group_id = ? AND user_id = ?",
+		role, groupID, userID,
+	).Error
+}
+
 func (r *groupRepository) FindJoinedGroups(ctx context.Context, userID uint) ([]*entities.Group, error) {
 	var groups []*entities.Group
 	err := r.db.WithContext(ctx).
@@ -0,0 +1,163 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 同一次查詢或寫入的匯入資料數量上限
+const importBatchSize = 500
+
+// ImportRepository 匯入外部聊天記錄時使用的存取介面，建立資料與記錄外部ID對應在同一交易中完成，
+// 中途失敗重新匯入時不會產生重複資料
+type ImportRepository interface {
+	// FindMappings 查詢外部ID對應的本地ID，返回以外部ID為鍵的對應表，未匯入的ID不在結果中
+	FindMappings(ctx context.Context, source string, kind entities.ImportKind, externalIDs []string) (map[string]uint, error)
+	// FindUserIDsByEmail 依電子郵件查詢既有帳號，返回以小寫電子郵件為鍵的用戶ID
+	FindUserIDsByEmail(ctx context.Context, emails []string) (map[string]uint, error)
+	// CreateUser 建立用戶並記錄對應
+	CreateUser(ctx context.Context, user *entities.User, mapping *entities.ImportMapping) error
+	// LinkUser 將外部用戶對應到既有帳號
+	LinkUser(ctx context.Context, mapping *entities.ImportMapping) error
+	// CreateGroup 建立群組與成員並記錄對應
+	CreateGroup(ctx context.Context, group *entities.Group, members []*entities.GroupMember, mapping *entities.ImportMapping) error
+	// AddMembers 將用戶加入群組，已是成員的略過，返回實際加入的數量
+	AddMembers(ctx context.Context, members []*entities.GroupMember) (int, error)
+	// InsertMessages 批次寫入訊息並記錄對應，externalIDs 與 messages 依序一一對應
+	InsertMessages(ctx context.Context, source string, messages []*entities.Message, externalIDs []string) error
+	// FindLatestMessageTime 返回訊息所屬對話中最新訊息的發送時間，包含討論串回覆，對話沒有訊息時返回零值
+	FindLatestMessageTime(ctx context.Context, message *entities.Message) (time.Time, error)
+}
+
+type importRepository struct {
+	db *gorm.DB
+}
+
+func NewImportRepository(db *gorm.DB) ImportRepository {
+	return &importRepository{db: db}
+}
+
+func (r *importRepository) FindMappings(ctx context.Context, source string, kind entities.ImportKind, externalIDs []string) (map[string]uint, error) {
+	result := make(map[string]uint, len(externalIDs))
+	for start := 0; start < len(externalIDs); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(externalIDs) {
+			end = len(externalIDs)
+		}
+
+		var mappings []*entities.ImportMapping
+		if err := r.db.WithContext(ctx).
+			Where("source = ? AND kind = ? AND external_id IN ?", source, kind, externalIDs[start:end]).
+			Find(&mappings).Error; err != nil {
+			return nil, err
+		}
+		for _, mapping := range mappings {
+			result[mapping.ExternalID] = mapping.LocalID
+		}
+	}
+	return result, nil
+}
+
+func (r *importRepository) FindUserIDsByEmail(ctx context.Context, emails []string) (map[string]uint, error) {
+	result := make(map[string]uint, len(emails))
+	for start := 0; start < len(emails); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(emails) {
+			end = len(emails)
+		}
+
+		var users []*entities.User
+		if err := r.db.WithContext(ctx).Select("id", "email").
+			Where("email IN ?", emails[start:end]).
+			Find(&users).Error; err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			result[strings.ToLower(user.Email)] = user.ID
+		}
+	}
+	return result, nil
+}
+
+func (r *importRepository) CreateUser(ctx context.Context, user *entities.User, mapping *entities.ImportMapping) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		mapping.LocalID = user.ID
+		return tx.Create(mapping).Error
+	})
+}
+
+func (r *importRepository) LinkUser(ctx context.Context, mapping *entities.ImportMapping) error {
+	return r.db.WithContext(ctx).Create(mapping).Error
+}
+
+func (r *importRepository) CreateGroup(ctx context.Context, group *entities.Group, members []*entities.GroupMember, mapping *entities.ImportMapping) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(group).Error; err != nil {
+			return err
+		}
+		for _, member := range members {
+			member.GroupID = group.ID
+		}
+		if len(members) > 0 {
+			if err := tx.CreateInBatches(members, importBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		mapping.LocalID = group.ID
+		return tx.Create(mapping).Error
+	})
+}
+
+func (r *importRepository) AddMembers(ctx context.Context, members []*entities.GroupMember) (int, error) {
+	if len(members) == 0 {
+		return 0, nil
+	}
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(members, importBatchSize)
+	return int(result.RowsAffected), result.Error
+}
+
+func (r *importRepository) InsertMessages(ctx context.Context, source string, messages []*entities.Message, externalIDs []string) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.CreateInBatches(messages, importBatchSize).Error; err != nil {
+			return err
+		}
+		mappings := make([]*entities.ImportMapping, len(messages))
+		for i, message := range messages {
+			mappings[i] = &entities.ImportMapping{
+				Source:     source,
+				Kind:       entities.ImportKindMessage,
+				ExternalID: externalIDs[i],
+				LocalID:    message.ID,
+			}
+		}
+		return tx.CreateInBatches(mappings, importBatchSize).Error
+	})
+}
+
+func (r *importRepository) FindLatestMessageTime(ctx context.Context, message *entities.Message) (time.Time, error) {
+	db := r.db.WithContext(ctx).Model(&entities.Message{})
+	if message.IsGroupConversation() {
+		db = db.Where("room_id = ?", message.RoomID)
+	} else {
+		db = db.Where("room_id = 0 AND ((user_id = ? AND target_id = ?) OR (user_id = ? AND target_id = ?))",
+			message.UserId, message.TargetId, message.TargetId, message.UserId)
+	}
+
+	var latest sql.NullTime
+	if err := db.Select("MAX(created_at)").Scan(&latest).Error; err != nil {
+		return time.Time{}, err
+	}
+	return latest.Time, nil
+}
@@ -3,18 +3,64 @@ package repositories
 import (
 	"clean-architecture-gochat/internal/domain/entities"
 	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/go-sql-driver/mysql"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	// ErrDuplicateMessage 表示同一發送者的 client_msg_id 已經存在
+	ErrDuplicateMessage = errors.New("duplicate client message id")
+	// ErrDuplicateSeq 表示訊息的序號已被同一對話的其他訊息使用
+	ErrDuplicateSeq = errors.New("duplicate message sequence")
+	// ErrMessageNotFound 表示訊息不存在
+	ErrMessageNotFound = errors.New("message not found")
+)
+
+// MySQL 唯一鍵衝突的錯誤碼
+const mysqlDuplicateEntry = 1062
+
+// 對話序號唯一索引的名稱，用於區分序號衝突與 client_msg_id 衝突
+const messageSeqIndex = "idx_messages_scope_seq"
+
 type MessageRepository interface {
 	SaveMessage(ctx context.Context, message *entities.Message) error
 	Create(ctx context.Context, message *entities.Message) error
 	FindByID(ctx context.Context, id uint) (*entities.Message, error)
+	FindByClientMsgID(ctx context.Context, userID uint, clientMsgID string) (*entities.Message, error)
+	FindByIDs(ctx context.Context, ids []uint) ([]*entities.Message, error)
 	FindMessagesByUserID(ctx context.Context, userId uint) ([]*entities.Message, error)
 	FindMessagesBetweenUsers(ctx context.Context, userID, targetID uint) ([]*entities.Message, error)
 	FindMessagesByRoomID(ctx context.Context, roomID uint) ([]*entities.Message, error)
+	FindMessagesBetweenUsersPage(ctx context.Context, userID, targetID uint, query entities.HistoryQuery) (*entities.MessagePage, error)
+	FindMessagesByRoomIDPage(ctx context.Context, roomID uint, query entities.HistoryQuery) (*entities.MessagePage, error)
+	// FindThreadRepliesPage 以游標分頁查詢討論串中的回覆
+	FindThreadRepliesPage(ctx context.Context, rootID uint, query entities.HistoryQuery) (*entities.MessagePage, error)
+	// AddThreadReply 更新根訊息的討論串回覆數、參與者與最後回覆時間，返回更新後的根訊息
+	AddThreadReply(ctx context.Context, rootID, userID uint, repliedAt time.Time) (*entities.Message, error)
+	// UpdateContent 更新訊息內容，並在同一交易中保存編輯前的版本
+	UpdateContent(ctx context.Context, message *entities.Message, revision *entities.MessageRevision) error
+	// UpdateMetadata 更新訊息的附加資料，訊息內容已變更或已撤回時不更新並返回 false
+	UpdateMetadata(ctx context.Context, message *entities.Message) (bool, error)
+	// Recall 將訊息改寫為墓碑，並刪除所有保存原始內容的歷史版本、表情回應、釘選、投票與收藏
+	Recall(ctx context.Context, message *entities.Message) error
+	// FindRevisions 依時間由舊到新返回訊息的歷史版本
+	FindRevisions(ctx context.Context, messageID uint) ([]*entities.MessageRevision, error)
 	Delete(ctx context.Context, id uint) error
+	// FindExpired 查詢在 now 之前已到自動消失時間的訊息，最多 limit 筆，依到期時間排列
+	FindExpired(ctx context.Context, now time.Time, limit int) ([]*entities.Message, error)
+	// DeleteMessages 刪除訊息及其編輯歷史、表情回應、釘選、投票與收藏
+	DeleteMessages(ctx context.Context, ids []uint) error
+	// FindConversationBatch 依ID由舊到新讀取對話中 afterID 之後最多 limit 則訊息，包含討論串回覆與系統通知，
+	// 不包含會自動消失的訊息。用於逐批匯出完整歷史
+	FindConversationBatch(ctx context.Context, convType entities.ConversationType, userID, targetID, afterID uint, limit int) ([]*entities.Message, error)
+	// CountConversationMessages 統計 FindConversationBatch 會讀取的訊息總數
+	CountConversationMessages(ctx context.Context, convType entities.ConversationType, userID, targetID uint) (int, error)
 }
 
 type messageRepository struct {
@@ -30,15 +76,42 @@ func (r *messageRepository) SaveMessage(ctx context.Context, message *entities.M
 }
 
 func (r *messageRepository) Create(ctx context.Context, message *entities.Message) error {
-	return r.db.WithContext(ctx).Create(message).Error
+	err := r.db.WithContext(ctx).Create(message).Error
+	if isDuplicateKeyError(err) {
+		if strings.Contains(err.Error(), messageSeqIndex) {
+			return ErrDuplicateSeq
+		}
+		return ErrDuplicateMessage
+	}
+	return err
 }
 
 func (r *messageRepository) FindByID(ctx context.Context, id uint) (*entities.Message, error) {
 	var message entities.Message
 	err := r.db.WithContext(ctx).First(&message, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	return &message, err
+}
+
+func (r *messageRepository) FindByClientMsgID(ctx context.Context, userID uint, clientMsgID string) (*entities.Message, error) {
+	var message entities.Message
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND client_msg_id = ?", userID, clientMsgID).
+		First(&message).Error
 	return &message, err
 }
 
+func (r *messageRepository) FindByIDs(ctx context.Context, ids []uint) ([]*entities.Message, error) {
+	var messages []*entities.Message
+	if len(ids) == 0 {
+		return messages, nil
+	}
+	err := r.db.WithContext(ctx).Where("id IN ?", ids).Find(&messages).Error
+	return messages, err
+}
+
 func (r *messageRepository) FindMessagesByUserID(ctx context.Context, userId uint) ([]*entities.Message, error) {
 	var messages []*entities.Message
 	err := r.db.WithContext(ctx).Where("user_id = ?", userId).Find(&messages).Error
@@ -60,6 +133,291 @@ func (r *messageRepository) FindMessagesByRoomID(ctx context.Context, roomID uin
 	return messages, err
 }
 
+func (r *messageRepository) FindMessagesBetweenUsersPage(ctx context.Context, userID, targetID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	query = query.Normalize()
+
+	// 兩個方向分別走 (user_id, target_id, seq) 索引，各取一頁後再合併，避免 OR 條件導致全表排序
+	// 討論串回覆只在討論串中顯示
+	sent, err := findSequencedRows(unexpired(r.db.WithContext(ctx)).Where("user_id = ? AND target_id = ? AND thread_root_id IS NULL", userID, targetID), query)
+	if err != nil {
+		return nil, err
+	}
+	received, err := findSequencedRows(unexpired(r.db.WithContext(ctx)).Where("user_id = ? AND target_id = ? AND thread_root_id IS NULL", targetID, userID), query)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := append(sent, received...)
+	sort.Slice(merged, func(i, j int) bool {
+		if query.IsForward() {
+			return merged[i].Seq < merged[j].Seq
+		}
+		return merged[i].Seq > merged[j].Seq
+	})
+	if len(merged) > query.Limit+1 {
+		merged = merged[:query.Limit+1]
+	}
+
+	return entities.NewMessagePage(merged, query), nil
+}
+
+func (r *messageRepository) FindMessagesByRoomIDPage(ctx context.Context, roomID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	query = query.Normalize()
+
+	messages, err := findSequencedRows(unexpired(r.db.WithContext(ctx)).Where("room_id = ? AND thread_root_id IS NULL", roomID), query)
+	if err != nil {
+		return nil, err
+	}
+
+	return entities.NewMessagePage(messages, query), nil
+}
+
+func (r *messageRepository) FindThreadRepliesPage(ctx context.Context, rootID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	db := unexpired(r.db.WithContext(ctx)).Where("thread_root_id = ?", rootID)
+	return findMessagePage(db, query)
+}
+
+func (r *messageRepository) AddThreadReply(ctx context.Context, rootID, userID uint, repliedAt time.Time) (*entities.Message, error) {
+	var root entities.Message
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 鎖定根訊息，避免併發回覆遺失參與者
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&root, rootID).Error; err != nil {
+			return err
+		}
+
+		root.ThreadReplyCount++
+		root.ThreadLastReplyAt = &repliedAt
+		if !root.ThreadParticipants.Contains(userID) {
+			root.ThreadParticipants = append(root.ThreadParticipants, userID)
+		}
+
+		return tx.Model(&root).UpdateColumns(map[string]interface{}{
+			"thread_reply_count":   root.ThreadReplyCount,
+			"thread_participants":  root.ThreadParticipants,
+			"thread_last_reply_at": root.ThreadLastReplyAt,
+		}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &root, nil
+}
+
+func (r *messageRepository) UpdateContent(ctx context.Context, message *entities.Message, revision *entities.MessageRevision) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entities.Message{}).Where("id = ?", message.ID).UpdateColumns(map[string]interface{}{
+			"content":    message.Content,
+			"plain_text": message.PlainText,
+			"metadata":   message.Metadata,
+			"edited_at":  message.EditedAt,
+			"updated_at": message.UpdatedAt,
+		}).Error
+	})
+}
+
+func (r *messageRepository) UpdateMetadata(ctx context.Context, message *entities.Message) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entities.Message{}).
+		Where("id = ? AND content = ? AND recalled_at IS NULL", message.ID, message.Content).
+		UpdateColumn("metadata", message.Metadata)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *messageRepository) Recall(ctx context.Context, message *entities.Message) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id = ?", message.ID).Delete(&entities.MessageRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&entities.MessageReaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&entities.MessagePin{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&entities.PollVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&entities.Poll{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id = ?", message.ID).Delete(&entities.MessageStar{}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&entities.Message{}).Where("id = ?", message.ID).UpdateColumns(map[string]interface{}{
+			"content":     message.Content,
+			"media":       message.Media,
+			"plain_text":  "",
+			"metadata":    nil,
+			"recalled_at": message.RecalledAt,
+			"recalled_by": message.RecalledBy,
+			"updated_at":  message.UpdatedAt,
+		}).Error
+	})
+}
+
+func (r *messageRepository) FindRevisions(ctx context.Context, messageID uint) ([]*entities.MessageRevision, error) {
+	var revisions []*entities.MessageRevision
+	err := r.db.WithContext(ctx).Where("message_id = ?", messageID).Order("id ASC").Find(&revisions).Error
+	return revisions, err
+}
+
 func (r *messageRepository) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&entities.Message{}, id).Error
 }
+
+func (r *messageRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*entities.Message, error) {
+	var messages []*entities.Message
+	err := r.db.WithContext(ctx).
+		Where("expires_at <= ?", now).
+		Order("expires_at, id").
+		Limit(limit).
+		Find(&messages).Error
+	return messages, err
+}
+
+func (r *messageRepository) DeleteMessages(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("message_id IN ?", ids).Delete(&entities.MessageRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&entities.MessageReaction{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&entities.MessagePin{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&entities.PollVote{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&entities.Poll{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("message_id IN ?", ids).Delete(&entities.MessageStar{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", ids).Delete(&entities.Message{}).Error
+	})
+}
+
+func (r *messageRepository) FindConversationBatch(ctx context.Context, convType entities.ConversationType, userID, targetID, afterID uint, limit int) ([]*entities.Message, error) {
+	var merged []*entities.Message
+	for _, db := range r.conversationScopes(ctx, convType, userID, targetID) {
+		var messages []*entities.Message
+		if err := db.Where("id > ?", afterID).Order("id ASC").Limit(limit).Find(&messages).Error; err != nil {
+			return nil, err
+		}
+		merged = append(merged, messages...)
+	}
+
+	sort.Slice(merged, func(i, j int) bool { return merged[i].ID < merged[j].ID })
+	if len(merged) > limit {
+		merged = merged[:limit]
+	}
+	return merged, nil
+}
+
+func (r *messageRepository) CountConversationMessages(ctx context.Context, convType entities.ConversationType, userID, targetID uint) (int, error) {
+	total := 0
+	for _, db := range r.conversationScopes(ctx, convType, userID, targetID) {
+		var count int64
+		if err := db.Model(&entities.Message{}).Count(&count).Error; err != nil {
+			return 0, err
+		}
+		total += int(count)
+	}
+	return total, nil
+}
+
+// conversationScopes 返回涵蓋對話所有訊息的查詢條件。私聊的兩個方向分別走 (user_id, target_id, id) 索引，
+// 由呼叫者合併結果，避免 OR 條件導致全表掃描
+func (r *messageRepository) conversationScopes(ctx context.Context, convType entities.ConversationType, userID, targetID uint) []*gorm.DB {
+	db := r.db.WithContext(ctx).Where("expires_at IS NULL")
+	if convType == entities.ConversationTypeGroup {
+		return []*gorm.DB{db.Where("room_id = ?", targetID)}
+	}
+	return []*gorm.DB{
+		db.Session(&gorm.Session{}).Where("room_id = 0 AND user_id = ? AND target_id = ?", userID, targetID),
+		db.Session(&gorm.Session{}).Where("room_id = 0 AND user_id = ? AND target_id = ?", targetID, userID),
+	}
+}
+
+// isDuplicateKeyError 判斷是否為唯一索引衝突
+func isDuplicateKeyError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, gorm.ErrDuplicatedKey) {
+		return true
+	}
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == mysqlDuplicateEntry
+}
+
+// findMessagePage 以訊息ID為游標查詢一頁訊息
+func findMessagePage(db *gorm.DB, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	query = query.Normalize()
+
+	messages, err := findMessageRows(db, query)
+	if err != nil {
+		return nil, err
+	}
+
+	return entities.NewMessagePage(messages, query), nil
+}
+
+// unexpired 排除已到自動消失時間、尚未被背景工作刪除的訊息
+func unexpired(db *gorm.DB) *gorm.DB {
+	return db.Where("(expires_at IS NULL OR expires_at > ?)", time.Now())
+}
+
+// findMessageRows 依游標方向查詢訊息，多取一筆用於判斷是否還有更多
+func findMessageRows(db *gorm.DB, query entities.HistoryQuery) ([]*entities.Message, error) {
+	switch {
+	case query.AfterID > 0:
+		db = db.Where("id > ?", query.AfterID).Order("id ASC")
+	case query.BeforeID > 0:
+		db = db.Where("id < ?", query.BeforeID).Order("id DESC")
+	default:
+		db = db.Order("id DESC")
+	}
+
+	var messages []*entities.Message
+	err := db.Limit(query.Limit + 1).Find(&messages).Error
+	return messages, err
+}
+
+// findSequencedRows 依對話序號排序查詢訊息，多取一筆用於判斷是否還有更多。
+// 序號游標優先；呼叫者無法換算為序號的 ID 游標（例如訊息已刪除）以 ID 過濾，
+// 序號與 ID 大致同序，只在游標附近可能有少量差異
+func findSequencedRows(db *gorm.DB, query entities.HistoryQuery) ([]*entities.Message, error) {
+	switch {
+	case query.AfterSeq > 0:
+		db = db.Where("seq > ?", query.AfterSeq)
+	case query.BeforeSeq > 0:
+		db = db.Where("seq < ?", query.BeforeSeq)
+	case query.AfterID > 0:
+		db = db.Where("id > ?", query.AfterID)
+	case query.BeforeID > 0:
+		db = db.Where("id < ?", query.BeforeID)
+	}
+	if query.IsForward() {
+		db = db.Order("seq ASC")
+	} else {
+		db = db.Order("seq DESC")
+	}
+
+	var messages []*entities.Message
+	err := db.Limit(query.Limit + 1).Find(&messages).Error
+	return messages, err
+}
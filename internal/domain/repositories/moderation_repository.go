@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// ErrHeldMessageNotFound 表示待審核訊息不存在
+var ErrHeldMessageNotFound = errors.New("held message not found")
+
+type ModerationRepository interface {
+	// ListRules 列出群組的審核規則，依建立順序排列
+	ListRules(ctx context.Context, groupID uint) ([]*entities.ModerationRule, error)
+	// CreateRule 新增群組的審核規則
+	CreateRule(ctx context.Context, rule *entities.ModerationRule) error
+	// DeleteRule 刪除群組的審核規則，規則不存在或不屬於此群組時返回 removed = false
+	DeleteRule(ctx context.Context, groupID, ruleID uint) (removed bool, err error)
+	// CreateHeld 將訊息放入審核佇列
+	CreateHeld(ctx context.Context, held *entities.HeldMessage) error
+	// FindHeld 查詢待審核訊息，不存在時返回 ErrHeldMessageNotFound
+	FindHeld(ctx context.Context, id uint) (*entities.HeldMessage, error)
+	// ListHeld 依ID由新到舊列出審核佇列，最多返回 query.Limit + 1 筆供判斷是否還有下一頁
+	ListHeld(ctx context.Context, query entities.HeldQuery) ([]*entities.HeldMessage, error)
+	// UpdateHeld 在狀態仍為 from 時更新審核結果，狀態已被其他審核者變更時返回 updated = false
+	UpdateHeld(ctx context.Context, held *entities.HeldMessage, from entities.HeldStatus) (updated bool, err error)
+	// CreateLog 寫入稽核日誌
+	CreateLog(ctx context.Context, log *entities.ModerationLog) error
+	// ListLogs 依ID由新到舊列出稽核日誌，最多返回 query.Limit + 1 筆供判斷是否還有下一頁
+	ListLogs(ctx context.Context, query entities.ModerationLogQuery) ([]*entities.ModerationLog, error)
+}
+
+type moderationRepository struct {
+	db *gorm.DB
+}
+
+func NewModerationRepository(db *gorm.DB) ModerationRepository {
+	return &moderationRepository{db: db}
+}
+
+func (r *moderationRepository) ListRules(ctx context.Context, groupID uint) ([]*entities.ModerationRule, error) {
+	var rules []*entities.ModerationRule
+	err := r.db.WithContext(ctx).Where("group_id = ?", groupID).Order("id ASC").Find(&rules).Error
+	return rules, err
+}
+
+func (r *moderationRepository) CreateRule(ctx context.Context, rule *entities.ModerationRule) error {
+	return r.db.WithContext(ctx).Create(rule).Error
+}
+
+func (r *moderationRepository) DeleteRule(ctx context.Context, groupID, ruleID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("id = ? AND group_id = ?", ruleID, groupID).
+		Delete(&entities.ModerationRule{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *moderationRepository) CreateHeld(ctx context.Context, held *entities.HeldMessage) error {
+	return r.db.WithContext(ctx).Create(held).Error
+}
+
+func (r *moderationRepository) FindHeld(ctx context.Context, id uint) (*entities.HeldMessage, error) {
+	var held entities.HeldMessage
+	err := r.db.WithContext(ctx).First(&held, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrHeldMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &held, nil
+}
+
+func (r *moderationRepository) ListHeld(ctx context.Context, query entities.HeldQuery) ([]*entities.HeldMessage, error) {
+	db := r.db.WithContext(ctx).Where("status = ?", query.Status)
+	if query.GroupID != 0 {
+		db = db.Where("type = ? AND target_id = ?", entities.ConversationTypeGroup, query.GroupID)
+	}
+	if query.BeforeID != 0 {
+		db = db.Where("id < ?", query.BeforeID)
+	}
+
+	var held []*entities.HeldMessage
+	err := db.Order("id DESC").Limit(query.Limit + 1).Find(&held).Error
+	return held, err
+}
+
+func (r *moderationRepository) UpdateHeld(ctx context.Context, held *entities.HeldMessage, from entities.HeldStatus) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entities.HeldMessage{}).
+		Where("id = ? AND status = ?", held.ID, from).
+		Updates(map[string]interface{}{
+			"status":      held.Status,
+			"reviewer_id": held.ReviewerID,
+			"review_note": held.ReviewNote,
+			"reviewed_at": held.ReviewedAt,
+			"message_id":  held.MessageID,
+			"updated_at":  held.UpdatedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *moderationRepository) CreateLog(ctx context.Context, log *entities.ModerationLog) error {
+	return r.db.WithContext(ctx).Create(log).Error
+}
+
+func (r *moderationRepository) ListLogs(ctx context.Context, query entities.ModerationLogQuery) ([]*entities.ModerationLog, error) {
+	db := r.db.WithContext(ctx)
+	if query.Type != 0 {
+		db = db.Where("type = ? AND target_id = ?", query.Type, query.TargetID)
+	}
+	if query.BeforeID != 0 {
+		db = db.Where("id < ?", query.BeforeID)
+	}
+
+	var logs []*entities.ModerationLog
+	err := db.Order("id DESC").Limit(query.Limit + 1).Find(&logs).Error
+	return logs, err
+}
@@ -0,0 +1,68 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPinLimitReached 表示對話的釘選數量已達上限
+var ErrPinLimitReached = errors.New("pin limit reached")
+
+type PinRepository interface {
+	// Pin 釘選訊息，已釘選時返回 added = false，對話釘選數達到 limit 時返回 ErrPinLimitReached
+	Pin(ctx context.Context, pin *entities.MessagePin, limit int) (added bool, err error)
+	// Unpin 取消釘選，未釘選時返回 removed = false
+	Unpin(ctx context.Context, messageID uint) (removed bool, err error)
+	// ListByScope 列出對話中的釘選，依釘選時間由新到舊排列
+	ListByScope(ctx context.Context, scope string) ([]*entities.MessagePin, error)
+}
+
+type pinRepository struct {
+	db *gorm.DB
+}
+
+func NewPinRepository(db *gorm.DB) PinRepository {
+	return &pinRepository{db: db}
+}
+
+func (r *pinRepository) Pin(ctx context.Context, pin *entities.MessagePin, limit int) (bool, error) {
+	added := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 鎖定對話的釘選範圍，避免併發釘選超過上限
+		var pins []*entities.MessagePin
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("scope = ?", pin.Scope).
+			Find(&pins).Error; err != nil {
+			return err
+		}
+		for _, existing := range pins {
+			if existing.MessageID == pin.MessageID {
+				*pin = *existing
+				return nil
+			}
+		}
+		if len(pins) >= limit {
+			return ErrPinLimitReached
+		}
+
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(pin)
+		added = result.RowsAffected > 0
+		return result.Error
+	})
+	return added, err
+}
+
+func (r *pinRepository) Unpin(ctx context.Context, messageID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("message_id = ?", messageID).Delete(&entities.MessagePin{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *pinRepository) ListByScope(ctx context.Context, scope string) ([]*entities.MessagePin, error) {
+	var pins []*entities.MessagePin
+	err := r.db.WithContext(ctx).Where("scope = ?", scope).Order("created_at DESC, id DESC").Find(&pins).Error
+	return pins, err
+}
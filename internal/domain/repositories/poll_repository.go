@@ -0,0 +1,127 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	// ErrPollNotFound 表示投票不存在
+	ErrPollNotFound = errors.New("poll not found")
+	// ErrPollClosed 表示投票已截止
+	ErrPollClosed = errors.New("poll is closed")
+)
+
+type PollRepository interface {
+	// Create 建立投票
+	Create(ctx context.Context, poll *entities.Poll) error
+	// FindByMessageID 查詢投票訊息對應的投票，不存在時返回 ErrPollNotFound
+	FindByMessageID(ctx context.Context, messageID uint) (*entities.Poll, error)
+	// FindByMessageIDs 以單一查詢獲取多則投票訊息對應的投票
+	FindByMessageIDs(ctx context.Context, messageIDs []uint) ([]*entities.Poll, error)
+	// FindVotes 以單一查詢獲取多個投票的所有選票
+	FindVotes(ctx context.Context, messageIDs []uint) ([]*entities.PollVote, error)
+	// Vote 以 options 取代用戶原本的選擇，options 為空時撤回投票。
+	// 與截止在同一列上加鎖，投票在 now 時已截止則返回 ErrPollClosed
+	Vote(ctx context.Context, messageID, userID uint, options []int, now time.Time) error
+	// Close 截止投票，已截止時返回 closed = false
+	Close(ctx context.Context, messageID uint, at time.Time) (closed bool, err error)
+	// FindDue 查詢在 now 之前已到截止時間但尚未截止的投票，最多 limit 筆，依截止時間排列
+	FindDue(ctx context.Context, now time.Time, limit int) ([]*entities.Poll, error)
+}
+
+type pollRepository struct {
+	db *gorm.DB
+}
+
+func NewPollRepository(db *gorm.DB) PollRepository {
+	return &pollRepository{db: db}
+}
+
+func (r *pollRepository) Create(ctx context.Context, poll *entities.Poll) error {
+	return r.db.WithContext(ctx).Create(poll).Error
+}
+
+func (r *pollRepository) FindByMessageID(ctx context.Context, messageID uint) (*entities.Poll, error) {
+	var poll entities.Poll
+	err := r.db.WithContext(ctx).Where("message_id = ?", messageID).First(&poll).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPollNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &poll, nil
+}
+
+func (r *pollRepository) FindByMessageIDs(ctx context.Context, messageIDs []uint) ([]*entities.Poll, error) {
+	var polls []*entities.Poll
+	if len(messageIDs) == 0 {
+		return polls, nil
+	}
+	err := r.db.WithContext(ctx).Where("message_id IN ?", messageIDs).Find(&polls).Error
+	return polls, err
+}
+
+func (r *pollRepository) FindVotes(ctx context.Context, messageIDs []uint) ([]*entities.PollVote, error) {
+	var votes []*entities.PollVote
+	if len(messageIDs) == 0 {
+		return votes, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("message_id IN ?", messageIDs).
+		Order("message_id, created_at, user_id").
+		Find(&votes).Error
+	return votes, err
+}
+
+func (r *pollRepository) Vote(ctx context.Context, messageID, userID uint, options []int, now time.Time) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var poll entities.Poll
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("message_id = ?", messageID).First(&poll).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrPollNotFound
+		}
+		if err != nil {
+			return err
+		}
+		if poll.IsClosed(now) {
+			return ErrPollClosed
+		}
+
+		if err := tx.Where("message_id = ? AND user_id = ?", messageID, userID).Delete(&entities.PollVote{}).Error; err != nil {
+			return err
+		}
+		if len(options) == 0 {
+			return nil
+		}
+
+		votes := make([]*entities.PollVote, len(options))
+		for i, option := range options {
+			votes[i] = &entities.PollVote{MessageID: messageID, UserID: userID, Option: option, CreatedAt: now}
+		}
+		return tx.Create(&votes).Error
+	})
+}
+
+func (r *pollRepository) Close(ctx context.Context, messageID uint, at time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).Model(&entities.Poll{}).
+		Where("message_id = ? AND closed_at IS NULL", messageID).
+		UpdateColumn("closed_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *pollRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*entities.Poll, error) {
+	var polls []*entities.Poll
+	err := r.db.WithContext(ctx).
+		Where("closed_at IS NULL AND closes_at <= ?", now).
+		Order("closes_at, message_id").
+		Limit(limit).
+		Find(&polls).Error
+	return polls, err
+}
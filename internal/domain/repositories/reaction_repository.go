@@ -0,0 +1,65 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ReactionRepository interface {
+	// Add 新增表情回應，已存在時返回 added = false
+	Add(ctx context.Context, reaction *entities.MessageReaction) (added bool, err error)
+	// Remove 移除表情回應，不存在時返回 removed = false
+	Remove(ctx context.Context, messageID, userID uint, emoji string) (removed bool, err error)
+	// CountByMessageIDs 以單一查詢統計多則訊息的表情回應
+	CountByMessageIDs(ctx context.Context, messageIDs []uint) (map[uint][]entities.ReactionCount, error)
+}
+
+type reactionRepository struct {
+	db *gorm.DB
+}
+
+func NewReactionRepository(db *gorm.DB) ReactionRepository {
+	return &reactionRepository{db: db}
+}
+
+func (r *reactionRepository) Add(ctx context.Context, reaction *entities.MessageReaction) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(reaction)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *reactionRepository) Remove(ctx context.Context, messageID, userID uint, emoji string) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("message_id = ? AND user_id = ? AND emoji = ?", messageID, userID, emoji).
+		Delete(&entities.MessageReaction{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *reactionRepository) CountByMessageIDs(ctx context.Context, messageIDs []uint) (map[uint][]entities.ReactionCount, error) {
+	counts := make(map[uint][]entities.ReactionCount)
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		MessageID uint
+		Emoji     string
+		Count     int
+	}
+	err := r.db.WithContext(ctx).Model(&entities.MessageReaction{}).
+		Select("message_id, emoji, COUNT(*) AS count, MIN(created_at) AS first_at").
+		Where("message_id IN ?", messageIDs).
+		Group("message_id, emoji").
+		Order("message_id, first_at").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		counts[row.MessageID] = append(counts[row.MessageID], entities.ReactionCount{Emoji: row.Emoji, Count: row.Count})
+	}
+	return counts, nil
+}
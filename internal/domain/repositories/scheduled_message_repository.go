@@ -0,0 +1,180 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrScheduledMessageNotFound 表示排程訊息不存在
+	ErrScheduledMessageNotFound = errors.New("scheduled message not found")
+	// ErrScheduleNotPending 表示排程訊息已被認領、發送或取消，不能再修改
+	ErrScheduleNotPending = errors.New("scheduled message is not pending")
+)
+
+type ScheduledMessageRepository interface {
+	// Create 建立排程訊息
+	Create(ctx context.Context, scheduled *entities.ScheduledMessage) error
+	// FindByID 依ID查詢排程訊息，不存在時返回 ErrScheduledMessageNotFound
+	FindByID(ctx context.Context, id uint) (*entities.ScheduledMessage, error)
+	// ListPending 列出用戶待發送的排程訊息，依發送時間由近到遠排列
+	ListPending(ctx context.Context, userID uint) ([]*entities.ScheduledMessage, error)
+	// UpdatePending 更新待發送排程的內容與時間，已不是待發送狀態時返回 ErrScheduleNotPending
+	UpdatePending(ctx context.Context, scheduled *entities.ScheduledMessage) error
+	// Cancel 取消待發送的排程，已不是待發送狀態時返回 ErrScheduleNotPending
+	Cancel(ctx context.Context, id uint) error
+	// ClaimDue 認領最多 limit 則到期的排程，以及認領逾期未完成的排程，認領在 lease 後失效。
+	// 以單一 UPDATE 搶佔，多個程序同時執行時每則排程只會被其中一個認領
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entities.ScheduledMessage, error)
+	// MarkSent 記錄排程已發送，認領已失效時不會更新
+	MarkSent(ctx context.Context, scheduled *entities.ScheduledMessage, messageID uint) error
+	// MarkFailed 記錄發送失敗，retry 為 true 時放回待發送狀態，否則標記為失敗；認領已失效時不會更新
+	MarkFailed(ctx context.Context, scheduled *entities.ScheduledMessage, reason string, retry bool) error
+}
+
+type scheduledMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewScheduledMessageRepository(db *gorm.DB) ScheduledMessageRepository {
+	return &scheduledMessageRepository{db: db}
+}
+
+func (r *scheduledMessageRepository) Create(ctx context.Context, scheduled *entities.ScheduledMessage) error {
+	return r.db.WithContext(ctx).Create(scheduled).Error
+}
+
+func (r *scheduledMessageRepository) FindByID(ctx context.Context, id uint) (*entities.ScheduledMessage, error) {
+	var scheduled entities.ScheduledMessage
+	err := r.db.WithContext(ctx).First(&scheduled, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrScheduledMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &scheduled, nil
+}
+
+func (r *scheduledMessageRepository) ListPending(ctx context.Context, userID uint) ([]*entities.ScheduledMessage, error) {
+	var scheduled []*entities.ScheduledMessage
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND status = ?", userID, entities.SchedulePending).
+		Order("send_at, id").
+		Find(&scheduled).Error
+	return scheduled, err
+}
+
+func (r *scheduledMessageRepository) UpdatePending(ctx context.Context, scheduled *entities.ScheduledMessage) error {
+	result := r.db.WithContext(ctx).Model(&entities.ScheduledMessage{}).
+		Where("id = ? AND status = ?", scheduled.ID, entities.SchedulePending).
+		Updates(map[string]interface{}{
+			"content":    scheduled.Content,
+			"send_at":    scheduled.SendAt,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduleNotPending
+	}
+	return nil
+}
+
+func (r *scheduledMessageRepository) Cancel(ctx context.Context, id uint) error {
+	result := r.db.WithContext(ctx).Model(&entities.ScheduledMessage{}).
+		Where("id = ? AND status = ?", id, entities.SchedulePending).
+		Updates(map[string]interface{}{
+			"status":     entities.ScheduleCanceled,
+			"updated_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrScheduleNotPending
+	}
+	return nil
+}
+
+func (r *scheduledMessageRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entities.ScheduledMessage, error) {
+	token, err := newClaimToken()
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 以認領標記搶佔到期的排程，條件與更新在同一個語句中完成
+	result := r.db.WithContext(ctx).Model(&entities.ScheduledMessage{}).
+		Where("(status = ? AND send_at <= ?) OR (status = ? AND claimed_until < ?)",
+			entities.SchedulePending, now, entities.ScheduleSending, now).
+		Order("send_at, id").
+		Limit(limit).
+		UpdateColumns(map[string]interface{}{
+			"status":        entities.ScheduleSending,
+			"claim_token":   token,
+			"claimed_until": now.Add(lease),
+			"attempts":      gorm.Expr("attempts + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, nil
+	}
+
+	// 2. 取回此次認領的排程
+	var claimed []*entities.ScheduledMessage
+	err = r.db.WithContext(ctx).
+		Where("claim_token = ? AND status = ?", token, entities.ScheduleSending).
+		Order("send_at, id").
+		Find(&claimed).Error
+	return claimed, err
+}
+
+func (r *scheduledMessageRepository) MarkSent(ctx context.Context, scheduled *entities.ScheduledMessage, messageID uint) error {
+	return r.finishClaim(ctx, scheduled, map[string]interface{}{
+		"status":     entities.ScheduleSent,
+		"message_id": messageID,
+		"last_error": "",
+	})
+}
+
+func (r *scheduledMessageRepository) MarkFailed(ctx context.Context, scheduled *entities.ScheduledMessage, reason string, retry bool) error {
+	status := entities.ScheduleFailed
+	if retry {
+		status = entities.SchedulePending
+	}
+	if runes := []rune(reason); len(runes) > 255 {
+		reason = string(runes[:255])
+	}
+	return r.finishClaim(ctx, scheduled, map[string]interface{}{
+		"status":     status,
+		"last_error": reason,
+	})
+}
+
+// finishClaim 在仍持有認領時更新排程並釋放認領
+func (r *scheduledMessageRepository) finishClaim(ctx context.Context, scheduled *entities.ScheduledMessage, updates map[string]interface{}) error {
+	updates["claim_token"] = ""
+	updates["claimed_until"] = nil
+	updates["updated_at"] = time.Now()
+	return r.db.WithContext(ctx).Model(&entities.ScheduledMessage{}).
+		Where("id = ? AND claim_token = ?", scheduled.ID, scheduled.ClaimToken).
+		UpdateColumns(updates).Error
+}
+
+// newClaimToken 產生隨機的認領標記，用於辨識同一次認領的排程
+func newClaimToken() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,92 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// SequenceRepository 對話訊息序號的資料庫計數器，Redis 不可用時由此配發序號
+type SequenceRepository interface {
+	// Current 返回訊息所屬對話目前的最大序號，包含 Redis 已配發並寫入訊息的序號
+	Current(ctx context.Context, message *entities.Message) (uint64, error)
+	// Next 在資料庫中為訊息所屬對話配發下一個序號，同一對話的配發以計數器的列鎖串行化
+	Next(ctx context.Context, message *entities.Message) (uint64, error)
+}
+
+type sequenceRepository struct {
+	db *gorm.DB
+}
+
+func NewSequenceRepository(db *gorm.DB) SequenceRepository {
+	return &sequenceRepository{db: db}
+}
+
+func (r *sequenceRepository) Current(ctx context.Context, message *entities.Message) (uint64, error) {
+	return currentSequence(r.db.WithContext(ctx), message)
+}
+
+func (r *sequenceRepository) Next(ctx context.Context, message *entities.Message) (uint64, error) {
+	scope := entities.PinScopeOf(message)
+
+	var seq uint64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// 確保計數器存在後鎖定，同一對話的配發依序進行
+		counter := entities.ConversationSequence{Scope: scope}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&counter).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("scope = ?", scope).First(&counter).Error; err != nil {
+			return err
+		}
+
+		// Redis 配發的序號只寫入訊息，需與訊息中的最大序號比較
+		current, err := currentSequence(tx, message)
+		if err != nil {
+			return err
+		}
+		seq = current + 1
+
+		return tx.Model(&entities.ConversationSequence{}).Where("scope = ?", scope).Update("seq", seq).Error
+	})
+	return seq, err
+}
+
+// currentSequence 返回計數器與對話訊息中較大的序號
+func currentSequence(db *gorm.DB, message *entities.Message) (uint64, error) {
+	var counter uint64
+	err := db.Model(&entities.ConversationSequence{}).
+		Where("scope = ?", entities.PinScopeOf(message)).
+		Select("COALESCE(MAX(seq), 0)").
+		Scan(&counter).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// 群組走 (room_id, seq) 索引；私聊兩個方向分別走 (user_id, target_id, seq) 索引
+	conditions := [][]interface{}{{"room_id = ?", message.RoomID}}
+	if !message.IsGroupConversation() {
+		conditions = [][]interface{}{
+			{"user_id = ? AND target_id = ?", message.UserId, message.TargetId},
+			{"user_id = ? AND target_id = ?", message.TargetId, message.UserId},
+		}
+	}
+
+	latest := counter
+	for _, condition := range conditions {
+		var seq uint64
+		err := db.Model(&entities.Message{}).
+			Where(condition[0], condition[1:]...).
+			Select("COALESCE(MAX(seq), 0)").
+			Scan(&seq).Error
+		if err != nil {
+			return 0, err
+		}
+		if seq > latest {
+			latest = seq
+		}
+	}
+	return latest, nil
+}
@@ -0,0 +1,77 @@
+package repositories
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StarRepository interface {
+	// Star 收藏訊息，已收藏時只更新備註，返回 created 表示是否為新收藏
+	Star(ctx context.Context, star *entities.MessageStar) (created bool, err error)
+	// Unstar 取消收藏，未收藏時返回 removed = false
+	Unstar(ctx context.Context, userID, messageID uint) (removed bool, err error)
+	// ListByUser 依收藏ID由新到舊列出用戶的收藏，最多返回 query.Limit + 1 筆供判斷是否還有下一頁
+	ListByUser(ctx context.Context, userID uint, query entities.StarQuery) ([]*entities.MessageStar, error)
+	// DeleteByMessageIDs 刪除訊息的所有收藏，用於清理已不存在或無法顯示的訊息
+	DeleteByMessageIDs(ctx context.Context, messageIDs []uint) error
+}
+
+type starRepository struct {
+	db *gorm.DB
+}
+
+func NewStarRepository(db *gorm.DB) StarRepository {
+	return &starRepository{db: db}
+}
+
+func (r *starRepository) Star(ctx context.Context, star *entities.MessageStar) (bool, error) {
+	created := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(star)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			created = true
+			return nil
+		}
+
+		// 已收藏時保留原本的收藏時間，只更新備註
+		if err := tx.Model(&entities.MessageStar{}).
+			Where("user_id = ? AND message_id = ?", star.UserID, star.MessageID).
+			Updates(map[string]interface{}{"note": star.Note, "updated_at": star.UpdatedAt}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ? AND message_id = ?", star.UserID, star.MessageID).First(star).Error
+	})
+	return created, err
+}
+
+func (r *starRepository) Unstar(ctx context.Context, userID, messageID uint) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Where("user_id = ? AND message_id = ?", userID, messageID).
+		Delete(&entities.MessageStar{})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *starRepository) ListByUser(ctx context.Context, userID uint, query entities.StarQuery) ([]*entities.MessageStar, error) {
+	query = query.Normalize()
+
+	db := r.db.WithContext(ctx).Where("user_id = ?", userID)
+	if query.BeforeID > 0 {
+		db = db.Where("id < ?", query.BeforeID)
+	}
+	var stars []*entities.MessageStar
+	err := db.Order("id DESC").Limit(query.Limit + 1).Find(&stars).Error
+	return stars, err
+}
+
+func (r *starRepository) DeleteByMessageIDs(ctx context.Context, messageIDs []uint) error {
+	if len(messageIDs) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Where("message_id IN ?", messageIDs).Delete(&entities.MessageStar{}).Error
+}
@@ -0,0 +1,67 @@
+package search
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"context"
+	"time"
+)
+
+const (
+	// DefaultLimit 未指定數量時每頁返回的搜尋結果數
+	DefaultLimit = 20
+	// MaxLimit 每頁最多返回的搜尋結果數
+	MaxLimit = 100
+)
+
+// MessageIndex 訊息全文索引，實作需只返回 Query 中用戶可見、未撤回且未到自動消失時間的私聊與群聊訊息
+type MessageIndex interface {
+	// Index 新增或更新訊息的索引
+	Index(ctx context.Context, message *entities.Message) error
+	// Remove 從索引中移除訊息
+	Remove(ctx context.Context, messageID uint) error
+	// Search 依條件搜尋訊息，結果依訊息ID由新到舊排列
+	Search(ctx context.Context, query Query) (*Result, error)
+}
+
+// Query 搜尋條件，零值欄位表示不限制
+type Query struct {
+	Terms    []string // 由 Tokenize 產生的搜尋詞，結果需包含全部搜尋詞
+	ViewerID uint     // 發起搜尋的用戶，可見範圍為其私聊與 RoomIDs 中的群組
+	RoomIDs  []uint   // 用戶所屬的群組
+
+	ConversationType entities.ConversationType // 限定單一對話，需與 TargetID 一起使用
+	TargetID         uint                      // 私聊為對方用戶ID，群聊為群組ID
+	SenderID         uint
+	From             time.Time // 包含
+	To               time.Time // 不包含
+	Media            entities.MediaType
+
+	Offset int
+	Limit  int
+}
+
+// Normalize 修正超出範圍的分頁參數
+func (q Query) Normalize() Query {
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultLimit
+	}
+	if q.Limit > MaxLimit {
+		q.Limit = MaxLimit
+	}
+	return q
+}
+
+// Hit 一筆搜尋結果，Snippet 為已跳脫 HTML 並以 <mark> 標示搜尋詞的內容片段
+type Hit struct {
+	Message *entities.Message `json:"message"`
+	Snippet string            `json:"snippet"`
+}
+
+// Result 一頁搜尋結果
+type Result struct {
+	Hits    []*Hit `json:"hits"`
+	HasMore bool   `json:"has_more"`
+}
@@ -0,0 +1,125 @@
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// snippetLength 搜尋結果片段的最大字數
+	snippetLength = 80
+	// snippetLead 片段中第一個搜尋詞之前保留的字數
+	snippetLead = 20
+)
+
+// Tokenize 將文字切分為搜尋詞：英數字以整個單字為詞並轉為小寫，
+// 中日韓文字沒有空白分隔，以相鄰兩字（bigram）為詞，與 MySQL ngram 解析器一致
+func Tokenize(text string) []string {
+	var tokens []string
+	seen := make(map[string]bool)
+	add := func(token string) {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			tokens = append(tokens, token)
+		}
+	}
+
+	var word []rune
+	var cjk []rune
+	flushWord := func() {
+		add(string(word))
+		word = word[:0]
+	}
+	flushCJK := func() {
+		if len(cjk) == 1 {
+			add(string(cjk))
+		}
+		for i := 0; i+1 < len(cjk); i++ {
+			add(string(cjk[i : i+2]))
+		}
+		cjk = cjk[:0]
+	}
+
+	for _, r := range text {
+		switch {
+		case isCJK(r):
+			flushWord()
+			cjk = append(cjk, r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			flushCJK()
+			word = append(word, unicode.ToLower(r))
+		default:
+			flushWord()
+			flushCJK()
+		}
+	}
+	flushWord()
+	flushCJK()
+	return tokens
+}
+
+// isCJK 判斷字元是否為不以空白分詞的中日韓文字
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// Snippet 擷取內容中第一個搜尋詞附近的片段，跳脫 HTML 後以 <mark> 標示所有搜尋詞
+func Snippet(content string, terms []string) string {
+	runes := []rune(content)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		pattern := []rune(term)
+		if len(pattern) == 0 {
+			continue
+		}
+		for i := 0; i+len(pattern) <= len(lower); i++ {
+			if string(lower[i:i+len(pattern)]) != term {
+				continue
+			}
+			for j := i; j < i+len(pattern); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+
+	start := 0
+	if first > snippetLead {
+		start = first - snippetLead
+	}
+	end := start + snippetLength
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; {
+		j := i
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			b.WriteString("<mark>" + segment + "</mark>")
+		} else {
+			b.WriteString(segment)
+		}
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String()
+}
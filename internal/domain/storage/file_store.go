@@ -0,0 +1,28 @@
+package storage
+
+import (
+	"errors"
+	"io"
+)
+
+// ErrNotFound 表示檔案不存在或不在儲存區可存取的範圍內
+var ErrNotFound = errors.New("file not found")
+
+// ExportStore 保存對話匯出檔案的儲存區。檔名由呼叫者產生，實作需拒絕包含路徑的檔名，
+// 且儲存區不可透過靜態檔案路由公開存取
+type ExportStore interface {
+	// Create 建立新檔案，檔案已存在時返回錯誤
+	Create(name string) (io.WriteCloser, error)
+	// Open 開啟檔案供下載，不存在時返回 ErrNotFound
+	Open(name string) (io.ReadCloser, error)
+	// Remove 刪除檔案，不存在時不返回錯誤
+	Remove(name string) error
+}
+
+// AttachmentStore 讀取訊息附件，只接受指向本機上傳目錄的網址
+type AttachmentStore interface {
+	// Lookup 返回附件網址對應的檔名，網址不是本機附件或檔案不存在時 ok 為 false
+	Lookup(url string) (name string, ok bool)
+	// Open 開啟本機附件，網址不是本機附件或檔案不存在時返回 ErrNotFound
+	Open(url string) (io.ReadCloser, error)
+}
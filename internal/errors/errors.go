@@ -4,15 +4,29 @@ package errors
 import (
 	"clean-architecture-gochat/internal/common/enum"
 	baseErrors "clean-architecture-gochat/pkg/errors"
+	"net/http"
 
 	pkgErrors "github.com/pkg/errors"
 )
 
+// statusOverrides 無法由錯誤碼區段推得的HTTP狀態碼
+var statusOverrides = map[enum.ErrorCode]int{
+	enum.ErrRateLimited: http.StatusTooManyRequests,
+}
+
+// withStatus 依錯誤碼套用特定的HTTP狀態碼，未指定的錯誤碼沿用區段對應的狀態碼
+func withStatus(appErr baseErrors.Error, code enum.ErrorCode) baseErrors.Error {
+	if status, ok := statusOverrides[code]; ok {
+		return appErr.(*baseErrors.AppError).WithStatusCode(status)
+	}
+	return appErr
+}
+
 // 創建帶有錯誤碼的應用錯誤
 func New(code enum.ErrorCode, details ...interface{}) baseErrors.Error {
 	key, message := enum.GetErrorDetails(code)
 
-	appErr := baseErrors.New(int(code), key, message)
+	appErr := withStatus(baseErrors.New(int(code), key, message), code)
 
 	if len(details) > 0 && details[0] != nil {
 		appErr = appErr.WithDetails(details[0])
@@ -31,7 +45,7 @@ func Wrap(err error, code enum.ErrorCode, details ...interface{}) baseErrors.Err
 
 	// 使用 pkg/errors.Cause 確保獲取根本錯誤
 	cause := pkgErrors.Cause(err)
-	appErr := baseErrors.Wrap(cause, int(code), key, message)
+	appErr := withStatus(baseErrors.Wrap(cause, int(code), key, message), code)
 
 	if len(details) > 0 && details[0] != nil {
 		appErr = appErr.WithDetails(details[0])
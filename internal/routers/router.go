@@ -2,16 +2,28 @@ package routers
 
 import (
 	"clean-architecture-gochat/docs"
+	linkpreviewInfra "clean-architecture-gochat/infrastructure/linkpreview"
 	"clean-architecture-gochat/infrastructure/mysql"
+	redisInfra "clean-architecture-gochat/infrastructure/redis"
+	searchInfra "clean-architecture-gochat/infrastructure/search"
+	storageInfra "clean-architecture-gochat/infrastructure/storage"
+	websocketInfra "clean-architecture-gochat/infrastructure/websocket"
 	"clean-architecture-gochat/interface/controllers"
+	"clean-architecture-gochat/internal/config"
+	"clean-architecture-gochat/internal/domain/entities"
 	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/domain/search"
 	"clean-architecture-gochat/internal/usecases/chat"
 	"clean-architecture-gochat/internal/usecases/user"
 	"clean-architecture-gochat/internal/usecases/websocket"
+	"context"
+	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	swaggerfiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"gorm.io/gorm"
 )
 
 func SetupRouter() *gin.Engine {
@@ -28,6 +40,13 @@ func SetupRouter() *gin.Engine {
 
 	// 初始化依賴
 	db := mysql.Connect()
+	redisClient, err := redisInfra.Connect()
+	if err != nil {
+		// Redis 不可用時仍可啟動，快取相關功能會降級為直接存取資料庫
+		log.Printf("Redis 初始化失敗: %v", err)
+	}
+	messageCacheRepo := redisInfra.NewMessageCacheRepository(redisClient)
+	unreadCounter := redisInfra.NewUnreadCounterCache(redisClient)
 
 	userRepo := repositories.NewUserRepository(db)
 	contactRepo := repositories.NewContactRepository(db)
@@ -41,8 +60,82 @@ func SetupRouter() *gin.Engine {
 	privateChatService := chat.NewPrivateChatService(messageRepo)
 	groupChatService := chat.NewGroupChatService(groupRepo, messageRepo)
 	connectionService := websocket.NewConnectionService()
-	messageService := chat.NewMessageService(messageRepo)
-	chatController := controllers.NewChatController(privateChatService, groupChatService, connectionService, messageService)
+	eventPublisher := chat.NewEventPublisher(connectionService, redisInfra.NewOfflineEventCache(redisClient))
+	conversationRepo := repositories.NewConversationRepository(db)
+	reactionRepo := repositories.NewReactionRepository(db)
+	pinRepo := repositories.NewPinRepository(db)
+	searchIndex := newSearchIndex(db)
+	messageSettings := chat.MessageSettings{
+		EditWindow:   time.Duration(config.Config.Chat.EditWindow) * time.Second,
+		RecallWindow: time.Duration(config.Config.Chat.RecallWindow) * time.Second,
+		PinLimit:     config.Config.Chat.PinLimit,
+	}
+	linkPreviewer := chat.NewLinkPreviewer(linkpreviewInfra.NewHTTPFetcher(linkpreviewInfra.Options{
+		Timeout:     time.Duration(config.Config.LinkPreview.Timeout) * time.Second,
+		MaxBodySize: int64(config.Config.LinkPreview.MaxBodySize) * 1024,
+	}), redisInfra.NewLinkPreviewCache(redisClient))
+	sequencer := chat.NewSequencer(redisInfra.NewSequenceCache(redisClient), repositories.NewSequenceRepository(db))
+	moderationRepo := repositories.NewModerationRepository(db)
+	moderator, err := chat.NewRuleModerator(moderationRules(), moderationRepo)
+	if err != nil {
+		log.Fatalf("全域審核規則設定錯誤: %v", err)
+	}
+	moderationStage := chat.NewModerationStage(moderator, moderationRepo, groupRepo, eventPublisher, config.Config.Moderation.Reviewers)
+	messageUseCase := chat.NewMessageUseCase(chat.MessageDeps{
+		MessageRepo:      messageRepo,
+		MessageCache:     messageCacheRepo,
+		UnreadCounter:    unreadCounter,
+		GroupRepo:        groupRepo,
+		ConversationRepo: conversationRepo,
+		ReactionRepo:     reactionRepo,
+		PinRepo:          pinRepo,
+		PollRepo:         repositories.NewPollRepository(db),
+		StarRepo:         repositories.NewStarRepository(db),
+		SearchIndex:      searchIndex,
+		LinkPreviewer:    linkPreviewer,
+		Sequencer:        sequencer,
+		Moderation:       moderationStage,
+		Publisher:        eventPublisher,
+		Settings:         messageSettings,
+	})
+	draftUseCase := chat.NewDraftUseCase(repositories.NewDraftRepository(db), redisInfra.NewDraftCache(redisClient), groupRepo, eventPublisher)
+	// 用戶發送、轉發與建立投票時限制頻率，排程與審核核准由系統代為發送，使用未包裝的用例
+	rateLimiter := chat.NewRateLimiter(redisInfra.NewRateLimitCache(redisClient), userRepo, rateLimitSettings())
+	userMessageUseCase := chat.NewRateLimitedMessageUseCase(messageUseCase, rateLimiter, messageCacheRepo)
+	// 從輸入框發送的訊息會清除草稿，轉發與排程不會清除
+	composeUseCase := chat.NewDraftClearingMessageUseCase(userMessageUseCase, draftUseCase)
+	chatController := controllers.NewChatController(privateChatService, groupChatService, connectionService, composeUseCase)
+	messageController := controllers.NewMessageController(userMessageUseCase)
+	conversationController := controllers.NewConversationController(chat.NewConversationUseCase(conversationRepo, groupRepo, unreadCounter, draftUseCase, eventPublisher))
+	draftController := controllers.NewDraftController(draftUseCase, chat.NewDraftDebouncer(draftUseCase, time.Duration(config.Config.Chat.DraftDebounce)*time.Millisecond))
+	searchController := controllers.NewSearchController(chat.NewSearchUseCase(searchIndex, groupRepo))
+	scheduleUseCase := chat.NewScheduleUseCase(repositories.NewScheduledMessageRepository(db), messageUseCase, groupRepo)
+	scheduleController := controllers.NewScheduleController(scheduleUseCase)
+	exportDir := config.Config.Export.Dir
+	if exportDir == "" {
+		exportDir = "data/exports"
+	}
+	exportUseCase := chat.NewExportUseCase(repositories.NewExportJobRepository(db), messageRepo, groupRepo, userRepo,
+		storageInfra.NewLocalExportStore(exportDir), storageInfra.NewLocalAttachmentStore("web/asset/files", "/web/asset/files/"), eventPublisher)
+	exportController := controllers.NewExportController(exportUseCase)
+	importUseCase := chat.NewImportUseCase(repositories.NewImportRepository(db), messageRepo, messageCacheRepo, groupRepo, conversationRepo, unreadCounter, searchIndex, sequencer)
+	importController := controllers.NewImportController(importUseCase, config.Config.Import.Token)
+	moderationController := controllers.NewModerationController(chat.NewModerationUseCase(moderationRepo, groupRepo, messageUseCase, eventPublisher, config.Config.Moderation.Reviewers))
+
+	// 背景工作：發送到期的排程訊息，多個副本同時執行時以資料庫認領避免重複發送
+	go chat.NewScheduleDispatcher(scheduleUseCase, time.Duration(config.Config.Chat.ScheduleInterval)*time.Second).Run(context.Background())
+	// 背景工作：刪除已到自動消失時間的訊息並通知參與者
+	go chat.NewDisappearingPurger(messageUseCase, time.Duration(config.Config.Chat.PurgeInterval)*time.Second).Run(context.Background())
+	// 背景工作：截止到期的投票並推送最終結果
+	go chat.NewPollCloser(messageUseCase, time.Duration(config.Config.Chat.PollCloseInterval)*time.Second).Run(context.Background())
+	// 背景工作：產生對話匯出檔，多個副本同時執行時以資料庫認領避免重複匯出
+	go chat.NewExportRunner(exportUseCase, time.Duration(config.Config.Export.Interval)*time.Second).Run(context.Background())
+	// 背景工作：將停止編輯的草稿寫回資料庫
+	go chat.NewDraftFlusher(draftUseCase, time.Duration(config.Config.Chat.DraftFlushInterval)*time.Second).Run(context.Background())
+	// 客戶端透過 WebSocket 送出的草稿更新
+	websocketInfra.GetHub().Handle("draft.update", draftController.HandleDraftUpdate)
+	// 客戶端透過 WebSocket 發送的訊息，與 HTTP 發送走相同的流程與頻率限制
+	websocketInfra.GetHub().Handle("message.send", chatController.HandleSendMessage)
 
 	// 首頁相關路由
 	r.GET("/", indexController.GetIndex)
@@ -98,9 +191,107 @@ func SetupRouter() *gin.Engine {
 		chatGroup.GET("/group/:id/members", chatController.GetGroupMembers)
 		chatGroup.POST("/group/:id/members", chatController.AddGroupMember)
 		chatGroup.DELETE("/group/:id/members", chatController.RemoveGroupMember)
+		chatGroup.PUT("/group/:id/members/role", chatController.SetGroupMemberRole)
 		chatGroup.POST("/group/send", chatController.SendGroupMessage)
 		chatGroup.GET("/group/history", chatController.GetGroupHistory)
+
+		// 訊息操作相關路由
+		chatGroup.POST("/message/forward", messageController.ForwardMessages)
+		chatGroup.PUT("/message/:id", messageController.EditMessage)
+		chatGroup.GET("/message/:id/revisions", messageController.GetRevisions)
+		chatGroup.GET("/message/:id/thread", messageController.GetThread)
+		chatGroup.POST("/message/:id/recall", messageController.RecallMessage)
+		chatGroup.POST("/message/:id/reactions", messageController.AddReaction)
+		chatGroup.DELETE("/message/:id/reactions", messageController.RemoveReaction)
+		chatGroup.POST("/message/:id/pin", messageController.PinMessage)
+		chatGroup.DELETE("/message/:id/pin", messageController.UnpinMessage)
+		chatGroup.POST("/poll", messageController.CreatePoll)
+		chatGroup.GET("/message/:id/poll", messageController.GetPoll)
+		chatGroup.PUT("/message/:id/poll/vote", messageController.VotePoll)
+		chatGroup.POST("/message/:id/poll/close", messageController.ClosePoll)
+		chatGroup.POST("/message/:id/star", messageController.StarMessage)
+		chatGroup.DELETE("/message/:id/star", messageController.UnstarMessage)
+		chatGroup.GET("/pins", messageController.ListPinnedMessages)
+		chatGroup.GET("/stars", messageController.ListStarredMessages)
+		chatGroup.PUT("/disappearing", messageController.SetDisappearing)
+		chatGroup.GET("/disappearing", messageController.GetDisappearing)
+		chatGroup.GET("/search", searchController.SearchMessages)
+
+		// 排程訊息相關路由
+		chatGroup.POST("/scheduled", scheduleController.ScheduleMessage)
+		chatGroup.GET("/scheduled", scheduleController.ListScheduled)
+		chatGroup.PUT("/scheduled/:id", scheduleController.UpdateScheduled)
+		chatGroup.DELETE("/scheduled/:id", scheduleController.CancelScheduled)
+
+		// 對話匯出相關路由
+		chatGroup.POST("/exports", exportController.CreateExport)
+		chatGroup.GET("/exports", exportController.ListExports)
+		chatGroup.GET("/exports/:id", exportController.GetExport)
+		chatGroup.GET("/exports/:id/download", exportController.DownloadExport)
+		chatGroup.POST("/import", importController.ImportHistory)
+
+		// 內容審核相關路由
+		chatGroup.GET("/group/:id/moderation/rules", moderationController.ListRules)
+		chatGroup.POST("/group/:id/moderation/rules", moderationController.CreateRule)
+		chatGroup.DELETE("/group/:id/moderation/rules/:ruleId", moderationController.DeleteRule)
+		chatGroup.GET("/moderation/queue", moderationController.ListHeld)
+		chatGroup.POST("/moderation/queue/:id/approve", moderationController.ApproveHeld)
+		chatGroup.POST("/moderation/queue/:id/reject", moderationController.RejectHeld)
+		chatGroup.GET("/moderation/logs", moderationController.ListLogs)
+
+		// 會話列表相關路由
+		chatGroup.GET("/conversations", conversationController.GetConversations)
+		chatGroup.GET("/conversations/unread", conversationController.GetUnreadCount)
+		chatGroup.POST("/conversations/read", conversationController.MarkRead)
+		chatGroup.POST("/conversations/mute", conversationController.SetMuted)
+		chatGroup.POST("/conversations/pin", conversationController.SetPinned)
+
+		// 草稿相關路由
+		chatGroup.GET("/drafts", draftController.ListDrafts)
+		chatGroup.PUT("/drafts", draftController.SaveDraft)
 	}
 
 	return r
 }
+
+// newSearchIndex 依設定選擇訊息搜尋索引的實作
+func newSearchIndex(db *gorm.DB) search.MessageIndex {
+	if config.Config.Search.Driver == "memory" {
+		return searchInfra.NewMemoryIndex()
+	}
+	return mysql.NewMessageSearchIndex(db)
+}
+
+// moderationRules 將設定檔中的全域審核規則轉為審核規則
+func moderationRules() []entities.ModerationRule {
+	rules := make([]entities.ModerationRule, 0, len(config.Config.Moderation.Rules))
+	for _, rule := range config.Config.Moderation.Rules {
+		rules = append(rules, entities.ModerationRule{
+			Pattern: rule.Pattern,
+			IsRegex: rule.Regex,
+			Action:  entities.ModerationAction(rule.Action),
+		})
+	}
+	return rules
+}
+
+// rateLimitSettings 將設定檔中的發送頻率限制轉為訊息用例的設定
+func rateLimitSettings() chat.RateLimitSettings {
+	cfg := config.Config.RateLimit
+	return chat.RateLimitSettings{
+		Text:          rateLimitTier(cfg.Text),
+		Media:         rateLimitTier(cfg.Media),
+		NewAccountAge: time.Duration(cfg.NewAccountAge) * time.Second,
+	}
+}
+
+func rateLimitTier(tier config.RateLimitTier) chat.RateLimitTier {
+	limit := func(l config.RateLimit) chat.RateLimit {
+		return chat.RateLimit{Burst: l.Burst, Interval: time.Duration(l.Interval) * time.Millisecond}
+	}
+	return chat.RateLimitTier{
+		User:         limit(tier.User),
+		NewUser:      limit(tier.NewUser),
+		Conversation: limit(tier.Conversation),
+	}
+}
@@ -39,8 +39,8 @@ func (s *service) UpgradeConnection(ctx context.Context, conn *ws.Conn, userID i
 
 // 發送訊息到單一 WebSocket 客戶端
 func (s *service) SendMessage(ctx context.Context, msg *entities.Message) error {
-	client, exists := websocketInfra.GetClient(fmt.Sprintf("%d", msg.TargetId))
-	if !exists {
+	clients := websocketInfra.GetClients(fmt.Sprintf("%d", msg.TargetId))
+	if len(clients) == 0 {
 		return errors.New("target user is not online")
 	}
 
@@ -55,7 +55,9 @@ func (s *service) SendMessage(ctx context.Context, msg *entities.Message) error
 		return err
 	}
 
-	client.SendMessage(data)
+	for _, client := range clients {
+		client.SendMessage(data)
+	}
 	return nil
 }
 
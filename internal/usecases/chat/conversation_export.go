@@ -0,0 +1,417 @@
+package chat
+
+import (
+	"archive/zip"
+	"bufio"
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/domain/storage"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	// exportClaimLease 背景工作認領匯出後的有效期限，每寫完一批訊息延長一次
+	exportClaimLease = 2 * time.Minute
+	// exportBatchSize 每次從資料庫讀取的訊息數
+	exportBatchSize = 500
+	// exportListLimit 列出匯出工作時返回的數量
+	exportListLimit = 20
+	// defaultExportInterval 預設檢查待處理匯出的間隔
+	defaultExportInterval = 5 * time.Second
+)
+
+// errExportClaimLost 表示認領已逾期並被其他程序接手，此次產生的檔案需捨棄
+var errExportClaimLost = errors.New("export claim lost")
+
+// ExportUseCase 對話匯出的用例：建立匯出工作、查詢進度、下載檔案與背景執行
+type ExportUseCase interface {
+	// CreateExport 建立對話匯出工作，由背景工作產生檔案，群組限成員
+	CreateExport(ctx context.Context, job *entities.ExportJob) error
+	// GetExport 獲取匯出工作與進度，限建立者
+	GetExport(ctx context.Context, userID, id uint) (*entities.ExportJob, error)
+	// ListExports 列出用戶最近的匯出工作
+	ListExports(ctx context.Context, userID uint) ([]*entities.ExportJob, error)
+	// OpenExport 開啟已完成的匯出檔案供下載，限建立者，呼叫者需關閉返回的檔案
+	OpenExport(ctx context.Context, userID, id uint) (*entities.ExportJob, io.ReadCloser, error)
+	// RunNext 認領並執行一個待處理的匯出工作，沒有待處理的工作時返回 false
+	RunNext(ctx context.Context, now time.Time) (bool, error)
+}
+
+type exportUseCase struct {
+	exportRepo  repositories.ExportJobRepository
+	messageRepo repositories.MessageRepository
+	groupRepo   repositories.GroupRepository
+	userRepo    repositories.UserRepository
+	store       storage.ExportStore
+	attachments storage.AttachmentStore
+	publisher   EventPublisher
+}
+
+// NewExportUseCase 創建新的對話匯出用例
+func NewExportUseCase(
+	exportRepo repositories.ExportJobRepository,
+	messageRepo repositories.MessageRepository,
+	groupRepo repositories.GroupRepository,
+	userRepo repositories.UserRepository,
+	store storage.ExportStore,
+	attachments storage.AttachmentStore,
+	publisher EventPublisher,
+) ExportUseCase {
+	return &exportUseCase{
+		exportRepo:  exportRepo,
+		messageRepo: messageRepo,
+		groupRepo:   groupRepo,
+		userRepo:    userRepo,
+		store:       store,
+		attachments: attachments,
+		publisher:   publisher,
+	}
+}
+
+func (uc *exportUseCase) CreateExport(ctx context.Context, job *entities.ExportJob) error {
+	if job == nil {
+		return appErrors.New(enum.ErrInvalidInput, "匯出工作不能為空")
+	}
+	if err := validateConversation(job.UserID, job.Type, job.TargetID); err != nil {
+		return err
+	}
+	if !job.Format.IsValid() {
+		return appErrors.New(enum.ErrInvalidInput, "匯出格式只支援 json、html 與 csv")
+	}
+	if err := uc.checkAccess(ctx, job); err != nil {
+		return err
+	}
+
+	job.Status = entities.ExportPending
+	job.Processed, job.Total, job.Attempts = 0, 0, 0
+	job.FileName, job.LastError = "", ""
+	if err := uc.exportRepo.Create(ctx, job); err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"userId":   job.UserID,
+			"targetId": job.TargetID,
+		})
+	}
+	return nil
+}
+
+func (uc *exportUseCase) GetExport(ctx context.Context, userID, id uint) (*entities.ExportJob, error) {
+	job, err := uc.exportRepo.FindByID(ctx, id)
+	if errors.Is(err, repositories.ErrExportJobNotFound) || (err == nil && job.UserID != userID) {
+		// 不是建立者時同樣返回不存在，避免洩漏其他用戶的匯出工作
+		return nil, appErrors.New(enum.ErrExportNotFound, map[string]interface{}{
+			"exportId": id,
+		})
+	}
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"exportId": id,
+		})
+	}
+	return job, nil
+}
+
+func (uc *exportUseCase) ListExports(ctx context.Context, userID uint) ([]*entities.ExportJob, error) {
+	jobs, err := uc.exportRepo.ListByUser(ctx, userID, exportListLimit)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+	return jobs, nil
+}
+
+func (uc *exportUseCase) OpenExport(ctx context.Context, userID, id uint) (*entities.ExportJob, io.ReadCloser, error) {
+	job, err := uc.GetExport(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if job.Status != entities.ExportCompleted {
+		return nil, nil, appErrors.New(enum.ErrExportNotReady, map[string]interface{}{
+			"exportId": id,
+			"status":   job.Status,
+		})
+	}
+
+	file, err := uc.store.Open(job.FileName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, appErrors.New(enum.ErrFileNotFound, map[string]interface{}{
+			"exportId": id,
+		})
+	}
+	if err != nil {
+		return nil, nil, appErrors.Wrap(err, enum.ErrInternalServer, map[string]interface{}{
+			"exportId": id,
+		})
+	}
+	return job, file, nil
+}
+
+func (uc *exportUseCase) RunNext(ctx context.Context, now time.Time) (bool, error) {
+	job, err := uc.exportRepo.ClaimNext(ctx, now, exportClaimLease)
+	if err != nil {
+		return false, err
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	// 1. 建立工作後才離開群組的用戶不能再匯出
+	err = uc.checkAccess(ctx, job)
+	retry := false
+	if err == nil {
+		err = uc.run(ctx, job)
+		retry = job.Attempts < entities.MaxExportAttempts
+	}
+	if err == nil || errors.Is(err, errExportClaimLost) {
+		return true, nil
+	}
+
+	// 2. 失敗時放回待處理狀態由下一次認領重試，超過次數或已無權限時標記為失敗並通知用戶
+	if markErr := uc.exportRepo.MarkFailed(ctx, job, err.Error(), retry); markErr != nil {
+		fmt.Printf("記錄匯出失敗狀態失敗: exportID=%d, err=%v\n", job.ID, markErr)
+	}
+	if !retry {
+		job.Status = entities.ExportFailed
+		job.LastError = err.Error()
+		uc.publishProgress(ctx, job, false)
+	}
+	return true, fmt.Errorf("匯出對話失敗: exportID=%d: %w", job.ID, err)
+}
+
+// run 將對話歷史寫入新的匯出檔，失敗或認領失效時刪除已寫入的檔案
+func (uc *exportUseCase) run(ctx context.Context, job *entities.ExportJob) error {
+	total, err := uc.messageRepo.CountConversationMessages(ctx, job.Type, job.UserID, job.TargetID)
+	if err != nil {
+		return err
+	}
+	job.Total = total
+	if err := uc.reportProgress(ctx, job); err != nil {
+		return err
+	}
+
+	name := job.OutputName()
+	file, err := uc.store.Create(name)
+	if err != nil {
+		return err
+	}
+	err = uc.write(ctx, job, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		var held bool
+		held, err = uc.exportRepo.MarkCompleted(ctx, job, name)
+		if err == nil && !held {
+			err = errExportClaimLost
+		}
+	}
+	if err != nil {
+		if removeErr := uc.store.Remove(name); removeErr != nil {
+			fmt.Printf("刪除未完成的匯出檔失敗: file=%s, err=%v\n", name, removeErr)
+		}
+		return err
+	}
+
+	now := time.Now()
+	job.Status = entities.ExportCompleted
+	job.FileName = name
+	job.CompletedAt = &now
+	uc.publishProgress(ctx, job, false)
+	return nil
+}
+
+// write 逐批讀取訊息並寫出匯出檔，打包附件時訊息檔與附件一起寫入 zip
+func (uc *exportUseCase) write(ctx context.Context, job *entities.ExportJob, w io.Writer) error {
+	var zw *zip.Writer
+	var bundle *attachmentBundle
+	out := w
+	if job.BundleAttachments {
+		zw = zip.NewWriter(w)
+		entry, err := zw.Create("messages." + string(job.Format))
+		if err != nil {
+			return err
+		}
+		out = entry
+		bundle = newAttachmentBundle(uc.attachments)
+	}
+	buffered := bufio.NewWriterSize(out, 64*1024)
+	writer := newExportWriter(job.Format, buffered)
+
+	header := &exportHeader{
+		Type:       job.Type,
+		TargetID:   job.TargetID,
+		Title:      uc.title(ctx, job),
+		ExportedAt: time.Now(),
+	}
+	if err := writer.begin(header); err != nil {
+		return err
+	}
+
+	// 以訊息ID為游標逐批讀取，記憶體中只保留一批訊息與發送者名稱
+	names := make(map[uint]string)
+	var afterID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		batch, err := uc.messageRepo.FindConversationBatch(ctx, job.Type, job.UserID, job.TargetID, afterID, exportBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		uc.resolveNames(ctx, batch, names)
+		for _, message := range batch {
+			if err := writer.write(newExportRecord(message, names, bundle)); err != nil {
+				return err
+			}
+		}
+		afterID = batch[len(batch)-1].ID
+		job.Processed += len(batch)
+		if err := uc.reportProgress(ctx, job); err != nil {
+			return err
+		}
+		if len(batch) < exportBatchSize {
+			break
+		}
+	}
+
+	if err := writer.end(); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return err
+	}
+	if zw == nil {
+		return nil
+	}
+	if err := bundle.writeTo(zw); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// checkAccess 確認用戶仍可存取要匯出的對話
+func (uc *exportUseCase) checkAccess(ctx context.Context, job *entities.ExportJob) error {
+	if !job.IsGroup() {
+		return nil
+	}
+	isMember, err := uc.groupRepo.IsMember(ctx, job.TargetID, job.UserID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": job.TargetID,
+			"userId": job.UserID,
+		})
+	}
+	if !isMember {
+		return appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": job.TargetID,
+			"userId": job.UserID,
+		})
+	}
+	return nil
+}
+
+// title 返回匯出檔的標題，群組為群組名稱，私聊為對方名稱
+func (uc *exportUseCase) title(ctx context.Context, job *entities.ExportJob) string {
+	if job.IsGroup() {
+		if group, err := uc.groupRepo.FindByID(ctx, job.TargetID); err == nil && group.Name != "" {
+			return group.Name
+		}
+		return fmt.Sprintf("群組 %d", job.TargetID)
+	}
+	if user, err := uc.userRepo.FindByID(ctx, job.TargetID); err == nil && user.Name != "" {
+		return "與 " + user.Name + " 的私聊"
+	}
+	return fmt.Sprintf("與用戶 %d 的私聊", job.TargetID)
+}
+
+// resolveNames 查詢批次中尚未查過的發送者名稱，失敗時只記錄錯誤，匯出檔以用戶ID代替
+func (uc *exportUseCase) resolveNames(ctx context.Context, batch []*entities.Message, names map[uint]string) {
+	var ids []uint
+	for _, message := range batch {
+		if _, ok := names[message.UserId]; !ok {
+			names[message.UserId] = ""
+			ids = append(ids, message.UserId)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	users, err := uc.userRepo.FindUserByIds(ctx, ids)
+	if err != nil {
+		fmt.Printf("查詢匯出訊息的發送者失敗: %v\n", err)
+		return
+	}
+	for _, user := range users {
+		names[user.ID] = user.Name
+	}
+}
+
+// reportProgress 記錄進度並延長認領，再將進度推送給建立者
+func (uc *exportUseCase) reportProgress(ctx context.Context, job *entities.ExportJob) error {
+	held, err := uc.exportRepo.UpdateProgress(ctx, job, exportClaimLease)
+	if err != nil {
+		return err
+	}
+	if !held {
+		return errExportClaimLost
+	}
+	uc.publishProgress(ctx, job, true)
+	return nil
+}
+
+// publishProgress 推送匯出狀態給建立者，進度更新為暫時事件，完成與失敗會保留到用戶上線。
+// 推送的是當下的副本，之後的進度更新不會影響已送出的事件
+func (uc *exportUseCase) publishProgress(ctx context.Context, job *entities.ExportJob, transient bool) {
+	snapshot := *job
+	event := &Event{Type: EventExportProgress, Data: &snapshot, Transient: transient}
+	if err := uc.publisher.Publish(ctx, []uint{job.UserID}, event); err != nil {
+		fmt.Printf("推送事件失敗: event=%s, err=%v\n", event.Type, err)
+	}
+}
+
+// ExportRunner 定期執行待處理對話匯出的背景工作，可在多個應用程式副本同時執行
+type ExportRunner struct {
+	useCase  ExportUseCase
+	interval time.Duration
+}
+
+// NewExportRunner 創建新的匯出執行器，interval 為 0 時使用預設間隔
+func NewExportRunner(useCase ExportUseCase, interval time.Duration) *ExportRunner {
+	if interval <= 0 {
+		interval = defaultExportInterval
+	}
+	return &ExportRunner{useCase: useCase, interval: interval}
+}
+
+// Run 持續執行待處理的匯出直到 ctx 結束，處理完一個工作後立即檢查下一個
+func (r *ExportRunner) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		ran, err := r.useCase.RunNext(ctx, time.Now())
+		if err != nil {
+			fmt.Printf("執行對話匯出失敗: %v\n", err)
+		}
+		if ran {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,239 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// maxUnreadLoadAttempts 載入未讀計數期間持續有新訊息時重新讀取資料庫的次數上限，超過後直接返回資料庫的結果
+const maxUnreadLoadAttempts = 3
+
+// ConversationUseCase 用戶會話列表（收件匣）的用例
+type ConversationUseCase interface {
+	// ListConversations 分頁獲取用戶的會話列表，並附加各會話的草稿
+	ListConversations(ctx context.Context, userID uint, query entities.ConversationQuery) (*entities.ConversationPage, error)
+	// GetUnreadCount 獲取會話的未讀數，優先讀取計數器，計數器不存在時從資料庫載入
+	GetUnreadCount(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (int, error)
+	// MarkRead 將會話的已讀位置推進到指定訊息，messageID 為 0 時表示全部已讀，
+	// 並通知用戶的所有裝置同步已讀狀態
+	MarkRead(ctx context.Context, userID uint, convType entities.ConversationType, targetID, messageID uint) (*entities.Conversation, error)
+	// SetMuted 設定會話免打擾
+	SetMuted(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, muted bool) error
+	// SetPinned 設定會話置頂
+	SetPinned(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, pinned bool) error
+}
+
+type conversationUseCase struct {
+	conversationRepo repositories.ConversationRepository
+	groupRepo        repositories.GroupRepository
+	unreadCounter    cache.UnreadCounterCache
+	drafts           DraftUseCase
+	publisher        EventPublisher
+}
+
+// NewConversationUseCase 創建新的會話用例
+func NewConversationUseCase(
+	conversationRepo repositories.ConversationRepository,
+	groupRepo repositories.GroupRepository,
+	unreadCounter cache.UnreadCounterCache,
+	drafts DraftUseCase,
+	publisher EventPublisher,
+) ConversationUseCase {
+	return &conversationUseCase{
+		conversationRepo: conversationRepo,
+		groupRepo:        groupRepo,
+		unreadCounter:    unreadCounter,
+		drafts:           drafts,
+		publisher:        publisher,
+	}
+}
+
+func (uc *conversationUseCase) ListConversations(ctx context.Context, userID uint, query entities.ConversationQuery) (*entities.ConversationPage, error) {
+	if userID == 0 {
+		return nil, appErrors.New(enum.ErrInvalidInput, "用戶ID不能為空")
+	}
+
+	page, err := uc.conversationRepo.ListByUser(ctx, userID, query.Normalize())
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+
+	// 附加草稿，讓在其他裝置開始輸入的訊息可以接續編輯，失敗時只略過草稿
+	drafts, err := uc.drafts.ListDrafts(ctx, userID)
+	if err != nil {
+		fmt.Printf("獲取草稿失敗: userID=%d, err=%v\n", userID, err)
+		return page, nil
+	}
+	byConversation := make(map[string]*entities.Draft, len(drafts))
+	for _, draft := range drafts {
+		byConversation[draft.ConversationKey()] = draft
+	}
+	for _, conversation := range page.Conversations {
+		conversation.Draft = byConversation[entities.DraftConversationKey(conversation.Type, conversation.TargetID)]
+	}
+	return page, nil
+}
+
+func (uc *conversationUseCase) GetUnreadCount(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (int, error) {
+	if err := validateConversation(userID, convType, targetID); err != nil {
+		return 0, err
+	}
+
+	// 1. 先讀取計數器
+	count, found, err := uc.unreadCounter.GetUnread(ctx, userID, convType, targetID)
+	if err == nil && found {
+		return count, nil
+	}
+	if err != nil {
+		fmt.Printf("讀取未讀計數失敗: userID=%d, targetID=%d, err=%v\n", userID, targetID, err)
+	}
+
+	// 2. 計數器不存在，從資料庫載入。讀取資料庫與寫入計數器之間若有新訊息，計數器會少算，
+	// 因此以載入版本確認期間沒有新訊息，否則重新讀取
+	for attempt := 0; attempt < maxUnreadLoadAttempts; attempt++ {
+		version, err := uc.unreadCounter.PrepareLoad(ctx, userID, convType, targetID)
+		if err != nil {
+			fmt.Printf("載入未讀計數失敗: userID=%d, targetID=%d, err=%v\n", userID, targetID, err)
+			return uc.findUnreadCount(ctx, userID, convType, targetID)
+		}
+
+		count, err = uc.findUnreadCount(ctx, userID, convType, targetID)
+		if err != nil {
+			return 0, err
+		}
+
+		loaded, err := uc.unreadCounter.LoadUnread(ctx, userID, convType, targetID, count, version)
+		if err != nil {
+			fmt.Printf("載入未讀計數失敗: userID=%d, targetID=%d, err=%v\n", userID, targetID, err)
+			return count, nil
+		}
+		if loaded {
+			break
+		}
+	}
+	return count, nil
+}
+
+// findUnreadCount 從資料庫讀取會話的未讀數，沒有會話代表沒有未讀訊息
+func (uc *conversationUseCase) findUnreadCount(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (int, error) {
+	conversation, err := uc.conversationRepo.Find(ctx, userID, convType, targetID)
+	if errors.Is(err, repositories.ErrConversationNotFound) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, appErrors.NewDBError(err, map[string]interface{}{
+			"userId":   userID,
+			"type":     convType,
+			"targetId": targetID,
+		})
+	}
+	return conversation.UnreadCount, nil
+}
+
+func (uc *conversationUseCase) MarkRead(ctx context.Context, userID uint, convType entities.ConversationType, targetID, messageID uint) (*entities.Conversation, error) {
+	if err := validateConversation(userID, convType, targetID); err != nil {
+		return nil, err
+	}
+
+	conversation, err := uc.conversationRepo.MarkRead(ctx, userID, convType, targetID, messageID)
+	if errors.Is(err, repositories.ErrConversationNotFound) {
+		return nil, appErrors.New(enum.ErrConversationNotFound, map[string]interface{}{
+			"userId":   userID,
+			"type":     convType,
+			"targetId": targetID,
+		})
+	}
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId":   userID,
+			"type":     convType,
+			"targetId": targetID,
+		})
+	}
+
+	// 移除計數器而非直接寫入新值，避免與同時送出的訊息重複計數，下次讀取時再從資料庫載入
+	if err := uc.unreadCounter.InvalidateUnread(ctx, userID, convType, targetID); err != nil {
+		fmt.Printf("清除未讀計數失敗: userID=%d, targetID=%d, err=%v\n", userID, targetID, err)
+	}
+
+	// 推送給用戶自己的所有裝置，讓其他裝置同步清除未讀標記
+	if err := uc.publisher.Publish(ctx, []uint{userID}, &Event{Type: EventConversationRead, Data: conversation}); err != nil {
+		fmt.Printf("推送事件失敗: event=%s, err=%v\n", EventConversationRead, err)
+	}
+	return conversation, nil
+}
+
+func (uc *conversationUseCase) SetMuted(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, muted bool) error {
+	if err := uc.checkAccess(ctx, userID, convType, targetID); err != nil {
+		return err
+	}
+
+	if err := uc.conversationRepo.SetMuted(ctx, userID, convType, targetID, muted); err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"userId":   userID,
+			"targetId": targetID,
+		})
+	}
+	return nil
+}
+
+func (uc *conversationUseCase) SetPinned(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, pinned bool) error {
+	if err := uc.checkAccess(ctx, userID, convType, targetID); err != nil {
+		return err
+	}
+
+	if err := uc.conversationRepo.SetPinned(ctx, userID, convType, targetID, pinned); err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"userId":   userID,
+			"targetId": targetID,
+		})
+	}
+	return nil
+}
+
+// checkAccess 檢查用戶能否設定此會話，群組會話僅限成員
+func (uc *conversationUseCase) checkAccess(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	if err := validateConversation(userID, convType, targetID); err != nil {
+		return err
+	}
+	if convType != entities.ConversationTypeGroup {
+		return nil
+	}
+
+	isMember, err := uc.groupRepo.IsMember(ctx, targetID, userID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": targetID,
+			"userId": userID,
+		})
+	}
+	if !isMember {
+		return appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": targetID,
+			"userId": userID,
+		})
+	}
+	return nil
+}
+
+// validateConversation 驗證會話識別參數
+func validateConversation(userID uint, convType entities.ConversationType, targetID uint) error {
+	if userID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "用戶ID不能為空")
+	}
+	if convType != entities.ConversationTypePrivate && convType != entities.ConversationTypeGroup {
+		return appErrors.New(enum.ErrInvalidInput, "無效的會話類型")
+	}
+	if targetID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "會話對象ID不能為空")
+	}
+	return nil
+}
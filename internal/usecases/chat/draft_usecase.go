@@ -0,0 +1,315 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// draftFlushDelay 草稿最後一次變更後等待多久才寫回資料庫，連續編輯時只寫入最後的內容
+	draftFlushDelay = 3 * time.Second
+	// draftFlushBatchSize 每次寫回資料庫的草稿數量上限
+	draftFlushBatchSize = 200
+	// defaultDraftFlushInterval 預設檢查待寫回草稿的間隔
+	defaultDraftFlushInterval = 5 * time.Second
+	// defaultDraftDebounce 預設 WebSocket 草稿更新的合併時間
+	defaultDraftDebounce = time.Second
+)
+
+// DraftUseCase 會話草稿的用例：草稿即時寫入快取並同步到用戶的其他裝置，稍後再寫回資料庫
+type DraftUseCase interface {
+	// SaveDraft 儲存用戶在會話中的草稿，內容為空時清除草稿
+	SaveDraft(ctx context.Context, draft *entities.Draft) error
+	// ClearDraft 清除用戶在會話中的草稿
+	ClearDraft(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error
+	// ListDrafts 列出用戶所有的草稿，依更新時間由新到舊排列
+	ListDrafts(ctx context.Context, userID uint) ([]*entities.Draft, error)
+	// FlushDrafts 將 now 之前一段時間內未再變更的草稿寫回資料庫，返回處理的數量
+	FlushDrafts(ctx context.Context, now time.Time) (int, error)
+}
+
+type draftUseCase struct {
+	draftRepo  repositories.DraftRepository
+	draftCache cache.DraftCache
+	groupRepo  repositories.GroupRepository
+	publisher  EventPublisher
+}
+
+// NewDraftUseCase 創建新的草稿用例
+func NewDraftUseCase(
+	draftRepo repositories.DraftRepository,
+	draftCache cache.DraftCache,
+	groupRepo repositories.GroupRepository,
+	publisher EventPublisher,
+) DraftUseCase {
+	return &draftUseCase{
+		draftRepo:  draftRepo,
+		draftCache: draftCache,
+		groupRepo:  groupRepo,
+		publisher:  publisher,
+	}
+}
+
+func (uc *draftUseCase) SaveDraft(ctx context.Context, draft *entities.Draft) error {
+	if draft == nil {
+		return appErrors.New(enum.ErrInvalidInput, "草稿不能為空")
+	}
+	if err := validateConversation(draft.UserID, draft.Type, draft.TargetID); err != nil {
+		return err
+	}
+	if utf8.RuneCountInString(draft.Content) > entities.MaxDraftLength {
+		return appErrors.New(enum.ErrInvalidInput, fmt.Sprintf("草稿不能超過 %d 字", entities.MaxDraftLength))
+	}
+	if draft.Type == entities.ConversationTypeGroup {
+		isMember, err := uc.groupRepo.IsMember(ctx, draft.TargetID, draft.UserID)
+		if err != nil {
+			return appErrors.NewDBError(err, map[string]interface{}{
+				"roomId": draft.TargetID,
+				"userId": draft.UserID,
+			})
+		}
+		if !isMember {
+			return appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+				"roomId": draft.TargetID,
+				"userId": draft.UserID,
+			})
+		}
+	}
+
+	draft.UpdatedAt = time.Now()
+	return uc.store(ctx, draft)
+}
+
+func (uc *draftUseCase) ClearDraft(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	if err := validateConversation(userID, convType, targetID); err != nil {
+		return err
+	}
+	return uc.store(ctx, &entities.Draft{UserID: userID, Type: convType, TargetID: targetID, UpdatedAt: time.Now()})
+}
+
+func (uc *draftUseCase) ListDrafts(ctx context.Context, userID uint) ([]*entities.Draft, error) {
+	if userID == 0 {
+		return nil, appErrors.New(enum.ErrInvalidInput, "用戶ID不能為空")
+	}
+
+	// 1. 先嘗試從快取獲取
+	drafts, found, err := uc.draftCache.GetDrafts(ctx, userID)
+	if err != nil {
+		fmt.Printf("讀取草稿快取失敗: userID=%d, err=%v\n", userID, err)
+	}
+	if err != nil || !found {
+		// 2. 快取未載入，從資料庫載入
+		if drafts, err = uc.load(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.Slice(drafts, func(i, j int) bool {
+		return drafts[i].UpdatedAt.After(drafts[j].UpdatedAt)
+	})
+	return drafts, nil
+}
+
+func (uc *draftUseCase) FlushDrafts(ctx context.Context, now time.Time) (int, error) {
+	drafts, err := uc.draftCache.TakeDirty(ctx, now.Add(-draftFlushDelay), draftFlushBatchSize)
+	if err != nil && len(drafts) == 0 {
+		return 0, err
+	}
+
+	for _, draft := range drafts {
+		if persistErr := uc.persist(ctx, draft); persistErr != nil {
+			fmt.Printf("寫回草稿失敗: userID=%d, err=%v\n", draft.UserID, persistErr)
+			// 重新標記，由下一次寫回重試
+			if markErr := uc.draftCache.MarkDirty(ctx, draft); markErr != nil {
+				fmt.Printf("重新標記草稿失敗: userID=%d, err=%v\n", draft.UserID, markErr)
+			}
+		}
+	}
+	return len(drafts), err
+}
+
+// store 將草稿寫入快取並通知用戶的所有裝置，快取無法使用時直接寫入資料庫
+func (uc *draftUseCase) store(ctx context.Context, draft *entities.Draft) error {
+	saved, err := uc.draftCache.SaveDraft(ctx, draft)
+	if err == nil && !saved {
+		// 用戶的草稿尚未載入，先從資料庫載入再寫入，避免快取中只有部分草稿
+		if _, err = uc.load(ctx, draft.UserID); err == nil {
+			saved, err = uc.draftCache.SaveDraft(ctx, draft)
+		}
+	}
+	if err != nil || !saved {
+		if err != nil {
+			fmt.Printf("寫入草稿快取失敗，直接寫入資料庫: userID=%d, err=%v\n", draft.UserID, err)
+		}
+		if err := uc.persist(ctx, draft); err != nil {
+			return appErrors.NewDBError(err, map[string]interface{}{
+				"userId":   draft.UserID,
+				"targetId": draft.TargetID,
+			})
+		}
+	}
+
+	if err := uc.publisher.Publish(ctx, []uint{draft.UserID}, &Event{Type: EventDraftUpdated, Data: draft, Transient: true}); err != nil {
+		fmt.Printf("推送草稿更新失敗: userID=%d, err=%v\n", draft.UserID, err)
+	}
+	return nil
+}
+
+// load 從資料庫讀取用戶的草稿並載入快取，載入快取失敗只記錄錯誤
+func (uc *draftUseCase) load(ctx context.Context, userID uint) ([]*entities.Draft, error) {
+	drafts, err := uc.draftRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+	if err := uc.draftCache.LoadDrafts(ctx, userID, drafts); err != nil {
+		fmt.Printf("載入草稿快取失敗: userID=%d, err=%v\n", userID, err)
+	}
+	return drafts, nil
+}
+
+// persist 將草稿寫入資料庫，內容為空時刪除
+func (uc *draftUseCase) persist(ctx context.Context, draft *entities.Draft) error {
+	if draft.Content == "" {
+		return uc.draftRepo.Delete(ctx, draft.UserID, draft.Type, draft.TargetID)
+	}
+	saved := *draft
+	saved.ID = 0
+	return uc.draftRepo.Save(ctx, &saved)
+}
+
+// draftClearingMessageUseCase 在用戶發送訊息後清除該會話的草稿，
+// 轉發與排程等非由輸入框發出的訊息直接使用內層的用例，不會清除草稿
+type draftClearingMessageUseCase struct {
+	MessageUseCase
+	drafts DraftUseCase
+}
+
+// NewDraftClearingMessageUseCase 包裝訊息用例，發送成功後清除發送者在該會話的草稿
+func NewDraftClearingMessageUseCase(messageUseCase MessageUseCase, drafts DraftUseCase) MessageUseCase {
+	return &draftClearingMessageUseCase{MessageUseCase: messageUseCase, drafts: drafts}
+}
+
+func (uc *draftClearingMessageUseCase) SendPrivateMessage(ctx context.Context, message *entities.Message) error {
+	if err := uc.MessageUseCase.SendPrivateMessage(ctx, message); err != nil {
+		return err
+	}
+	uc.clearDraft(ctx, message, entities.ConversationTypePrivate, message.TargetId)
+	return nil
+}
+
+func (uc *draftClearingMessageUseCase) SendGroupMessage(ctx context.Context, message *entities.Message) error {
+	if err := uc.MessageUseCase.SendGroupMessage(ctx, message); err != nil {
+		return err
+	}
+	uc.clearDraft(ctx, message, entities.ConversationTypeGroup, message.RoomID)
+	return nil
+}
+
+// clearDraft 清除發送者的會話草稿，討論串回覆不是從主對話的輸入框發出，不清除
+func (uc *draftClearingMessageUseCase) clearDraft(ctx context.Context, message *entities.Message, convType entities.ConversationType, targetID uint) {
+	if message.IsThreadReply() {
+		return
+	}
+	if err := uc.drafts.ClearDraft(ctx, message.UserId, convType, targetID); err != nil {
+		fmt.Printf("清除草稿失敗: userID=%d, err=%v\n", message.UserId, err)
+	}
+}
+
+// DraftDebouncer 合併客戶端頻繁送出的草稿更新，同一會話在一段時間內沒有新的變更才儲存
+type DraftDebouncer struct {
+	useCase DraftUseCase
+	delay   time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*entities.Draft
+	timers  map[string]*time.Timer
+}
+
+// NewDraftDebouncer 創建新的草稿更新合併器，delay 為 0 時使用預設時間
+func NewDraftDebouncer(useCase DraftUseCase, delay time.Duration) *DraftDebouncer {
+	if delay <= 0 {
+		delay = defaultDraftDebounce
+	}
+	return &DraftDebouncer{
+		useCase: useCase,
+		delay:   delay,
+		pending: make(map[string]*entities.Draft),
+		timers:  make(map[string]*time.Timer),
+	}
+}
+
+// Update 記錄會話最新的草稿並重新計時
+func (d *DraftDebouncer) Update(draft *entities.Draft) {
+	key := fmt.Sprintf("%d:%s", draft.UserID, draft.ConversationKey())
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.pending[key] = draft
+	if timer, ok := d.timers[key]; ok {
+		timer.Stop()
+	}
+	d.timers[key] = time.AfterFunc(d.delay, func() { d.fire(key) })
+}
+
+// fire 儲存會話最新的草稿
+func (d *DraftDebouncer) fire(key string) {
+	d.mu.Lock()
+	draft := d.pending[key]
+	delete(d.pending, key)
+	delete(d.timers, key)
+	d.mu.Unlock()
+
+	if draft == nil {
+		return
+	}
+	if err := d.useCase.SaveDraft(context.Background(), draft); err != nil {
+		fmt.Printf("儲存草稿失敗: userID=%d, err=%v\n", draft.UserID, err)
+	}
+}
+
+// DraftFlusher 定期將草稿寫回資料庫的背景工作，可在多個應用程式副本同時執行
+type DraftFlusher struct {
+	useCase  DraftUseCase
+	interval time.Duration
+}
+
+// NewDraftFlusher 創建新的草稿寫回器，interval 為 0 時使用預設間隔
+func NewDraftFlusher(useCase DraftUseCase, interval time.Duration) *DraftFlusher {
+	if interval <= 0 {
+		interval = defaultDraftFlushInterval
+	}
+	return &DraftFlusher{useCase: useCase, interval: interval}
+}
+
+// Run 持續寫回草稿直到 ctx 結束，一批處理滿時立即處理下一批
+func (f *DraftFlusher) Run(ctx context.Context) {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		count, err := f.useCase.FlushDrafts(ctx, time.Now())
+		if err != nil {
+			fmt.Printf("寫回草稿失敗: %v\n", err)
+		}
+		if err == nil && count >= draftFlushBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
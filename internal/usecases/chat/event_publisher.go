@@ -0,0 +1,124 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/usecases/websocket"
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// EventType 定義推送給客戶端的事件類型
+type EventType string
+
+const (
+	EventMessageNew      EventType = "message.new"      // 新訊息
+	EventMessageEdited   EventType = "message.edited"   // 訊息被編輯
+	EventMessageRecalled EventType = "message.recalled" // 訊息被撤回
+	EventMessageReaction EventType = "message.reaction" // 訊息的表情回應變更
+	EventThreadUpdated   EventType = "message.thread"   // 討論串有新回覆
+	EventMessageMention  EventType = "message.mention"  // 用戶在群組訊息中被提及
+	EventMessagePinned   EventType = "message.pinned"   // 訊息被釘選或取消釘選
+	EventMessageExpired  EventType = "message.expired"  // 訊息已到自動消失時間並被刪除
+	EventMessagePreview  EventType = "message.preview"  // 訊息的連結預覽已產生
+	EventMessagePoll     EventType = "message.poll"     // 投票結果更新或投票已截止
+
+	EventConversationRead   EventType = "conversation.read"   // 會話已讀位置變更，同步到用戶的其他裝置
+	EventDraftUpdated       EventType = "draft.updated"       // 會話草稿變更，同步到用戶的其他裝置
+	EventMessageStarred     EventType = "message.starred"     // 訊息被收藏或取消收藏，同步到用戶的其他裝置
+	EventExportProgress     EventType = "export.progress"     // 對話匯出的進度、完成或失敗，只推送給建立者
+	EventModerationHeld     EventType = "moderation.held"     // 有訊息進入審核佇列，推送給可審核的管理員
+	EventModerationReviewed EventType = "moderation.reviewed" // 待審核訊息已被核准或駁回，推送給發送者
+
+	EventMessageAck    EventType = "message.ack"    // 透過 WebSocket 發送的訊息已送出，只回覆給送出的裝置
+	EventMessageFailed EventType = "message.failed" // 透過 WebSocket 發送的訊息失敗，data 與 HTTP 的錯誤回應相同
+)
+
+// Event 透過 WebSocket 推送給客戶端的事件封包
+type Event struct {
+	Type EventType   `json:"event"`
+	Data interface{} `json:"data"`
+
+	// Transient 為 true 時只推送給在線用戶，不暫存到離線佇列，用於重新連線後會另行取得最新狀態的事件
+	Transient bool `json:"-"`
+}
+
+// EventPublisher 負責將聊天事件推送給用戶
+type EventPublisher interface {
+	// Publish 將事件推送給在線用戶，離線用戶的事件會暫存待上線後補發
+	Publish(ctx context.Context, userIDs []uint, event *Event) error
+
+	// DeliverPending 補發用戶離線期間累積的事件
+	DeliverPending(ctx context.Context, userID uint) error
+
+	// DiscardPending 移除尚未送達給用戶、與指定訊息相關的離線事件
+	DiscardPending(ctx context.Context, userIDs []uint, messageIDs ...uint) error
+}
+
+type eventPublisher struct {
+	connectionService websocket.ConnectionService
+	offlineCache      cache.OfflineEventCache
+}
+
+// NewEventPublisher 創建新的事件推送器
+func NewEventPublisher(connectionService websocket.ConnectionService, offlineCache cache.OfflineEventCache) EventPublisher {
+	return &eventPublisher{
+		connectionService: connectionService,
+		offlineCache:      offlineCache,
+	}
+}
+
+func (p *eventPublisher) Publish(ctx context.Context, userIDs []uint, event *Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if p.connectionService.IsUserOnline(ctx, userID) {
+			if err := p.connectionService.SendToUser(ctx, userID, data); err == nil {
+				continue
+			}
+		}
+
+		// 用戶離線或推送失敗，暫存到離線佇列
+		if event.Transient {
+			continue
+		}
+		if err := p.offlineCache.PushOfflineEvent(ctx, userID, data); err != nil {
+			fmt.Printf("儲存離線事件失敗: userID=%d, err=%v\n", userID, err)
+		}
+	}
+
+	return nil
+}
+
+func (p *eventPublisher) DeliverPending(ctx context.Context, userID uint) error {
+	events, err := p.offlineCache.PopOfflineEvents(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	for i, data := range events {
+		if err := p.connectionService.SendToUser(ctx, userID, data); err != nil {
+			// 補發途中斷線，將剩餘事件放回佇列
+			for _, rest := range events[i:] {
+				if pushErr := p.offlineCache.PushOfflineEvent(ctx, userID, rest); pushErr != nil {
+					fmt.Printf("回存離線事件失敗: userID=%d, err=%v\n", userID, pushErr)
+				}
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *eventPublisher) DiscardPending(ctx context.Context, userIDs []uint, messageIDs ...uint) error {
+	for _, userID := range userIDs {
+		if err := p.offlineCache.RemoveMessageEvents(ctx, userID, messageIDs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,324 @@
+package chat
+
+import (
+	"archive/zip"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/storage"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// exportHeader 匯出檔開頭的對話資訊
+type exportHeader struct {
+	Type       entities.ConversationType `json:"type"`
+	TargetID   uint                      `json:"target_id"`
+	Title      string                    `json:"title"`
+	ExportedAt time.Time                 `json:"exported_at"`
+}
+
+// exportRecord 匯出檔中的一則訊息
+type exportRecord struct {
+	ID           uint          `json:"id"`
+	Seq          uint64        `json:"seq,omitempty"`
+	SenderID     uint          `json:"sender_id"`
+	SenderName   string        `json:"sender_name,omitempty"`
+	Kind         string        `json:"kind"`
+	Content      string        `json:"content"`              // 原始內容，富文本為 Markdown 原文
+	PlainText    string        `json:"plain_text,omitempty"` // 富文本去除格式後的純文字
+	Attachment   string        `json:"attachment,omitempty"` // 附件網址，打包附件時為 zip 中的相對路徑
+	ReplyToID    *uint         `json:"reply_to_id,omitempty"`
+	ThreadRootID *uint         `json:"thread_root_id,omitempty"`
+	CreatedAt    time.Time     `json:"created_at"`
+	EditedAt     *time.Time    `json:"edited_at,omitempty"`
+	Recalled     bool          `json:"recalled,omitempty"`
+	HTML         template.HTML `json:"-"` // 富文本經伺服器清理後的 HTML，只用於 HTML 格式
+}
+
+// exportKinds 匯出檔中各媒體類型的名稱
+var exportKinds = map[entities.MediaType]string{
+	entities.MediaTypeText:     "text",
+	entities.MediaTypeImage:    "image",
+	entities.MediaTypeVoice:    "voice",
+	entities.MediaTypeVideo:    "video",
+	entities.MediaTypeFile:     "file",
+	entities.MediaTypeRichText: "rich_text",
+	entities.MediaTypePoll:     "poll",
+}
+
+// newExportRecord 將訊息轉為匯出記錄，附件交給 bundle 打包，bundle 為 nil 時只保留網址
+func newExportRecord(message *entities.Message, names map[uint]string, bundle *attachmentBundle) *exportRecord {
+	record := &exportRecord{
+		ID:           message.ID,
+		Seq:          message.Seq,
+		SenderID:     message.UserId,
+		SenderName:   names[message.UserId],
+		Kind:         exportKinds[message.Media],
+		Content:      message.Content,
+		ReplyToID:    message.ReplyToID,
+		ThreadRootID: message.ThreadRootID,
+		CreatedAt:    message.CreatedAt,
+		EditedAt:     message.EditedAt,
+		Recalled:     message.IsRecalled(),
+	}
+	switch {
+	case message.IsSystem():
+		record.Kind = "system"
+	case record.Recalled:
+	case message.IsRichText():
+		record.PlainText = message.DisplayText()
+		record.HTML = template.HTML(message.RichTextHTML())
+	case isAttachment(message.Media):
+		record.Attachment = message.Content
+		record.Content = ""
+		if bundle != nil {
+			if path, ok := bundle.add(message.Content); ok {
+				record.Attachment = path
+			}
+		}
+	}
+	if record.Kind == "" {
+		record.Kind = "text"
+	}
+	return record
+}
+
+// isAttachment 判斷媒體類型的內容是否為附件網址
+func isAttachment(media entities.MediaType) bool {
+	switch media {
+	case entities.MediaTypeImage, entities.MediaTypeVoice, entities.MediaTypeVideo, entities.MediaTypeFile:
+		return true
+	}
+	return false
+}
+
+// exportWriter 以串流方式寫出匯出檔，記錄依訊息ID由舊到新寫入
+type exportWriter interface {
+	begin(header *exportHeader) error
+	write(record *exportRecord) error
+	end() error
+}
+
+// newExportWriter 依格式創建匯出檔寫入器
+func newExportWriter(format entities.ExportFormat, w io.Writer) exportWriter {
+	switch format {
+	case entities.ExportFormatHTML:
+		return &htmlExportWriter{w: w}
+	case entities.ExportFormatCSV:
+		return &csvExportWriter{w: w, csv: csv.NewWriter(w)}
+	default:
+		return &jsonExportWriter{w: w}
+	}
+}
+
+// jsonExportWriter 寫出 {"conversation": ..., "messages": [...]}，每則訊息一行
+type jsonExportWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (jw *jsonExportWriter) begin(header *exportHeader) error {
+	data, err := json.Marshal(header)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(jw.w, "{\"conversation\":%s,\"messages\":[", data)
+	return err
+}
+
+func (jw *jsonExportWriter) write(record *exportRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	sep := ",\n"
+	if jw.count == 0 {
+		sep = "\n"
+	}
+	jw.count++
+	_, err = fmt.Fprintf(jw.w, "%s%s", sep, data)
+	return err
+}
+
+func (jw *jsonExportWriter) end() error {
+	_, err := io.WriteString(jw.w, "\n]}\n")
+	return err
+}
+
+// csvHeader CSV 匯出的欄位
+var csvHeader = []string{"id", "seq", "created_at", "sender_id", "sender_name", "kind", "content", "attachment", "reply_to_id", "thread_root_id", "edited_at", "recalled"}
+
+// csvExportWriter 寫出每則訊息一列的 CSV，開頭加上 BOM 讓試算表軟體以 UTF-8 開啟
+type csvExportWriter struct {
+	w   io.Writer
+	csv *csv.Writer
+}
+
+func (cw *csvExportWriter) begin(header *exportHeader) error {
+	if _, err := io.WriteString(cw.w, "\ufeff"); err != nil {
+		return err
+	}
+	return cw.csv.Write(csvHeader)
+}
+
+func (cw *csvExportWriter) write(record *exportRecord) error {
+	content := record.Content
+	if record.PlainText != "" {
+		content = record.PlainText
+	}
+	return cw.csv.Write([]string{
+		strconv.FormatUint(uint64(record.ID), 10),
+		strconv.FormatUint(record.Seq, 10),
+		record.CreatedAt.Format(time.RFC3339),
+		strconv.FormatUint(uint64(record.SenderID), 10),
+		csvSafe(record.SenderName),
+		record.Kind,
+		csvSafe(content),
+		csvSafe(record.Attachment),
+		formatOptionalID(record.ReplyToID),
+		formatOptionalID(record.ThreadRootID),
+		formatOptionalTime(record.EditedAt),
+		strconv.FormatBool(record.Recalled),
+	})
+}
+
+func (cw *csvExportWriter) end() error {
+	cw.csv.Flush()
+	return cw.csv.Error()
+}
+
+// csvSafe 在可能被試算表當作公式的內容前加上單引號，避免開啟匯出檔時執行公式
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+func formatOptionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// exportHTMLTemplates 單一 HTML 匯出檔的模板，內容一律經 html/template 跳脫，
+// 富文本只使用伺服器清理後的 HTML，並以 CSP 禁止執行任何腳本
+var exportHTMLTemplates = template.Must(template.New("head").Parse(`<!DOCTYPE html>
+<html lang="zh-Hant">
+<head>
+<meta charset="utf-8">
+<meta http-equiv="Content-Security-Policy" content="default-src 'none'; img-src * data:; media-src *; style-src 'unsafe-inline'">
+<title>{{.Title}}</title>
+<style>
+body{font-family:sans-serif;max-width:860px;margin:0 auto;padding:16px;color:#222}
+.meta{color:#888;font-size:12px}
+ol{list-style:none;padding:0}
+li{padding:8px 0;border-bottom:1px solid #eee}
+.sender{font-weight:bold}
+.content{white-space:pre-wrap;word-break:break-word}
+.system,.recalled{color:#888;font-style:italic}
+.thread{margin-left:24px}
+img{max-width:320px}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">匯出時間 {{.ExportedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+<ol>
+`))
+
+func init() {
+	template.Must(exportHTMLTemplates.New("message").Parse(`<li id="m{{.ID}}" class="{{.Kind}}{{if .Recalled}} recalled{{end}}{{if .ThreadRootID}} thread{{end}}">
+<div class="meta"><span class="sender">{{if .SenderName}}{{.SenderName}}{{else}}#{{.SenderID}}{{end}}</span> {{.CreatedAt.Format "2006-01-02 15:04:05"}}{{if .EditedAt}}（已編輯）{{end}}{{if .ReplyToID}} 回覆 <a href="#m{{.ReplyToID}}">#{{.ReplyToID}}</a>{{end}}</div>
+{{if .HTML}}<div class="content">{{.HTML}}</div>{{else if .Attachment}}{{if eq .Kind "image"}}<a href="{{.Attachment}}"><img src="{{.Attachment}}" alt="圖片"></a>{{else}}<a href="{{.Attachment}}">{{.Attachment}}</a>{{end}}{{else}}<div class="content">{{.Content}}</div>{{end}}
+</li>
+`))
+	template.Must(exportHTMLTemplates.New("foot").Parse("</ol>\n</body>\n</html>\n"))
+}
+
+// htmlExportWriter 寫出可直接以瀏覽器開啟的單一 HTML 檔
+type htmlExportWriter struct {
+	w io.Writer
+}
+
+func (hw *htmlExportWriter) begin(header *exportHeader) error {
+	return exportHTMLTemplates.ExecuteTemplate(hw.w, "head", header)
+}
+
+func (hw *htmlExportWriter) write(record *exportRecord) error {
+	return exportHTMLTemplates.ExecuteTemplate(hw.w, "message", record)
+}
+
+func (hw *htmlExportWriter) end() error {
+	return exportHTMLTemplates.ExecuteTemplate(hw.w, "foot", nil)
+}
+
+// attachmentBundle 收集匯出時要打包的本機附件，寫完訊息檔後再依序加入 zip
+type attachmentBundle struct {
+	store storage.AttachmentStore
+	paths map[string]string // 附件網址 → zip 中的路徑
+	urls  []string
+	used  map[string]bool
+}
+
+func newAttachmentBundle(store storage.AttachmentStore) *attachmentBundle {
+	return &attachmentBundle{store: store, paths: make(map[string]string), used: make(map[string]bool)}
+}
+
+// add 登記要打包的附件並返回其在 zip 中的路徑，不是本機附件時 ok 為 false
+func (b *attachmentBundle) add(url string) (string, bool) {
+	if path, ok := b.paths[url]; ok {
+		return path, true
+	}
+	name, ok := b.store.Lookup(url)
+	if !ok {
+		return "", false
+	}
+	path := "attachments/" + name
+	if b.used[path] {
+		path = fmt.Sprintf("attachments/%d_%s", len(b.urls), name)
+	}
+	b.paths[url] = path
+	b.used[path] = true
+	b.urls = append(b.urls, url)
+	return path, true
+}
+
+// writeTo 將登記的附件寫入 zip，匯出期間被刪除的附件只記錄錯誤
+func (b *attachmentBundle) writeTo(zw *zip.Writer) error {
+	for _, url := range b.urls {
+		if err := b.copy(zw, url); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *attachmentBundle) copy(zw *zip.Writer, url string) error {
+	file, err := b.store.Open(url)
+	if err != nil {
+		fmt.Printf("讀取匯出附件失敗: url=%s, err=%v\n", url, err)
+		return nil
+	}
+	defer file.Close()
+
+	entry, err := zw.Create(b.paths[url])
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(entry, file)
+	return err
+}
@@ -1,8 +1,10 @@
 package chat
 
 import (
+	"clean-architecture-gochat/internal/common/enum"
 	"clean-architecture-gochat/internal/domain/entities"
 	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
 	"context"
 	"errors"
 	"time"
@@ -18,6 +20,8 @@ type GroupChatService interface {
 	GetGroupsBySize(ctx context.Context, size int) ([]*entities.Group, error)
 	AddMember(ctx context.Context, groupID, userID uint) error
 	RemoveMember(ctx context.Context, groupID, userID uint) error
+	// SetMemberRole 由群主設定成員角色
+	SetMemberRole(ctx context.Context, groupID, operatorID, userID uint, role entities.GroupRole) error
 	GetGroupMembers(ctx context.Context, groupID uint) ([]uint, error)
 	IsGroupMember(ctx context.Context, groupID, userID uint) (bool, error)
 	SendGroupMessage(ctx context.Context, message *entities.Message) error
@@ -147,6 +151,44 @@ func (s *groupChatService) RemoveMember(ctx context.Context, groupID, userID uin
 	return s.groupRepo.RemoveMember(ctx, groupID, userID)
 }
 
+func (s *groupChatService) SetMemberRole(ctx context.Context, groupID, operatorID, userID uint, role entities.GroupRole) error {
+	if !role.IsValid() {
+		return appErrors.New(enum.ErrInvalidInput, "無效的成員角色")
+	}
+
+	group, err := s.groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrGroupNotFound, map[string]interface{}{
+			"roomId": groupID,
+		})
+	}
+	if group.OwnerId != operatorID {
+		return appErrors.New(enum.ErrAccessDenied, "只有群主可以設定成員角色")
+	}
+
+	isMember, err := s.groupRepo.IsMember(ctx, groupID, userID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": groupID,
+			"userId": userID,
+		})
+	}
+	if !isMember {
+		return appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": groupID,
+			"userId": userID,
+		})
+	}
+
+	if err := s.groupRepo.SetMemberRole(ctx, groupID, userID, role); err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": groupID,
+			"userId": userID,
+		})
+	}
+	return nil
+}
+
 func (s *groupChatService) GetGroupMembers(ctx context.Context, groupID uint) ([]uint, error) {
 	return s.groupRepo.GetMembers(ctx, groupID)
 }
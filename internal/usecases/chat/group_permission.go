@@ -0,0 +1,30 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+)
+
+// isGroupManager 判斷用戶是否為群主或群組管理員
+func isGroupManager(ctx context.Context, groupRepo repositories.GroupRepository, groupID, userID uint) (bool, error) {
+	group, err := groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return false, appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": groupID,
+		})
+	}
+	if group.OwnerId == userID {
+		return true, nil
+	}
+
+	role, err := groupRepo.GetMemberRole(ctx, groupID, userID)
+	if err != nil {
+		return false, appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": groupID,
+			"userId": userID,
+		})
+	}
+	return role == entities.GroupRoleAdmin, nil
+}
@@ -0,0 +1,720 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/domain/search"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/pkg/utils"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// importBatchSize 每次寫入資料庫的訊息數
+	importBatchSize = 500
+	// 群組名稱與描述的長度上限，與 entities.Group 的欄位大小相同
+	importGroupNameLength = 100
+	importGroupDescLength = 200
+	// importEmailLength 電子郵件的長度上限，與 entities.User 的欄位大小相同
+	importEmailLength = 128
+)
+
+// ImportUseCase 匯入外部聊天記錄的用例
+type ImportUseCase interface {
+	// Import 依序匯入用戶、聊天室與訊息，先前已匯入的資料會略過，有誤的資料記錄在報告中並略過。
+	// dryRun 為 true 時只檢查資料並產生報告，不寫入任何資料
+	Import(ctx context.Context, file *entities.ImportFile, dryRun bool) (*entities.ImportReport, error)
+}
+
+type importUseCase struct {
+	importRepo       repositories.ImportRepository
+	messageRepo      repositories.MessageRepository
+	messageCache     cache.MessageCacheRepository
+	groupRepo        repositories.GroupRepository
+	conversationRepo repositories.ConversationRepository
+	unreadCounter    cache.UnreadCounterCache
+	searchIndex      search.MessageIndex
+	sequencer        Sequencer
+}
+
+// NewImportUseCase 創建新的聊天記錄匯入用例
+func NewImportUseCase(
+	importRepo repositories.ImportRepository,
+	messageRepo repositories.MessageRepository,
+	messageCache cache.MessageCacheRepository,
+	groupRepo repositories.GroupRepository,
+	conversationRepo repositories.ConversationRepository,
+	unreadCounter cache.UnreadCounterCache,
+	searchIndex search.MessageIndex,
+	sequencer Sequencer,
+) ImportUseCase {
+	return &importUseCase{
+		importRepo:       importRepo,
+		messageRepo:      messageRepo,
+		messageCache:     messageCache,
+		groupRepo:        groupRepo,
+		conversationRepo: conversationRepo,
+		unreadCounter:    unreadCounter,
+		searchIndex:      searchIndex,
+		sequencer:        sequencer,
+	}
+}
+
+func (uc *importUseCase) Import(ctx context.Context, file *entities.ImportFile, dryRun bool) (*entities.ImportReport, error) {
+	if file == nil {
+		return nil, appErrors.New(enum.ErrInvalidInput, "匯入檔不能為空")
+	}
+	source := file.NormalizedSource()
+	if source == "" || len(source) > entities.MaxImportSourceLength {
+		return nil, appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message":   "來源名稱不能為空且不可過長",
+			"maxLength": entities.MaxImportSourceLength,
+		})
+	}
+
+	run := &importRun{
+		importUseCase: uc,
+		source:        source,
+		dryRun:        dryRun,
+		report:        &entities.ImportReport{Source: source, DryRun: dryRun, Issues: []entities.ImportIssue{}},
+		users:         make(map[string]uint),
+		rooms:         make(map[string]*importedRoom),
+		messages:      make(map[string]*importedMessage),
+		latest:        make(map[string]*entities.Message),
+		newest:        make(map[string]time.Time),
+	}
+	if err := run.importUsers(ctx, file.Users); err != nil {
+		return nil, err
+	}
+	if err := run.importRooms(ctx, file.Rooms); err != nil {
+		return nil, err
+	}
+	if err := run.importMessages(ctx, file.Messages); err != nil {
+		return nil, err
+	}
+	return run.report, nil
+}
+
+// importRun 單次匯入的狀態。試跑時不會取得新資料的本地ID，將建立的資料本地ID為 0
+type importRun struct {
+	*importUseCase
+	source string
+	dryRun bool
+	report *entities.ImportReport
+
+	users    map[string]uint             // 外部用戶ID → 本地用戶ID
+	rooms    map[string]*importedRoom    // 外部聊天室ID → 群組
+	messages map[string]*importedMessage // 外部訊息ID → 已匯入或本次匯入的訊息
+
+	pending    []*entities.Message
+	pendingIDs []string
+	latest     map[string]*entities.Message // 對話 → 本次匯入的最後一則訊息，用於更新會話列表
+	newest     map[string]time.Time         // 對話 → 目前最新訊息的發送時間，用於確認匯入的訊息不早於既有訊息
+}
+
+type importedRoom struct {
+	id           uint
+	members      map[string]bool // 匯入檔中列出的成員
+	localMembers map[uint]bool   // 群組中原有的成員
+}
+
+// canSend 判斷外部用戶是否為聊天室成員
+func (r *importedRoom) canSend(externalID string, userID uint) bool {
+	return r.members[externalID] || (userID != 0 && r.localMembers[userID])
+}
+
+type importedMessage struct {
+	id           uint
+	message      *entities.Message // 本次匯入建立的訊息，寫入前 ID 為 0
+	conversation string
+	threadRoot   string // 所屬討論串根訊息的外部ID，不是討論串回覆時為空
+}
+
+// localID 返回訊息的本地ID，尚未寫入時為 0
+func (m *importedMessage) localID() uint {
+	if m.message != nil {
+		return m.message.ID
+	}
+	return m.id
+}
+
+func (r *importRun) importUsers(ctx context.Context, users []entities.ImportUser) error {
+	valid := make([]entities.ImportUser, 0, len(users))
+	ids := make([]string, 0, len(users))
+	seen := make(map[string]bool, len(users))
+	for _, user := range users {
+		user.ID = strings.TrimSpace(user.ID)
+		user.Email = strings.ToLower(strings.TrimSpace(user.Email))
+		if reason := checkExternalID(user.ID, seen); reason != "" {
+			r.skip(&r.report.Users, entities.ImportKindUser, user.ID, reason)
+			continue
+		}
+		if len(user.Email) > importEmailLength {
+			r.skip(&r.report.Users, entities.ImportKindUser, user.ID, "電子郵件過長")
+			continue
+		}
+		valid = append(valid, user)
+		ids = append(ids, user.ID)
+	}
+
+	existing, err := r.importRepo.FindMappings(ctx, r.source, entities.ImportKindUser, ids)
+	if err != nil {
+		return r.dbError(err)
+	}
+	var emails []string
+	for _, user := range valid {
+		if _, ok := existing[user.ID]; !ok && user.Email != "" {
+			emails = append(emails, user.Email)
+		}
+	}
+	accounts, err := r.importRepo.FindUserIDsByEmail(ctx, emails)
+	if err != nil {
+		return r.dbError(err)
+	}
+
+	claimed := make(map[string]bool)
+	for _, user := range valid {
+		if id, ok := existing[user.ID]; ok {
+			r.users[user.ID] = id
+			r.report.Users.Existing++
+			continue
+		}
+
+		mapping := &entities.ImportMapping{Source: r.source, Kind: entities.ImportKindUser, ExternalID: user.ID}
+		if id, ok := accounts[user.Email]; ok && user.Email != "" {
+			mapping.LocalID = id
+			if !r.dryRun {
+				if err := r.importRepo.LinkUser(ctx, mapping); err != nil {
+					return r.dbError(err)
+				}
+			}
+			r.users[user.ID] = id
+			r.report.Users.Linked++
+			continue
+		}
+		if user.Email != "" {
+			if claimed[user.Email] {
+				r.skip(&r.report.Users, entities.ImportKindUser, user.ID, "電子郵件與其他用戶重複")
+				continue
+			}
+			claimed[user.Email] = true
+		}
+
+		account, err := newImportedUser(r.source, user)
+		if err != nil {
+			return appErrors.Wrap(err, enum.ErrInternalServer, "產生帳號密碼失敗")
+		}
+		if !r.dryRun {
+			if err := r.importRepo.CreateUser(ctx, account, mapping); err != nil {
+				return r.dbError(err)
+			}
+		}
+		r.users[user.ID] = account.ID
+		r.report.Users.Created++
+	}
+	return nil
+}
+
+func (r *importRun) importRooms(ctx context.Context, rooms []entities.ImportRoom) error {
+	valid := make([]entities.ImportRoom, 0, len(rooms))
+	ids := make([]string, 0, len(rooms))
+	seen := make(map[string]bool, len(rooms))
+	for _, room := range rooms {
+		room.ID = strings.TrimSpace(room.ID)
+		room.Owner = strings.TrimSpace(room.Owner)
+		if reason := checkExternalID(room.ID, seen); reason != "" {
+			r.skip(&r.report.Rooms, entities.ImportKindRoom, room.ID, reason)
+			continue
+		}
+		if _, ok := r.users[room.Owner]; !ok {
+			r.skip(&r.report.Rooms, entities.ImportKindRoom, room.ID, "群主不存在")
+			continue
+		}
+		valid = append(valid, room)
+		ids = append(ids, room.ID)
+	}
+
+	existing, err := r.importRepo.FindMappings(ctx, r.source, entities.ImportKindRoom, ids)
+	if err != nil {
+		return r.dbError(err)
+	}
+
+	for _, room := range valid {
+		members := r.roomMembers(room)
+		imported := &importedRoom{members: make(map[string]bool, len(members)), localMembers: map[uint]bool{}}
+		for _, member := range members {
+			imported.members[member.externalID] = true
+		}
+
+		if id, ok := existing[room.ID]; ok {
+			imported.id = id
+			if err := r.addMissingMembers(ctx, imported, members); err != nil {
+				return err
+			}
+			r.rooms[room.ID] = imported
+			r.report.Rooms.Existing++
+			continue
+		}
+
+		now := time.Now()
+		group := &entities.Group{
+			Name:      truncateRunes(strings.TrimSpace(room.Name), importGroupNameLength),
+			OwnerId:   r.users[room.Owner],
+			Desc:      truncateRunes(strings.TrimSpace(room.Description), importGroupDescLength),
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if group.Name == "" {
+			group.Name = truncateRunes(room.ID, importGroupNameLength)
+		}
+		groupMembers := make([]*entities.GroupMember, len(members))
+		for i, member := range members {
+			groupMembers[i] = &entities.GroupMember{UserID: member.userID, Role: member.role, CreatedAt: now}
+		}
+		if !r.dryRun {
+			mapping := &entities.ImportMapping{Source: r.source, Kind: entities.ImportKindRoom, ExternalID: room.ID}
+			if err := r.importRepo.CreateGroup(ctx, group, groupMembers, mapping); err != nil {
+				return r.dbError(err)
+			}
+		}
+		imported.id = group.ID
+		r.rooms[room.ID] = imported
+		r.report.Rooms.Created++
+		r.report.MembersAdded += len(groupMembers)
+	}
+	return nil
+}
+
+type importMember struct {
+	externalID string
+	userID     uint
+	role       entities.GroupRole
+}
+
+// roomMembers 整理聊天室成員：包含群主，略過不存在的用戶，管理員需同時為成員
+func (r *importRun) roomMembers(room entities.ImportRoom) []importMember {
+	admins := make(map[string]bool, len(room.Admins))
+	for _, admin := range room.Admins {
+		admins[strings.TrimSpace(admin)] = true
+	}
+
+	seen := make(map[string]bool, len(room.Members)+1)
+	members := make([]importMember, 0, len(room.Members)+1)
+	for _, externalID := range append([]string{room.Owner}, room.Members...) {
+		externalID = strings.TrimSpace(externalID)
+		if seen[externalID] {
+			continue
+		}
+		seen[externalID] = true
+		userID, ok := r.users[externalID]
+		if !ok {
+			r.report.AddIssue(entities.ImportKindRoom, room.ID, fmt.Sprintf("成員 %s 不存在，未加入群組", externalID))
+			continue
+		}
+		role := entities.GroupRoleMember
+		if admins[externalID] && externalID != room.Owner {
+			role = entities.GroupRoleAdmin
+		}
+		members = append(members, importMember{externalID: externalID, userID: userID, role: role})
+	}
+	return members
+}
+
+// addMissingMembers 將先前已匯入的群組補上新列出的成員，既有成員的角色不變
+func (r *importRun) addMissingMembers(ctx context.Context, room *importedRoom, members []importMember) error {
+	current, err := r.groupRepo.GetMembers(ctx, room.id)
+	if err != nil {
+		return r.dbError(err)
+	}
+	for _, userID := range current {
+		room.localMembers[userID] = true
+	}
+
+	var missing []*entities.GroupMember
+	for _, member := range members {
+		if member.userID != 0 && room.localMembers[member.userID] {
+			continue
+		}
+		missing = append(missing, &entities.GroupMember{GroupID: room.id, UserID: member.userID, Role: member.role, CreatedAt: time.Now()})
+	}
+	if r.dryRun {
+		r.report.MembersAdded += len(missing)
+		return nil
+	}
+	added, err := r.importRepo.AddMembers(ctx, missing)
+	if err != nil {
+		return r.dbError(err)
+	}
+	r.report.MembersAdded += added
+	return nil
+}
+
+func (r *importRun) importMessages(ctx context.Context, messages []entities.ImportMessage) error {
+	// 依原始發送時間排序，讓序號與引用的先後順序與原對話一致
+	order := make([]int, len(messages))
+	ids := make([]string, 0, len(messages))
+	for i := range messages {
+		order[i] = i
+		message := &messages[i]
+		for _, field := range []*string{&message.ID, &message.Room, &message.To, &message.From, &message.ReplyTo, &message.Thread} {
+			*field = strings.TrimSpace(*field)
+		}
+		if message.ID != "" {
+			ids = append(ids, message.ID)
+		}
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return messages[order[a]].SentAt.Before(messages[order[b]].SentAt)
+	})
+
+	existing, err := r.importRepo.FindMappings(ctx, r.source, entities.ImportKindMessage, ids)
+	if err != nil {
+		return r.dbError(err)
+	}
+
+	seen := make(map[string]bool, len(messages))
+	for _, i := range order {
+		if err := r.importMessage(ctx, messages[i], existing, seen); err != nil {
+			return err
+		}
+	}
+	if err := r.flush(ctx); err != nil {
+		return err
+	}
+	return r.updateConversations(ctx)
+}
+
+func (r *importRun) importMessage(ctx context.Context, external entities.ImportMessage, existing map[string]uint, seen map[string]bool) error {
+	if reason := checkExternalID(external.ID, seen); reason != "" {
+		r.skip(&r.report.Messages, entities.ImportKindMessage, external.ID, reason)
+		return nil
+	}
+	message, conversation, reason := r.newMessage(external)
+	if reason != "" {
+		r.skip(&r.report.Messages, entities.ImportKindMessage, external.ID, reason)
+		return nil
+	}
+
+	var replyTo, root *importedMessage
+	if external.ReplyTo != "" {
+		if replyTo, reason = r.reference(external.ReplyTo, conversation); reason != "" {
+			r.skip(&r.report.Messages, entities.ImportKindMessage, external.ID, "引用"+reason)
+			return nil
+		}
+	}
+	imported := &importedMessage{conversation: conversation}
+	if external.Thread != "" {
+		if root, reason = r.reference(external.Thread, conversation); reason != "" {
+			r.skip(&r.report.Messages, entities.ImportKindMessage, external.ID, "討論串"+reason)
+			return nil
+		}
+		// 回覆討論串中的回覆時歸入同一個討論串
+		imported.threadRoot = external.Thread
+		if root.threadRoot != "" {
+			imported.threadRoot = root.threadRoot
+			root = r.messages[root.threadRoot]
+		}
+	}
+
+	if id, ok := existing[external.ID]; ok {
+		imported.id = id
+		r.messages[external.ID] = imported
+		r.report.Messages.Existing++
+		return nil
+	}
+	if reason, err := r.checkOrder(ctx, message, conversation); err != nil {
+		return err
+	} else if reason != "" {
+		r.skip(&r.report.Messages, entities.ImportKindMessage, external.ID, reason)
+		return nil
+	}
+	if r.dryRun {
+		r.messages[external.ID] = imported
+		r.report.Messages.Created++
+		return nil
+	}
+
+	// 被引用的訊息還在同一批次中尚未寫入時先寫入，才能取得本地ID
+	for _, ref := range []*importedMessage{replyTo, root} {
+		if ref != nil && ref.localID() == 0 {
+			if err := r.flush(ctx); err != nil {
+				return err
+			}
+		}
+	}
+	if replyTo != nil {
+		id := replyTo.localID()
+		message.ReplyToID = &id
+	}
+	if root != nil {
+		id := root.localID()
+		message.ThreadRootID = &id
+	}
+	if err := r.sequencer.Assign(ctx, message); err != nil {
+		return r.dbError(err)
+	}
+
+	imported.message = message
+	r.messages[external.ID] = imported
+	r.pending = append(r.pending, message)
+	r.pendingIDs = append(r.pendingIDs, external.ID)
+	if len(r.pending) >= importBatchSize {
+		return r.flush(ctx)
+	}
+	return nil
+}
+
+// checkOrder 確認訊息不早於對話中已有的最新訊息，否則返回略過的原因。
+// 序號依寫入順序配發，早於既有訊息的匯入訊息會取得比較新訊息更大的序號，
+// 因此已有較新訊息（例如上線後已有往來）的對話中，只能匯入在這些訊息之後發送的訊息
+func (r *importRun) checkOrder(ctx context.Context, message *entities.Message, conversation string) (string, error) {
+	newest, ok := r.newest[conversation]
+	// 試跑時新建立的用戶與群組沒有本地ID，對話中也不會有既有訊息
+	if !ok && message.UserId != 0 && message.TargetId != 0 {
+		latest, err := r.importRepo.FindLatestMessageTime(ctx, message)
+		if err != nil {
+			return "", r.dbError(err)
+		}
+		newest = latest
+	}
+	if message.CreatedAt.Before(newest) {
+		r.newest[conversation] = newest
+		return "對話中已有較新的訊息，匯入會打亂訊息順序", nil
+	}
+	r.newest[conversation] = message.CreatedAt
+	return "", nil
+}
+
+// newMessage 將外部訊息轉為本地訊息並返回所屬對話，資料有誤時返回略過的原因
+func (r *importRun) newMessage(external entities.ImportMessage) (*entities.Message, string, string) {
+	from, ok := r.users[external.From]
+	if !ok {
+		return nil, "", "發送者不存在"
+	}
+
+	message := &entities.Message{UserId: from}
+	var conversation string
+	switch {
+	case external.Room != "" && external.To != "":
+		return nil, "", "room 與 to 只能擇一"
+	case external.Room != "":
+		room, ok := r.rooms[external.Room]
+		if !ok {
+			return nil, "", "聊天室不存在"
+		}
+		if !room.canSend(external.From, from) {
+			return nil, "", "發送者不是聊天室成員"
+		}
+		message.Type = entities.MessageTypeGroup
+		message.RoomID = room.id
+		message.TargetId = room.id
+		conversation = "room:" + external.Room
+	case external.To != "":
+		to, ok := r.users[external.To]
+		if !ok {
+			return nil, "", "接收者不存在"
+		}
+		if external.To == external.From {
+			return nil, "", "不能發送訊息給自己"
+		}
+		message.Type = entities.MessageTypePrivate
+		message.TargetId = to
+		pair := []string{external.From, external.To}
+		sort.Strings(pair)
+		conversation = "private:" + pair[0] + "\x00" + pair[1]
+	default:
+		return nil, "", "缺少 room 或 to"
+	}
+
+	if external.SentAt.IsZero() {
+		return nil, "", "缺少發送時間"
+	}
+	if strings.TrimSpace(external.Text) == "" {
+		return nil, "", "訊息內容不能為空"
+	}
+	if len(external.Text) > entities.MaxImportTextLength {
+		return nil, "", "訊息內容過長"
+	}
+	switch external.Format {
+	case "", entities.ImportFormatText:
+		message.Media = entities.MediaTypeText
+	case entities.ImportFormatMarkdown:
+		message.Media = entities.MediaTypeRichText
+	default:
+		return nil, "", "不支援的訊息格式"
+	}
+
+	message.Content = external.Text
+	message.CreatedAt = external.SentAt
+	message.UpdatedAt = external.SentAt
+	if external.EditedAt != nil && external.EditedAt.After(external.SentAt) {
+		editedAt := *external.EditedAt
+		message.EditedAt = &editedAt
+		message.UpdatedAt = editedAt
+	}
+	if err := renderRichText(message); err != nil {
+		return nil, "", "無效的 Markdown 內容"
+	}
+	return message, conversation, ""
+}
+
+// reference 查詢被引用的訊息，需在此訊息之前發送且屬於同一對話
+func (r *importRun) reference(externalID, conversation string) (*importedMessage, string) {
+	ref, ok := r.messages[externalID]
+	if !ok {
+		return nil, "的訊息不存在或晚於此訊息"
+	}
+	if ref.conversation != conversation {
+		return nil, "的訊息不屬於此對話"
+	}
+	return ref, ""
+}
+
+// flush 寫入待寫入的訊息，並更新訊息快取、討論串統計與搜尋索引
+func (r *importRun) flush(ctx context.Context) error {
+	if len(r.pending) == 0 {
+		return nil
+	}
+	if err := r.importRepo.InsertMessages(ctx, r.source, r.pending, r.pendingIDs); err != nil {
+		return r.dbError(err)
+	}
+
+	// 匯入的訊息無法依序追加到快取列表，刪除受影響對話的快取，下次查詢時由資料庫重建
+	invalidated := make(map[string]bool)
+	for i, message := range r.pending {
+		if conversation := r.messages[r.pendingIDs[i]].conversation; !invalidated[conversation] {
+			invalidated[conversation] = true
+			if err := r.messageCache.InvalidateMessages(ctx, message); err != nil {
+				fmt.Printf("清除訊息快取失敗: conversation=%s, err=%v\n", conversation, err)
+			}
+		}
+	}
+
+	for i, message := range r.pending {
+		if message.IsThreadReply() {
+			if _, err := r.messageRepo.AddThreadReply(ctx, *message.ThreadRootID, message.UserId, message.CreatedAt); err != nil {
+				fmt.Printf("更新討論串失敗: rootID=%d, err=%v\n", *message.ThreadRootID, err)
+			}
+		} else {
+			r.latest[r.messages[r.pendingIDs[i]].conversation] = message
+		}
+		if err := r.searchIndex.Index(ctx, message); err != nil {
+			fmt.Printf("更新搜尋索引失敗: messageID=%d, err=%v\n", message.ID, err)
+		}
+	}
+	r.report.Messages.Created += len(r.pending)
+	r.pending, r.pendingIDs = nil, nil
+	return nil
+}
+
+// updateConversations 為每個對話中尚未有會話的參與者建立會話，以最後匯入的訊息為預覽並視為已讀。
+// 已存在的會話不變更：匯入的訊息ID比既有訊息新，以其更新預覽或已讀位置會蓋過真正較新的訊息
+func (r *importRun) updateConversations(ctx context.Context) error {
+	keys := make([]string, 0, len(r.latest))
+	for key := range r.latest {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		message := r.latest[key]
+		participants := []uint{message.UserId, message.TargetId}
+		if message.IsGroupConversation() {
+			members, err := r.groupRepo.GetMembers(ctx, message.RoomID)
+			if err != nil {
+				return r.dbError(err)
+			}
+			participants = append([]uint{message.UserId}, members...)
+		}
+		participants = uniqueUints(participants)
+		if err := r.conversationRepo.RecordImported(ctx, message, participants); err != nil {
+			return r.dbError(err)
+		}
+
+		// 匯入的訊息會被計入之後重新計算的未讀數，移除計數器讓下次讀取時從資料庫載入
+		for _, userID := range participants {
+			convType, targetID := entities.ConversationOf(message, userID)
+			if err := r.unreadCounter.InvalidateUnread(ctx, userID, convType, targetID); err != nil {
+				fmt.Printf("清除未讀計數器失敗: userID=%d, err=%v\n", userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *importRun) skip(counts *entities.ImportCounts, kind entities.ImportKind, externalID, reason string) {
+	counts.Skipped++
+	r.report.AddIssue(kind, externalID, reason)
+}
+
+func (r *importRun) dbError(err error) error {
+	return appErrors.NewDBError(err, map[string]interface{}{
+		"source": r.source,
+		"dryRun": r.dryRun,
+	})
+}
+
+// checkExternalID 檢查外部ID是否有效且未重複，有誤時返回原因
+func checkExternalID(id string, seen map[string]bool) string {
+	switch {
+	case id == "":
+		return "缺少ID"
+	case len(id) > entities.MaxImportExternalIDLength:
+		return "ID過長"
+	case seen[id]:
+		return "ID重複"
+	}
+	seen[id] = true
+	return ""
+}
+
+// newImportedUser 建立匯入的帳號。密碼為隨機值，用戶需重設密碼後才能登入；
+// 沒有電子郵件時以來源與外部ID產生固定的佔位信箱
+func newImportedUser(source string, external entities.ImportUser) (*entities.User, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	salt := hex.EncodeToString(secret[:8])
+	digest := sha256.Sum256([]byte(source + "\x00" + external.ID))
+
+	now := time.Now()
+	user := &entities.User{
+		Name:          strings.TrimSpace(external.Name),
+		Password:      utils.MakePassword(hex.EncodeToString(secret[8:]), salt),
+		Salt:          salt,
+		Phone:         strings.TrimSpace(external.Phone),
+		Email:         external.Email,
+		Avatar:        strings.TrimSpace(external.Avatar),
+		Identity:      fmt.Sprintf("import-%s", hex.EncodeToString(digest[:12])),
+		LoginTime:     now,
+		HeartbeatTime: now,
+		LogoutTime:    now,
+		IsLogout:      true,
+	}
+	if user.Name == "" {
+		user.Name = external.ID
+	}
+	if user.Email == "" {
+		user.Email = fmt.Sprintf("import_%s@example.com", hex.EncodeToString(digest[:8]))
+	}
+	return user, nil
+}
+
+// truncateRunes 將字串截斷為最多 n 個字元
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,160 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/linkpreview"
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// linkPreviewTimeout 一則訊息產生連結預覽的時間上限
+	linkPreviewTimeout = 20 * time.Second
+	// maxConcurrentPreviews 同時讀取的網址數上限，避免大量含網址的訊息佔用連線
+	maxConcurrentPreviews = 16
+)
+
+// LinkPreviewEvent 訊息產生連結預覽後推送給對話參與者的內容
+type LinkPreviewEvent struct {
+	MessageID    uint                   `json:"message_id"`
+	RoomID       uint                   `json:"room_id,omitempty"`
+	LinkPreviews []entities.LinkPreview `json:"link_previews"`
+}
+
+// LinkPreviewer 為網址產生連結預覽，同一網址的結果會被快取
+type LinkPreviewer interface {
+	// Preview 依 urls 的順序返回可顯示的預覽，讀取失敗或沒有內容的網址會被略過
+	Preview(ctx context.Context, urls []string) []entities.LinkPreview
+}
+
+type linkPreviewer struct {
+	fetcher linkpreview.Fetcher
+	cache   cache.LinkPreviewCache
+	slots   chan struct{}
+}
+
+// NewLinkPreviewer 創建新的連結預覽產生器
+func NewLinkPreviewer(fetcher linkpreview.Fetcher, previewCache cache.LinkPreviewCache) LinkPreviewer {
+	return &linkPreviewer{
+		fetcher: fetcher,
+		cache:   previewCache,
+		slots:   make(chan struct{}, maxConcurrentPreviews),
+	}
+}
+
+func (p *linkPreviewer) Preview(ctx context.Context, urls []string) []entities.LinkPreview {
+	results := make([]*entities.LinkPreview, len(urls))
+	var wg sync.WaitGroup
+	for i, url := range urls {
+		wg.Add(1)
+		go func(i int, url string) {
+			defer wg.Done()
+			results[i] = p.preview(ctx, url)
+		}(i, url)
+	}
+	wg.Wait()
+
+	var previews []entities.LinkPreview
+	for _, preview := range results {
+		if preview != nil {
+			previews = append(previews, *preview)
+		}
+	}
+	return previews
+}
+
+// preview 先查詢快取，未快取時讀取網址並快取結果，讀取失敗或沒有內容時返回 nil
+func (p *linkPreviewer) preview(ctx context.Context, url string) *entities.LinkPreview {
+	preview, found, err := p.cache.GetLinkPreview(ctx, url)
+	if err != nil {
+		fmt.Printf("讀取連結預覽快取失敗: url=%s, err=%v\n", url, err)
+	}
+	if found {
+		return preview
+	}
+
+	select {
+	case p.slots <- struct{}{}:
+		defer func() { <-p.slots }()
+	case <-ctx.Done():
+		return nil
+	}
+
+	preview, err = p.fetcher.Fetch(ctx, url)
+	if err != nil {
+		fmt.Printf("讀取連結預覽失敗: url=%s, err=%v\n", url, err)
+		if ctx.Err() != nil {
+			// 逾時或取消不代表網址無法讀取，不快取失敗結果
+			return nil
+		}
+		preview = nil
+	} else if preview.IsEmpty() {
+		preview = nil
+	}
+
+	if err := p.cache.StoreLinkPreview(ctx, url, preview); err != nil {
+		fmt.Printf("快取連結預覽失敗: url=%s, err=%v\n", url, err)
+	}
+	return preview
+}
+
+// unfurlLinks 在背景為訊息中的網址產生連結預覽，完成後寫回訊息並通知對話參與者。
+// 轉發的副本沿用原訊息的預覽，不重新產生
+func (uc *messageUseCase) unfurlLinks(message *entities.Message) {
+	if !message.IsTextual() || message.IsSystem() || message.ForwardOrigin() != nil {
+		return
+	}
+	urls := entities.ExtractURLs(message.DisplayText())
+	if len(urls) == 0 {
+		return
+	}
+
+	// 呼叫者仍會使用原訊息，背景工作只修改副本
+	snapshot := *message
+	snapshot.Metadata = append(entities.JSON(nil), message.Metadata...)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), linkPreviewTimeout)
+		defer cancel()
+
+		previews := uc.linkPreviewer.Preview(ctx, urls)
+		if len(previews) == 0 {
+			return
+		}
+		uc.attachLinkPreviews(ctx, &snapshot, previews)
+	}()
+}
+
+// attachLinkPreviews 將預覽寫入訊息的附加資料，訊息在產生預覽期間被編輯或撤回時放棄寫入
+func (uc *messageUseCase) attachLinkPreviews(ctx context.Context, message *entities.Message, previews []entities.LinkPreview) {
+	if err := message.SetLinkPreviews(previews); err != nil {
+		fmt.Printf("寫入連結預覽失敗: messageID=%d, err=%v\n", message.ID, err)
+		return
+	}
+	updated, err := uc.messageRepo.UpdateMetadata(ctx, message)
+	if err != nil {
+		fmt.Printf("儲存連結預覽失敗: messageID=%d, err=%v\n", message.ID, err)
+		return
+	}
+	if !updated {
+		return
+	}
+
+	if err := uc.messageCacheRepo.UpdateMessage(ctx, message); err != nil {
+		// 快取失敗不影響主要功能，只記錄錯誤
+		fmt.Printf("更新訊息快取失敗: %v\n", err)
+	}
+
+	recipients, err := uc.participants(ctx, message, message.UserId)
+	if err != nil {
+		fmt.Printf("獲取對話參與者失敗，略過連結預覽通知: %v\n", err)
+		return
+	}
+	uc.publish(ctx, &Event{Type: EventMessagePreview, Data: &LinkPreviewEvent{
+		MessageID:    message.ID,
+		RoomID:       message.RoomID,
+		LinkPreviews: previews,
+	}}, recipients)
+}
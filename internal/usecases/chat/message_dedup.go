@@ -0,0 +1,63 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"context"
+	"errors"
+	"fmt"
+)
+
+// createMessageOnce 依 client_msg_id 去重後配發對話序號並寫入訊息。
+// 先查 Redis 的去重記錄，未命中時寫入資料庫，並以唯一索引處理併發重試；
+// 若判定為重複請求，會以原先儲存的訊息覆寫 message，並返回 duplicate = true。
+func createMessageOnce(
+	ctx context.Context,
+	messageRepo repositories.MessageRepository,
+	messageCache cache.MessageCacheRepository,
+	sequencer Sequencer,
+	message *entities.Message,
+) (duplicate bool, err error) {
+	if !message.HasClientMsgID() {
+		return false, createSequenced(ctx, messageRepo, sequencer, message)
+	}
+	clientMsgID := *message.ClientMsgID
+
+	// 1. 先查 Redis，命中則直接返回原訊息
+	if messageCache != nil {
+		existingID, err := messageCache.GetClientMessageID(ctx, message.UserId, clientMsgID)
+		if err != nil {
+			// 快取失敗不影響主要功能，交由唯一索引兜底
+			fmt.Printf("查詢訊息去重記錄失敗: %v\n", err)
+		} else if existingID != 0 {
+			existing, err := messageRepo.FindByID(ctx, existingID)
+			if err == nil {
+				*message = *existing
+				return true, nil
+			}
+		}
+	}
+
+	// 2. 寫入資料庫，唯一索引衝突代表同一請求已被處理，此時配發的序號會被跳過
+	if err := createSequenced(ctx, messageRepo, sequencer, message); err != nil {
+		if !errors.Is(err, repositories.ErrDuplicateMessage) {
+			return false, err
+		}
+		existing, findErr := messageRepo.FindByClientMsgID(ctx, message.UserId, clientMsgID)
+		if findErr != nil {
+			return false, findErr
+		}
+		*message = *existing
+		duplicate = true
+	}
+
+	// 3. 記錄去重資訊
+	if messageCache != nil {
+		if err := messageCache.StoreClientMessageID(ctx, message.UserId, clientMsgID, message.ID); err != nil {
+			fmt.Printf("儲存訊息去重記錄失敗: %v\n", err)
+		}
+	}
+
+	return duplicate, nil
+}
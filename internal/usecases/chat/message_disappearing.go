@@ -0,0 +1,256 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	// purgeBatchSize 每次刪除的過期訊息數量上限
+	purgeBatchSize = 200
+	// defaultPurgeInterval 預設檢查過期訊息的間隔
+	defaultPurgeInterval = 30 * time.Second
+)
+
+// ExpiredEvent 訊息自動消失後推送給對話參與者的內容
+type ExpiredEvent struct {
+	MessageIDs []uint `json:"message_ids"`
+	RoomID     uint   `json:"room_id,omitempty"`
+}
+
+func (uc *messageUseCase) SetDisappearing(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, ttl time.Duration) error {
+	if !entities.IsValidDisappearingTTL(ttl) {
+		return appErrors.New(enum.ErrInvalidInput, "不支援的自動消失時間")
+	}
+	if convType == entities.ConversationTypePrivate && targetID == userID {
+		return appErrors.New(enum.ErrInvalidInput, "無法為與自己的對話設定自動消失")
+	}
+	scope, err := uc.conversationScope(ctx, userID, convType, targetID)
+	if err != nil {
+		return err
+	}
+
+	// 私聊雙方皆可設定，群組限群主與管理員
+	about := &entities.Message{UserId: userID, TargetId: targetID, Type: entities.MessageTypePrivate}
+	recipients := []uint{userID, targetID}
+	if convType == entities.ConversationTypeGroup {
+		isManager, err := isGroupManager(ctx, uc.groupRepo, targetID, userID)
+		if err != nil {
+			return err
+		}
+		if !isManager {
+			return appErrors.New(enum.ErrAccessDenied, "只有群主或管理員可以設定訊息自動消失")
+		}
+		about = &entities.Message{UserId: userID, RoomID: targetID, Type: entities.MessageTypeGroup}
+		if recipients, err = uc.groupRepo.GetMembers(ctx, targetID); err != nil {
+			return appErrors.NewDBError(err, map[string]interface{}{
+				"roomId": targetID,
+			})
+		}
+	}
+
+	current, err := uc.conversationRepo.FindDisappearingTTL(ctx, scope)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"scope": scope,
+		})
+	}
+	if current == ttl {
+		return nil
+	}
+
+	setting := &entities.DisappearingSetting{
+		Scope:     scope,
+		TTL:       int(ttl / time.Second),
+		UpdatedBy: userID,
+		UpdatedAt: time.Now(),
+	}
+	if err := uc.conversationRepo.SaveDisappearingSetting(ctx, setting); err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"scope": scope,
+		})
+	}
+
+	content := "已關閉訊息自動消失"
+	if ttl > 0 {
+		content = "已將訊息自動消失設為 " + entities.DisappearingLabel(ttl)
+	}
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"disappearing": map[string]interface{}{"ttl": setting.TTL},
+	})
+	uc.sendSystemNotice(ctx, about, userID, content, metadata, recipients)
+	return nil
+}
+
+func (uc *messageUseCase) GetDisappearing(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (time.Duration, error) {
+	scope, err := uc.conversationScope(ctx, userID, convType, targetID)
+	if err != nil {
+		return 0, err
+	}
+
+	ttl, err := uc.conversationRepo.FindDisappearingTTL(ctx, scope)
+	if err != nil {
+		return 0, appErrors.NewDBError(err, map[string]interface{}{
+			"scope": scope,
+		})
+	}
+	return ttl, nil
+}
+
+func (uc *messageUseCase) PurgeExpiredMessages(ctx context.Context, now time.Time) (int, error) {
+	messages, err := uc.messageRepo.FindExpired(ctx, now, purgeBatchSize)
+	if err != nil {
+		return 0, appErrors.NewDBError(err, nil)
+	}
+	if len(messages) == 0 {
+		return 0, nil
+	}
+
+	ids := make([]uint, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	if err := uc.messageRepo.DeleteMessages(ctx, ids); err != nil {
+		return 0, appErrors.NewDBError(err, map[string]interface{}{
+			"messageIds": ids,
+		})
+	}
+
+	// 資料庫已刪除，以下清理失敗只記錄錯誤，不影響下一批
+	if err := uc.messageCacheRepo.RemoveMessages(ctx, messages); err != nil {
+		fmt.Printf("從快取移除過期訊息失敗: %v\n", err)
+	}
+	for _, id := range ids {
+		if err := uc.searchIndex.Remove(ctx, id); err != nil {
+			fmt.Printf("從搜尋索引移除過期訊息失敗: messageID=%d, err=%v\n", id, err)
+		}
+	}
+	if err := uc.conversationRepo.ClearPreview(ctx, ids); err != nil {
+		fmt.Printf("清除過期訊息的會話預覽失敗: %v\n", err)
+	}
+
+	// 依對話分組通知參與者移除訊息
+	var scopes []string
+	byScope := make(map[string][]*entities.Message)
+	for _, message := range messages {
+		scope := entities.PinScopeOf(message)
+		if _, ok := byScope[scope]; !ok {
+			scopes = append(scopes, scope)
+		}
+		byScope[scope] = append(byScope[scope], message)
+	}
+	for _, scope := range scopes {
+		uc.expired(ctx, byScope[scope])
+	}
+
+	return len(messages), nil
+}
+
+// expired 通知同一對話的參與者訊息已自動消失，並移除尚未送達的相關離線事件
+func (uc *messageUseCase) expired(ctx context.Context, messages []*entities.Message) {
+	first := messages[0]
+	uc.invalidatePins(ctx, first)
+
+	recipients := []uint{first.UserId, first.TargetId}
+	if first.IsGroupConversation() {
+		members, err := uc.groupRepo.GetMembers(ctx, first.RoomID)
+		if err != nil {
+			fmt.Printf("獲取群組成員失敗，略過過期訊息通知: %v\n", err)
+			return
+		}
+		recipients = members
+	}
+
+	ids := make([]uint, len(messages))
+	for i, message := range messages {
+		ids[i] = message.ID
+	}
+	if err := uc.publisher.DiscardPending(ctx, recipients, ids...); err != nil {
+		fmt.Printf("移除過期訊息的離線事件失敗: %v\n", err)
+	}
+	uc.publish(ctx, &Event{Type: EventMessageExpired, Data: &ExpiredEvent{
+		MessageIDs: ids,
+		RoomID:     first.RoomID,
+	}}, recipients)
+}
+
+// applyDisappearing 依對話的自動消失設定為新訊息標記到期時間
+func (uc *messageUseCase) applyDisappearing(ctx context.Context, message *entities.Message) error {
+	ttl, err := uc.conversationRepo.FindDisappearingTTL(ctx, entities.PinScopeOf(message))
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"userId": message.UserId,
+		})
+	}
+	if ttl > 0 {
+		expiresAt := message.CreatedAt.Add(ttl)
+		message.ExpiresAt = &expiresAt
+	}
+	return nil
+}
+
+// conversationScope 檢查用戶可存取對話並返回對話範圍，群組需為成員
+func (uc *messageUseCase) conversationScope(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (string, error) {
+	if err := validateConversation(userID, convType, targetID); err != nil {
+		return "", err
+	}
+	if convType != entities.ConversationTypeGroup {
+		return entities.PrivatePinScope(userID, targetID), nil
+	}
+
+	isMember, err := uc.groupRepo.IsMember(ctx, targetID, userID)
+	if err != nil {
+		return "", appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": targetID,
+			"userId": userID,
+		})
+	}
+	if !isMember {
+		return "", appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": targetID,
+			"userId": userID,
+		})
+	}
+	return entities.GroupPinScope(targetID), nil
+}
+
+// DisappearingPurger 定期刪除已到自動消失時間訊息的背景工作，多個副本同時執行時重複刪除不影響結果
+type DisappearingPurger struct {
+	useCase  MessageUseCase
+	interval time.Duration
+}
+
+// NewDisappearingPurger 創建新的過期訊息清理器，interval 為 0 時使用預設間隔
+func NewDisappearingPurger(useCase MessageUseCase, interval time.Duration) *DisappearingPurger {
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+	return &DisappearingPurger{useCase: useCase, interval: interval}
+}
+
+// Run 持續清理過期訊息直到 ctx 結束，一批處理滿時立即處理下一批
+func (p *DisappearingPurger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		count, err := p.useCase.PurgeExpiredMessages(ctx, time.Now())
+		if err != nil {
+			fmt.Printf("清理過期訊息失敗: %v\n", err)
+		}
+		if err == nil && count >= purgeBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,197 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"sort"
+)
+
+const (
+	// MaxForwardMessages 單次最多轉發的訊息數
+	MaxForwardMessages = 50
+	// MaxForwardTargets 單次最多轉發到的對話數
+	MaxForwardTargets = 20
+)
+
+// ForwardTarget 轉發的目的對話
+type ForwardTarget struct {
+	Type     entities.ConversationType `json:"type"`     // 1-私聊，2-群聊
+	TargetID uint                      `json:"targetId"` // 私聊為對方用戶ID，群聊為群組ID
+}
+
+func (uc *messageUseCase) ForwardMessages(ctx context.Context, userID uint, messageIDs []uint, targets []ForwardTarget) ([]*entities.Message, error) {
+	if userID == 0 {
+		return nil, appErrors.New(enum.ErrInvalidInput, "用戶ID不能為空")
+	}
+	messageIDs = uniqueUints(messageIDs)
+	if len(messageIDs) == 0 || len(messageIDs) > MaxForwardMessages {
+		return nil, appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"reason": "轉發的訊息數量無效",
+			"max":    MaxForwardMessages,
+		})
+	}
+	targets = uniqueForwardTargets(targets)
+	if len(targets) == 0 || len(targets) > MaxForwardTargets {
+		return nil, appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"reason": "轉發的對話數量無效",
+			"max":    MaxForwardTargets,
+		})
+	}
+
+	// 1. 檢查來源訊息與目的對話的權限，全部通過後才開始發送，避免只轉發了一部分
+	sources, err := uc.forwardSources(ctx, userID, messageIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, target := range targets {
+		if err := uc.checkForwardTarget(ctx, userID, target); err != nil {
+			return nil, err
+		}
+	}
+
+	// 2. 依原始順序建立副本，經由一般的發送流程寫入、快取並推送
+	forwarded := make([]*entities.Message, 0, len(sources)*len(targets))
+	for _, target := range targets {
+		for _, source := range sources {
+			message, err := source.ForwardCopy(userID)
+			if err != nil {
+				return nil, appErrors.New(enum.ErrMessageInvalid, map[string]interface{}{
+					"messageId": source.ID,
+				})
+			}
+
+			if target.Type == entities.ConversationTypeGroup {
+				message.RoomID = target.TargetID
+				err = uc.SendGroupMessage(ctx, message)
+			} else {
+				message.TargetId = target.TargetID
+				err = uc.SendPrivateMessage(ctx, message)
+			}
+			if err != nil {
+				return nil, err
+			}
+			forwarded = append(forwarded, message)
+		}
+	}
+
+	return forwarded, nil
+}
+
+// forwardSources 載入要轉發的訊息並依發送順序排列，用戶必須能看到每則訊息，
+// 已撤回的訊息、系統通知與投票不能轉發
+func (uc *messageUseCase) forwardSources(ctx context.Context, userID uint, messageIDs []uint) ([]*entities.Message, error) {
+	sources, err := uc.messageRepo.FindByIDs(ctx, messageIDs)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageIds": messageIDs,
+		})
+	}
+	if len(sources) != len(messageIDs) {
+		found := make(map[uint]bool, len(sources))
+		for _, source := range sources {
+			found[source.ID] = true
+		}
+		for _, id := range messageIDs {
+			if !found[id] {
+				return nil, appErrors.New(enum.ErrMessageNotFound, map[string]interface{}{
+					"messageId": id,
+				})
+			}
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].ID < sources[j].ID })
+
+	memberOf := make(map[uint]bool)
+	for _, source := range sources {
+		if source.IsRecalled() || source.IsSystem() || source.Media == entities.MediaTypePoll {
+			return nil, appErrors.New(enum.ErrMessageInvalid, map[string]interface{}{
+				"messageId": source.ID,
+				"reason":    "已撤回的訊息、系統通知與投票不能轉發",
+			})
+		}
+
+		if !source.IsGroupConversation() {
+			if source.UserId != userID && source.TargetId != userID {
+				return nil, appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+					"messageId": source.ID,
+					"userId":    userID,
+				})
+			}
+			continue
+		}
+
+		isMember, checked := memberOf[source.RoomID]
+		if !checked {
+			isMember, err = uc.groupRepo.IsMember(ctx, source.RoomID, userID)
+			if err != nil {
+				return nil, appErrors.NewDBError(err, map[string]interface{}{
+					"roomId": source.RoomID,
+					"userId": userID,
+				})
+			}
+			memberOf[source.RoomID] = isMember
+		}
+		if !isMember {
+			return nil, appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+				"messageId": source.ID,
+				"userId":    userID,
+			})
+		}
+	}
+	return sources, nil
+}
+
+// checkForwardTarget 檢查用戶能否發送到目的對話，群組限成員
+func (uc *messageUseCase) checkForwardTarget(ctx context.Context, userID uint, target ForwardTarget) error {
+	if err := validateConversation(userID, target.Type, target.TargetID); err != nil {
+		return err
+	}
+	if target.Type != entities.ConversationTypeGroup {
+		return nil
+	}
+
+	isMember, err := uc.groupRepo.IsMember(ctx, target.TargetID, userID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": target.TargetID,
+			"userId": userID,
+		})
+	}
+	if !isMember {
+		return appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": target.TargetID,
+			"userId": userID,
+		})
+	}
+	return nil
+}
+
+// uniqueUints 移除重複與為 0 的ID，保留第一次出現的順序
+func uniqueUints(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	result := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if id == 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
+
+// uniqueForwardTargets 移除重複的目的對話，保留第一次出現的順序
+func uniqueForwardTargets(targets []ForwardTarget) []ForwardTarget {
+	seen := make(map[ForwardTarget]bool, len(targets))
+	result := make([]ForwardTarget, 0, len(targets))
+	for _, target := range targets {
+		if seen[target] {
+			continue
+		}
+		seen[target] = true
+		result = append(result, target)
+	}
+	return result
+}
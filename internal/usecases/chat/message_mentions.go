@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+)
+
+// MentionEvent 用戶在群組訊息中被提及時推送的內容
+type MentionEvent struct {
+	MessageID uint   `json:"message_id"`
+	RoomID    uint   `json:"room_id"`
+	SenderID  uint   `json:"sender_id"`
+	All       bool   `json:"all,omitempty"` // 是否因 @all 被提及
+	Preview   string `json:"preview"`
+}
+
+// resolveMentions 解析群組訊息內容中的提及並寫入附加資料。
+// 非群組成員與發送者自己的提及會被忽略，@all 僅限群主與管理員使用。
+// 轉發的副本不會提及目的群組的成員
+func (uc *messageUseCase) resolveMentions(ctx context.Context, message *entities.Message) error {
+	if message.ForwardOrigin() != nil {
+		return nil
+	}
+
+	parsed := entities.ParseMentions(message.Content)
+	mentions := make([]entities.Mention, 0, len(parsed))
+
+	for _, mention := range parsed {
+		if mention.All {
+			isManager, err := isGroupManager(ctx, uc.groupRepo, message.RoomID, message.UserId)
+			if err != nil {
+				return err
+			}
+			if !isManager {
+				return appErrors.New(enum.ErrAccessDenied, "只有群主或管理員可以提及全體成員")
+			}
+			mentions = append(mentions, mention)
+			continue
+		}
+
+		if mention.UserID == message.UserId {
+			continue
+		}
+		isMember, err := uc.groupRepo.IsMember(ctx, message.RoomID, mention.UserID)
+		if err != nil {
+			return appErrors.NewDBError(err, map[string]interface{}{
+				"roomId": message.RoomID,
+				"userId": mention.UserID,
+			})
+		}
+		if isMember {
+			mentions = append(mentions, mention)
+		}
+	}
+
+	if err := message.SetMentions(mentions); err != nil {
+		return appErrors.New(enum.ErrMessageInvalid, "無效的訊息附加資料")
+	}
+	return nil
+}
+
+// notifyMentions 通知被提及的成員並累加其會話的提及數，失敗只記錄錯誤
+func (uc *messageUseCase) notifyMentions(ctx context.Context, message *entities.Message, members []uint) {
+	mentions := message.Mentions()
+	if len(mentions) == 0 {
+		return
+	}
+
+	all := message.MentionsAll()
+	var targets []uint
+	if all {
+		for _, userID := range members {
+			if userID != message.UserId {
+				targets = append(targets, userID)
+			}
+		}
+	} else {
+		for _, mention := range mentions {
+			targets = append(targets, mention.UserID)
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+
+	if err := uc.conversationRepo.RecordMentions(ctx, message, targets); err != nil {
+		fmt.Printf("更新會話提及數失敗: messageID=%d, err=%v\n", message.ID, err)
+	}
+
+	uc.publish(ctx, &Event{Type: EventMessageMention, Data: &MentionEvent{
+		MessageID: message.ID,
+		RoomID:    message.RoomID,
+		SenderID:  message.UserId,
+		All:       all,
+		Preview:   entities.MessagePreview(message),
+	}}, targets)
+}
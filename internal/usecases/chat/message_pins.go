@@ -0,0 +1,223 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// PinAction 釘選的變更類型
+type PinAction string
+
+const (
+	PinAdded   PinAction = "pin"
+	PinRemoved PinAction = "unpin"
+)
+
+// PinEvent 釘選變更時推送給對話參與者的內容
+type PinEvent struct {
+	MessageID uint      `json:"message_id"`
+	RoomID    uint      `json:"room_id,omitempty"`
+	UserID    uint      `json:"user_id"`
+	Action    PinAction `json:"action"`
+}
+
+// pinNoticeContents 釘選變更時系統通知的內容
+var pinNoticeContents = map[PinAction]string{
+	PinAdded:   "釘選了一則訊息",
+	PinRemoved: "取消釘選了一則訊息",
+}
+
+func (uc *messageUseCase) PinMessage(ctx context.Context, userID, messageID uint) (*entities.MessagePin, error) {
+	message, recipients, err := uc.pinTarget(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsRecalled() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "訊息已撤回")
+	}
+	if message.IsSystem() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "系統通知無法釘選")
+	}
+
+	pin := &entities.MessagePin{
+		Scope:     entities.PinScopeOf(message),
+		MessageID: message.ID,
+		PinnedBy:  userID,
+		CreatedAt: time.Now(),
+	}
+	added, err := uc.pinRepo.Pin(ctx, pin, uc.settings.PinLimit)
+	if errors.Is(err, repositories.ErrPinLimitReached) {
+		return nil, appErrors.New(enum.ErrPinLimitReached, map[string]interface{}{
+			"messageId": messageID,
+			"limit":     uc.settings.PinLimit,
+		})
+	}
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	if added {
+		uc.pinChanged(ctx, message, recipients, userID, PinAdded)
+	}
+	return pin, nil
+}
+
+func (uc *messageUseCase) UnpinMessage(ctx context.Context, userID, messageID uint) error {
+	message, recipients, err := uc.pinTarget(ctx, userID, messageID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := uc.pinRepo.Unpin(ctx, messageID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	if removed {
+		uc.pinChanged(ctx, message, recipients, userID, PinRemoved)
+	}
+	return nil
+}
+
+func (uc *messageUseCase) ListPinnedMessages(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) ([]*entities.PinnedMessage, error) {
+	scope, err := uc.conversationScope(ctx, userID, convType, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. 先嘗試從快取獲取
+	pinned, hit, err := uc.messageCacheRepo.GetPins(ctx, scope)
+	if err == nil && hit {
+		return pinned, nil
+	}
+
+	// 2. 從資料庫獲取釘選與對應訊息
+	pins, err := uc.pinRepo.ListByScope(ctx, scope)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"scope": scope,
+		})
+	}
+	ids := make([]uint, len(pins))
+	for i, pin := range pins {
+		ids[i] = pin.MessageID
+	}
+	messages, err := uc.messageRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"scope": scope,
+		})
+	}
+
+	byID := make(map[uint]*entities.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	pinned = make([]*entities.PinnedMessage, 0, len(pins))
+	for _, pin := range pins {
+		if msg, ok := byID[pin.MessageID]; ok {
+			pinned = append(pinned, &entities.PinnedMessage{Pin: pin, Message: msg})
+		}
+	}
+
+	// 3. 寫回快取
+	if err := uc.messageCacheRepo.StorePins(ctx, scope, pinned); err != nil {
+		fmt.Printf("更新釘選快取失敗: %v\n", err)
+	}
+	return pinned, nil
+}
+
+// pinTarget 查詢要變更釘選的訊息並檢查權限，返回訊息與其對話參與者
+func (uc *messageUseCase) pinTarget(ctx context.Context, userID, messageID uint) (*entities.Message, []uint, error) {
+	message, err := uc.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recipients, err := uc.participants(ctx, message, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if message.IsGroupConversation() {
+		isManager, err := isGroupManager(ctx, uc.groupRepo, message.RoomID, userID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !isManager {
+			return nil, nil, appErrors.New(enum.ErrAccessDenied, "只有群主或管理員可以釘選群組訊息")
+		}
+	}
+	return message, recipients, nil
+}
+
+// pinChanged 清除釘選快取，在對話中發出系統通知並通知所有參與者
+func (uc *messageUseCase) pinChanged(ctx context.Context, message *entities.Message, recipients []uint, userID uint, action PinAction) {
+	uc.invalidatePins(ctx, message)
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"pin": map[string]interface{}{"message_id": message.ID, "action": action},
+	})
+	uc.sendSystemNotice(ctx, message, userID, pinNoticeContents[action], metadata, recipients)
+
+	uc.publish(ctx, &Event{Type: EventMessagePinned, Data: &PinEvent{
+		MessageID: message.ID,
+		RoomID:    message.RoomID,
+		UserID:    userID,
+		Action:    action,
+	}}, recipients)
+}
+
+// invalidatePins 刪除訊息所屬對話的釘選快取，失敗只記錄錯誤
+func (uc *messageUseCase) invalidatePins(ctx context.Context, message *entities.Message) {
+	if err := uc.messageCacheRepo.InvalidatePins(ctx, entities.PinScopeOf(message)); err != nil {
+		fmt.Printf("清除釘選快取失敗: %v\n", err)
+	}
+}
+
+// sendSystemNotice 由 userID 在 about 所屬的對話中發出系統通知，失敗只記錄錯誤
+func (uc *messageUseCase) sendSystemNotice(ctx context.Context, about *entities.Message, userID uint, content string, metadata []byte, recipients []uint) {
+	notice := &entities.Message{
+		UserId:   userID,
+		RoomID:   about.RoomID,
+		Type:     entities.MessageTypeSystem,
+		Content:  content,
+		Metadata: entities.JSON(metadata),
+	}
+	if !about.IsGroupConversation() {
+		notice.TargetId = about.TargetId
+		if about.TargetId == userID {
+			notice.TargetId = about.UserId
+		}
+	}
+	prepareMessage(notice)
+
+	if err := createSequenced(ctx, uc.messageRepo, uc.sequencer, notice); err != nil {
+		fmt.Printf("儲存系統通知失敗: %v\n", err)
+		return
+	}
+
+	var err error
+	if notice.IsGroupConversation() {
+		err = uc.messageCacheRepo.StoreGroupMessage(ctx, notice)
+	} else {
+		err = uc.messageCacheRepo.StorePrivateMessage(ctx, notice)
+	}
+	if err != nil {
+		fmt.Printf("儲存訊息到快取失敗: %v\n", err)
+	}
+
+	uc.recordConversation(ctx, notice, recipients)
+	uc.deliver(ctx, notice, recipients)
+}
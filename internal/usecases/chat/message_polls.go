@@ -0,0 +1,377 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// pollCloseBatchSize 每次截止的投票數量上限
+	pollCloseBatchSize = 100
+	// defaultPollCloseInterval 預設檢查到期投票的間隔
+	defaultPollCloseInterval = 10 * time.Second
+)
+
+func (uc *messageUseCase) CreatePoll(ctx context.Context, poll *entities.Poll) (*entities.Message, error) {
+	if poll == nil {
+		return nil, appErrors.New(enum.ErrInvalidInput, "投票不能為空")
+	}
+	if err := validatePoll(poll, time.Now()); err != nil {
+		return nil, err
+	}
+
+	message := &entities.Message{
+		UserId:  poll.CreatorID,
+		RoomID:  poll.RoomID,
+		Media:   entities.MediaTypePoll,
+		Content: poll.Question,
+	}
+	if err := uc.sendGroupMessage(ctx, message, poll); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (uc *messageUseCase) VotePoll(ctx context.Context, userID, messageID uint, options []int) (*entities.PollResult, error) {
+	poll, err := uc.pollTarget(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := validatePollChoices(poll, options); err != nil {
+		return nil, err
+	}
+
+	if err := uc.pollRepo.Vote(ctx, messageID, userID, options, time.Now()); err != nil {
+		return nil, pollError(err, messageID)
+	}
+
+	// 投票頻繁且只有最新結果有意義，不保存為離線事件
+	votes, err := uc.publishPoll(ctx, poll, true)
+	if err != nil {
+		return nil, err
+	}
+	return entities.NewPollResult(poll, votes, userID), nil
+}
+
+func (uc *messageUseCase) GetPoll(ctx context.Context, userID, messageID uint) (*entities.PollResult, error) {
+	poll, err := uc.pollTarget(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+
+	votes, err := uc.pollRepo.FindVotes(ctx, []uint{messageID})
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+	return entities.NewPollResult(poll, votes, userID), nil
+}
+
+func (uc *messageUseCase) ClosePoll(ctx context.Context, userID, messageID uint) (*entities.PollResult, error) {
+	poll, err := uc.pollTarget(ctx, userID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if poll.CreatorID != userID {
+		isManager, err := isGroupManager(ctx, uc.groupRepo, poll.RoomID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isManager {
+			return nil, appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+				"messageId": messageID,
+				"userId":    userID,
+			})
+		}
+	}
+
+	now := time.Now()
+	closed, err := uc.pollRepo.Close(ctx, messageID, now)
+	if err != nil {
+		return nil, pollError(err, messageID)
+	}
+	if !closed {
+		return nil, pollError(repositories.ErrPollClosed, messageID)
+	}
+	poll.ClosedAt = &now
+
+	votes, err := uc.publishPoll(ctx, poll, false)
+	if err != nil {
+		return nil, err
+	}
+	return entities.NewPollResult(poll, votes, userID), nil
+}
+
+func (uc *messageUseCase) CloseDuePolls(ctx context.Context, now time.Time) (int, error) {
+	polls, err := uc.pollRepo.FindDue(ctx, now, pollCloseBatchSize)
+	if err != nil {
+		return 0, appErrors.NewDBError(err, "查詢到期投票失敗")
+	}
+
+	processed := 0
+	for _, poll := range polls {
+		// 以截止時間記錄，多個副本同時執行時只有成功更新的一方推送結果
+		closedAt := poll.ClosesAt
+		closed, err := uc.pollRepo.Close(ctx, poll.MessageID, closedAt)
+		if err != nil {
+			fmt.Printf("截止投票失敗: messageID=%d, err=%v\n", poll.MessageID, err)
+			continue
+		}
+		processed++
+		if !closed {
+			continue
+		}
+		poll.ClosedAt = &closedAt
+		if _, err := uc.publishPoll(ctx, poll, false); err != nil {
+			fmt.Printf("推送投票結果失敗: messageID=%d, err=%v\n", poll.MessageID, err)
+		}
+	}
+	return processed, nil
+}
+
+// createPoll 為剛寫入的投票訊息建立投票，失敗時刪除訊息，避免留下無法投票的投票訊息
+func (uc *messageUseCase) createPoll(ctx context.Context, message *entities.Message, poll *entities.Poll) error {
+	poll.MessageID = message.ID
+	poll.RoomID = message.RoomID
+	poll.CreatorID = message.UserId
+	poll.ClosedAt = nil
+	poll.CreatedAt = message.CreatedAt
+	if err := uc.pollRepo.Create(ctx, poll); err != nil {
+		if deleteErr := uc.messageRepo.DeleteMessages(ctx, []uint{message.ID}); deleteErr != nil {
+			fmt.Printf("刪除建立投票失敗的訊息失敗: messageID=%d, err=%v\n", message.ID, deleteErr)
+		}
+		return appErrors.Wrap(err, enum.ErrMessageSendFailed, map[string]interface{}{
+			"userId": message.UserId,
+			"roomId": message.RoomID,
+		})
+	}
+	return nil
+}
+
+// pollTarget 返回用戶可查看與投票的投票，只有群組成員可以存取
+func (uc *messageUseCase) pollTarget(ctx context.Context, userID, messageID uint) (*entities.Poll, error) {
+	message, err := uc.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.Media != entities.MediaTypePoll || message.IsRecalled() || !message.IsGroupConversation() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, map[string]interface{}{
+			"messageId": messageID,
+			"reason":    "訊息不是投票",
+		})
+	}
+
+	isMember, err := uc.groupRepo.IsMember(ctx, message.RoomID, userID)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": message.RoomID,
+			"userId": userID,
+		})
+	}
+	if !isMember {
+		return nil, appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": message.RoomID,
+			"userId": userID,
+		})
+	}
+
+	poll, err := uc.pollRepo.FindByMessageID(ctx, messageID)
+	if err != nil {
+		return nil, pollError(err, messageID)
+	}
+	return poll, nil
+}
+
+// publishPoll 統計最新結果並推送給群組成員，推送失敗只記錄錯誤，返回所有選票
+func (uc *messageUseCase) publishPoll(ctx context.Context, poll *entities.Poll, transient bool) ([]*entities.PollVote, error) {
+	votes, err := uc.pollRepo.FindVotes(ctx, []uint{poll.MessageID})
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": poll.MessageID,
+		})
+	}
+
+	members, err := uc.groupRepo.GetMembers(ctx, poll.RoomID)
+	if err != nil {
+		fmt.Printf("獲取群組成員失敗，略過投票結果推送: %v\n", err)
+		return votes, nil
+	}
+	uc.publish(ctx, &Event{
+		Type:      EventMessagePoll,
+		Data:      entities.NewPollResult(poll, votes, 0),
+		Transient: transient,
+	}, members)
+	return votes, nil
+}
+
+// attachPolls 以單一查詢為一批訊息附加投票結果，失敗時只記錄錯誤
+func (uc *messageUseCase) attachPolls(ctx context.Context, messages []*entities.Message) {
+	var ids []uint
+	for _, msg := range messages {
+		if msg.Media == entities.MediaTypePoll {
+			ids = append(ids, msg.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	polls, err := uc.pollRepo.FindByMessageIDs(ctx, ids)
+	if err != nil {
+		fmt.Printf("獲取投票失敗: %v\n", err)
+		return
+	}
+	votes, err := uc.pollRepo.FindVotes(ctx, ids)
+	if err != nil {
+		fmt.Printf("獲取投票結果失敗: %v\n", err)
+		return
+	}
+
+	votesByPoll := make(map[uint][]*entities.PollVote)
+	for _, vote := range votes {
+		votesByPoll[vote.MessageID] = append(votesByPoll[vote.MessageID], vote)
+	}
+	byID := make(map[uint]*entities.Poll, len(polls))
+	for _, poll := range polls {
+		byID[poll.MessageID] = poll
+	}
+	for _, msg := range messages {
+		if poll, ok := byID[msg.ID]; ok {
+			msg.Poll = entities.NewPollResult(poll, votesByPoll[msg.ID], 0)
+		}
+	}
+}
+
+// validatePoll 驗證投票的問題、選項與截止時間，並去除前後空白
+func validatePoll(poll *entities.Poll, now time.Time) error {
+	if poll.CreatorID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "建立者ID不能為空")
+	}
+	if poll.RoomID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "聊天室ID不能為空")
+	}
+
+	poll.Question = strings.TrimSpace(poll.Question)
+	if poll.Question == "" || utf8.RuneCountInString(poll.Question) > entities.MaxPollQuestionLength {
+		return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message":   "投票問題不能為空或過長",
+			"maxLength": entities.MaxPollQuestionLength,
+		})
+	}
+
+	if len(poll.Options) < entities.MinPollOptions || len(poll.Options) > entities.MaxPollOptions {
+		return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message":    "投票選項數量不符",
+			"minOptions": entities.MinPollOptions,
+			"maxOptions": entities.MaxPollOptions,
+		})
+	}
+	seen := make(map[string]bool, len(poll.Options))
+	for i, option := range poll.Options {
+		option = strings.TrimSpace(option)
+		if option == "" || utf8.RuneCountInString(option) > entities.MaxPollOptionLength {
+			return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+				"message":   "投票選項不能為空或過長",
+				"maxLength": entities.MaxPollOptionLength,
+			})
+		}
+		if seen[option] {
+			return appErrors.New(enum.ErrInvalidInput, "投票選項不能重複")
+		}
+		seen[option] = true
+		poll.Options[i] = option
+	}
+
+	if !poll.ClosesAt.After(now) {
+		return appErrors.New(enum.ErrInvalidInput, "截止時間必須晚於現在")
+	}
+	if poll.ClosesAt.After(now.Add(entities.MaxPollDuration)) {
+		return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"reason":      "截止時間超過可設定的範圍",
+			"maxDuration": entities.MaxPollDuration.String(),
+		})
+	}
+	return nil
+}
+
+// validatePollChoices 驗證選擇的選項：索引需在範圍內且不可重複，單選最多一個
+func validatePollChoices(poll *entities.Poll, options []int) error {
+	if !poll.MultipleChoice && len(options) > 1 {
+		return appErrors.New(enum.ErrInvalidInput, "此投票只能選擇一個選項")
+	}
+	seen := make(map[int]bool, len(options))
+	for _, option := range options {
+		if option < 0 || option >= len(poll.Options) || seen[option] {
+			return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+				"message": "無效的投票選項",
+				"option":  option,
+			})
+		}
+		seen[option] = true
+	}
+	return nil
+}
+
+// pollError 將投票儲存庫的錯誤轉換為應用錯誤
+func pollError(err error, messageID uint) error {
+	switch {
+	case errors.Is(err, repositories.ErrPollClosed):
+		return appErrors.New(enum.ErrPollClosed, map[string]interface{}{
+			"messageId": messageID,
+		})
+	case errors.Is(err, repositories.ErrPollNotFound):
+		return appErrors.New(enum.ErrMessageInvalid, map[string]interface{}{
+			"messageId": messageID,
+			"reason":    "訊息不是投票",
+		})
+	default:
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+}
+
+// PollCloser 定期截止到期投票的背景工作，多個副本同時執行時每個投票只會被其中一個截止
+type PollCloser struct {
+	useCase  MessageUseCase
+	interval time.Duration
+}
+
+// NewPollCloser 創建新的投票截止器，interval 為 0 時使用預設間隔
+func NewPollCloser(useCase MessageUseCase, interval time.Duration) *PollCloser {
+	if interval <= 0 {
+		interval = defaultPollCloseInterval
+	}
+	return &PollCloser{useCase: useCase, interval: interval}
+}
+
+// Run 持續截止到期的投票直到 ctx 結束，一批處理滿時立即處理下一批
+func (c *PollCloser) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		count, err := c.useCase.CloseDuePolls(ctx, time.Now())
+		if err != nil {
+			fmt.Printf("截止到期投票失敗: %v\n", err)
+		}
+		if err == nil && count >= pollCloseBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
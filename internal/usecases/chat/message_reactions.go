@@ -0,0 +1,159 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReactionAction 表情回應的變更類型
+type ReactionAction string
+
+const (
+	ReactionAdded   ReactionAction = "add"
+	ReactionRemoved ReactionAction = "remove"
+)
+
+// ReactionEvent 表情回應變更時推送給對話參與者的內容
+type ReactionEvent struct {
+	MessageID uint                     `json:"message_id"`
+	RoomID    uint                     `json:"room_id,omitempty"`
+	UserID    uint                     `json:"user_id"`
+	Emoji     string                   `json:"emoji"`
+	Action    ReactionAction           `json:"action"`
+	Reactions []entities.ReactionCount `json:"reactions"`
+}
+
+func (uc *messageUseCase) AddReaction(ctx context.Context, userID, messageID uint, emoji string) ([]entities.ReactionCount, error) {
+	message, recipients, err := uc.reactionTarget(ctx, userID, messageID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	// 群組可限制允許的表情
+	if message.IsGroupConversation() {
+		group, err := uc.groupRepo.FindByID(ctx, message.RoomID)
+		if err != nil {
+			return nil, appErrors.Wrap(err, enum.ErrGroupNotFound, map[string]interface{}{
+				"roomId": message.RoomID,
+			})
+		}
+		if !group.AllowsReaction(emoji) {
+			return nil, appErrors.New(enum.ErrReactionNotAllowed, map[string]interface{}{
+				"roomId": message.RoomID,
+				"emoji":  emoji,
+			})
+		}
+	}
+
+	added, err := uc.reactionRepo.Add(ctx, &entities.MessageReaction{
+		MessageID: messageID,
+		UserID:    userID,
+		Emoji:     emoji,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	return uc.reactionChanged(ctx, message, recipients, userID, emoji, ReactionAdded, added)
+}
+
+func (uc *messageUseCase) RemoveReaction(ctx context.Context, userID, messageID uint, emoji string) ([]entities.ReactionCount, error) {
+	message, recipients, err := uc.reactionTarget(ctx, userID, messageID, emoji)
+	if err != nil {
+		return nil, err
+	}
+
+	removed, err := uc.reactionRepo.Remove(ctx, messageID, userID, emoji)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	return uc.reactionChanged(ctx, message, recipients, userID, emoji, ReactionRemoved, removed)
+}
+
+// reactionTarget 驗證表情並返回可回應的訊息與其對話參與者
+func (uc *messageUseCase) reactionTarget(ctx context.Context, userID, messageID uint, emoji string) (*entities.Message, []uint, error) {
+	if strings.TrimSpace(emoji) == "" || len(emoji) > entities.MaxReactionLength {
+		return nil, nil, appErrors.New(enum.ErrInvalidInput, "無效的表情回應")
+	}
+
+	message, err := uc.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if message.IsRecalled() {
+		return nil, nil, appErrors.New(enum.ErrMessageInvalid, "訊息已撤回")
+	}
+
+	recipients, err := uc.participants(ctx, message, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+	return message, recipients, nil
+}
+
+// reactionChanged 返回最新統計，並在實際有變更時通知所有參與者
+func (uc *messageUseCase) reactionChanged(
+	ctx context.Context,
+	message *entities.Message,
+	recipients []uint,
+	userID uint,
+	emoji string,
+	action ReactionAction,
+	changed bool,
+) ([]entities.ReactionCount, error) {
+	counts, err := uc.reactionRepo.CountByMessageIDs(ctx, []uint{message.ID})
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": message.ID,
+		})
+	}
+	reactions := counts[message.ID]
+	if reactions == nil {
+		reactions = []entities.ReactionCount{}
+	}
+
+	if changed {
+		uc.publish(ctx, &Event{Type: EventMessageReaction, Data: &ReactionEvent{
+			MessageID: message.ID,
+			RoomID:    message.RoomID,
+			UserID:    userID,
+			Emoji:     emoji,
+			Action:    action,
+			Reactions: reactions,
+		}}, recipients)
+	}
+
+	return reactions, nil
+}
+
+// attachReactions 以單一查詢為一批訊息附加表情回應統計，失敗時只記錄錯誤
+func (uc *messageUseCase) attachReactions(ctx context.Context, messages []*entities.Message) {
+	if len(messages) == 0 {
+		return
+	}
+
+	ids := make([]uint, len(messages))
+	for i, msg := range messages {
+		ids[i] = msg.ID
+	}
+
+	counts, err := uc.reactionRepo.CountByMessageIDs(ctx, ids)
+	if err != nil {
+		fmt.Printf("獲取表情回應失敗: %v\n", err)
+		return
+	}
+	for _, msg := range messages {
+		msg.Reactions = counts[msg.ID]
+	}
+}
@@ -0,0 +1,56 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/pkg/richtext"
+	"errors"
+)
+
+// renderRichText 驗證富文本並寫入清理後的 HTML 與純文字版本。
+// 其他類型的訊息會移除客戶端自行帶入的 HTML，避免未經清理的內容被當作富文本顯示
+func renderRichText(message *entities.Message) error {
+	if !message.IsRichText() {
+		if err := message.SetRichText("", ""); err != nil {
+			return appErrors.New(enum.ErrMessageInvalid, "無效的訊息附加資料")
+		}
+		return nil
+	}
+
+	doc, err := parseRichText(message.Content)
+	if err != nil {
+		return err
+	}
+	if err := message.SetRichText(doc.HTML(), doc.PlainText()); err != nil {
+		return appErrors.New(enum.ErrMessageInvalid, "無效的訊息附加資料")
+	}
+	return nil
+}
+
+// validateRichText 在訊息實際發送前檢查富文本內容，用於排程訊息
+func validateRichText(media entities.MediaType, content string) error {
+	if media != entities.MediaTypeRichText {
+		return nil
+	}
+	_, err := parseRichText(content)
+	return err
+}
+
+func parseRichText(content string) (*richtext.Document, error) {
+	doc, err := richtext.Parse(content)
+	switch {
+	case errors.Is(err, richtext.ErrEmpty):
+		return nil, appErrors.New(enum.ErrInvalidInput, "消息內容不能為空")
+	case errors.Is(err, richtext.ErrTooLong):
+		return nil, appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message":   "富文本內容過長",
+			"maxLength": richtext.MaxSourceLength,
+		})
+	case errors.Is(err, richtext.ErrUnsafeLink):
+		return nil, appErrors.New(enum.ErrInvalidInput, "連結只支援 http、https 與 mailto 網址")
+	case err != nil:
+		return nil, appErrors.New(enum.ErrMessageInvalid, "無效的富文本內容")
+	}
+	return doc, nil
+}
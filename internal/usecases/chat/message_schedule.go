@@ -0,0 +1,286 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// scheduleClaimLease 發送程序認領排程後的有效期限，逾期未完成時由其他程序重新認領
+	scheduleClaimLease = time.Minute
+	// scheduleBatchSize 每次認領的排程數量上限
+	scheduleBatchSize = 100
+	// defaultScheduleInterval 預設檢查到期排程的間隔
+	defaultScheduleInterval = 5 * time.Second
+)
+
+// ScheduleUseCase 排程訊息的用例：建立、查詢、修改、取消與到期發送
+type ScheduleUseCase interface {
+	// ScheduleMessage 建立在指定時間發送的私聊或群聊訊息
+	ScheduleMessage(ctx context.Context, scheduled *entities.ScheduledMessage) error
+	// ListScheduled 列出用戶待發送的排程訊息
+	ListScheduled(ctx context.Context, userID uint) ([]*entities.ScheduledMessage, error)
+	// UpdateScheduled 修改待發送排程的內容與發送時間
+	UpdateScheduled(ctx context.Context, userID, id uint, content string, sendAt time.Time) (*entities.ScheduledMessage, error)
+	// CancelScheduled 取消待發送的排程
+	CancelScheduled(ctx context.Context, userID, id uint) error
+	// DispatchDue 認領並以一般發送流程發送到期的排程，返回處理的數量
+	DispatchDue(ctx context.Context, now time.Time) (int, error)
+}
+
+type scheduleUseCase struct {
+	scheduleRepo   repositories.ScheduledMessageRepository
+	messageUseCase MessageUseCase
+	groupRepo      repositories.GroupRepository
+}
+
+// NewScheduleUseCase 創建新的排程訊息用例
+func NewScheduleUseCase(scheduleRepo repositories.ScheduledMessageRepository, messageUseCase MessageUseCase, groupRepo repositories.GroupRepository) ScheduleUseCase {
+	return &scheduleUseCase{
+		scheduleRepo:   scheduleRepo,
+		messageUseCase: messageUseCase,
+		groupRepo:      groupRepo,
+	}
+}
+
+func (uc *scheduleUseCase) ScheduleMessage(ctx context.Context, scheduled *entities.ScheduledMessage) error {
+	if scheduled == nil {
+		return appErrors.New(enum.ErrInvalidInput, "排程訊息不能為空")
+	}
+	if scheduled.UserID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "發送者ID不能為空")
+	}
+	if scheduled.Type != entities.MessageTypePrivate && scheduled.Type != entities.MessageTypeGroup {
+		return appErrors.New(enum.ErrInvalidInput, "無效的訊息類型")
+	}
+	if scheduled.TargetID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "接收對象ID不能為空")
+	}
+	if err := validateSchedule(scheduled.Content, scheduled.SendAt); err != nil {
+		return err
+	}
+
+	// 群聊排程限成員建立，發送時會再經由一般發送流程檢查一次
+	if scheduled.IsGroup() {
+		isMember, err := uc.groupRepo.IsMember(ctx, scheduled.TargetID, scheduled.UserID)
+		if err != nil {
+			return appErrors.NewDBError(err, map[string]interface{}{
+				"roomId": scheduled.TargetID,
+				"userId": scheduled.UserID,
+			})
+		}
+		if !isMember {
+			return appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+				"roomId": scheduled.TargetID,
+				"userId": scheduled.UserID,
+			})
+		}
+	}
+
+	if scheduled.Media == 0 {
+		scheduled.Media = entities.MediaTypeText
+	}
+	if scheduled.Media == entities.MediaTypePoll {
+		return appErrors.New(enum.ErrInvalidInput, "投票不能排程發送")
+	}
+	if err := validateRichText(scheduled.Media, scheduled.Content); err != nil {
+		return err
+	}
+	scheduled.ID = 0
+	scheduled.Status = entities.SchedulePending
+	scheduled.Attempts = 0
+	scheduled.MessageID = 0
+	if err := uc.scheduleRepo.Create(ctx, scheduled); err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"userId":   scheduled.UserID,
+			"targetId": scheduled.TargetID,
+		})
+	}
+	return nil
+}
+
+func (uc *scheduleUseCase) ListScheduled(ctx context.Context, userID uint) ([]*entities.ScheduledMessage, error) {
+	if userID == 0 {
+		return nil, appErrors.New(enum.ErrInvalidInput, "用戶ID不能為空")
+	}
+
+	scheduled, err := uc.scheduleRepo.ListPending(ctx, userID)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+	return scheduled, nil
+}
+
+func (uc *scheduleUseCase) UpdateScheduled(ctx context.Context, userID, id uint, content string, sendAt time.Time) (*entities.ScheduledMessage, error) {
+	if err := validateSchedule(content, sendAt); err != nil {
+		return nil, err
+	}
+
+	scheduled, err := uc.findOwned(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRichText(scheduled.Media, content); err != nil {
+		return nil, err
+	}
+
+	scheduled.Content = content
+	scheduled.SendAt = sendAt
+	if err := uc.scheduleRepo.UpdatePending(ctx, scheduled); err != nil {
+		return nil, scheduleError(err, id)
+	}
+	return scheduled, nil
+}
+
+func (uc *scheduleUseCase) CancelScheduled(ctx context.Context, userID, id uint) error {
+	if _, err := uc.findOwned(ctx, userID, id); err != nil {
+		return err
+	}
+
+	if err := uc.scheduleRepo.Cancel(ctx, id); err != nil {
+		return scheduleError(err, id)
+	}
+	return nil
+}
+
+func (uc *scheduleUseCase) DispatchDue(ctx context.Context, now time.Time) (int, error) {
+	claimed, err := uc.scheduleRepo.ClaimDue(ctx, now, scheduleClaimLease, scheduleBatchSize)
+	if err != nil {
+		return 0, appErrors.NewDBError(err, "認領到期排程訊息失敗")
+	}
+
+	for _, scheduled := range claimed {
+		uc.dispatch(ctx, scheduled)
+	}
+	return len(claimed), nil
+}
+
+// dispatch 以一般發送流程發送單則排程，失敗時在重試次數內放回待發送狀態
+func (uc *scheduleUseCase) dispatch(ctx context.Context, scheduled *entities.ScheduledMessage) {
+	if scheduled.Attempts > entities.MaxScheduleAttempts {
+		uc.markFailed(ctx, scheduled, "超過最大發送次數", false)
+		return
+	}
+
+	message := scheduled.ToMessage()
+	var err error
+	if scheduled.IsGroup() {
+		err = uc.messageUseCase.SendGroupMessage(ctx, message)
+	} else {
+		err = uc.messageUseCase.SendPrivateMessage(ctx, message)
+	}
+	if err != nil {
+		uc.markFailed(ctx, scheduled, err.Error(), scheduled.Attempts < entities.MaxScheduleAttempts)
+		return
+	}
+
+	if err := uc.scheduleRepo.MarkSent(ctx, scheduled, message.ID); err != nil {
+		fmt.Printf("更新排程訊息狀態失敗: scheduledID=%d, err=%v\n", scheduled.ID, err)
+	}
+}
+
+// markFailed 記錄發送失敗，失敗只記錄錯誤
+func (uc *scheduleUseCase) markFailed(ctx context.Context, scheduled *entities.ScheduledMessage, reason string, retry bool) {
+	if err := uc.scheduleRepo.MarkFailed(ctx, scheduled, reason, retry); err != nil {
+		fmt.Printf("更新排程訊息狀態失敗: scheduledID=%d, err=%v\n", scheduled.ID, err)
+	}
+}
+
+// findOwned 查詢排程並確認屬於此用戶
+func (uc *scheduleUseCase) findOwned(ctx context.Context, userID, id uint) (*entities.ScheduledMessage, error) {
+	scheduled, err := uc.scheduleRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, scheduleError(err, id)
+	}
+	if scheduled.UserID != userID {
+		return nil, appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+			"scheduledId": id,
+			"userId":      userID,
+		})
+	}
+	if scheduled.Status != entities.SchedulePending {
+		return nil, scheduleError(repositories.ErrScheduleNotPending, id)
+	}
+	return scheduled, nil
+}
+
+// validateSchedule 驗證排程內容與發送時間
+func validateSchedule(content string, sendAt time.Time) error {
+	if strings.TrimSpace(content) == "" {
+		return appErrors.New(enum.ErrInvalidInput, "消息內容不能為空")
+	}
+	now := time.Now()
+	if !sendAt.After(now) {
+		return appErrors.New(enum.ErrInvalidInput, "發送時間必須晚於現在")
+	}
+	if sendAt.After(now.Add(entities.MaxScheduleAhead)) {
+		return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"reason":   "發送時間超過可排程的範圍",
+			"maxAhead": entities.MaxScheduleAhead.String(),
+		})
+	}
+	return nil
+}
+
+// scheduleError 將排程儲存庫的錯誤轉換為應用錯誤
+func scheduleError(err error, id uint) error {
+	switch {
+	case errors.Is(err, repositories.ErrScheduledMessageNotFound):
+		return appErrors.New(enum.ErrScheduleNotFound, map[string]interface{}{
+			"scheduledId": id,
+		})
+	case errors.Is(err, repositories.ErrScheduleNotPending):
+		return appErrors.New(enum.ErrScheduleNotPending, map[string]interface{}{
+			"scheduledId": id,
+		})
+	default:
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"scheduledId": id,
+		})
+	}
+}
+
+// ScheduleDispatcher 定期發送到期排程訊息的背景工作，可在多個應用程式副本同時執行
+type ScheduleDispatcher struct {
+	useCase  ScheduleUseCase
+	interval time.Duration
+}
+
+// NewScheduleDispatcher 創建新的排程發送器，interval 為 0 時使用預設間隔
+func NewScheduleDispatcher(useCase ScheduleUseCase, interval time.Duration) *ScheduleDispatcher {
+	if interval <= 0 {
+		interval = defaultScheduleInterval
+	}
+	return &ScheduleDispatcher{useCase: useCase, interval: interval}
+}
+
+// Run 持續發送到期的排程直到 ctx 結束，一批處理滿時立即處理下一批
+func (d *ScheduleDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for ctx.Err() == nil {
+		count, err := d.useCase.DispatchDue(ctx, time.Now())
+		if err != nil {
+			fmt.Printf("發送排程訊息失敗: %v\n", err)
+		}
+		if err == nil && count >= scheduleBatchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/domain/search"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+	"time"
+)
+
+// SearchQuery 訊息搜尋的請求條件，零值欄位表示不限制
+type SearchQuery struct {
+	Text             string
+	ConversationType entities.ConversationType // 限定單一對話，需與 TargetID 一起使用
+	TargetID         uint
+	SenderID         uint
+	From             time.Time // 包含
+	To               time.Time // 不包含
+	Media            entities.MediaType
+	Offset           int
+	Limit            int
+}
+
+// SearchUseCase 在用戶可見的訊息中進行全文搜尋
+type SearchUseCase interface {
+	// SearchMessages 搜尋用戶的私聊與所屬群組中的訊息，結果由新到舊排列
+	SearchMessages(ctx context.Context, userID uint, query SearchQuery) (*search.Result, error)
+}
+
+type searchUseCase struct {
+	index     search.MessageIndex
+	groupRepo repositories.GroupRepository
+}
+
+// NewSearchUseCase 創建新的訊息搜尋用例
+func NewSearchUseCase(index search.MessageIndex, groupRepo repositories.GroupRepository) SearchUseCase {
+	return &searchUseCase{
+		index:     index,
+		groupRepo: groupRepo,
+	}
+}
+
+func (uc *searchUseCase) SearchMessages(ctx context.Context, userID uint, query SearchQuery) (*search.Result, error) {
+	if userID == 0 {
+		return nil, appErrors.New(enum.ErrInvalidInput, "用戶ID不能為空")
+	}
+	terms := search.Tokenize(query.Text)
+	if len(terms) == 0 {
+		return nil, appErrors.New(enum.ErrInvalidInput, "搜尋關鍵字不能為空")
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, appErrors.New(enum.ErrInvalidInput, "無效的日期範圍")
+	}
+	if query.Media < 0 || query.Media > entities.MediaTypePoll {
+		return nil, appErrors.New(enum.ErrInvalidInput, "無效的媒體類型")
+	}
+	if query.ConversationType != 0 {
+		if err := validateConversation(userID, query.ConversationType, query.TargetID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 可見範圍包含用戶所屬的所有群組
+	groups, err := uc.groupRepo.FindJoinedGroups(ctx, userID)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+	roomIDs := make([]uint, len(groups))
+	for i, group := range groups {
+		roomIDs[i] = group.ID
+	}
+	if query.ConversationType == entities.ConversationTypeGroup && !containsUint(roomIDs, query.TargetID) {
+		return nil, appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": query.TargetID,
+			"userId": userID,
+		})
+	}
+
+	result, err := uc.index.Search(ctx, search.Query{
+		Terms:            terms,
+		ViewerID:         userID,
+		RoomIDs:          roomIDs,
+		ConversationType: query.ConversationType,
+		TargetID:         query.TargetID,
+		SenderID:         query.SenderID,
+		From:             query.From,
+		To:               query.To,
+		Media:            query.Media,
+		Offset:           query.Offset,
+		Limit:            query.Limit,
+	})
+	if err != nil {
+		return nil, appErrors.Wrap(err, enum.ErrHistoryLoadFailed, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+	return result, nil
+}
+
+// indexMessage 更新訊息的搜尋索引，失敗只記錄錯誤
+func (uc *messageUseCase) indexMessage(ctx context.Context, message *entities.Message) {
+	if err := uc.searchIndex.Index(ctx, message); err != nil {
+		fmt.Printf("更新搜尋索引失敗: messageID=%d, err=%v\n", message.ID, err)
+	}
+}
+
+func containsUint(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// errSequenceNotInitialized 計數器初始化後立即被移除（例如 Redis 淘汰鍵），改由資料庫配發
+var errSequenceNotInitialized = errors.New("sequence counter not initialized")
+
+// seqResumeMargin Redis 恢復或序號衝突時，計數器在資料庫最大序號之上額外提高的幅度，
+// 涵蓋其他副本已由 Redis 配發但尚未寫入資料庫的序號
+const seqResumeMargin = 1000
+
+// Sequencer 為訊息配發所屬對話中嚴格遞增的序號。
+// 序號不保證連續：寫入失敗、重複請求、自動消失的訊息與衝突後的重新配發都會留下空號，
+// 客戶端發現序號不連續時以 afterSeq 補查，補查後仍缺少的序號即可視為不存在
+type Sequencer interface {
+	// Assign 為訊息配發對話中的下一個序號，討論串回覆不配發
+	Assign(ctx context.Context, message *entities.Message) error
+	// Reassign 在訊息的序號與資料庫既有訊息衝突後，以資料庫的最大序號為準提高計數器並重新配發
+	Reassign(ctx context.Context, message *entities.Message) error
+}
+
+// sequencer 以 Redis INCR 配發序號，Redis 失敗時改由資料庫的計數器配發。
+// 資料庫是序號的唯一權威：訊息表以 (seq_scope, seq) 唯一索引拒絕重複的序號，
+// Redis 主從切換後計數器倒退，或其他副本仍以 Redis 配發時產生的重複序號，寫入時會被拒絕並重新配發。
+// 資料庫配發過的對話會記錄下來，Redis 恢復後先將計數器提高到已配發的序號加上 seqResumeMargin 再繼續使用 Redis
+type sequencer struct {
+	cache cache.SequenceCache
+	repo  repositories.SequenceRepository
+
+	mu      sync.Mutex
+	pending map[string]uint64 // 由資料庫配發過的對話及其最大序號，尚未同步到 Redis
+}
+
+// NewSequencer 創建新的序號配發器
+func NewSequencer(sequenceCache cache.SequenceCache, repo repositories.SequenceRepository) Sequencer {
+	return &sequencer{
+		cache:   sequenceCache,
+		repo:    repo,
+		pending: make(map[string]uint64),
+	}
+}
+
+func (s *sequencer) Assign(ctx context.Context, message *entities.Message) error {
+	if message.IsThreadReply() {
+		message.Seq, message.SeqScope = 0, nil
+		return nil
+	}
+
+	scope := entities.PinScopeOf(message)
+	seq, err := s.nextFromCache(ctx, message, scope)
+	if err != nil {
+		return s.assignFromDatabase(ctx, message, scope, err)
+	}
+
+	message.Seq, message.SeqScope = seq, &scope
+	return nil
+}
+
+func (s *sequencer) Reassign(ctx context.Context, message *entities.Message) error {
+	if message.IsThreadReply() {
+		return s.Assign(ctx, message)
+	}
+
+	// 計數器落後於資料庫，提高到資料庫的最大序號之上後再配發
+	scope := entities.PinScopeOf(message)
+	current, err := s.repo.Current(ctx, message)
+	if err != nil {
+		return err
+	}
+	if err := s.cache.EnsureSeq(ctx, scope, current+seqResumeMargin); err != nil {
+		return s.assignFromDatabase(ctx, message, scope, err)
+	}
+	return s.Assign(ctx, message)
+}
+
+// assignFromDatabase Redis 不可用時由資料庫配發序號，並記錄待 Redis 恢復後同步
+func (s *sequencer) assignFromDatabase(ctx context.Context, message *entities.Message, scope string, cause error) error {
+	fmt.Printf("Redis 配發序號失敗，改由資料庫配發: scope=%s, err=%v\n", scope, cause)
+	seq, err := s.repo.Next(ctx, message)
+	if err != nil {
+		return err
+	}
+	s.markPending(scope, seq)
+
+	message.Seq, message.SeqScope = seq, &scope
+	return nil
+}
+
+// nextFromCache 以 Redis 配發序號，計數器不存在時以資料庫中的最大序號初始化
+func (s *sequencer) nextFromCache(ctx context.Context, message *entities.Message, scope string) (uint64, error) {
+	if err := s.syncPending(ctx); err != nil {
+		return 0, err
+	}
+
+	seq, ok, err := s.cache.NextSeq(ctx, scope)
+	if err != nil || ok {
+		return seq, err
+	}
+
+	// 首次使用或 Redis 資料遺失，多個副本同時初始化時 EnsureSeq 只會取較大值，不會倒退
+	current, err := s.repo.Current(ctx, message)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.cache.EnsureSeq(ctx, scope, current); err != nil {
+		return 0, err
+	}
+	seq, ok, err = s.cache.NextSeq(ctx, scope)
+	if err == nil && !ok {
+		err = errSequenceNotInitialized
+	}
+	return seq, err
+}
+
+// maxSeqConflictRetries 序號衝突時最多重新配發的次數
+const maxSeqConflictRetries = 3
+
+// createSequenced 配發序號並寫入訊息，序號與既有訊息衝突時重新配發後重試，
+// 其他錯誤（包含 client_msg_id 重複）直接返回
+func createSequenced(ctx context.Context, messageRepo repositories.MessageRepository, sequencer Sequencer, message *entities.Message) error {
+	if err := sequencer.Assign(ctx, message); err != nil {
+		return err
+	}
+	for attempt := 0; ; attempt++ {
+		err := messageRepo.Create(ctx, message)
+		if !errors.Is(err, repositories.ErrDuplicateSeq) || attempt >= maxSeqConflictRetries {
+			return err
+		}
+		fmt.Printf("訊息序號衝突，重新配發: scope=%s, seq=%d\n", entities.PinScopeOf(message), message.Seq)
+		if err := sequencer.Reassign(ctx, message); err != nil {
+			return err
+		}
+	}
+}
+
+// markPending 記錄由資料庫配發的序號
+func (s *sequencer) markPending(scope string, seq uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if seq > s.pending[scope] {
+		s.pending[scope] = seq
+	}
+}
+
+// syncPending 將資料庫配發過的序號同步到 Redis，任一對話同步失敗時返回錯誤，繼續由資料庫配發
+func (s *sequencer) syncPending(ctx context.Context) error {
+	s.mu.Lock()
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+		return nil
+	}
+	pending := make(map[string]uint64, len(s.pending))
+	for scope, seq := range s.pending {
+		pending[scope] = seq
+	}
+	s.mu.Unlock()
+
+	for scope, seq := range pending {
+		// 其他副本可能在 Redis 不可用期間也由資料庫配發了序號，額外提高計數器避免與其重複
+		if err := s.cache.EnsureSeq(ctx, scope, seq+seqResumeMargin); err != nil {
+			return err
+		}
+		s.mu.Lock()
+		// 同步期間可能又由資料庫配發了更大的序號，只移除已同步的部分
+		if s.pending[scope] == seq {
+			delete(s.pending, scope)
+		}
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+// withSeqCursor 將對話歷史的 ID 游標換算為該訊息的序號；
+// 訊息已刪除或沒有序號時保留 ID 游標，由資料庫以 ID 過濾
+func (uc *messageUseCase) withSeqCursor(ctx context.Context, query entities.HistoryQuery) entities.HistoryQuery {
+	if query.AfterSeq > 0 || query.BeforeSeq > 0 {
+		return query
+	}
+	cursorID := query.AfterID
+	if cursorID == 0 {
+		cursorID = query.BeforeID
+	}
+	if cursorID == 0 {
+		return query
+	}
+
+	cursor, err := uc.messageRepo.FindByID(ctx, cursorID)
+	if err != nil || cursor.Seq == 0 {
+		return query
+	}
+	if query.AfterID > 0 {
+		query.AfterSeq = cursor.Seq
+	} else {
+		query.BeforeSeq = cursor.Seq
+	}
+	query.AfterID, query.BeforeID = 0, 0
+	return query
+}
@@ -0,0 +1,140 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// StarEvent 收藏變更時同步到用戶其他裝置的內容，取消收藏時 Star 為 nil
+type StarEvent struct {
+	MessageID uint                  `json:"message_id"`
+	Starred   bool                  `json:"starred"`
+	Star      *entities.MessageStar `json:"star,omitempty"`
+}
+
+func (uc *messageUseCase) StarMessage(ctx context.Context, userID, messageID uint, note string) (*entities.MessageStar, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > entities.MaxStarNoteLength {
+		return nil, appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message":   "收藏備註過長",
+			"maxLength": entities.MaxStarNoteLength,
+		})
+	}
+
+	message, err := uc.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.participants(ctx, message, userID); err != nil {
+		return nil, err
+	}
+	if message.IsRecalled() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "訊息已撤回")
+	}
+	if message.IsSystem() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "系統通知無法收藏")
+	}
+
+	now := time.Now()
+	star := &entities.MessageStar{
+		UserID:    userID,
+		MessageID: messageID,
+		Note:      note,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if _, err := uc.starRepo.Star(ctx, star); err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	uc.publishStar(ctx, userID, &StarEvent{MessageID: messageID, Starred: true, Star: star})
+	return star, nil
+}
+
+func (uc *messageUseCase) UnstarMessage(ctx context.Context, userID, messageID uint) error {
+	// 只刪除用戶自己的收藏，不需檢查訊息是否仍可存取
+	removed, err := uc.starRepo.Unstar(ctx, userID, messageID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	if removed {
+		uc.publishStar(ctx, userID, &StarEvent{MessageID: messageID})
+	}
+	return nil
+}
+
+func (uc *messageUseCase) ListStarredMessages(ctx context.Context, userID uint, query entities.StarQuery) (*entities.StarPage, error) {
+	query = query.Normalize()
+
+	// 1. 收藏與訊息都從資料庫讀取，不受訊息快取過期影響
+	stars, err := uc.starRepo.ListByUser(ctx, userID, query)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+	hasMore := len(stars) > query.Limit
+	if hasMore {
+		stars = stars[:query.Limit]
+	}
+
+	ids := make([]uint, len(stars))
+	for i, star := range stars {
+		ids[i] = star.MessageID
+	}
+	messages, err := uc.messageRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": userID,
+		})
+	}
+
+	// 2. 略過已刪除、已撤回或已到自動消失時間的訊息，並清除殘留的收藏
+	now := time.Now()
+	byID := make(map[uint]*entities.Message, len(messages))
+	for _, msg := range messages {
+		byID[msg.ID] = msg
+	}
+	page := &entities.StarPage{Items: make([]*entities.StarredMessage, 0, len(stars)), HasMore: hasMore}
+	var stale []uint
+	for _, star := range stars {
+		msg, ok := byID[star.MessageID]
+		if !ok || msg.IsRecalled() || msg.IsExpired(now) {
+			stale = append(stale, star.MessageID)
+			continue
+		}
+		page.Items = append(page.Items, &entities.StarredMessage{Star: star, Message: msg})
+	}
+	if hasMore {
+		page.NextCursor = stars[len(stars)-1].ID
+	}
+	if len(stale) > 0 {
+		if err := uc.starRepo.DeleteByMessageIDs(ctx, stale); err != nil {
+			fmt.Printf("清除失效收藏失敗: %v\n", err)
+		}
+	}
+
+	// 3. 附加表情回應、引用與投票
+	visible := make([]*entities.Message, len(page.Items))
+	for i, item := range page.Items {
+		visible[i] = item.Message
+	}
+	uc.decorate(ctx, visible)
+	return page, nil
+}
+
+// publishStar 將收藏變更同步到用戶的其他裝置，失敗只記錄錯誤
+func (uc *messageUseCase) publishStar(ctx context.Context, userID uint, data *StarEvent) {
+	uc.publish(ctx, &Event{Type: EventMessageStarred, Data: data, Transient: true}, []uint{userID})
+}
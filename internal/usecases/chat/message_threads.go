@@ -0,0 +1,153 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"fmt"
+	"time"
+)
+
+// ThreadEvent 討論串有新回覆時推送給對話參與者的內容
+type ThreadEvent struct {
+	RootID       uint      `json:"root_id"`
+	RoomID       uint      `json:"room_id,omitempty"`
+	ReplyID      uint      `json:"reply_id"`
+	ReplyCount   int       `json:"reply_count"`
+	Participants []uint    `json:"participants"`
+	LastReplyAt  time.Time `json:"last_reply_at"`
+}
+
+func (uc *messageUseCase) GetThread(ctx context.Context, userID, rootID uint, query entities.HistoryQuery) (*entities.ThreadPage, error) {
+	root, err := uc.findMessage(ctx, rootID)
+	if err != nil {
+		return nil, err
+	}
+	// 已到自動消失時間的根訊息視為不存在，即使背景工作尚未刪除
+	if root.IsExpired(time.Now()) {
+		return nil, appErrors.New(enum.ErrMessageNotFound, map[string]interface{}{
+			"messageId": rootID,
+		})
+	}
+	if root.IsThreadReply() {
+		return nil, appErrors.New(enum.ErrInvalidInput, "討論串回覆不能作為根訊息")
+	}
+	if _, err := uc.participants(ctx, root, userID); err != nil {
+		return nil, err
+	}
+
+	// 討論串回覆沒有對話序號，只以 ID 游標分頁
+	query.BeforeSeq, query.AfterSeq = 0, 0
+	page, err := uc.messageRepo.FindThreadRepliesPage(ctx, rootID, query.Normalize())
+	if err != nil {
+		return nil, appErrors.Wrap(err, enum.ErrHistoryLoadFailed, map[string]interface{}{
+			"rootId": rootID,
+		})
+	}
+
+	uc.decorate(ctx, append([]*entities.Message{root}, page.Messages...))
+	return &entities.ThreadPage{Root: root, Replies: page.Messages, HasMore: page.HasMore}, nil
+}
+
+// prepareReply 驗證引用與討論串的目標訊息屬於同一對話，並將討論串歸到最上層的根訊息
+func (uc *messageUseCase) prepareReply(ctx context.Context, message *entities.Message) error {
+	if message.ThreadRootID != nil {
+		root, err := uc.replyTarget(ctx, message, *message.ThreadRootID)
+		if err != nil {
+			return err
+		}
+		if root.IsThreadReply() {
+			message.ThreadRootID = root.ThreadRootID
+		}
+	}
+
+	if message.ReplyToID != nil {
+		quoted, err := uc.replyTarget(ctx, message, *message.ReplyToID)
+		if err != nil {
+			return err
+		}
+		message.ReplyTo = entities.NewQuotedMessage(quoted)
+	}
+	return nil
+}
+
+// replyTarget 查詢被回覆的訊息，不屬於同一對話時視為無效輸入
+func (uc *messageUseCase) replyTarget(ctx context.Context, message *entities.Message, targetID uint) (*entities.Message, error) {
+	target, err := uc.findMessage(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !message.SameConversation(target) {
+		return nil, appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"messageId": targetID,
+			"reason":    "被回覆的訊息不屬於此對話",
+		})
+	}
+	return target, nil
+}
+
+// recordThreadReply 更新根訊息的討論串統計並通知所有參與者，失敗不影響回覆發送
+func (uc *messageUseCase) recordThreadReply(ctx context.Context, reply *entities.Message, recipients []uint) {
+	root, err := uc.messageRepo.AddThreadReply(ctx, *reply.ThreadRootID, reply.UserId, reply.CreatedAt)
+	if err != nil {
+		fmt.Printf("更新討論串失敗: rootID=%d, err=%v\n", *reply.ThreadRootID, err)
+		return
+	}
+
+	if err := uc.messageCacheRepo.UpdateMessage(ctx, root); err != nil {
+		fmt.Printf("更新快取中的根訊息失敗: %v\n", err)
+	}
+
+	uc.publish(ctx, &Event{Type: EventThreadUpdated, Data: &ThreadEvent{
+		RootID:       root.ID,
+		RoomID:       root.RoomID,
+		ReplyID:      reply.ID,
+		ReplyCount:   root.ThreadReplyCount,
+		Participants: root.ThreadParticipants,
+		LastReplyAt:  reply.CreatedAt,
+	}}, recipients)
+}
+
+// decorate 為一批訊息附加表情回應、引用預覽與投票結果
+func (uc *messageUseCase) decorate(ctx context.Context, messages []*entities.Message) {
+	uc.attachReactions(ctx, messages)
+	uc.attachQuotes(ctx, messages)
+	uc.attachPolls(ctx, messages)
+}
+
+// attachQuotes 以單一查詢為一批訊息附加被引用訊息的預覽，失敗時只記錄錯誤
+func (uc *messageUseCase) attachQuotes(ctx context.Context, messages []*entities.Message) {
+	var ids []uint
+	for _, msg := range messages {
+		if msg.ReplyToID != nil {
+			ids = append(ids, *msg.ReplyToID)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+
+	quoted, err := uc.messageRepo.FindByIDs(ctx, ids)
+	if err != nil {
+		fmt.Printf("獲取引用訊息失敗: %v\n", err)
+		return
+	}
+
+	// 已到自動消失時間的被引用訊息不顯示預覽
+	now := time.Now()
+	byID := make(map[uint]*entities.Message, len(quoted))
+	for _, msg := range quoted {
+		if !msg.IsExpired(now) {
+			byID[msg.ID] = msg
+		}
+	}
+	for _, msg := range messages {
+		if msg.ReplyToID == nil {
+			continue
+		}
+		if target, ok := byID[*msg.ReplyToID]; ok {
+			msg.ReplyTo = entities.NewQuotedMessage(target)
+		}
+	}
+}
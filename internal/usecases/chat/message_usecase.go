@@ -1,161 +1,707 @@
 package chat
 
 import (
+	"clean-architecture-gochat/internal/common/enum"
 	"clean-architecture-gochat/internal/domain/cache"
 	"clean-architecture-gochat/internal/domain/entities"
 	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/domain/search"
+	appErrors "clean-architecture-gochat/internal/errors"
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 )
 
+const (
+	defaultEditWindow   = 15 * time.Minute // 預設的訊息可編輯時間
+	defaultRecallWindow = 2 * time.Minute  // 預設的訊息可撤回時間
+	defaultPinLimit     = 50               // 預設每個對話可釘選的訊息數
+)
+
+// MessageSettings 訊息用例的可調整參數，零值欄位使用預設值
+type MessageSettings struct {
+	EditWindow   time.Duration // 發送後可編輯的時間
+	RecallWindow time.Duration // 發送後發送者可撤回的時間，群主與管理員不受限制
+	PinLimit     int           // 每個對話最多可釘選的訊息數
+}
+
+// withDefaults 為未設定的欄位套用預設值
+func (s MessageSettings) withDefaults() MessageSettings {
+	if s.EditWindow <= 0 {
+		s.EditWindow = defaultEditWindow
+	}
+	if s.RecallWindow <= 0 {
+		s.RecallWindow = defaultRecallWindow
+	}
+	if s.PinLimit <= 0 {
+		s.PinLimit = defaultPinLimit
+	}
+	return s
+}
+
+// MessageUseCase 統一的訊息用例：寫入資料庫、更新快取並即時推送給接收者
 type MessageUseCase interface {
 	// 發送私聊訊息
 	SendPrivateMessage(ctx context.Context, message *entities.Message) error
 	// 發送群聊訊息
 	SendGroupMessage(ctx context.Context, message *entities.Message) error
-	// 獲取私聊訊息歷史
-	GetPrivateMessageHistory(ctx context.Context, fromUserID, toUserID uint) ([]*entities.Message, error)
-	// 獲取群聊訊息歷史
-	GetGroupMessageHistory(ctx context.Context, roomID uint) ([]*entities.Message, error)
-	// 獲取用戶的最近訊息
-	GetUserRecentMessages(ctx context.Context, userID uint) ([]*entities.Message, error)
+	// 以游標分頁獲取私聊訊息歷史
+	GetPrivateMessageHistory(ctx context.Context, fromUserID, toUserID uint, query entities.HistoryQuery) (*entities.MessagePage, error)
+	// 以游標分頁獲取群聊訊息歷史
+	GetGroupMessageHistory(ctx context.Context, roomID uint, query entities.HistoryQuery) (*entities.MessagePage, error)
+	// 補發用戶離線期間的訊息
+	DeliverPendingMessages(ctx context.Context, userID uint) error
+	// 編輯自己發送的文字訊息
+	EditMessage(ctx context.Context, userID, messageID uint, content string) (*entities.Message, error)
+	// 撤回訊息，發送者受時間限制，群主與管理員可撤回任何群組訊息
+	RecallMessage(ctx context.Context, userID, messageID uint) (*entities.Message, error)
+	// 獲取訊息的編輯歷史
+	GetMessageRevisions(ctx context.Context, userID, messageID uint) ([]*entities.MessageRevision, error)
+	// 對訊息新增表情回應，返回更新後的統計
+	AddReaction(ctx context.Context, userID, messageID uint, emoji string) ([]entities.ReactionCount, error)
+	// 移除對訊息的表情回應，返回更新後的統計
+	RemoveReaction(ctx context.Context, userID, messageID uint, emoji string) ([]entities.ReactionCount, error)
+	// 以游標分頁獲取討論串的根訊息與回覆
+	GetThread(ctx context.Context, userID, rootID uint, query entities.HistoryQuery) (*entities.ThreadPage, error)
+	// 釘選訊息，群組限群主與管理員，私聊雙方皆可
+	PinMessage(ctx context.Context, userID, messageID uint) (*entities.MessagePin, error)
+	// 取消釘選訊息，權限與釘選相同
+	UnpinMessage(ctx context.Context, userID, messageID uint) error
+	// 列出對話中的釘選訊息，依釘選時間由新到舊排列
+	ListPinnedMessages(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) ([]*entities.PinnedMessage, error)
+	// 將多則訊息轉發到多個私聊或群組，返回建立的副本
+	ForwardMessages(ctx context.Context, userID uint, messageIDs []uint, targets []ForwardTarget) ([]*entities.Message, error)
+	// 設定對話的訊息自動消失時間，0 表示關閉，群組限群主與管理員
+	SetDisappearing(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, ttl time.Duration) error
+	// 獲取對話的訊息自動消失時間，未開啟時返回 0
+	GetDisappearing(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (time.Duration, error)
+	// 刪除已到自動消失時間的訊息並通知參與者，返回刪除的數量
+	PurgeExpiredMessages(ctx context.Context, now time.Time) (int, error)
+	// 在群組中建立投票，返回投票訊息
+	CreatePoll(ctx context.Context, poll *entities.Poll) (*entities.Message, error)
+	// 投票，options 為選項索引，空列表表示撤回投票，返回最新結果
+	VotePoll(ctx context.Context, userID, messageID uint, options []int) (*entities.PollResult, error)
+	// 獲取投票目前的結果與用戶選擇的選項
+	GetPoll(ctx context.Context, userID, messageID uint) (*entities.PollResult, error)
+	// 提前截止投票，限建立者、群主與管理員
+	ClosePoll(ctx context.Context, userID, messageID uint) (*entities.PollResult, error)
+	// 截止已到截止時間的投票並推送最終結果，返回成功處理的數量
+	CloseDuePolls(ctx context.Context, now time.Time) (int, error)
+	// 收藏可存取的訊息並附加個人備註，已收藏時更新備註
+	StarMessage(ctx context.Context, userID, messageID uint, note string) (*entities.MessageStar, error)
+	// 取消收藏訊息
+	UnstarMessage(ctx context.Context, userID, messageID uint) error
+	// 以游標分頁列出用戶跨對話的收藏，依收藏時間由新到舊排列
+	ListStarredMessages(ctx context.Context, userID uint, query entities.StarQuery) (*entities.StarPage, error)
 }
 
 type messageUseCase struct {
 	messageRepo      repositories.MessageRepository
 	messageCacheRepo cache.MessageCacheRepository
+	unreadCounter    cache.UnreadCounterCache
 	groupRepo        repositories.GroupRepository
+	conversationRepo repositories.ConversationRepository
+	reactionRepo     repositories.ReactionRepository
+	pinRepo          repositories.PinRepository
+	pollRepo         repositories.PollRepository
+	starRepo         repositories.StarRepository
+	searchIndex      search.MessageIndex
+	linkPreviewer    LinkPreviewer
+	sequencer        Sequencer
+	moderation       ModerationStage
+	publisher        EventPublisher
+	settings         MessageSettings
+}
+
+// MessageDeps 訊息用例的依賴，以欄位名稱指定，新增依賴時既有的呼叫者不需修改。
+// Moderation 為 nil 時不審核訊息，Settings 未設定的欄位套用預設值
+type MessageDeps struct {
+	MessageRepo      repositories.MessageRepository
+	MessageCache     cache.MessageCacheRepository
+	UnreadCounter    cache.UnreadCounterCache
+	GroupRepo        repositories.GroupRepository
+	ConversationRepo repositories.ConversationRepository
+	ReactionRepo     repositories.ReactionRepository
+	PinRepo          repositories.PinRepository
+	PollRepo         repositories.PollRepository
+	StarRepo         repositories.StarRepository
+	SearchIndex      search.MessageIndex
+	LinkPreviewer    LinkPreviewer
+	Sequencer        Sequencer
+	Moderation       ModerationStage
+	Publisher        EventPublisher
+	Settings         MessageSettings
 }
 
 // NewMessageUseCase 創建新的訊息用例
-func NewMessageUseCase(
-	messageRepo repositories.MessageRepository,
-	messageCacheRepo cache.MessageCacheRepository,
-	groupRepo repositories.GroupRepository,
-) MessageUseCase {
+func NewMessageUseCase(deps MessageDeps) MessageUseCase {
 	return &messageUseCase{
-		messageRepo:      messageRepo,
-		messageCacheRepo: messageCacheRepo,
-		groupRepo:        groupRepo,
+		messageRepo:      deps.MessageRepo,
+		messageCacheRepo: deps.MessageCache,
+		unreadCounter:    deps.UnreadCounter,
+		groupRepo:        deps.GroupRepo,
+		conversationRepo: deps.ConversationRepo,
+		reactionRepo:     deps.ReactionRepo,
+		pinRepo:          deps.PinRepo,
+		pollRepo:         deps.PollRepo,
+		starRepo:         deps.StarRepo,
+		searchIndex:      deps.SearchIndex,
+		linkPreviewer:    deps.LinkPreviewer,
+		sequencer:        deps.Sequencer,
+		moderation:       deps.Moderation,
+		publisher:        deps.Publisher,
+		settings:         deps.Settings.withDefaults(),
 	}
 }
 
 func (uc *messageUseCase) SendPrivateMessage(ctx context.Context, message *entities.Message) error {
-	// 1. 儲存訊息到資料庫
-	if err := uc.messageRepo.Create(ctx, message); err != nil {
-		return fmt.Errorf("儲存訊息到資料庫失敗: %v", err)
+	if message == nil {
+		return appErrors.New(enum.ErrInvalidInput, "消息不能為空")
+	}
+	if message.UserId == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "發送者ID不能為空")
+	}
+	if message.TargetId == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "接收者ID不能為空")
+	}
+	if message.Media == entities.MediaTypePoll {
+		return appErrors.New(enum.ErrInvalidInput, "投票只能在群組中建立")
+	}
+
+	message.Type = entities.MessageTypePrivate
+	prepareMessage(message)
+	if err := renderRichText(message); err != nil {
+		return err
+	}
+	if err := uc.prepareReply(ctx, message); err != nil {
+		return err
+	}
+	if err := uc.applyDisappearing(ctx, message); err != nil {
+		return err
+	}
+	if err := uc.moderate(ctx, message, true); err != nil {
+		return err
 	}
 
-	// 2. 儲存訊息到快取
+	// 1. 儲存訊息到資料庫，重複的 client_msg_id 直接返回原訊息
+	duplicate, err := createMessageOnce(ctx, uc.messageRepo, uc.messageCacheRepo, uc.sequencer, message)
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrMessageSendFailed, map[string]interface{}{
+			"userId":   message.UserId,
+			"targetId": message.TargetId,
+		})
+	}
+	if duplicate {
+		return nil
+	}
+	uc.indexMessage(ctx, message)
+	uc.unfurlLinks(message)
+
+	// 2. 討論串回覆只更新根訊息，不進入主對話的快取與會話
+	recipients := []uint{message.UserId, message.TargetId}
+	if message.IsThreadReply() {
+		uc.deliver(ctx, message, recipients)
+		uc.recordThreadReply(ctx, message, recipients)
+		return nil
+	}
+
+	// 3. 儲存訊息到快取
 	if err := uc.messageCacheRepo.StorePrivateMessage(ctx, message); err != nil {
 		// 快取失敗不影響主要功能，只記錄錯誤
 		fmt.Printf("儲存訊息到快取失敗: %v\n", err)
 	}
 
+	// 4. 更新雙方會話並推送給接收者
+	uc.recordConversation(ctx, message, recipients)
+	uc.deliver(ctx, message, recipients)
+
 	return nil
 }
 
 func (uc *messageUseCase) SendGroupMessage(ctx context.Context, message *entities.Message) error {
+	if message == nil {
+		return appErrors.New(enum.ErrInvalidInput, "消息不能為空")
+	}
+	if message.Media == entities.MediaTypePoll {
+		return appErrors.New(enum.ErrInvalidInput, "投票需透過投票功能建立")
+	}
+	return uc.sendGroupMessage(ctx, message, nil)
+}
+
+// sendGroupMessage 發送群聊訊息，poll 不為 nil 時在訊息寫入後建立對應的投票
+func (uc *messageUseCase) sendGroupMessage(ctx context.Context, message *entities.Message, poll *entities.Poll) error {
+	if message.UserId == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "發送者ID不能為空")
+	}
+	if message.RoomID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "聊天室ID不能為空")
+	}
+
 	// 1. 檢查群組是否存在
 	group, err := uc.groupRepo.FindByID(ctx, message.RoomID)
 	if err != nil {
-		return fmt.Errorf("群組不存在: %v", err)
+		return appErrors.Wrap(err, enum.ErrGroupNotFound, map[string]interface{}{
+			"roomId": message.RoomID,
+		})
 	}
 
 	// 2. 檢查發送者是否為群組成員
 	isMember, err := uc.groupRepo.IsMember(ctx, group.ID, message.UserId)
 	if err != nil {
-		return fmt.Errorf("檢查群組成員失敗: %v", err)
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": message.RoomID,
+			"userId": message.UserId,
+		})
 	}
 	if !isMember {
-		return fmt.Errorf("用戶不是群組成員")
+		return appErrors.New(enum.ErrNotGroupMember, map[string]interface{}{
+			"roomId": message.RoomID,
+			"userId": message.UserId,
+		})
 	}
 
-	// 3. 儲存訊息到資料庫
-	if err := uc.messageRepo.Create(ctx, message); err != nil {
-		return fmt.Errorf("儲存訊息到資料庫失敗: %v", err)
+	message.Type = entities.MessageTypeGroup
+	prepareMessage(message)
+	if err := renderRichText(message); err != nil {
+		return err
+	}
+	if err := uc.prepareReply(ctx, message); err != nil {
+		return err
+	}
+	if err := uc.resolveMentions(ctx, message); err != nil {
+		return err
+	}
+	if err := uc.applyDisappearing(ctx, message); err != nil {
+		return err
+	}
+	// 投票無法暫扣等待審核，需審核的投票直接拒絕
+	if err := uc.moderate(ctx, message, poll == nil); err != nil {
+		return err
+	}
+	if poll != nil {
+		poll.Question = message.Content
 	}
 
-	// 4. 儲存訊息到快取
-	if err := uc.messageCacheRepo.StoreGroupMessage(ctx, message); err != nil {
-		// 快取失敗不影響主要功能，只記錄錯誤
-		fmt.Printf("儲存訊息到快取失敗: %v\n", err)
+	// 3. 儲存訊息到資料庫，重複的 client_msg_id 直接返回原訊息
+	duplicate, err := createMessageOnce(ctx, uc.messageRepo, uc.messageCacheRepo, uc.sequencer, message)
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrMessageSendFailed, map[string]interface{}{
+			"userId": message.UserId,
+			"roomId": message.RoomID,
+		})
+	}
+	if duplicate {
+		return nil
+	}
+	if poll != nil {
+		if err := uc.createPoll(ctx, message, poll); err != nil {
+			return err
+		}
 	}
+	uc.indexMessage(ctx, message)
+	uc.unfurlLinks(message)
+
+	// 4. 儲存訊息到快取，討論串回覆不進入主對話的快取
+	if !message.IsThreadReply() {
+		if err := uc.messageCacheRepo.StoreGroupMessage(ctx, message); err != nil {
+			// 快取失敗不影響主要功能，只記錄錯誤
+			fmt.Printf("儲存訊息到快取失敗: %v\n", err)
+		}
+	}
+	if poll != nil {
+		// 投票結果不寫入快取，推送時附上初始的選項
+		message.Poll = entities.NewPollResult(poll, nil, 0)
+	}
+
+	// 5. 更新成員會話並推送給其他群組成員
+	members, err := uc.groupRepo.GetMembers(ctx, group.ID)
+	if err != nil {
+		fmt.Printf("獲取群組成員失敗，略過會話更新與即時推送: %v\n", err)
+		return nil
+	}
+	if message.IsThreadReply() {
+		uc.deliver(ctx, message, members)
+		uc.recordThreadReply(ctx, message, members)
+		uc.notifyMentions(ctx, message, members)
+		return nil
+	}
+	uc.recordConversation(ctx, message, members)
+	uc.deliver(ctx, message, members)
+	uc.notifyMentions(ctx, message, members)
 
 	return nil
 }
 
-func (uc *messageUseCase) GetPrivateMessageHistory(ctx context.Context, fromUserID, toUserID uint) ([]*entities.Message, error) {
+func (uc *messageUseCase) GetPrivateMessageHistory(ctx context.Context, fromUserID, toUserID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	query = uc.withSeqCursor(ctx, query.Normalize())
+
 	// 1. 先嘗試從快取獲取
-	messages, err := uc.messageCacheRepo.GetPrivateMessages(ctx, fromUserID, toUserID)
-	if err == nil && len(messages) > 0 {
-		return messages, nil
+	page, hit, err := uc.messageCacheRepo.GetPrivateMessagesPage(ctx, fromUserID, toUserID, query)
+	if err == nil && hit {
+		uc.decorate(ctx, page.Messages)
+		return page, nil
 	}
 
-	// 2. 快取未命中，從資料庫獲取
-	messages, err = uc.messageRepo.FindMessagesBetweenUsers(ctx, fromUserID, toUserID)
+	// 2. 快取無法回答，從資料庫獲取
+	page, err = uc.messageRepo.FindMessagesBetweenUsersPage(ctx, fromUserID, toUserID, warmQuery(query))
 	if err != nil {
-		return nil, fmt.Errorf("從資料庫獲取訊息失敗: %v", err)
+		return nil, appErrors.Wrap(err, enum.ErrHistoryLoadFailed, map[string]interface{}{
+			"fromUserId": fromUserID,
+			"toUserId":   toUserID,
+		})
 	}
 
-	// 3. 更新快取（非阻塞）
-	go func() {
-		for _, msg := range messages {
-			if err := uc.messageCacheRepo.StorePrivateMessage(context.Background(), msg); err != nil {
+	// 3. 以最新一頁重建快取（非阻塞）
+	if query.IsLatest() {
+		messages := snapshotMessages(page.Messages)
+		go func() {
+			cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := uc.messageCacheRepo.WarmPrivateMessages(cacheCtx, fromUserID, toUserID, messages); err != nil {
 				fmt.Printf("更新訊息快取失敗: %v\n", err)
 			}
-		}
-	}()
+		}()
+		page = latestPage(page, query.Limit)
+	}
 
-	return messages, nil
+	uc.decorate(ctx, page.Messages)
+	return page, nil
 }
 
-func (uc *messageUseCase) GetGroupMessageHistory(ctx context.Context, roomID uint) ([]*entities.Message, error) {
+func (uc *messageUseCase) GetGroupMessageHistory(ctx context.Context, roomID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	query = uc.withSeqCursor(ctx, query.Normalize())
+
 	// 1. 先嘗試從快取獲取
-	messages, err := uc.messageCacheRepo.GetGroupMessages(ctx, roomID)
-	if err == nil && len(messages) > 0 {
-		return messages, nil
+	page, hit, err := uc.messageCacheRepo.GetGroupMessagesPage(ctx, roomID, query)
+	if err == nil && hit {
+		uc.decorate(ctx, page.Messages)
+		return page, nil
 	}
 
-	// 2. 快取未命中，從資料庫獲取
-	messages, err = uc.messageRepo.FindMessagesByRoomID(ctx, roomID)
+	// 2. 快取無法回答，從資料庫獲取
+	page, err = uc.messageRepo.FindMessagesByRoomIDPage(ctx, roomID, warmQuery(query))
 	if err != nil {
-		return nil, fmt.Errorf("從資料庫獲取訊息失敗: %v", err)
+		return nil, appErrors.Wrap(err, enum.ErrHistoryLoadFailed, map[string]interface{}{
+			"roomId": roomID,
+		})
 	}
 
-	// 3. 更新快取（非阻塞）
-	go func() {
-		for _, msg := range messages {
-			if err := uc.messageCacheRepo.StoreGroupMessage(context.Background(), msg); err != nil {
+	// 3. 以最新一頁重建快取（非阻塞）
+	if query.IsLatest() {
+		messages := snapshotMessages(page.Messages)
+		go func() {
+			cacheCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := uc.messageCacheRepo.WarmGroupMessages(cacheCtx, roomID, messages); err != nil {
 				fmt.Printf("更新訊息快取失敗: %v\n", err)
 			}
+		}()
+		page = latestPage(page, query.Limit)
+	}
+
+	uc.decorate(ctx, page.Messages)
+	return page, nil
+}
+
+func (uc *messageUseCase) DeliverPendingMessages(ctx context.Context, userID uint) error {
+	return uc.publisher.DeliverPending(ctx, userID)
+}
+
+func (uc *messageUseCase) EditMessage(ctx context.Context, userID, messageID uint, content string) (*entities.Message, error) {
+	if strings.TrimSpace(content) == "" {
+		return nil, appErrors.New(enum.ErrInvalidInput, "消息內容不能為空")
+	}
+
+	// 1. 檢查訊息是否可由此用戶編輯
+	message, err := uc.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.UserId != userID {
+		return nil, appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+			"messageId": messageID,
+			"userId":    userID,
+		})
+	}
+	if message.IsRecalled() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "訊息已撤回")
+	}
+	if message.IsSystem() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "系統通知無法編輯")
+	}
+	if message.ForwardOrigin() != nil {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "轉發的訊息無法編輯")
+	}
+	if !message.IsTextual() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "只能編輯文字訊息")
+	}
+	if time.Since(message.CreatedAt) > uc.settings.EditWindow {
+		return nil, appErrors.New(enum.ErrMessageEditExpired, map[string]interface{}{
+			"messageId":  messageID,
+			"editWindow": uc.settings.EditWindow.String(),
+		})
+	}
+	if message.Content == content {
+		return message, nil
+	}
+
+	recipients, err := uc.participants(ctx, message, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. 保存舊版本並更新訊息
+	now := time.Now()
+	revision := &entities.MessageRevision{
+		MessageID: message.ID,
+		Content:   message.Content,
+		EditedBy:  userID,
+		CreatedAt: now,
+	}
+	message.Content = content
+	message.EditedAt = &now
+	message.UpdatedAt = now
+	// 編輯無法暫扣等待審核，需審核的內容直接拒絕
+	if err := uc.moderate(ctx, message, false); err != nil {
+		return nil, err
+	}
+	if err := renderRichText(message); err != nil {
+		return nil, err
+	}
+	// 舊內容的連結預覽不再適用，新內容的預覽在背景重新產生
+	if err := message.SetLinkPreviews(nil); err != nil {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "無效的訊息附加資料")
+	}
+	// 群組訊息依新內容重新解析提及，編輯不會再次通知
+	if message.IsGroupConversation() {
+		if err := uc.resolveMentions(ctx, message); err != nil {
+			return nil, err
 		}
-	}()
+	}
+	if err := uc.messageRepo.UpdateContent(ctx, message, revision); err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	// 3. 同步快取與會話預覽
+	if err := uc.messageCacheRepo.UpdateMessage(ctx, message); err != nil {
+		// 快取失敗不影響主要功能，只記錄錯誤
+		fmt.Printf("更新訊息快取失敗: %v\n", err)
+	}
+	if err := uc.conversationRepo.RefreshPreview(ctx, message); err != nil {
+		fmt.Printf("更新會話預覽失敗: messageID=%d, err=%v\n", message.ID, err)
+	}
+	uc.invalidatePins(ctx, message)
+	uc.indexMessage(ctx, message)
 
-	return messages, nil
+	// 4. 通知所有參與者（包含發送者的其他裝置）
+	uc.publish(ctx, &Event{Type: EventMessageEdited, Data: message}, recipients)
+	uc.unfurlLinks(message)
+
+	return message, nil
 }
 
-func (uc *messageUseCase) GetUserRecentMessages(ctx context.Context, userID uint) ([]*entities.Message, error) {
-	// 1. 先嘗試從快取獲取
-	messages, err := uc.messageCacheRepo.GetUserMessageList(ctx, userID)
-	if err == nil && len(messages) > 0 {
-		return messages, nil
+func (uc *messageUseCase) RecallMessage(ctx context.Context, userID, messageID uint) (*entities.Message, error) {
+	// 1. 檢查用戶是否有權撤回
+	message, err := uc.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if message.IsRecalled() {
+		return message, nil
+	}
+	if message.IsSystem() {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "系統通知無法撤回")
 	}
 
-	// 2. 快取未命中，從資料庫獲取
-	messages, err = uc.messageRepo.FindMessagesByUserID(ctx, userID)
+	recipients, err := uc.participants(ctx, message, userID)
 	if err != nil {
-		return nil, fmt.Errorf("從資料庫獲取訊息失敗: %v", err)
+		return nil, err
+	}
+	if err := uc.checkRecallPermission(ctx, message, userID); err != nil {
+		return nil, err
 	}
 
-	// 3. 更新快取（非阻塞）
-	go func() {
-		for _, msg := range messages {
-			if err := uc.messageCacheRepo.StorePrivateMessage(context.Background(), msg); err != nil {
-				fmt.Printf("更新訊息快取失敗: %v\n", err)
-			}
+	// 2. 將訊息改寫為墓碑，並刪除保存原始內容的歷史版本與釘選
+	message.Recall(userID, time.Now())
+	if err := uc.messageRepo.Recall(ctx, message); err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+
+	// 3. 清除快取與尚未送達的離線事件中的原始內容
+	if err := uc.messageCacheRepo.UpdateMessage(ctx, message); err != nil {
+		// 快取失敗不影響主要功能，只記錄錯誤
+		fmt.Printf("更新訊息快取失敗: %v\n", err)
+	}
+	if err := uc.publisher.DiscardPending(ctx, recipients, message.ID); err != nil {
+		fmt.Printf("清除離線事件失敗: messageID=%d, err=%v\n", message.ID, err)
+	}
+	if err := uc.conversationRepo.RefreshPreview(ctx, message); err != nil {
+		fmt.Printf("更新會話預覽失敗: messageID=%d, err=%v\n", message.ID, err)
+	}
+	uc.invalidatePins(ctx, message)
+	uc.indexMessage(ctx, message)
+
+	// 4. 通知所有參與者
+	uc.publish(ctx, &Event{Type: EventMessageRecalled, Data: message}, recipients)
+
+	return message, nil
+}
+
+// checkRecallPermission 發送者可在時限內撤回；群組訊息另允許群主與管理員不限時撤回
+func (uc *messageUseCase) checkRecallPermission(ctx context.Context, message *entities.Message, userID uint) error {
+	if message.IsGroupConversation() {
+		isManager, err := isGroupManager(ctx, uc.groupRepo, message.RoomID, userID)
+		if err != nil {
+			return err
 		}
-	}()
+		if isManager {
+			return nil
+		}
+	}
+
+	if message.UserId != userID {
+		return appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+			"messageId": message.ID,
+			"userId":    userID,
+		})
+	}
+	if time.Since(message.CreatedAt) > uc.settings.RecallWindow {
+		return appErrors.New(enum.ErrMessageRecallExpired, map[string]interface{}{
+			"messageId":    message.ID,
+			"recallWindow": uc.settings.RecallWindow.String(),
+		})
+	}
+	return nil
+}
+
+func (uc *messageUseCase) GetMessageRevisions(ctx context.Context, userID, messageID uint) ([]*entities.MessageRevision, error) {
+	message, err := uc.findMessage(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := uc.participants(ctx, message, userID); err != nil {
+		return nil, err
+	}
 
-	return messages, nil
+	revisions, err := uc.messageRepo.FindRevisions(ctx, messageID)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+	return revisions, nil
+}
+
+// findMessage 依ID查詢訊息並轉換為應用錯誤
+func (uc *messageUseCase) findMessage(ctx context.Context, messageID uint) (*entities.Message, error) {
+	message, err := uc.messageRepo.FindByID(ctx, messageID)
+	if errors.Is(err, repositories.ErrMessageNotFound) {
+		return nil, appErrors.New(enum.ErrMessageNotFound, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"messageId": messageID,
+		})
+	}
+	return message, nil
+}
+
+// participants 返回訊息所屬對話的所有參與者，並確認 userID 為其中之一
+func (uc *messageUseCase) participants(ctx context.Context, message *entities.Message, userID uint) ([]uint, error) {
+	var members []uint
+	if message.IsGroupConversation() {
+		var err error
+		members, err = uc.groupRepo.GetMembers(ctx, message.RoomID)
+		if err != nil {
+			return nil, appErrors.NewDBError(err, map[string]interface{}{
+				"roomId": message.RoomID,
+			})
+		}
+	} else {
+		members = []uint{message.UserId, message.TargetId}
+	}
+
+	for _, member := range members {
+		if member == userID {
+			return members, nil
+		}
+	}
+	return nil, appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+		"messageId": message.ID,
+		"userId":    userID,
+	})
+}
+
+// recordConversation 更新發送者與接收者的會話及未讀計數器，失敗不影響訊息發送
+func (uc *messageUseCase) recordConversation(ctx context.Context, message *entities.Message, recipients []uint) {
+	if err := uc.conversationRepo.RecordMessage(ctx, message, recipients); err != nil {
+		fmt.Printf("更新會話失敗: messageID=%d, err=%v\n", message.ID, err)
+		return
+	}
+	if err := uc.unreadCounter.RecordMessage(ctx, message, recipients); err != nil {
+		fmt.Printf("更新未讀計數失敗: messageID=%d, err=%v\n", message.ID, err)
+	}
+}
+
+// deliver 將新訊息推送給除發送者外的所有接收者
+func (uc *messageUseCase) deliver(ctx context.Context, message *entities.Message, recipients []uint) {
+	targets := make([]uint, 0, len(recipients))
+	for _, userID := range recipients {
+		if userID != message.UserId {
+			targets = append(targets, userID)
+		}
+	}
+
+	uc.publish(ctx, &Event{Type: EventMessageNew, Data: message}, targets)
+}
+
+// publish 推送事件給指定用戶，失敗只記錄錯誤
+func (uc *messageUseCase) publish(ctx context.Context, event *Event, userIDs []uint) {
+	if err := uc.publisher.Publish(ctx, userIDs, event); err != nil {
+		fmt.Printf("推送事件失敗: event=%s, err=%v\n", event.Type, err)
+	}
+}
+
+// warmQuery 查詢最新一頁時多取一筆，寫入快取後之後的最新一頁查詢才能確定快取中的結果完整
+func warmQuery(query entities.HistoryQuery) entities.HistoryQuery {
+	if query.IsLatest() {
+		query.Limit++
+	}
+	return query
+}
+
+// latestPage 將多取一筆的最新一頁裁成 limit 則，只保留較新的訊息
+func latestPage(page *entities.MessagePage, limit int) *entities.MessagePage {
+	if len(page.Messages) <= limit {
+		return page
+	}
+	return &entities.MessagePage{Messages: page.Messages[len(page.Messages)-limit:], HasMore: true}
+}
+
+// snapshotMessages 複製訊息供背景寫入快取，避免與之後附加表情回應的修改互相競爭
+func snapshotMessages(messages []*entities.Message) []*entities.Message {
+	snapshot := make([]*entities.Message, len(messages))
+	for i, msg := range messages {
+		copied := *msg
+		snapshot[i] = &copied
+	}
+	return snapshot
+}
+
+// prepareMessage 補齊訊息的預設媒體類型與時間戳
+func prepareMessage(message *entities.Message) {
+	if message.Media == 0 {
+		message.Media = entities.MediaTypeText
+	}
+	now := time.Now()
+	message.CreatedAt = now
+	message.UpdatedAt = now
 }
@@ -0,0 +1,326 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Moderator 判斷訊息內容的處理方式，內建關鍵字與正規表達式規則，也可替換為外部審核服務
+type Moderator interface {
+	// Moderate 以訊息所屬對話適用的規則審核內容，未命中任何規則時返回 allow
+	Moderate(ctx context.Context, message *entities.Message) (*entities.ModerationDecision, error)
+}
+
+// compiledRule 編譯後的審核規則，關鍵字轉為不分大小寫的字面比對
+type compiledRule struct {
+	rule  *entities.ModerationRule
+	label string
+	re    *regexp.Regexp
+}
+
+// compileModerationRule 驗證並編譯審核規則
+func compileModerationRule(rule *entities.ModerationRule) (*compiledRule, error) {
+	pattern := rule.Pattern
+	if strings.TrimSpace(pattern) == "" || utf8.RuneCountInString(pattern) > entities.MaxModerationPatternLength {
+		return nil, fmt.Errorf("規則內容不能為空且不可超過 %d 個字元", entities.MaxModerationPatternLength)
+	}
+	if !rule.Action.IsValid() {
+		return nil, fmt.Errorf("規則的處理方式只支援 mask、hold 與 reject")
+	}
+	if !rule.IsRegex {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	re, err := regexp.Compile("(?i)" + pattern)
+	if err != nil {
+		return nil, fmt.Errorf("無效的正規表達式: %v", err)
+	}
+	if re.MatchString("") {
+		return nil, fmt.Errorf("正規表達式不可比對空字串")
+	}
+
+	label := "global:" + rule.Pattern
+	if rule.GroupID != 0 {
+		label = fmt.Sprintf("group#%d:%s", rule.ID, rule.Pattern)
+	}
+	return &compiledRule{rule: rule, label: label, re: re}, nil
+}
+
+type ruleModerator struct {
+	global    []*compiledRule
+	rulesRepo repositories.ModerationRepository
+	compiled  sync.Map // 群組規則內容 → *regexp.Regexp，避免每則訊息重新編譯
+}
+
+// NewRuleModerator 創建以關鍵字與正規表達式審核的 Moderator。
+// global 為套用到所有對話的全域規則，群組訊息另外套用群組自訂的規則，全域規則無效時返回錯誤
+func NewRuleModerator(global []entities.ModerationRule, rulesRepo repositories.ModerationRepository) (Moderator, error) {
+	m := &ruleModerator{rulesRepo: rulesRepo}
+	for i := range global {
+		rule := global[i]
+		rule.GroupID = 0
+		compiled, err := compileModerationRule(&rule)
+		if err != nil {
+			return nil, fmt.Errorf("全域審核規則 %q: %w", rule.Pattern, err)
+		}
+		m.global = append(m.global, compiled)
+	}
+	return m, nil
+}
+
+func (m *ruleModerator) Moderate(ctx context.Context, message *entities.Message) (*entities.ModerationDecision, error) {
+	rules := m.global
+	if message.IsGroupConversation() {
+		groupRules, err := m.groupRules(ctx, message.RoomID)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(append([]*compiledRule{}, rules...), groupRules...)
+	}
+	return applyModerationRules(rules, message), nil
+}
+
+// groupRules 載入並編譯群組規則，無法編譯的規則（例如舊版本寫入的）會被略過
+func (m *ruleModerator) groupRules(ctx context.Context, groupID uint) ([]*compiledRule, error) {
+	rules, err := m.rulesRepo.ListRules(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	compiled := make([]*compiledRule, 0, len(rules))
+	for _, rule := range rules {
+		key := fmt.Sprintf("%t:%s", rule.IsRegex, rule.Pattern)
+		if re, ok := m.compiled.Load(key); ok {
+			compiled = append(compiled, &compiledRule{rule: rule, label: fmt.Sprintf("group#%d:%s", rule.ID, rule.Pattern), re: re.(*regexp.Regexp)})
+			continue
+		}
+		c, err := compileModerationRule(rule)
+		if err != nil {
+			fmt.Printf("略過無效的審核規則: ruleID=%d, err=%v\n", rule.ID, err)
+			continue
+		}
+		m.compiled.Store(key, c.re)
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// applyModerationRules 比對所有規則，處理方式取命中規則中最嚴格的一項，並遮蔽所有命中的內容
+func applyModerationRules(rules []*compiledRule, message *entities.Message) *entities.ModerationDecision {
+	decision := &entities.ModerationDecision{Action: entities.ModerationAllow, Content: message.Content}
+	mask := "*"
+	if message.IsRichText() {
+		// 富文本中的星號是強調語法，需跳脫
+		mask = `\*`
+	}
+	for _, rule := range rules {
+		if !rule.re.MatchString(message.Content) {
+			continue
+		}
+		decision.Action = decision.Action.Stricter(rule.rule.Action)
+		decision.Matches = append(decision.Matches, rule.label)
+		decision.Content = rule.re.ReplaceAllStringFunc(decision.Content, func(matched string) string {
+			return strings.Repeat(mask, utf8.RuneCountInString(matched))
+		})
+	}
+	return decision
+}
+
+// moderationBypassKey 標記訊息已由管理員核准，發送時不再審核
+type moderationBypassKey struct{}
+
+func withModerationBypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, moderationBypassKey{}, true)
+}
+
+func moderationBypassed(ctx context.Context) bool {
+	bypassed, _ := ctx.Value(moderationBypassKey{}).(bool)
+	return bypassed
+}
+
+// ModerationStage 發送流程中寫入訊息前的審核階段：套用審核結果、將需審核的訊息放入佇列並寫入稽核日誌
+type ModerationStage interface {
+	// Screen 審核即將寫入的訊息。mask 時直接改寫 message.Content；
+	// hold 時放入審核佇列並返回 MESSAGE_HELD，canHold 為 false（例如編輯或投票）時改為拒絕；
+	// reject 時返回 MESSAGE_REJECTED
+	Screen(ctx context.Context, message *entities.Message, canHold bool) error
+}
+
+type moderationStage struct {
+	moderator      Moderator
+	moderationRepo repositories.ModerationRepository
+	groupRepo      repositories.GroupRepository
+	publisher      EventPublisher
+	reviewers      []uint
+}
+
+// NewModerationStage 創建審核階段，reviewers 為可審核所有對話的全域審核者，
+// 群組訊息另外由群主與管理員審核；沒有全域審核者時私聊訊息無法暫扣，需審核的私聊訊息會被拒絕
+func NewModerationStage(
+	moderator Moderator,
+	moderationRepo repositories.ModerationRepository,
+	groupRepo repositories.GroupRepository,
+	publisher EventPublisher,
+	reviewers []uint,
+) ModerationStage {
+	return &moderationStage{
+		moderator:      moderator,
+		moderationRepo: moderationRepo,
+		groupRepo:      groupRepo,
+		publisher:      publisher,
+		reviewers:      reviewers,
+	}
+}
+
+func (s *moderationStage) Screen(ctx context.Context, message *entities.Message, canHold bool) error {
+	if moderationBypassed(ctx) || (!message.IsTextual() && message.Media != entities.MediaTypePoll) {
+		return nil
+	}
+
+	decision, err := s.moderator.Moderate(ctx, message)
+	if err != nil {
+		return appErrors.Wrap(err, enum.ErrMessageSendFailed, map[string]interface{}{
+			"reason": "內容審核失敗",
+			"userId": message.UserId,
+		})
+	}
+	if decision.Action == entities.ModerationAllow {
+		return nil
+	}
+
+	action := decision.Action
+	if action == entities.ModerationHold && (!canHold || (!message.IsGroupConversation() && len(s.reviewers) == 0)) {
+		action = entities.ModerationReject
+	}
+	convType, targetID := entities.ConversationOf(message, message.UserId)
+	log := &entities.ModerationLog{
+		Type:      convType,
+		TargetID:  targetID,
+		UserID:    message.UserId,
+		Event:     entities.ModerationEventScreened,
+		Action:    action,
+		MessageID: message.ID,
+		Detail:    truncateRunes(strings.Join(decision.Matches, "\n"), 1000),
+	}
+
+	switch action {
+	case entities.ModerationMask:
+		message.Content = decision.Content
+		s.writeLog(ctx, log)
+		return nil
+	case entities.ModerationHold:
+		held, err := s.hold(ctx, message, convType, targetID, log.Detail)
+		if err != nil {
+			return err
+		}
+		log.HeldID = held.ID
+		s.writeLog(ctx, log)
+		return appErrors.New(enum.ErrMessageHeld, map[string]interface{}{
+			"heldId": held.ID,
+		})
+	default:
+		s.writeLog(ctx, log)
+		return appErrors.New(enum.ErrMessageRejected, map[string]interface{}{
+			"matches": len(decision.Matches),
+		})
+	}
+}
+
+// hold 保存發送者提供的訊息內容並通知審核者，伺服器產生的欄位在核准發送時重新產生
+func (s *moderationStage) hold(ctx context.Context, message *entities.Message, convType entities.ConversationType, targetID uint, matches string) (*entities.HeldMessage, error) {
+	payload, err := json.Marshal(&entities.Message{
+		UserId:       message.UserId,
+		TargetId:     message.TargetId,
+		RoomID:       message.RoomID,
+		Type:         message.Type,
+		Media:        message.Media,
+		Content:      message.Content,
+		Metadata:     message.Metadata,
+		ReplyToID:    message.ReplyToID,
+		ThreadRootID: message.ThreadRootID,
+		ClientMsgID:  message.ClientMsgID,
+	})
+	if err != nil {
+		return nil, appErrors.New(enum.ErrMessageInvalid, "無效的訊息內容")
+	}
+
+	now := time.Now()
+	held := &entities.HeldMessage{
+		UserID:    message.UserId,
+		Type:      convType,
+		TargetID:  targetID,
+		Status:    entities.HeldPending,
+		Payload:   entities.JSON(payload),
+		Matches:   matches,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.moderationRepo.CreateHeld(ctx, held); err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"userId": message.UserId,
+		})
+	}
+
+	reviewers := append([]uint{}, s.reviewers...)
+	if held.IsGroup() {
+		managers, err := groupManagers(ctx, s.groupRepo, targetID)
+		if err != nil {
+			fmt.Printf("查詢群組管理員失敗: roomID=%d, err=%v\n", targetID, err)
+		}
+		reviewers = uniqueUints(append(reviewers, managers...))
+	}
+	if err := s.publisher.Publish(ctx, reviewers, &Event{Type: EventModerationHeld, Data: held}); err != nil {
+		fmt.Printf("推送事件失敗: event=%s, err=%v\n", EventModerationHeld, err)
+	}
+	return held, nil
+}
+
+// writeLog 寫入稽核日誌，失敗只記錄錯誤，不影響訊息的審核結果
+func (s *moderationStage) writeLog(ctx context.Context, log *entities.ModerationLog) {
+	writeModerationLog(ctx, s.moderationRepo, log)
+}
+
+func writeModerationLog(ctx context.Context, repo repositories.ModerationRepository, log *entities.ModerationLog) {
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = time.Now()
+	}
+	if err := repo.CreateLog(ctx, log); err != nil {
+		fmt.Printf("寫入審核日誌失敗: event=%s, userID=%d, err=%v\n", log.Event, log.UserID, err)
+	}
+}
+
+// moderate 在訊息寫入前執行內容審核，內容被遮蔽時重新產生富文本
+func (uc *messageUseCase) moderate(ctx context.Context, message *entities.Message, canHold bool) error {
+	if uc.moderation == nil {
+		return nil
+	}
+	original := message.Content
+	if err := uc.moderation.Screen(ctx, message, canHold); err != nil {
+		return err
+	}
+	if message.Content != original {
+		return renderRichText(message)
+	}
+	return nil
+}
+
+// groupManagers 返回群主與群組管理員
+func groupManagers(ctx context.Context, groupRepo repositories.GroupRepository, groupID uint) ([]uint, error) {
+	group, err := groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return nil, err
+	}
+	admins, err := groupRepo.GetMembersByRole(ctx, groupID, entities.GroupRoleAdmin)
+	if err != nil {
+		return []uint{group.OwnerId}, err
+	}
+	return uniqueUints(append([]uint{group.OwnerId}, admins...)), nil
+}
@@ -0,0 +1,382 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ModerationUseCase 內容審核的管理用例：維護群組規則、審核佇列與查詢稽核日誌。
+// 群組的規則與佇列由群主與管理員管理，全域審核者可審核所有對話
+type ModerationUseCase interface {
+	// ListRules 列出群組的審核規則，限群主與管理員
+	ListRules(ctx context.Context, userID, groupID uint) ([]*entities.ModerationRule, error)
+	// CreateRule 新增群組的審核規則，rule.CreatedBy 為操作者，限群主與管理員
+	CreateRule(ctx context.Context, rule *entities.ModerationRule) error
+	// DeleteRule 刪除群組的審核規則，限群主與管理員
+	DeleteRule(ctx context.Context, userID, groupID, ruleID uint) error
+	// ListHeld 以游標分頁列出審核佇列，query.GroupID 為 0 時列出所有對話，限全域審核者
+	ListHeld(ctx context.Context, userID uint, query entities.HeldQuery) (*entities.HeldPage, error)
+	// ReviewHeld 核准或駁回待審核訊息，核准時以原內容發送並返回更新後的狀態
+	ReviewHeld(ctx context.Context, userID, heldID uint, approve bool, note string) (*entities.HeldMessage, error)
+	// ListLogs 以游標分頁列出稽核日誌，query.Type 為 0 時列出所有對話，限全域審核者
+	ListLogs(ctx context.Context, userID uint, query entities.ModerationLogQuery) (*entities.ModerationLogPage, error)
+}
+
+type moderationUseCase struct {
+	moderationRepo repositories.ModerationRepository
+	groupRepo      repositories.GroupRepository
+	messageUseCase MessageUseCase
+	publisher      EventPublisher
+	reviewers      []uint
+}
+
+// NewModerationUseCase 創建新的內容審核管理用例，reviewers 為可審核所有對話的全域審核者
+func NewModerationUseCase(
+	moderationRepo repositories.ModerationRepository,
+	groupRepo repositories.GroupRepository,
+	messageUseCase MessageUseCase,
+	publisher EventPublisher,
+	reviewers []uint,
+) ModerationUseCase {
+	return &moderationUseCase{
+		moderationRepo: moderationRepo,
+		groupRepo:      groupRepo,
+		messageUseCase: messageUseCase,
+		publisher:      publisher,
+		reviewers:      reviewers,
+	}
+}
+
+func (uc *moderationUseCase) ListRules(ctx context.Context, userID, groupID uint) ([]*entities.ModerationRule, error) {
+	if err := uc.checkManager(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+	rules, err := uc.moderationRepo.ListRules(ctx, groupID)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": groupID,
+		})
+	}
+	return rules, nil
+}
+
+func (uc *moderationUseCase) CreateRule(ctx context.Context, rule *entities.ModerationRule) error {
+	if rule == nil || rule.GroupID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "群組ID不能為空")
+	}
+	if err := uc.checkManager(ctx, rule.CreatedBy, rule.GroupID); err != nil {
+		return err
+	}
+	rule.Pattern = strings.TrimSpace(rule.Pattern)
+	if _, err := compileModerationRule(rule); err != nil {
+		return appErrors.New(enum.ErrInvalidInput, err.Error())
+	}
+
+	rules, err := uc.moderationRepo.ListRules(ctx, rule.GroupID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": rule.GroupID,
+		})
+	}
+	if len(rules) >= entities.MaxModerationRulesPerGroup {
+		return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message": "審核規則數已達上限",
+			"max":     entities.MaxModerationRulesPerGroup,
+		})
+	}
+
+	rule.ID = 0
+	rule.CreatedAt = time.Now()
+	if err := uc.moderationRepo.CreateRule(ctx, rule); err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": rule.GroupID,
+		})
+	}
+	writeModerationLog(ctx, uc.moderationRepo, &entities.ModerationLog{
+		Type:     entities.ConversationTypeGroup,
+		TargetID: rule.GroupID,
+		ActorID:  rule.CreatedBy,
+		Event:    entities.ModerationEventRuleCreated,
+		Action:   rule.Action,
+		RuleID:   rule.ID,
+		Detail:   ruleDescription(rule),
+	})
+	return nil
+}
+
+func (uc *moderationUseCase) DeleteRule(ctx context.Context, userID, groupID, ruleID uint) error {
+	if err := uc.checkManager(ctx, userID, groupID); err != nil {
+		return err
+	}
+	rules, err := uc.moderationRepo.ListRules(ctx, groupID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": groupID,
+		})
+	}
+	var target *entities.ModerationRule
+	for _, rule := range rules {
+		if rule.ID == ruleID {
+			target = rule
+			break
+		}
+	}
+	if target == nil {
+		return appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message": "審核規則不存在",
+			"ruleId":  ruleID,
+		})
+	}
+
+	removed, err := uc.moderationRepo.DeleteRule(ctx, groupID, ruleID)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": groupID,
+			"ruleId": ruleID,
+		})
+	}
+	if removed {
+		writeModerationLog(ctx, uc.moderationRepo, &entities.ModerationLog{
+			Type:     entities.ConversationTypeGroup,
+			TargetID: groupID,
+			ActorID:  userID,
+			Event:    entities.ModerationEventRuleDeleted,
+			Action:   target.Action,
+			RuleID:   ruleID,
+			Detail:   ruleDescription(target),
+		})
+	}
+	return nil
+}
+
+func (uc *moderationUseCase) ListHeld(ctx context.Context, userID uint, query entities.HeldQuery) (*entities.HeldPage, error) {
+	if err := uc.checkScope(ctx, userID, entities.ConversationTypeGroup, query.GroupID); err != nil {
+		return nil, err
+	}
+	query = query.Normalize()
+
+	held, err := uc.moderationRepo.ListHeld(ctx, query)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"roomId": query.GroupID,
+		})
+	}
+	page := &entities.HeldPage{Items: held}
+	if len(held) > query.Limit {
+		page.Items = held[:query.Limit]
+		page.HasMore = true
+		page.NextCursor = page.Items[len(page.Items)-1].ID
+	}
+	return page, nil
+}
+
+func (uc *moderationUseCase) ReviewHeld(ctx context.Context, userID, heldID uint, approve bool, note string) (*entities.HeldMessage, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > entities.MaxReviewNoteLength {
+		return nil, appErrors.New(enum.ErrInvalidInput, map[string]interface{}{
+			"message":   "審核備註過長",
+			"maxLength": entities.MaxReviewNoteLength,
+		})
+	}
+
+	// 1. 檢查審核權限，不可審核時同樣返回不存在，避免洩漏其他對話的佇列
+	held, err := uc.moderationRepo.FindHeld(ctx, heldID)
+	if errors.Is(err, repositories.ErrHeldMessageNotFound) || (err == nil && uc.checkReviewer(ctx, userID, held.Type, held.TargetID) != nil) {
+		return nil, appErrors.New(enum.ErrHeldMessageNotFound, map[string]interface{}{
+			"heldId": heldID,
+		})
+	}
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"heldId": heldID,
+		})
+	}
+	if held.Status != entities.HeldPending {
+		return nil, appErrors.New(enum.ErrHeldMessageReviewed, map[string]interface{}{
+			"heldId": heldID,
+			"status": held.Status,
+		})
+	}
+
+	// 2. 先以條件更新佔用審核結果，避免多位審核者同時核准而重複發送
+	now := time.Now()
+	reviewed := *held
+	reviewed.Status = entities.HeldRejected
+	if approve {
+		reviewed.Status = entities.HeldApproved
+	}
+	reviewed.ReviewerID = userID
+	reviewed.ReviewNote = note
+	reviewed.ReviewedAt = &now
+	reviewed.UpdatedAt = now
+	if err := uc.updateHeld(ctx, &reviewed, entities.HeldPending); err != nil {
+		return nil, err
+	}
+
+	// 3. 核准時以原內容發送，發送失敗時退回待審核
+	if approve {
+		message, err := uc.sendHeld(ctx, held)
+		if err != nil {
+			held.UpdatedAt = time.Now()
+			if revertErr := uc.updateHeld(ctx, held, entities.HeldApproved); revertErr != nil {
+				fmt.Printf("退回待審核訊息失敗: heldID=%d, err=%v\n", heldID, revertErr)
+			}
+			return nil, err
+		}
+		reviewed.MessageID = message.ID
+		if err := uc.updateHeld(ctx, &reviewed, entities.HeldApproved); err != nil {
+			fmt.Printf("記錄核准發送的訊息失敗: heldID=%d, err=%v\n", heldID, err)
+		}
+	}
+
+	// 4. 寫入稽核日誌並通知發送者
+	log := &entities.ModerationLog{
+		Type:      held.Type,
+		TargetID:  held.TargetID,
+		ActorID:   userID,
+		UserID:    held.UserID,
+		Event:     entities.ModerationEventRejected,
+		Action:    entities.ModerationReject,
+		MessageID: reviewed.MessageID,
+		HeldID:    held.ID,
+		Detail:    note,
+	}
+	if approve {
+		log.Event, log.Action = entities.ModerationEventApproved, entities.ModerationAllow
+	}
+	writeModerationLog(ctx, uc.moderationRepo, log)
+	if err := uc.publisher.Publish(ctx, []uint{held.UserID}, &Event{Type: EventModerationReviewed, Data: &reviewed}); err != nil {
+		fmt.Printf("推送事件失敗: event=%s, err=%v\n", EventModerationReviewed, err)
+	}
+	return &reviewed, nil
+}
+
+func (uc *moderationUseCase) ListLogs(ctx context.Context, userID uint, query entities.ModerationLogQuery) (*entities.ModerationLogPage, error) {
+	if err := uc.checkScope(ctx, userID, query.Type, query.TargetID); err != nil {
+		return nil, err
+	}
+	query = query.Normalize()
+
+	logs, err := uc.moderationRepo.ListLogs(ctx, query)
+	if err != nil {
+		return nil, appErrors.NewDBError(err, map[string]interface{}{
+			"type":     query.Type,
+			"targetId": query.TargetID,
+		})
+	}
+	page := &entities.ModerationLogPage{Items: logs}
+	if len(logs) > query.Limit {
+		page.Items = logs[:query.Limit]
+		page.HasMore = true
+		page.NextCursor = page.Items[len(page.Items)-1].ID
+	}
+	return page, nil
+}
+
+// sendHeld 以待審核時保存的內容經由一般發送流程發送，不再重新審核
+func (uc *moderationUseCase) sendHeld(ctx context.Context, held *entities.HeldMessage) (*entities.Message, error) {
+	var message entities.Message
+	if err := json.Unmarshal(held.Payload, &message); err != nil {
+		return nil, appErrors.New(enum.ErrMessageInvalid, map[string]interface{}{
+			"heldId": held.ID,
+		})
+	}
+	message.ID = 0
+
+	ctx = withModerationBypass(ctx)
+	var err error
+	if held.IsGroup() {
+		err = uc.messageUseCase.SendGroupMessage(ctx, &message)
+	} else {
+		err = uc.messageUseCase.SendPrivateMessage(ctx, &message)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+func (uc *moderationUseCase) updateHeld(ctx context.Context, held *entities.HeldMessage, from entities.HeldStatus) error {
+	updated, err := uc.moderationRepo.UpdateHeld(ctx, held, from)
+	if err != nil {
+		return appErrors.NewDBError(err, map[string]interface{}{
+			"heldId": held.ID,
+		})
+	}
+	if !updated {
+		return appErrors.New(enum.ErrHeldMessageReviewed, map[string]interface{}{
+			"heldId": held.ID,
+		})
+	}
+	return nil
+}
+
+// checkManager 檢查用戶是否為群主或群組管理員
+func (uc *moderationUseCase) checkManager(ctx context.Context, userID, groupID uint) error {
+	if userID == 0 || groupID == 0 {
+		return appErrors.New(enum.ErrInvalidInput, "用戶ID與群組ID不能為空")
+	}
+	manager, err := isGroupManager(ctx, uc.groupRepo, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if !manager {
+		return appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+			"roomId": groupID,
+			"userId": userID,
+		})
+	}
+	return nil
+}
+
+// checkScope 檢查用戶能否查看此範圍的佇列與日誌，targetID 為 0 表示所有對話
+func (uc *moderationUseCase) checkScope(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	if targetID == 0 {
+		if !uc.isReviewer(userID) {
+			return appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+				"userId": userID,
+			})
+		}
+		return nil
+	}
+	return uc.checkReviewer(ctx, userID, convType, targetID)
+}
+
+// checkReviewer 檢查用戶能否審核此對話：全域審核者可審核所有對話，群主與管理員可審核自己的群組
+func (uc *moderationUseCase) checkReviewer(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	if uc.isReviewer(userID) {
+		return nil
+	}
+	if convType == entities.ConversationTypeGroup {
+		return uc.checkManager(ctx, userID, targetID)
+	}
+	return appErrors.New(enum.ErrAccessDenied, map[string]interface{}{
+		"userId": userID,
+	})
+}
+
+func (uc *moderationUseCase) isReviewer(userID uint) bool {
+	for _, reviewer := range uc.reviewers {
+		if reviewer == userID && userID != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleDescription 稽核日誌中記錄的規則內容
+func ruleDescription(rule *entities.ModerationRule) string {
+	if rule.IsRegex {
+		return "regex:" + rule.Pattern
+	}
+	return "keyword:" + rule.Pattern
+}
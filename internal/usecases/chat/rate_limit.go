@@ -0,0 +1,228 @@
+package chat
+
+import (
+	"clean-architecture-gochat/internal/common/enum"
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit 令牌桶的限制：最多可連續發送 Burst 則，之後每經過 Interval 可再發送一則，Burst 為 0 表示不限制
+type RateLimit struct {
+	Burst    int
+	Interval time.Duration
+}
+
+func (l RateLimit) enabled() bool {
+	return l.Burst > 0 && l.Interval > 0
+}
+
+// RateLimitTier 一類訊息的發送頻率限制
+type RateLimitTier struct {
+	User         RateLimit // 每個用戶在所有對話中合計
+	NewUser      RateLimit // 新註冊帳號的用戶限制，未設定時沿用 User
+	Conversation RateLimit // 每個對話中所有成員合計
+}
+
+// RateLimitSettings 發送頻率限制的設定，文字與媒體訊息使用各自的令牌桶
+type RateLimitSettings struct {
+	Text          RateLimitTier
+	Media         RateLimitTier // 圖片、語音、檔案與投票等非文字訊息
+	NewAccountAge time.Duration // 註冊未滿此時間的帳號套用 NewUser 限制，0 表示不區分
+}
+
+// RateLimiter 限制用戶發送訊息的頻率
+type RateLimiter interface {
+	// Allow 檢查用戶發送 count 則訊息到 scopes 中的每個對話是否超過限制，scopes 格式與 entities.PinScopeOf 相同。
+	// 超過限制時不扣除任何額度並返回 RATE_LIMITED，details 中的 retryAfter 為需等待的秒數
+	Allow(ctx context.Context, userID uint, media bool, count int, scopes ...string) error
+}
+
+type rateLimiter struct {
+	cache    cache.RateLimitCache
+	userRepo repositories.UserRepository
+	settings RateLimitSettings
+
+	registered sync.Map // 用戶ID → 註冊時間，不會變動因此不需過期
+}
+
+// NewRateLimiter 創建以 Redis 令牌桶限制發送頻率的限制器，Redis 無法使用時不限制發送
+func NewRateLimiter(rateLimitCache cache.RateLimitCache, userRepo repositories.UserRepository, settings RateLimitSettings) RateLimiter {
+	return &rateLimiter{
+		cache:    rateLimitCache,
+		userRepo: userRepo,
+		settings: settings,
+	}
+}
+
+func (l *rateLimiter) Allow(ctx context.Context, userID uint, media bool, count int, scopes ...string) error {
+	if count <= 0 {
+		return nil
+	}
+	tier, name := l.settings.Text, "text"
+	if media {
+		tier, name = l.settings.Media, "media"
+	}
+
+	now := time.Now()
+	scopes = uniqueStrings(scopes)
+	var buckets []cache.TokenBucket
+	userLimit := tier.User
+	if tier.NewUser.enabled() && l.isNewAccount(ctx, userID, now) {
+		userLimit = tier.NewUser
+	}
+	if userLimit.enabled() {
+		buckets = append(buckets, tokenBucket(fmt.Sprintf("%s:user:%d", name, userID), userLimit, count*max(len(scopes), 1)))
+	}
+	if tier.Conversation.enabled() {
+		for _, scope := range scopes {
+			buckets = append(buckets, tokenBucket(name+":"+scope, tier.Conversation, count))
+		}
+	}
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	wait, err := l.cache.Take(ctx, buckets, now)
+	if err != nil {
+		fmt.Printf("檢查發送頻率失敗，暫不限制: userID=%d, err=%v\n", userID, err)
+		return nil
+	}
+	if wait > 0 {
+		return appErrors.New(enum.ErrRateLimited, map[string]interface{}{
+			"retryAfter": int(math.Ceil(wait.Seconds())),
+		})
+	}
+	return nil
+}
+
+// isNewAccount 判斷用戶是否為新註冊的帳號，查詢失敗時視為一般帳號
+func (l *rateLimiter) isNewAccount(ctx context.Context, userID uint, now time.Time) bool {
+	if l.settings.NewAccountAge <= 0 || l.userRepo == nil {
+		return false
+	}
+	if registered, ok := l.registered.Load(userID); ok {
+		return now.Sub(registered.(time.Time)) < l.settings.NewAccountAge
+	}
+
+	user, err := l.userRepo.FindByID(ctx, userID)
+	if err != nil || user == nil {
+		fmt.Printf("查詢用戶註冊時間失敗: userID=%d, err=%v\n", userID, err)
+		return false
+	}
+	l.registered.Store(userID, user.CreatedAt)
+	return now.Sub(user.CreatedAt) < l.settings.NewAccountAge
+}
+
+// tokenBucket 建立一次取用的令牌桶，一次發送超過容量時以整個桶計算，避免大量轉發永遠無法通過
+func tokenBucket(key string, limit RateLimit, cost int) cache.TokenBucket {
+	return cache.TokenBucket{Key: key, Burst: limit.Burst, Interval: limit.Interval, Cost: min(cost, limit.Burst)}
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
+
+// RetryAfter 返回 RATE_LIMITED 錯誤建議的重試等待秒數，其他錯誤返回 ok = false
+func RetryAfter(err error) (seconds int, ok bool) {
+	appErr, isAppErr := baseErrors.GetAppError(err)
+	if !isAppErr || appErr.Code() != int(enum.ErrRateLimited) {
+		return 0, false
+	}
+	details, _ := appErr.(*baseErrors.AppError).Details().(map[string]interface{})
+	seconds, ok = details["retryAfter"].(int)
+	return seconds, ok
+}
+
+// isMediaMessage 判斷是否以媒體訊息的限制計算，未指定類型的訊息發送時會視為文字
+func isMediaMessage(message *entities.Message) bool {
+	return message.Media != 0 && !message.IsTextual()
+}
+
+// rateLimitedMessageUseCase 在用戶發送訊息前檢查發送頻率，
+// 排程、審核核准等由系統代為發送的訊息直接使用內層的用例，不受限制
+type rateLimitedMessageUseCase struct {
+	MessageUseCase
+	limiter      RateLimiter
+	messageCache cache.MessageCacheRepository
+}
+
+// NewRateLimitedMessageUseCase 包裝訊息用例，發送、轉發與建立投票前檢查發送頻率。
+// messageCache 用於辨識 client_msg_id 已處理過的重試，重試不再計入頻率，為 nil 時一律計入
+func NewRateLimitedMessageUseCase(messageUseCase MessageUseCase, limiter RateLimiter, messageCache cache.MessageCacheRepository) MessageUseCase {
+	return &rateLimitedMessageUseCase{MessageUseCase: messageUseCase, limiter: limiter, messageCache: messageCache}
+}
+
+func (uc *rateLimitedMessageUseCase) SendPrivateMessage(ctx context.Context, message *entities.Message) error {
+	if !uc.isRetry(ctx, message) {
+		if err := uc.limiter.Allow(ctx, message.UserId, isMediaMessage(message), 1, entities.PrivatePinScope(message.UserId, message.TargetId)); err != nil {
+			return err
+		}
+	}
+	return uc.MessageUseCase.SendPrivateMessage(ctx, message)
+}
+
+func (uc *rateLimitedMessageUseCase) SendGroupMessage(ctx context.Context, message *entities.Message) error {
+	if !uc.isRetry(ctx, message) {
+		if err := uc.limiter.Allow(ctx, message.UserId, isMediaMessage(message), 1, entities.GroupPinScope(message.RoomID)); err != nil {
+			return err
+		}
+	}
+	return uc.MessageUseCase.SendGroupMessage(ctx, message)
+}
+
+// isRetry 判斷訊息是否為已處理過的 client_msg_id 重試，重試由內層用例返回原訊息，不應再消耗額度。
+// 查詢去重記錄失敗時視為新訊息
+func (uc *rateLimitedMessageUseCase) isRetry(ctx context.Context, message *entities.Message) bool {
+	if uc.messageCache == nil || !message.HasClientMsgID() {
+		return false
+	}
+	existingID, err := uc.messageCache.GetClientMessageID(ctx, message.UserId, *message.ClientMsgID)
+	if err != nil {
+		fmt.Printf("查詢訊息去重記錄失敗: %v\n", err)
+		return false
+	}
+	return existingID != 0
+}
+
+// ForwardMessages 每個目標對話各計 len(messageIDs) 則，轉發的內容不限文字，一律以媒體訊息的限制計算
+func (uc *rateLimitedMessageUseCase) ForwardMessages(ctx context.Context, userID uint, messageIDs []uint, targets []ForwardTarget) ([]*entities.Message, error) {
+	scopes := make([]string, 0, len(targets))
+	for _, target := range targets {
+		if target.Type == entities.ConversationTypeGroup {
+			scopes = append(scopes, entities.GroupPinScope(target.TargetID))
+		} else {
+			scopes = append(scopes, entities.PrivatePinScope(userID, target.TargetID))
+		}
+	}
+	if len(messageIDs) > 0 && len(scopes) > 0 {
+		if err := uc.limiter.Allow(ctx, userID, true, len(messageIDs), scopes...); err != nil {
+			return nil, err
+		}
+	}
+	return uc.MessageUseCase.ForwardMessages(ctx, userID, messageIDs, targets)
+}
+
+func (uc *rateLimitedMessageUseCase) CreatePoll(ctx context.Context, poll *entities.Poll) (*entities.Message, error) {
+	if poll != nil {
+		if err := uc.limiter.Allow(ctx, poll.CreatorID, true, 1, entities.GroupPinScope(poll.RoomID)); err != nil {
+			return nil, err
+		}
+	}
+	return uc.MessageUseCase.CreatePoll(ctx, poll)
+}
@@ -36,19 +36,18 @@ func (s *connectionService) Connect(ctx context.Context, conn *ws.Conn, userID u
 }
 
 func (s *connectionService) Disconnect(ctx context.Context, userID uint) error {
-	client, exists := websocketInfra.GetClient(fmt.Sprintf("%d", userID))
-	if exists {
+	// 中斷該用戶所有裝置的連線
+	for _, client := range websocketInfra.GetClients(fmt.Sprintf("%d", userID)) {
 		client.Hub.Unregister <- client
 	}
 	return nil
 }
 
 func (s *connectionService) SendToUser(ctx context.Context, userID uint, message []byte) error {
-	client, exists := websocketInfra.GetClient(fmt.Sprintf("%d", userID))
-	if !exists {
+	// 推送到該用戶所有已連線的裝置
+	if websocketInfra.SendToUser(fmt.Sprintf("%d", userID), message) == 0 {
 		return fmt.Errorf("user %d is not online", userID)
 	}
-	client.Send <- message
 	return nil
 }
 
@@ -58,6 +57,5 @@ func (s *connectionService) Broadcast(ctx context.Context, message []byte) error
 }
 
 func (s *connectionService) IsUserOnline(ctx context.Context, userID uint) bool {
-	_, exists := websocketInfra.GetClient(fmt.Sprintf("%d", userID))
-	return exists
+	return len(websocketInfra.GetClients(fmt.Sprintf("%d", userID))) > 0
 }
@@ -0,0 +1,443 @@
+// Package richtext 解析訊息使用的 Markdown 子集，並輸出安全的 HTML 與純文字。
+//
+// 支援的語法：
+//   - 粗體 **文字** 或 __文字__，斜體 *文字* 或 _文字_
+//   - 行內程式碼 `code` 與以 ``` 包圍的程式碼區塊
+//   - 連結 [文字](https://example.com)，只允許 http、https 與 mailto
+//   - 無序清單（- * +）與有序清單（1. 或 1)），不支援巢狀
+//   - 引用（> 開頭的行）
+//
+// 原始內容中的 HTML 一律視為文字並跳脫，輸出的 HTML 只包含上述語法對應的標籤
+package richtext
+
+import (
+	"errors"
+	"html"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// MaxSourceLength 原始內容的最大字數
+	MaxSourceLength = 4000
+	// maxInlineDepth 粗體、斜體與連結的最大巢狀層數
+	maxInlineDepth = 4
+	// maxURLLength 連結網址的最大長度
+	maxURLLength = 2048
+)
+
+var (
+	// ErrEmpty 內容為空
+	ErrEmpty = errors.New("richtext: content is empty")
+	// ErrTooLong 內容超過 MaxSourceLength
+	ErrTooLong = errors.New("richtext: content is too long")
+	// ErrUnsafeLink 連結不是 http、https 或 mailto 網址
+	ErrUnsafeLink = errors.New("richtext: link must be an http, https or mailto url")
+)
+
+var (
+	bulletItemPattern  = regexp.MustCompile(`^\s{0,3}[-*+]\s+(.*)$`)
+	orderedItemPattern = regexp.MustCompile(`^\s{0,3}(\d{1,9})[.)]\s+(.*)$`)
+	quotePattern       = regexp.MustCompile(`^\s{0,3}>\s?(.*)$`)
+	fencePattern       = regexp.MustCompile("^\\s{0,3}```")
+)
+
+type inlineKind int
+
+const (
+	inlineText inlineKind = iota
+	inlineStrong
+	inlineEmphasis
+	inlineCode
+	inlineLink
+	inlineBreak
+)
+
+// inline 行內節點，text 為文字或程式碼內容，url 只用於連結
+type inline struct {
+	kind     inlineKind
+	text     string
+	url      string
+	children []inline
+}
+
+type blockKind int
+
+const (
+	blockParagraph blockKind = iota
+	blockQuote
+	blockBulletList
+	blockOrderedList
+	blockCode
+)
+
+// block 區塊節點，段落與引用只有一個 item，清單每個項目一個 item
+type block struct {
+	kind  blockKind
+	items [][]inline
+	start int    // 有序清單的起始編號
+	code  string // 程式碼區塊內容
+}
+
+// Document 解析後的內容，只能由 Parse 建立
+type Document struct {
+	blocks []block
+}
+
+// Parse 驗證並解析原始內容
+func Parse(source string) (*Document, error) {
+	source = strings.ReplaceAll(strings.ReplaceAll(source, "\r\n", "\n"), "\r", "\n")
+	if strings.TrimSpace(source) == "" {
+		return nil, ErrEmpty
+	}
+	if !utf8.ValidString(source) {
+		source = strings.ToValidUTF8(source, "�")
+	}
+	if utf8.RuneCountInString(source) > MaxSourceLength {
+		return nil, ErrTooLong
+	}
+
+	p := &parser{}
+	doc := &Document{blocks: p.parseBlocks(strings.Split(source, "\n"))}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return doc, nil
+}
+
+// HTML 輸出 HTML 片段，所有文字與屬性皆已跳脫
+func (d *Document) HTML() string {
+	var b strings.Builder
+	for _, blk := range d.blocks {
+		switch blk.kind {
+		case blockParagraph:
+			b.WriteString("<p>")
+			writeInlineHTML(&b, blk.items[0])
+			b.WriteString("</p>")
+		case blockQuote:
+			b.WriteString("<blockquote>")
+			writeInlineHTML(&b, blk.items[0])
+			b.WriteString("</blockquote>")
+		case blockBulletList, blockOrderedList:
+			tag := "ul"
+			if blk.kind == blockOrderedList {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag)
+			if blk.kind == blockOrderedList && blk.start != 1 {
+				b.WriteString(` start="` + strconv.Itoa(blk.start) + `"`)
+			}
+			b.WriteString(">")
+			for _, item := range blk.items {
+				b.WriteString("<li>")
+				writeInlineHTML(&b, item)
+				b.WriteString("</li>")
+			}
+			b.WriteString("</" + tag + ">")
+		case blockCode:
+			b.WriteString("<pre><code>")
+			b.WriteString(html.EscapeString(blk.code))
+			b.WriteString("</code></pre>")
+		}
+	}
+	return b.String()
+}
+
+// PlainText 輸出去除格式的純文字，用於搜尋與通知，連結保留網址
+func (d *Document) PlainText() string {
+	lines := make([]string, 0, len(d.blocks))
+	for _, blk := range d.blocks {
+		switch blk.kind {
+		case blockParagraph, blockQuote:
+			lines = append(lines, plainInline(blk.items[0]))
+		case blockBulletList:
+			for _, item := range blk.items {
+				lines = append(lines, "- "+plainInline(item))
+			}
+		case blockOrderedList:
+			for i, item := range blk.items {
+				lines = append(lines, strconv.Itoa(blk.start+i)+". "+plainInline(item))
+			}
+		case blockCode:
+			lines = append(lines, blk.code)
+		}
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+type parser struct {
+	err error
+}
+
+// parseBlocks 逐行解析區塊，空行結束段落、清單與引用
+func (p *parser) parseBlocks(lines []string) []block {
+	var blocks []block
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, block{kind: blockParagraph, items: [][]inline{p.parseLines(paragraph)}})
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+		case fencePattern.MatchString(line):
+			flush()
+			var code []string
+			for i++; i < len(lines) && !fencePattern.MatchString(lines[i]); i++ {
+				code = append(code, lines[i])
+			}
+			blocks = append(blocks, block{kind: blockCode, code: strings.Join(code, "\n")})
+		case quotePattern.MatchString(line):
+			flush()
+			var quoted []string
+			for ; i < len(lines) && quotePattern.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quotePattern.FindStringSubmatch(lines[i])[1])
+			}
+			i--
+			blocks = append(blocks, block{kind: blockQuote, items: [][]inline{p.parseLines(quoted)}})
+		case bulletItemPattern.MatchString(line):
+			flush()
+			list := block{kind: blockBulletList}
+			for ; i < len(lines) && bulletItemPattern.MatchString(lines[i]); i++ {
+				list.items = append(list.items, p.parseInline(bulletItemPattern.FindStringSubmatch(lines[i])[1], 0, false))
+			}
+			i--
+			blocks = append(blocks, list)
+		case orderedItemPattern.MatchString(line):
+			flush()
+			list := block{kind: blockOrderedList}
+			list.start, _ = strconv.Atoi(orderedItemPattern.FindStringSubmatch(line)[1])
+			for ; i < len(lines) && orderedItemPattern.MatchString(lines[i]); i++ {
+				list.items = append(list.items, p.parseInline(orderedItemPattern.FindStringSubmatch(lines[i])[2], 0, false))
+			}
+			i--
+			blocks = append(blocks, list)
+		default:
+			paragraph = append(paragraph, line)
+		}
+	}
+	flush()
+	return blocks
+}
+
+// parseLines 解析多行文字，行與行之間保留換行
+func (p *parser) parseLines(lines []string) []inline {
+	var nodes []inline
+	for i, line := range lines {
+		if i > 0 {
+			nodes = append(nodes, inline{kind: inlineBreak})
+		}
+		nodes = append(nodes, p.parseInline(strings.TrimSpace(line), 0, false)...)
+	}
+	return nodes
+}
+
+// parseInline 解析行內語法，無法配對的符號保留為文字。inLink 為 true 時不再解析連結
+func (p *parser) parseInline(s string, depth int, inLink bool) []inline {
+	var nodes []inline
+	var text strings.Builder
+	emit := func(node inline) {
+		if text.Len() > 0 {
+			nodes = append(nodes, inline{kind: inlineText, text: text.String()})
+			text.Reset()
+		}
+		nodes = append(nodes, node)
+	}
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(s[i+1:], '`'); end > 0 {
+				emit(inline{kind: inlineCode, text: s[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case (c == '*' || c == '_') && depth < maxInlineDepth:
+			if node, width, ok := p.parseEmphasis(s, i, depth, inLink); ok {
+				emit(node)
+				i += width
+				continue
+			}
+		case c == '[' && !inLink && depth < maxInlineDepth:
+			if node, width, ok := p.parseLink(s, i, depth); ok {
+				emit(node)
+				i += width
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	if text.Len() > 0 {
+		nodes = append(nodes, inline{kind: inlineText, text: text.String()})
+	}
+	return nodes
+}
+
+// parseEmphasis 嘗試在 s[i] 解析粗體或斜體，返回節點與使用的位元組數
+func (p *parser) parseEmphasis(s string, i, depth int, inLink bool) (inline, int, bool) {
+	c := s[i]
+	// 底線在單字中間時不視為格式，避免 snake_case 被改寫
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return inline{}, 0, false
+	}
+
+	delim, kind := string(c), inlineEmphasis
+	if strings.HasPrefix(s[i:], strings.Repeat(string(c), 2)) {
+		delim, kind = strings.Repeat(string(c), 2), inlineStrong
+	}
+	start := i + len(delim)
+	for from := start; from < len(s); {
+		end := strings.Index(s[from:], delim)
+		if end < 0 {
+			break
+		}
+		end += from
+		// 連續三個以上的符號時以最右側的兩個結束粗體，讓 ***文字*** 成為粗斜體
+		for kind == inlineStrong && end+len(delim) < len(s) && s[end+len(delim)] == c {
+			end++
+		}
+		after := end + len(delim)
+		switch {
+		case end > start && s[end-1] == '\\':
+			// 跳脫的符號不能結束格式
+		case end == start || s[start] == ' ' || s[end-1] == ' ':
+			// 內容為空或緊鄰空白時不是格式
+		case kind == inlineEmphasis && after < len(s) && s[after] == c:
+			// 單一符號遇到連續符號時略過，留給粗體配對
+			after++
+		case c == '_' && after < len(s) && isWordByte(s[after]):
+		default:
+			return inline{kind: kind, children: p.parseInline(s[start:end], depth+1, inLink)}, after - i, true
+		}
+		from = after
+	}
+	return inline{}, 0, false
+}
+
+// parseLink 嘗試在 s[i] 解析 [文字](網址)，網址不安全時記錄錯誤
+func (p *parser) parseLink(s string, i, depth int) (inline, int, bool) {
+	closeText := strings.IndexByte(s[i+1:], ']')
+	if closeText < 0 {
+		return inline{}, 0, false
+	}
+	closeText += i + 1
+	if closeText+1 >= len(s) || s[closeText+1] != '(' {
+		return inline{}, 0, false
+	}
+	closeURL := strings.IndexByte(s[closeText+2:], ')')
+	if closeURL < 0 {
+		return inline{}, 0, false
+	}
+	closeURL += closeText + 2
+
+	label := s[i+1 : closeText]
+	href, ok := safeURL(s[closeText+2 : closeURL])
+	if !ok {
+		if p.err == nil {
+			p.err = ErrUnsafeLink
+		}
+		return inline{}, 0, false
+	}
+	node := inline{kind: inlineLink, url: href}
+	if strings.TrimSpace(label) == "" {
+		node.children = []inline{{kind: inlineText, text: href}}
+	} else {
+		node.children = p.parseInline(label, depth+1, true)
+	}
+	return node, closeURL + 1 - i, true
+}
+
+// safeURL 只接受 http、https 與 mailto 網址，返回正規化後的網址
+func safeURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" || len(raw) > maxURLLength || strings.ContainsAny(raw, " \t\n\"'<>`") {
+		return "", false
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https":
+		if u.Host == "" {
+			return "", false
+		}
+	case "mailto":
+		if u.Opaque == "" {
+			return "", false
+		}
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
+
+func writeInlineHTML(b *strings.Builder, nodes []inline) {
+	for _, node := range nodes {
+		switch node.kind {
+		case inlineText:
+			b.WriteString(html.EscapeString(node.text))
+		case inlineStrong:
+			b.WriteString("<strong>")
+			writeInlineHTML(b, node.children)
+			b.WriteString("</strong>")
+		case inlineEmphasis:
+			b.WriteString("<em>")
+			writeInlineHTML(b, node.children)
+			b.WriteString("</em>")
+		case inlineCode:
+			b.WriteString("<code>")
+			b.WriteString(html.EscapeString(node.text))
+			b.WriteString("</code>")
+		case inlineLink:
+			b.WriteString(`<a href="` + html.EscapeString(node.url) + `" rel="nofollow noopener noreferrer" target="_blank">`)
+			writeInlineHTML(b, node.children)
+			b.WriteString("</a>")
+		case inlineBreak:
+			b.WriteString("<br>")
+		}
+	}
+}
+
+func plainInline(nodes []inline) string {
+	var b strings.Builder
+	for _, node := range nodes {
+		switch node.kind {
+		case inlineText, inlineCode:
+			b.WriteString(node.text)
+		case inlineStrong, inlineEmphasis:
+			b.WriteString(plainInline(node.children))
+		case inlineLink:
+			label := plainInline(node.children)
+			b.WriteString(label)
+			if label != node.url {
+				b.WriteString(" (" + node.url + ")")
+			}
+		case inlineBreak:
+			b.WriteString("\n")
+		}
+	}
+	return b.String()
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
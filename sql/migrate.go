@@ -6,6 +6,7 @@
 package main
 
 import (
+	mysqlInfra "clean-architecture-gochat/infrastructure/mysql"
 	"clean-architecture-gochat/internal/domain/entities"
 	"log"
 	"os"
@@ -52,6 +53,22 @@ func main() {
 		&entities.User{},
 		&entities.Message{},
 		&entities.Contact{},
+		&entities.Conversation{},
+		&entities.MessageRevision{},
+		&entities.MessageReaction{},
+		&entities.MessagePin{},
+		&entities.ScheduledMessage{},
+		&entities.DisappearingSetting{},
+		&entities.Draft{},
+		&entities.ConversationSequence{},
+		&entities.Poll{},
+		&entities.PollVote{},
+		&entities.MessageStar{},
+		&entities.ExportJob{},
+		&entities.ImportMapping{},
+		&entities.ModerationRule{},
+		&entities.HeldMessage{},
+		&entities.ModerationLog{},
 	)
 	if err != nil {
 		log.Printf("Warning: failed to drop tables: %v", err)
@@ -64,11 +81,32 @@ func main() {
 		&entities.Contact{},
 		&entities.Group{},
 		&entities.GroupMember{},
+		&entities.Conversation{},
+		&entities.MessageRevision{},
+		&entities.MessageReaction{},
+		&entities.MessagePin{},
+		&entities.ScheduledMessage{},
+		&entities.DisappearingSetting{},
+		&entities.Draft{},
+		&entities.ConversationSequence{},
+		&entities.Poll{},
+		&entities.PollVote{},
+		&entities.MessageStar{},
+		&entities.ExportJob{},
+		&entities.ImportMapping{},
+		&entities.ModerationRule{},
+		&entities.HeldMessage{},
+		&entities.ModerationLog{},
 	)
 	if err != nil {
 		log.Fatal("failed to migrate database:", err)
 	}
 
+	// 建立訊息搜尋使用的 FULLTEXT 索引
+	if err := mysqlInfra.EnsureMessageFullTextIndex(db); err != nil {
+		log.Fatal("failed to create message search index:", err)
+	}
+
 	// 創建頭像目錄
 	avatarDir := "web/asset/avatars"
 	if err := os.MkdirAll(avatarDir, 0755); err != nil {
@@ -0,0 +1,406 @@
+package test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/domain/storage"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 以記憶體保存匯出工作的假儲存庫，認領規則與資料庫實作相同
+type memoryExportJobRepository struct {
+	jobs   []*entities.ExportJob
+	tokens int
+}
+
+func (r *memoryExportJobRepository) Create(ctx context.Context, job *entities.ExportJob) error {
+	job.ID = uint(len(r.jobs) + 1)
+	job.CreatedAt = time.Now()
+	copied := *job
+	r.jobs = append(r.jobs, &copied)
+	return nil
+}
+
+func (r *memoryExportJobRepository) FindByID(ctx context.Context, id uint) (*entities.ExportJob, error) {
+	for _, job := range r.jobs {
+		if job.ID == id {
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrExportJobNotFound
+}
+
+func (r *memoryExportJobRepository) ListByUser(ctx context.Context, userID uint, limit int) ([]*entities.ExportJob, error) {
+	var jobs []*entities.ExportJob
+	for i := len(r.jobs) - 1; i >= 0 && len(jobs) < limit; i-- {
+		if r.jobs[i].UserID == userID {
+			jobs = append(jobs, r.jobs[i])
+		}
+	}
+	return jobs, nil
+}
+
+func (r *memoryExportJobRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration) (*entities.ExportJob, error) {
+	for _, job := range r.jobs {
+		if job.Status == entities.ExportPending || (job.Status == entities.ExportRunning && job.ClaimedUntil.Before(now)) {
+			r.tokens++
+			until := now.Add(lease)
+			job.Status = entities.ExportRunning
+			job.ClaimToken = fmt.Sprintf("token%04d", r.tokens)
+			job.ClaimedUntil = &until
+			job.Processed = 0
+			job.Attempts++
+			copied := *job
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *memoryExportJobRepository) held(job *entities.ExportJob) *entities.ExportJob {
+	for _, stored := range r.jobs {
+		if stored.ID == job.ID && stored.ClaimToken == job.ClaimToken && stored.ClaimToken != "" {
+			return stored
+		}
+	}
+	return nil
+}
+
+func (r *memoryExportJobRepository) UpdateProgress(ctx context.Context, job *entities.ExportJob, lease time.Duration) (bool, error) {
+	stored := r.held(job)
+	if stored == nil {
+		return false, nil
+	}
+	stored.Processed, stored.Total = job.Processed, job.Total
+	return true, nil
+}
+
+func (r *memoryExportJobRepository) MarkCompleted(ctx context.Context, job *entities.ExportJob, fileName string) (bool, error) {
+	stored := r.held(job)
+	if stored == nil {
+		return false, nil
+	}
+	stored.Status = entities.ExportCompleted
+	stored.Processed = job.Processed
+	stored.FileName = fileName
+	stored.ClaimToken = ""
+	return true, nil
+}
+
+func (r *memoryExportJobRepository) MarkFailed(ctx context.Context, job *entities.ExportJob, reason string, retry bool) error {
+	stored := r.held(job)
+	if stored == nil {
+		return nil
+	}
+	stored.Status = entities.ExportFailed
+	if retry {
+		stored.Status = entities.ExportPending
+	}
+	stored.LastError = reason
+	stored.ClaimToken = ""
+	return nil
+}
+
+// 以記憶體保存匯出檔的假儲存區
+type memoryExportStore struct {
+	files map[string]*bytes.Buffer
+}
+
+type memoryExportFile struct {
+	*bytes.Buffer
+}
+
+func (f memoryExportFile) Close() error { return nil }
+
+func (s *memoryExportStore) Create(name string) (io.WriteCloser, error) {
+	if _, ok := s.files[name]; ok {
+		return nil, fmt.Errorf("file exists: %s", name)
+	}
+	s.files[name] = new(bytes.Buffer)
+	return memoryExportFile{s.files[name]}, nil
+}
+
+func (s *memoryExportStore) Open(name string) (io.ReadCloser, error) {
+	file, ok := s.files[name]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(file.Bytes())), nil
+}
+
+func (s *memoryExportStore) Remove(name string) error {
+	delete(s.files, name)
+	return nil
+}
+
+// 只認得 /web/asset/files/ 下檔案的假附件來源
+type stubAttachmentStore struct {
+	files map[string]string // 檔名 -> 內容
+}
+
+func (s *stubAttachmentStore) Lookup(url string) (string, bool) {
+	name := strings.TrimPrefix(url, "/web/asset/files/")
+	if _, ok := s.files[name]; !ok || name == url {
+		return "", false
+	}
+	return name, true
+}
+
+func (s *stubAttachmentStore) Open(url string) (io.ReadCloser, error) {
+	name, ok := s.Lookup(url)
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	return io.NopCloser(strings.NewReader(s.files[name])), nil
+}
+
+// 依ID返回固定名稱的假用戶儲存庫
+type stubUserRepository struct {
+	repositories.UserRepository
+	names map[uint]string
+}
+
+func (r *stubUserRepository) FindByID(ctx context.Context, id uint) (*entities.User, error) {
+	return &entities.User{ID: id, Name: r.names[id]}, nil
+}
+
+func (r *stubUserRepository) FindUserByIds(ctx context.Context, ids []uint) ([]entities.User, error) {
+	var users []entities.User
+	for _, id := range ids {
+		if name, ok := r.names[id]; ok {
+			users = append(users, entities.User{ID: id, Name: name})
+		}
+	}
+	return users, nil
+}
+
+type exportFixture struct {
+	useCase   chat.ExportUseCase
+	repo      *memoryExportJobRepository
+	store     *memoryExportStore
+	messages  *MockMessageRepository
+	groups    *stubGroupRepository
+	publisher *recordingPublisher
+}
+
+func newExportFixture() *exportFixture {
+	f := &exportFixture{
+		repo:      &memoryExportJobRepository{},
+		store:     &memoryExportStore{files: make(map[string]*bytes.Buffer)},
+		messages:  new(MockMessageRepository),
+		groups:    &stubGroupRepository{group: &entities.Group{ID: 5, Name: "專案群組", OwnerId: 9}, members: []uint{1, 2, 9}},
+		publisher: newRecordingPublisher(),
+	}
+	users := &stubUserRepository{names: map[uint]string{1: "Alice", 2: "Bob", 9: "Owner"}}
+	attachments := &stubAttachmentStore{files: map[string]string{"file_1.jpg": "JPEG", "file_2.pdf": "PDF"}}
+	f.useCase = chat.NewExportUseCase(f.repo, f.messages, f.groups, users, f.store, attachments, f.publisher)
+	return f
+}
+
+// download 以建立者身分下載匯出檔的完整內容
+func (f *exportFixture) download(t *testing.T, userID, id uint) []byte {
+	_, file, err := f.useCase.OpenExport(context.Background(), userID, id)
+	if !assert.NoError(t, err) {
+		return nil
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	assert.NoError(t, err)
+	return data
+}
+
+// 測試建立匯出工作時檢查格式與群組成員身分
+func TestExportUseCase_CreateExport(t *testing.T) {
+	f := newExportFixture()
+	ctx := context.Background()
+
+	err := f.useCase.CreateExport(ctx, &entities.ExportJob{UserID: 3, Type: entities.ConversationTypeGroup, TargetID: 5, Format: entities.ExportFormatJSON})
+	assertAppErrorKey(t, err, "NOT_GROUP_MEMBER")
+	err = f.useCase.CreateExport(ctx, &entities.ExportJob{UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, Format: "xml"})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+
+	job := &entities.ExportJob{UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, Format: entities.ExportFormatCSV, Processed: 99}
+	assert.NoError(t, f.useCase.CreateExport(ctx, job))
+	assert.Equal(t, entities.ExportPending, job.Status)
+	assert.Zero(t, job.Processed)
+
+	// 其他用戶無法查詢或下載
+	_, err = f.useCase.GetExport(ctx, 2, job.ID)
+	assertAppErrorKey(t, err, "EXPORT_NOT_FOUND")
+	_, _, err = f.useCase.OpenExport(ctx, 1, job.ID)
+	assertAppErrorKey(t, err, "EXPORT_NOT_READY")
+}
+
+// 測試匯出逐批讀取訊息並回報進度，產生的 JSON 包含所有訊息與發送者名稱
+func TestExportUseCase_RunNext_StreamsJSON(t *testing.T) {
+	f := newExportFixture()
+	ctx := context.Background()
+
+	job := &entities.ExportJob{UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, Format: entities.ExportFormatJSON}
+	assert.NoError(t, f.useCase.CreateExport(ctx, job))
+
+	first := make([]*entities.Message, 500)
+	for i := range first {
+		first[i] = &entities.Message{ID: uint(i + 1), UserId: uint(1 + i%2), RoomID: 5, Type: entities.MessageTypeGroup, Media: entities.MediaTypeText, Content: fmt.Sprintf("訊息 %d", i+1)}
+	}
+	second := []*entities.Message{
+		{ID: 501, UserId: 9, RoomID: 5, Type: entities.MessageTypeSystem, Content: "釘選了一則訊息"},
+		{ID: 502, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup, Media: entities.MediaTypeFile, Content: "/web/asset/files/file_2.pdf"},
+	}
+	f.messages.On("CountConversationMessages", mock.Anything, entities.ConversationTypeGroup, uint(1), uint(5)).Return(502, nil)
+	f.messages.On("FindConversationBatch", mock.Anything, entities.ConversationTypeGroup, uint(1), uint(5), uint(0), 500).Return(first, nil)
+	f.messages.On("FindConversationBatch", mock.Anything, entities.ConversationTypeGroup, uint(1), uint(5), uint(500), 500).Return(second, nil)
+
+	ran, err := f.useCase.RunNext(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, ran)
+	ran, err = f.useCase.RunNext(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, ran)
+
+	stored, err := f.useCase.GetExport(ctx, 1, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.ExportCompleted, stored.Status)
+	assert.Equal(t, 502, stored.Processed)
+	assert.Equal(t, 502, stored.Total)
+
+	var exported struct {
+		Conversation struct {
+			Title string `json:"title"`
+		} `json:"conversation"`
+		Messages []struct {
+			ID         uint   `json:"id"`
+			SenderName string `json:"sender_name"`
+			Kind       string `json:"kind"`
+			Content    string `json:"content"`
+			Attachment string `json:"attachment"`
+		} `json:"messages"`
+	}
+	assert.NoError(t, json.Unmarshal(f.download(t, 1, job.ID), &exported))
+	assert.Equal(t, "專案群組", exported.Conversation.Title)
+	if assert.Len(t, exported.Messages, 502) {
+		assert.Equal(t, "Bob", exported.Messages[1].SenderName)
+		assert.Equal(t, "system", exported.Messages[500].Kind)
+		assert.Equal(t, "/web/asset/files/file_2.pdf", exported.Messages[501].Attachment)
+		assert.Empty(t, exported.Messages[501].Content)
+	}
+
+	// 進度為暫時事件，完成事件保留到用戶上線
+	events := f.publisher.eventsOf(1, chat.EventExportProgress)
+	if assert.Len(t, events, 4) {
+		assert.True(t, events[1].Transient)
+		assert.Equal(t, 500, events[1].Data.(*entities.ExportJob).Processed)
+		assert.False(t, events[3].Transient)
+		assert.Equal(t, entities.ExportCompleted, events[3].Data.(*entities.ExportJob).Status)
+	}
+	assert.Empty(t, f.publisher.eventsOf(2, chat.EventExportProgress))
+}
+
+// 測試 HTML 匯出打包本機附件，並跳脫訊息中的 HTML
+func TestExportUseCase_RunNext_BundlesAttachments(t *testing.T) {
+	f := newExportFixture()
+	ctx := context.Background()
+
+	job := &entities.ExportJob{UserID: 2, Type: entities.ConversationTypePrivate, TargetID: 1, Format: entities.ExportFormatHTML, BundleAttachments: true}
+	assert.NoError(t, f.useCase.CreateExport(ctx, job))
+
+	rich := &entities.Message{ID: 3, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeRichText, Content: "**重點**"}
+	assert.NoError(t, rich.SetRichText("<p><strong>重點</strong></p>", "重點"))
+	messages := []*entities.Message{
+		{ID: 1, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "<script>alert(1)</script>"},
+		{ID: 2, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeImage, Content: "/web/asset/files/file_1.jpg"},
+		rich,
+		{ID: 4, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeImage, Content: "javascript:alert(1)"},
+		{ID: 5, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Media: entities.MediaTypeImage, Content: "/web/asset/files/file_1.jpg"},
+	}
+	f.messages.On("CountConversationMessages", mock.Anything, entities.ConversationTypePrivate, uint(2), uint(1)).Return(len(messages), nil)
+	f.messages.On("FindConversationBatch", mock.Anything, entities.ConversationTypePrivate, uint(2), uint(1), uint(0), 500).Return(messages, nil)
+
+	_, err := f.useCase.RunNext(ctx, time.Now())
+	assert.NoError(t, err)
+
+	data := f.download(t, 2, job.ID)
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if !assert.NoError(t, err) {
+		return
+	}
+	contents := make(map[string]string)
+	for _, entry := range archive.File {
+		file, err := entry.Open()
+		assert.NoError(t, err)
+		body, _ := io.ReadAll(file)
+		file.Close()
+		contents[entry.Name] = string(body)
+	}
+	assert.Len(t, contents, 2)
+	assert.Equal(t, "JPEG", contents["attachments/file_1.jpg"])
+
+	page := contents["messages.html"]
+	assert.Contains(t, page, "與 Alice 的私聊")
+	assert.Contains(t, page, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.NotContains(t, page, "<script>")
+	assert.Contains(t, page, `<img src="attachments/file_1.jpg"`)
+	assert.Contains(t, page, "<p><strong>重點</strong></p>")
+	assert.NotContains(t, page, `href="javascript:`)
+}
+
+// 測試 CSV 匯出加上 BOM，並避免內容被試算表當作公式
+func TestExportUseCase_RunNext_CSV(t *testing.T) {
+	f := newExportFixture()
+	ctx := context.Background()
+
+	job := &entities.ExportJob{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, Format: entities.ExportFormatCSV}
+	assert.NoError(t, f.useCase.CreateExport(ctx, job))
+	edited := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	f.messages.On("CountConversationMessages", mock.Anything, entities.ConversationTypePrivate, uint(1), uint(2)).Return(1, nil)
+	f.messages.On("FindConversationBatch", mock.Anything, entities.ConversationTypePrivate, uint(1), uint(2), uint(0), 500).Return([]*entities.Message{
+		{ID: 7, Seq: 3, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "=HYPERLINK(\"http://evil\")", CreatedAt: edited, EditedAt: &edited},
+	}, nil)
+
+	_, err := f.useCase.RunNext(ctx, time.Now())
+	assert.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSpace(string(f.download(t, 1, job.ID))), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.True(t, strings.HasPrefix(lines[0], "\ufeffid,seq,created_at"))
+		assert.Equal(t, `7,3,2024-01-02T03:04:05Z,2,Bob,text,"'=HYPERLINK(""http://evil"")",,,,2024-01-02T03:04:05Z,false`, lines[1])
+	}
+}
+
+// 測試建立後離開群組的用戶不能完成匯出，工作直接標記為失敗
+func TestExportUseCase_RunNext_AccessRevoked(t *testing.T) {
+	f := newExportFixture()
+	ctx := context.Background()
+
+	job := &entities.ExportJob{UserID: 2, Type: entities.ConversationTypeGroup, TargetID: 5, Format: entities.ExportFormatJSON}
+	assert.NoError(t, f.useCase.CreateExport(ctx, job))
+	f.groups.members = []uint{1, 9}
+
+	ran, err := f.useCase.RunNext(ctx, time.Now())
+	assert.True(t, ran)
+	assert.Error(t, err)
+
+	stored, err := f.useCase.GetExport(ctx, 2, job.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.ExportFailed, stored.Status)
+	assert.Empty(t, f.store.files)
+	f.messages.AssertNotCalled(t, "FindConversationBatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	events := f.publisher.eventsOf(2, chat.EventExportProgress)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, entities.ExportFailed, events[0].Data.(*entities.ExportJob).Status)
+	}
+}
@@ -0,0 +1,281 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 以記憶體保存設定的假會話儲存庫
+type memoryConversationRepository struct {
+	repositories.ConversationRepository
+	muted         map[uint]bool
+	conversations map[uint]*entities.Conversation // 以會話對象ID為鍵
+	finds         int
+	afterFind     func() // 讀取後執行，用於模擬讀取期間送出的訊息
+}
+
+func (r *memoryConversationRepository) Find(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (*entities.Conversation, error) {
+	r.finds++
+	if r.afterFind != nil {
+		defer r.afterFind()
+	}
+	if conversation, ok := r.conversations[targetID]; ok {
+		return conversation, nil
+	}
+	return nil, repositories.ErrConversationNotFound
+}
+
+func (r *memoryConversationRepository) SetMuted(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, muted bool) error {
+	r.muted[targetID] = muted
+	return nil
+}
+
+func (r *memoryConversationRepository) MarkRead(ctx context.Context, userID uint, convType entities.ConversationType, targetID, messageID uint) (*entities.Conversation, error) {
+	conversation, ok := r.conversations[targetID]
+	if !ok {
+		return nil, repositories.ErrConversationNotFound
+	}
+	conversation.LastReadMessageID = messageID
+	conversation.UnreadCount = 0
+	return conversation, nil
+}
+
+func (r *memoryConversationRepository) ListByUser(ctx context.Context, userID uint, query entities.ConversationQuery) (*entities.ConversationPage, error) {
+	page := &entities.ConversationPage{}
+	for _, conversation := range r.conversations {
+		page.Conversations = append(page.Conversations, conversation)
+	}
+	sort.Slice(page.Conversations, func(i, j int) bool {
+		return page.Conversations[i].TargetID < page.Conversations[j].TargetID
+	})
+	return page, nil
+}
+
+// 以記憶體保存計數器的假未讀計數器快取，計數器只在已載入時更新，載入期間的新訊息會改變載入版本
+type memoryUnreadCounter struct {
+	counts   map[string]int
+	versions map[string]int64
+}
+
+func newMemoryUnreadCounter() *memoryUnreadCounter {
+	return &memoryUnreadCounter{counts: make(map[string]int), versions: make(map[string]int64)}
+}
+
+func unreadCounterKey(userID uint, convType entities.ConversationType, targetID uint) string {
+	return fmt.Sprintf("%d:%d:%d", userID, convType, targetID)
+}
+
+func (c *memoryUnreadCounter) RecordMessage(ctx context.Context, message *entities.Message, recipients []uint) error {
+	for _, userID := range recipients {
+		convType, targetID := entities.ConversationOf(message, userID)
+		key := unreadCounterKey(userID, convType, targetID)
+		if _, ok := c.counts[key]; !ok {
+			if _, loading := c.versions[key]; loading {
+				c.versions[key]++
+			}
+			continue
+		}
+		if userID == message.UserId {
+			c.counts[key] = 0
+		} else {
+			c.counts[key]++
+		}
+	}
+	return nil
+}
+
+func (c *memoryUnreadCounter) GetUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (int, bool, error) {
+	count, ok := c.counts[unreadCounterKey(userID, convType, targetID)]
+	return count, ok, nil
+}
+
+func (c *memoryUnreadCounter) PrepareLoad(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) (int64, error) {
+	key := unreadCounterKey(userID, convType, targetID)
+	if _, ok := c.versions[key]; !ok {
+		c.versions[key] = 1
+	}
+	return c.versions[key], nil
+}
+
+func (c *memoryUnreadCounter) LoadUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint, count int, version int64) (bool, error) {
+	key := unreadCounterKey(userID, convType, targetID)
+	if _, ok := c.counts[key]; ok {
+		return true, nil
+	}
+	if c.versions[key] != version {
+		return false, nil
+	}
+	c.counts[key] = count
+	return true, nil
+}
+
+// load 直接載入計數器，用於準備測試資料
+func (c *memoryUnreadCounter) load(userID uint, convType entities.ConversationType, targetID uint, count int) {
+	c.counts[unreadCounterKey(userID, convType, targetID)] = count
+}
+
+func (c *memoryUnreadCounter) InvalidateUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	delete(c.counts, unreadCounterKey(userID, convType, targetID))
+	return nil
+}
+
+// 測試群組會話只有成員能設定免打擾
+func TestConversationUseCase_SetMuted_RequiresGroupMembership(t *testing.T) {
+	repo := &memoryConversationRepository{muted: make(map[uint]bool)}
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{2}}
+	useCase := chat.NewConversationUseCase(repo, groupRepo, newMemoryUnreadCounter(), &stubDraftUseCase{}, newRecordingPublisher())
+	ctx := context.Background()
+
+	err := useCase.SetMuted(ctx, 1, entities.ConversationTypeGroup, 5, true)
+	assert.Error(t, err)
+	assert.NotContains(t, repo.muted, uint(5))
+
+	err = useCase.SetMuted(ctx, 2, entities.ConversationTypeGroup, 5, true)
+	assert.NoError(t, err)
+	assert.True(t, repo.muted[5])
+}
+
+// 測試標記不存在的會話為已讀時返回會話不存在錯誤
+func TestConversationUseCase_MarkRead_NotFound(t *testing.T) {
+	useCase := chat.NewConversationUseCase(&memoryConversationRepository{}, &stubGroupRepository{}, newMemoryUnreadCounter(), &stubDraftUseCase{}, newRecordingPublisher())
+
+	_, err := useCase.MarkRead(context.Background(), 1, entities.ConversationTypePrivate, 2, 0)
+
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "CONVERSATION_NOT_FOUND", appErr.Key())
+	}
+}
+
+// 測試未讀數在計數器不存在時從資料庫載入，之後隨新訊息遞增且不再查詢資料庫
+func TestConversationUseCase_GetUnreadCount(t *testing.T) {
+	repo := &memoryConversationRepository{conversations: map[uint]*entities.Conversation{
+		5: {UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, UnreadCount: 3},
+	}}
+	counter := newMemoryUnreadCounter()
+	useCase := chat.NewConversationUseCase(repo, &stubGroupRepository{}, counter, &stubDraftUseCase{}, newRecordingPublisher())
+	ctx := context.Background()
+
+	count, err := useCase.GetUnreadCount(ctx, 1, entities.ConversationTypeGroup, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+
+	message := &entities.Message{ID: 20, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup}
+	assert.NoError(t, counter.RecordMessage(ctx, message, []uint{1, 2}))
+
+	count, err = useCase.GetUnreadCount(ctx, 1, entities.ConversationTypeGroup, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, 1, repo.finds)
+
+	count, err = useCase.GetUnreadCount(ctx, 1, entities.ConversationTypePrivate, 9)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// 測試從資料庫讀取未讀數後、寫入計數器前有新訊息時，重新讀取資料庫而不載入少算的計數
+func TestConversationUseCase_GetUnreadCount_SendDuringLoad(t *testing.T) {
+	conversation := &entities.Conversation{UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, UnreadCount: 3}
+	repo := &memoryConversationRepository{conversations: map[uint]*entities.Conversation{5: conversation}}
+	counter := newMemoryUnreadCounter()
+	useCase := chat.NewConversationUseCase(repo, &stubGroupRepository{}, counter, &stubDraftUseCase{}, newRecordingPublisher())
+	ctx := context.Background()
+
+	message := &entities.Message{ID: 20, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup}
+	repo.afterFind = func() {
+		repo.afterFind = nil
+		conversation.UnreadCount++
+		assert.NoError(t, counter.RecordMessage(ctx, message, []uint{1, 2}))
+	}
+
+	count, err := useCase.GetUnreadCount(ctx, 1, entities.ConversationTypeGroup, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, count)
+	assert.Equal(t, 2, repo.finds)
+
+	loaded, found, _ := counter.GetUnread(ctx, 1, entities.ConversationTypeGroup, 5)
+	assert.True(t, found)
+	assert.Equal(t, 4, loaded)
+}
+
+// 測試標記已讀會清除計數器，並推送已讀位置給用戶自己的所有裝置
+func TestConversationUseCase_MarkRead_SyncsDevices(t *testing.T) {
+	repo := &memoryConversationRepository{conversations: map[uint]*entities.Conversation{
+		2: {UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, UnreadCount: 2, LastMessageID: 30},
+	}}
+	counter := newMemoryUnreadCounter()
+	publisher := newRecordingPublisher()
+	useCase := chat.NewConversationUseCase(repo, &stubGroupRepository{}, counter, &stubDraftUseCase{}, publisher)
+	ctx := context.Background()
+	counter.load(1, entities.ConversationTypePrivate, 2, 2)
+
+	conversation, err := useCase.MarkRead(ctx, 1, entities.ConversationTypePrivate, 2, 30)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(30), conversation.LastReadMessageID)
+	_, found, _ := counter.GetUnread(ctx, 1, entities.ConversationTypePrivate, 2)
+	assert.False(t, found)
+	if assert.Len(t, publisher.published[1], 1) {
+		assert.Equal(t, chat.EventConversationRead, publisher.published[1][0].Type)
+		assert.Equal(t, conversation, publisher.published[1][0].Data)
+	}
+}
+
+// 測試無效的會話類型會被拒絕
+func TestConversationUseCase_MarkRead_InvalidType(t *testing.T) {
+	useCase := chat.NewConversationUseCase(&memoryConversationRepository{}, &stubGroupRepository{}, newMemoryUnreadCounter(), &stubDraftUseCase{}, newRecordingPublisher())
+
+	_, err := useCase.MarkRead(context.Background(), 1, entities.ConversationType(9), 2, 0)
+
+	assert.Error(t, err)
+}
+
+// 測試會話的訊息預覽與所屬對象
+func TestConversationOf_AndMessagePreview(t *testing.T) {
+	msg := &entities.Message{UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: strings.Repeat("字", 150)}
+
+	convType, targetID := entities.ConversationOf(msg, 1)
+	assert.Equal(t, entities.ConversationTypePrivate, convType)
+	assert.Equal(t, uint(2), targetID)
+
+	_, targetID = entities.ConversationOf(msg, 2)
+	assert.Equal(t, uint(1), targetID)
+
+	assert.Equal(t, strings.Repeat("字", 100)+"…", entities.MessagePreview(msg))
+
+	msg.Media = entities.MediaTypeImage
+	assert.Equal(t, "[圖片]", entities.MessagePreview(msg))
+}
+
+// 測試會話列表附加用戶在各會話的草稿
+func TestConversationUseCase_ListConversations_AttachesDrafts(t *testing.T) {
+	repo := &memoryConversationRepository{conversations: map[uint]*entities.Conversation{
+		2: {UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2},
+		5: {UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5},
+	}}
+	drafts := &stubDraftUseCase{drafts: []*entities.Draft{
+		{UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, Content: "half written"},
+		{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 5, Content: "other"},
+	}}
+	useCase := chat.NewConversationUseCase(repo, &stubGroupRepository{}, newMemoryUnreadCounter(), drafts, newRecordingPublisher())
+
+	page, err := useCase.ListConversations(context.Background(), 1, entities.ConversationQuery{})
+
+	assert.NoError(t, err)
+	if assert.Len(t, page.Conversations, 2) {
+		assert.Nil(t, page.Conversations[0].Draft)
+		if assert.NotNil(t, page.Conversations[1].Draft) {
+			assert.Equal(t, "half written", page.Conversations[1].Draft.Content)
+		}
+	}
+}
@@ -0,0 +1,241 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 只返回固定草稿的假草稿用例
+type stubDraftUseCase struct {
+	chat.DraftUseCase
+	drafts  []*entities.Draft
+	cleared []string
+}
+
+func (uc *stubDraftUseCase) ListDrafts(ctx context.Context, userID uint) ([]*entities.Draft, error) {
+	return uc.drafts, nil
+}
+
+func (uc *stubDraftUseCase) ClearDraft(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	uc.cleared = append(uc.cleared, fmt.Sprintf("%d:%s", userID, entities.DraftConversationKey(convType, targetID)))
+	return nil
+}
+
+// 以記憶體保存草稿的假草稿儲存庫
+type memoryDraftRepository struct {
+	mu     sync.Mutex                 // 防抖在背景寫入草稿
+	drafts map[string]*entities.Draft // userId:type:targetId -> 草稿
+}
+
+func newMemoryDraftRepository() *memoryDraftRepository {
+	return &memoryDraftRepository{drafts: make(map[string]*entities.Draft)}
+}
+
+func (r *memoryDraftRepository) Save(ctx context.Context, draft *entities.Draft) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *draft
+	r.drafts[fmt.Sprintf("%d:%s", draft.UserID, draft.ConversationKey())] = &saved
+	return nil
+}
+
+func (r *memoryDraftRepository) Delete(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.drafts, fmt.Sprintf("%d:%s", userID, entities.DraftConversationKey(convType, targetID)))
+	return nil
+}
+
+func (r *memoryDraftRepository) ListByUser(ctx context.Context, userID uint) ([]*entities.Draft, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var drafts []*entities.Draft
+	for _, draft := range r.drafts {
+		if draft.UserID == userID {
+			saved := *draft
+			drafts = append(drafts, &saved)
+		}
+	}
+	return drafts, nil
+}
+
+// 以記憶體保存草稿的假草稿快取，與 Redis 實作相同只在用戶的草稿已載入時寫入
+type memoryDraftCache struct {
+	mu    sync.Mutex
+	users map[uint]map[string]*entities.Draft
+	dirty map[string]time.Time // userId:type:targetId -> 標記時間
+}
+
+func newMemoryDraftCache() *memoryDraftCache {
+	return &memoryDraftCache{users: make(map[uint]map[string]*entities.Draft), dirty: make(map[string]time.Time)}
+}
+
+func (c *memoryDraftCache) GetDrafts(ctx context.Context, userID uint) ([]*entities.Draft, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	drafts, ok := c.users[userID]
+	if !ok {
+		return nil, false, nil
+	}
+	var list []*entities.Draft
+	for _, draft := range drafts {
+		list = append(list, draft)
+	}
+	return list, true, nil
+}
+
+func (c *memoryDraftCache) LoadDrafts(ctx context.Context, userID uint, drafts []*entities.Draft) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.users[userID]; ok {
+		return nil
+	}
+	c.users[userID] = make(map[string]*entities.Draft)
+	for _, draft := range drafts {
+		c.users[userID][draft.ConversationKey()] = draft
+	}
+	return nil
+}
+
+func (c *memoryDraftCache) SaveDraft(ctx context.Context, draft *entities.Draft) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	drafts, ok := c.users[draft.UserID]
+	if !ok {
+		return false, nil
+	}
+	if draft.Content == "" {
+		delete(drafts, draft.ConversationKey())
+	} else {
+		drafts[draft.ConversationKey()] = draft
+	}
+	c.dirty[fmt.Sprintf("%d:%s", draft.UserID, draft.ConversationKey())] = draft.UpdatedAt
+	return true, nil
+}
+
+func (c *memoryDraftCache) TakeDirty(ctx context.Context, before time.Time, limit int) ([]*entities.Draft, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var drafts []*entities.Draft
+	for member, markedAt := range c.dirty {
+		if markedAt.After(before) || len(drafts) >= limit {
+			continue
+		}
+		delete(c.dirty, member)
+		draft := &entities.Draft{}
+		fmt.Sscanf(member, "%d:%d:%d", &draft.UserID, &draft.Type, &draft.TargetID)
+		if cached, ok := c.users[draft.UserID][draft.ConversationKey()]; ok {
+			draft = cached
+		}
+		drafts = append(drafts, draft)
+	}
+	return drafts, nil
+}
+
+func (c *memoryDraftCache) MarkDirty(ctx context.Context, draft *entities.Draft) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.dirty[fmt.Sprintf("%d:%s", draft.UserID, draft.ConversationKey())] = time.Now()
+	return nil
+}
+
+// 測試草稿在快取未載入時先從資料庫載入，並同步到用戶的所有裝置且不進入離線佇列
+func TestDraftUseCase_SaveAndList(t *testing.T) {
+	repo := newMemoryDraftRepository()
+	repo.drafts["1:2:5"] = &entities.Draft{UserID: 1, Type: entities.ConversationTypeGroup, TargetID: 5, Content: "old", UpdatedAt: time.Now().Add(-time.Hour)}
+	draftCache := newMemoryDraftCache()
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1}}
+	publisher := newRecordingPublisher()
+	useCase := chat.NewDraftUseCase(repo, draftCache, groupRepo, publisher)
+	ctx := context.Background()
+
+	err := useCase.SaveDraft(ctx, &entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, Content: "hello"})
+	assert.NoError(t, err)
+
+	err = useCase.SaveDraft(ctx, &entities.Draft{UserID: 3, Type: entities.ConversationTypeGroup, TargetID: 5, Content: "x"})
+	assertAppErrorKey(t, err, "NOT_GROUP_MEMBER")
+
+	drafts, err := useCase.ListDrafts(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, drafts, 2) {
+		assert.Equal(t, "hello", drafts[0].Content)
+		assert.Equal(t, "old", drafts[1].Content)
+	}
+	if assert.Len(t, publisher.published[1], 1) {
+		event := publisher.published[1][0]
+		assert.Equal(t, chat.EventDraftUpdated, event.Type)
+		assert.True(t, event.Transient)
+	}
+}
+
+// 測試草稿在停止編輯一段時間後才寫回資料庫，清除的草稿從資料庫刪除
+func TestDraftUseCase_FlushDrafts(t *testing.T) {
+	repo := newMemoryDraftRepository()
+	repo.drafts["1:1:3"] = &entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 3, Content: "stale"}
+	useCase := chat.NewDraftUseCase(repo, newMemoryDraftCache(), &stubGroupRepository{}, newRecordingPublisher())
+	ctx := context.Background()
+
+	assert.NoError(t, useCase.SaveDraft(ctx, &entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, Content: "typing"}))
+	assert.NoError(t, useCase.ClearDraft(ctx, 1, entities.ConversationTypePrivate, 3))
+
+	count, err := useCase.FlushDrafts(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+	assert.Equal(t, "stale", repo.drafts["1:1:3"].Content)
+
+	count, err = useCase.FlushDrafts(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	if assert.Contains(t, repo.drafts, "1:1:2") {
+		assert.Equal(t, "typing", repo.drafts["1:1:2"].Content)
+	}
+	assert.NotContains(t, repo.drafts, "1:1:3")
+}
+
+// 測試頻繁的草稿更新在合併時間內只儲存最後一次
+func TestDraftDebouncer_KeepsLatest(t *testing.T) {
+	repo := newMemoryDraftRepository()
+	publisher := newRecordingPublisher()
+	useCase := chat.NewDraftUseCase(repo, newMemoryDraftCache(), &stubGroupRepository{}, publisher)
+	debouncer := chat.NewDraftDebouncer(useCase, 20*time.Millisecond)
+
+	for _, content := range []string{"h", "he", "hel", "hello"} {
+		debouncer.Update(&entities.Draft{UserID: 1, Type: entities.ConversationTypePrivate, TargetID: 2, Content: content})
+	}
+
+	assert.Eventually(t, func() bool {
+		drafts, _ := useCase.ListDrafts(context.Background(), 1)
+		return len(drafts) == 1 && drafts[0].Content == "hello"
+	}, time.Second, 10*time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return len(publisher.eventsOf(1, chat.EventDraftUpdated)) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+// 測試從輸入框發送訊息後清除發送者的會話草稿，討論串回覆不清除
+func TestDraftClearingMessageUseCase_ClearsOnSend(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	drafts := &stubDraftUseCase{}
+	inner := chat.NewMessageUseCase(newMessageDeps(mockRepo, mockCache))
+	useCase := chat.NewDraftClearingMessageUseCase(inner, drafts)
+	ctx := context.Background()
+
+	mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Content: "hi"}))
+	assert.Equal(t, []string{"1:1:2"}, drafts.cleared)
+
+	assert.Error(t, useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 1, Content: "no target"}))
+	assert.Len(t, drafts.cleared, 1)
+}
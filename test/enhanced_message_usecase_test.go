@@ -35,6 +35,14 @@ func (m *MockMessageRepository) FindByID(ctx context.Context, id uint) (*entitie
 	return args.Get(0).(*entities.Message), args.Error(1)
 }
 
+func (m *MockMessageRepository) FindByClientMsgID(ctx context.Context, userID uint, clientMsgID string) (*entities.Message, error) {
+	args := m.Called(ctx, userID, clientMsgID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Message), args.Error(1)
+}
+
 func (m *MockMessageRepository) FindMessagesByUserID(ctx context.Context, userId uint) ([]*entities.Message, error) {
 	args := m.Called(ctx, userId)
 	if args.Get(0) == nil {
@@ -59,11 +67,100 @@ func (m *MockMessageRepository) FindMessagesByRoomID(ctx context.Context, roomID
 	return args.Get(0).([]*entities.Message), args.Error(1)
 }
 
+func (m *MockMessageRepository) FindMessagesBetweenUsersPage(ctx context.Context, userID, targetID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	args := m.Called(ctx, userID, targetID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.MessagePage), args.Error(1)
+}
+
+func (m *MockMessageRepository) FindMessagesByRoomIDPage(ctx context.Context, roomID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	args := m.Called(ctx, roomID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.MessagePage), args.Error(1)
+}
+
+func (m *MockMessageRepository) UpdateContent(ctx context.Context, message *entities.Message, revision *entities.MessageRevision) error {
+	args := m.Called(ctx, message, revision)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) UpdateMetadata(ctx context.Context, message *entities.Message) (bool, error) {
+	args := m.Called(ctx, message)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessageRepository) Recall(ctx context.Context, message *entities.Message) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) FindByIDs(ctx context.Context, ids []uint) ([]*entities.Message, error) {
+	args := m.Called(ctx, ids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) FindThreadRepliesPage(ctx context.Context, rootID uint, query entities.HistoryQuery) (*entities.MessagePage, error) {
+	args := m.Called(ctx, rootID, query)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.MessagePage), args.Error(1)
+}
+
+func (m *MockMessageRepository) AddThreadReply(ctx context.Context, rootID, userID uint, repliedAt time.Time) (*entities.Message, error) {
+	args := m.Called(ctx, rootID, userID, repliedAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) FindRevisions(ctx context.Context, messageID uint) ([]*entities.MessageRevision, error) {
+	args := m.Called(ctx, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.MessageRevision), args.Error(1)
+}
+
 func (m *MockMessageRepository) Delete(ctx context.Context, id uint) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+func (m *MockMessageRepository) FindExpired(ctx context.Context, now time.Time, limit int) ([]*entities.Message, error) {
+	args := m.Called(ctx, now, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) DeleteMessages(ctx context.Context, ids []uint) error {
+	args := m.Called(ctx, ids)
+	return args.Error(0)
+}
+
+func (m *MockMessageRepository) FindConversationBatch(ctx context.Context, convType entities.ConversationType, userID, targetID, afterID uint, limit int) ([]*entities.Message, error) {
+	args := m.Called(ctx, convType, userID, targetID, afterID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*entities.Message), args.Error(1)
+}
+
+func (m *MockMessageRepository) CountConversationMessages(ctx context.Context, convType entities.ConversationType, userID, targetID uint) (int, error) {
+	args := m.Called(ctx, convType, userID, targetID)
+	return args.Int(0), args.Error(1)
+}
+
 // 模擬 MessageCacheRepository
 type MockMessageCacheRepository struct {
 	mock.Mock
@@ -96,6 +193,65 @@ func (m *MockMessageCacheRepository) GetGroupMessages(ctx context.Context, roomI
 }
 
 // CleanExpiredMessages 清理過期訊息
+func (m *MockMessageCacheRepository) GetPrivateMessagesPage(ctx context.Context, fromUserID, toUserID uint, query entities.HistoryQuery) (*entities.MessagePage, bool, error) {
+	args := m.Called(ctx, fromUserID, toUserID, query)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*entities.MessagePage), args.Bool(1), args.Error(2)
+}
+
+func (m *MockMessageCacheRepository) GetGroupMessagesPage(ctx context.Context, roomID uint, query entities.HistoryQuery) (*entities.MessagePage, bool, error) {
+	args := m.Called(ctx, roomID, query)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).(*entities.MessagePage), args.Bool(1), args.Error(2)
+}
+
+func (m *MockMessageCacheRepository) WarmPrivateMessages(ctx context.Context, userID, targetID uint, messages []*entities.Message) error {
+	args := m.Called(ctx, userID, targetID, messages)
+	return args.Error(0)
+}
+
+func (m *MockMessageCacheRepository) WarmGroupMessages(ctx context.Context, roomID uint, messages []*entities.Message) error {
+	args := m.Called(ctx, roomID, messages)
+	return args.Error(0)
+}
+
+func (m *MockMessageCacheRepository) UpdateMessage(ctx context.Context, message *entities.Message) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockMessageCacheRepository) GetPins(ctx context.Context, scope string) ([]*entities.PinnedMessage, bool, error) {
+	args := m.Called(ctx, scope)
+	if args.Get(0) == nil {
+		return nil, args.Bool(1), args.Error(2)
+	}
+	return args.Get(0).([]*entities.PinnedMessage), args.Bool(1), args.Error(2)
+}
+
+func (m *MockMessageCacheRepository) StorePins(ctx context.Context, scope string, pins []*entities.PinnedMessage) error {
+	args := m.Called(ctx, scope, pins)
+	return args.Error(0)
+}
+
+func (m *MockMessageCacheRepository) InvalidatePins(ctx context.Context, scope string) error {
+	args := m.Called(ctx, scope)
+	return args.Error(0)
+}
+
+func (m *MockMessageCacheRepository) InvalidateMessages(ctx context.Context, message *entities.Message) error {
+	args := m.Called(ctx, message)
+	return args.Error(0)
+}
+
+func (m *MockMessageCacheRepository) RemoveMessages(ctx context.Context, messages []*entities.Message) error {
+	args := m.Called(ctx, messages)
+	return args.Error(0)
+}
+
 func (m *MockMessageCacheRepository) CleanExpiredMessages(ctx context.Context) error {
 	args := m.Called(ctx)
 	return args.Error(0)
@@ -110,6 +266,18 @@ func (m *MockMessageCacheRepository) GetUserMessageList(ctx context.Context, use
 	return args.Get(0).([]*entities.Message), args.Error(1)
 }
 
+// GetClientMessageID 查詢去重記錄
+func (m *MockMessageCacheRepository) GetClientMessageID(ctx context.Context, userID uint, clientMsgID string) (uint, error) {
+	args := m.Called(ctx, userID, clientMsgID)
+	return args.Get(0).(uint), args.Error(1)
+}
+
+// StoreClientMessageID 儲存去重記錄
+func (m *MockMessageCacheRepository) StoreClientMessageID(ctx context.Context, userID uint, clientMsgID string, messageID uint) error {
+	args := m.Called(ctx, userID, clientMsgID, messageID)
+	return args.Error(0)
+}
+
 // 測試發送私人訊息
 func TestEnhancedMessageUseCase_SendPrivateMessage(t *testing.T) {
 	mockRepo := new(MockMessageRepository)
@@ -0,0 +1,368 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 以記憶體保存匯入資料的假儲存庫
+type memoryImportRepository struct {
+	mappings map[string]uint
+	emails   map[string]uint
+	users    []*entities.User
+	groups   []*entities.Group
+	members  map[uint]map[uint]entities.GroupRole
+	messages []*entities.Message
+}
+
+func newMemoryImportRepository() *memoryImportRepository {
+	return &memoryImportRepository{
+		mappings: make(map[string]uint),
+		emails:   make(map[string]uint),
+		members:  make(map[uint]map[uint]entities.GroupRole),
+	}
+}
+
+func importMappingKey(source string, kind entities.ImportKind, externalID string) string {
+	return source + "|" + string(kind) + "|" + externalID
+}
+
+func (r *memoryImportRepository) FindMappings(ctx context.Context, source string, kind entities.ImportKind, externalIDs []string) (map[string]uint, error) {
+	result := make(map[string]uint)
+	for _, id := range externalIDs {
+		if localID, ok := r.mappings[importMappingKey(source, kind, id)]; ok {
+			result[id] = localID
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryImportRepository) FindUserIDsByEmail(ctx context.Context, emails []string) (map[string]uint, error) {
+	result := make(map[string]uint)
+	for _, email := range emails {
+		if id, ok := r.emails[email]; ok {
+			result[email] = id
+		}
+	}
+	return result, nil
+}
+
+func (r *memoryImportRepository) saveMapping(mapping *entities.ImportMapping) {
+	r.mappings[importMappingKey(mapping.Source, mapping.Kind, mapping.ExternalID)] = mapping.LocalID
+}
+
+func (r *memoryImportRepository) CreateUser(ctx context.Context, user *entities.User, mapping *entities.ImportMapping) error {
+	user.ID = uint(100 + len(r.users))
+	r.users = append(r.users, user)
+	r.emails[user.Email] = user.ID
+	mapping.LocalID = user.ID
+	r.saveMapping(mapping)
+	return nil
+}
+
+func (r *memoryImportRepository) LinkUser(ctx context.Context, mapping *entities.ImportMapping) error {
+	r.saveMapping(mapping)
+	return nil
+}
+
+func (r *memoryImportRepository) CreateGroup(ctx context.Context, group *entities.Group, members []*entities.GroupMember, mapping *entities.ImportMapping) error {
+	group.ID = uint(10 + len(r.groups))
+	r.groups = append(r.groups, group)
+	r.members[group.ID] = make(map[uint]entities.GroupRole)
+	for _, member := range members {
+		member.GroupID = group.ID
+	}
+	_, _ = r.AddMembers(ctx, members)
+	mapping.LocalID = group.ID
+	r.saveMapping(mapping)
+	return nil
+}
+
+func (r *memoryImportRepository) AddMembers(ctx context.Context, members []*entities.GroupMember) (int, error) {
+	added := 0
+	for _, member := range members {
+		if _, ok := r.members[member.GroupID][member.UserID]; !ok {
+			r.members[member.GroupID][member.UserID] = member.Role
+			added++
+		}
+	}
+	return added, nil
+}
+
+func (r *memoryImportRepository) InsertMessages(ctx context.Context, source string, messages []*entities.Message, externalIDs []string) error {
+	for i, message := range messages {
+		message.ID = uint(1000 + len(r.messages))
+		r.messages = append(r.messages, message)
+		r.saveMapping(&entities.ImportMapping{Source: source, Kind: entities.ImportKindMessage, ExternalID: externalIDs[i], LocalID: message.ID})
+	}
+	return nil
+}
+
+func (r *memoryImportRepository) FindLatestMessageTime(ctx context.Context, message *entities.Message) (time.Time, error) {
+	var latest time.Time
+	for _, existing := range r.messages {
+		if entities.PinScopeOf(existing) == entities.PinScopeOf(message) && existing.CreatedAt.After(latest) {
+			latest = existing.CreatedAt
+		}
+	}
+	return latest, nil
+}
+
+// 由匯入儲存庫提供成員資料的假群組儲存庫
+type importGroupRepository struct {
+	repositories.GroupRepository
+	repo *memoryImportRepository
+}
+
+func (r *importGroupRepository) GetMembers(ctx context.Context, groupID uint) ([]uint, error) {
+	var members []uint
+	for userID := range r.repo.members[groupID] {
+		members = append(members, userID)
+	}
+	return members, nil
+}
+
+// 記錄討論串回覆的假訊息儲存庫
+type threadRecordingMessageRepository struct {
+	repositories.MessageRepository
+	replies map[uint]int // rootID -> 回覆數
+}
+
+func (r *threadRecordingMessageRepository) AddThreadReply(ctx context.Context, rootID, userID uint, repliedAt time.Time) (*entities.Message, error) {
+	r.replies[rootID]++
+	return &entities.Message{ID: rootID}, nil
+}
+
+// 記錄匯入時建立會話的假會話儲存庫
+type importRecordingConversationRepository struct {
+	repositories.ConversationRepository
+	imported map[uint][]uint // messageID -> 建立會話的用戶
+}
+
+func (r *importRecordingConversationRepository) RecordImported(ctx context.Context, message *entities.Message, userIDs []uint) error {
+	r.imported[message.ID] = userIDs
+	return nil
+}
+
+// 記錄被清除快取列表的假訊息快取，以對話的釘選範圍表示
+type invalidationRecordingMessageCache struct {
+	cache.MessageCacheRepository
+	invalidated []string
+}
+
+func (c *invalidationRecordingMessageCache) InvalidateMessages(ctx context.Context, message *entities.Message) error {
+	c.invalidated = append(c.invalidated, entities.PinScopeOf(message))
+	return nil
+}
+
+// 記錄被清除的未讀計數器
+type invalidationRecordingUnreadCounter struct {
+	*memoryUnreadCounter
+	invalidated []string
+}
+
+func (c *invalidationRecordingUnreadCounter) InvalidateUnread(ctx context.Context, userID uint, convType entities.ConversationType, targetID uint) error {
+	c.invalidated = append(c.invalidated, unreadCounterKey(userID, convType, targetID))
+	return c.memoryUnreadCounter.InvalidateUnread(ctx, userID, convType, targetID)
+}
+
+type importFixture struct {
+	repo          *memoryImportRepository
+	messages      *threadRecordingMessageRepository
+	conversations *importRecordingConversationRepository
+	unread        *invalidationRecordingUnreadCounter
+	cache         *invalidationRecordingMessageCache
+	useCase       chat.ImportUseCase
+}
+
+func newImportFixture() *importFixture {
+	f := &importFixture{
+		repo:          newMemoryImportRepository(),
+		messages:      &threadRecordingMessageRepository{replies: make(map[uint]int)},
+		conversations: &importRecordingConversationRepository{imported: make(map[uint][]uint)},
+		unread:        &invalidationRecordingUnreadCounter{memoryUnreadCounter: newMemoryUnreadCounter()},
+		cache:         &invalidationRecordingMessageCache{},
+	}
+	f.useCase = chat.NewImportUseCase(f.repo, f.messages, f.cache, &importGroupRepository{repo: f.repo}, f.conversations, f.unread, newMemorySearchIndex(), newMemorySequencer())
+	return f
+}
+
+func newImportFile() *entities.ImportFile {
+	at := func(minute int) time.Time {
+		return time.Date(2024, 3, 1, 9, minute, 0, 0, time.UTC)
+	}
+	edited := at(3)
+	return &entities.ImportFile{
+		Source: "slack-acme",
+		Users: []entities.ImportUser{
+			{ID: "U1", Name: "Alice", Email: "Alice@Example.com"},
+			{ID: "U2", Name: "Bob"},
+			{ID: "U3"},
+			{ID: "U2", Name: "重複"},
+		},
+		Rooms: []entities.ImportRoom{
+			{ID: "C1", Name: "general", Owner: "U1", Members: []string{"U2", "U9"}, Admins: []string{"U2"}},
+			{ID: "C2", Name: "orphan", Owner: "U9"},
+		},
+		Messages: []entities.ImportMessage{
+			// 刻意打亂順序，匯入時依發送時間排序
+			{ID: "M3", Room: "C1", From: "U1", Text: "細節", SentAt: at(5), Thread: "M1"},
+			{ID: "M1", Room: "C1", From: "U1", Text: "大家好", SentAt: at(0)},
+			{ID: "M2", Room: "C1", From: "U2", Text: "**收到**", Format: entities.ImportFormatMarkdown, SentAt: at(1), EditedAt: &edited, ReplyTo: "M1"},
+			{ID: "M4", To: "U2", From: "U1", Text: "私訊", SentAt: at(10)},
+			{ID: "M5", Room: "C1", From: "U3", Text: "我不是成員", SentAt: at(11)},
+			{ID: "M6", Room: "C1", From: "U1", Text: "回覆不存在的訊息", SentAt: at(12), ReplyTo: "M404"},
+			{ID: "M7", Room: "C1", From: "U2", Text: "回覆私訊", SentAt: at(13), ReplyTo: "M4"},
+		},
+	}
+}
+
+// 測試試跑只產生報告不寫入資料，正式匯入保留原始時間並依時間配發序號與解析引用
+func TestImportUseCase_DryRunThenImport(t *testing.T) {
+	f := newImportFixture()
+	ctx := context.Background()
+	f.repo.emails["alice@example.com"] = 7 // 既有帳號
+
+	preview, err := f.useCase.Import(ctx, newImportFile(), true)
+	assert.NoError(t, err)
+	assert.True(t, preview.DryRun)
+	assert.Empty(t, f.repo.users)
+	assert.Empty(t, f.repo.groups)
+	assert.Empty(t, f.repo.messages)
+	assert.Empty(t, f.repo.mappings)
+
+	report, err := f.useCase.Import(ctx, newImportFile(), false)
+	assert.NoError(t, err)
+
+	// 試跑的統計與正式匯入一致
+	preview.DryRun = false
+	assert.Equal(t, report, preview)
+
+	assert.Equal(t, entities.ImportCounts{Created: 2, Linked: 1, Skipped: 1}, report.Users)
+	assert.Equal(t, entities.ImportCounts{Created: 1, Skipped: 1}, report.Rooms)
+	assert.Equal(t, 2, report.MembersAdded)
+	assert.Equal(t, entities.ImportCounts{Created: 4, Skipped: 3}, report.Messages)
+	assert.Equal(t, 6, report.IssueCount)
+
+	// 電子郵件相同的用戶對應到既有帳號，其餘建立新帳號
+	if assert.Len(t, f.repo.users, 2) {
+		assert.Equal(t, "Bob", f.repo.users[0].Name)
+		assert.Equal(t, "U3", f.repo.users[1].Name)
+		assert.True(t, strings.HasPrefix(f.repo.users[0].Email, "import_"))
+		assert.NotEmpty(t, f.repo.users[0].Password)
+	}
+	bob := f.repo.users[0].ID
+	if assert.Len(t, f.repo.groups, 1) {
+		group := f.repo.groups[0]
+		assert.Equal(t, uint(7), group.OwnerId)
+		assert.Equal(t, map[uint]entities.GroupRole{7: entities.GroupRoleMember, bob: entities.GroupRoleAdmin}, f.repo.members[group.ID])
+	}
+
+	if assert.Len(t, f.repo.messages, 4) {
+		m1, m2, m3, m4 := f.repo.messages[0], f.repo.messages[1], f.repo.messages[2], f.repo.messages[3]
+		assert.Equal(t, "大家好", m1.Content)
+		assert.Equal(t, time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC), m1.CreatedAt)
+		assert.Equal(t, uint64(1), m1.Seq)
+
+		assert.Equal(t, uint64(2), m2.Seq)
+		assert.Equal(t, &m1.ID, m2.ReplyToID)
+		assert.True(t, m2.IsEdited())
+		assert.Equal(t, "<p><strong>收到</strong></p>", m2.RichTextHTML())
+
+		assert.Equal(t, &m1.ID, m3.ThreadRootID)
+		assert.Equal(t, uint64(0), m3.Seq)
+		assert.Equal(t, 1, f.messages.replies[m1.ID])
+
+		assert.Equal(t, entities.MessageTypePrivate, m4.Type)
+		assert.Equal(t, bob, m4.TargetId)
+
+		// 只為尚未有會話的參與者以最後一則訊息建立會話，並清除所有參與者的未讀計數器
+		assert.ElementsMatch(t, []uint{7, bob}, f.conversations.imported[m2.ID])
+		assert.ElementsMatch(t, []uint{m4.UserId, bob}, f.conversations.imported[m4.ID])
+		assert.Contains(t, f.unread.invalidated, unreadCounterKey(bob, entities.ConversationTypePrivate, m4.UserId))
+		assert.Contains(t, f.unread.invalidated, unreadCounterKey(bob, entities.ConversationTypeGroup, m2.RoomID))
+	}
+}
+
+// 測試重複匯入時略過已匯入的資料，只補上新增的成員與訊息
+func TestImportUseCase_Idempotent(t *testing.T) {
+	f := newImportFixture()
+	ctx := context.Background()
+
+	_, err := f.useCase.Import(ctx, newImportFile(), false)
+	assert.NoError(t, err)
+
+	file := newImportFile()
+	file.Rooms[0].Members = append(file.Rooms[0].Members, "U3")
+	file.Messages = append(file.Messages, entities.ImportMessage{ID: "M8", Room: "C1", From: "U3", Text: "新成員", SentAt: time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC)})
+
+	report, err := f.useCase.Import(ctx, file, false)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.ImportCounts{Existing: 3, Skipped: 1}, report.Users)
+	assert.Equal(t, entities.ImportCounts{Existing: 1, Skipped: 1}, report.Rooms)
+	assert.Equal(t, 1, report.MembersAdded)
+	// U3 成為成員後，先前被略過的 M5 也一併匯入
+	assert.Equal(t, entities.ImportCounts{Created: 2, Existing: 4, Skipped: 2}, report.Messages)
+	assert.Len(t, f.repo.users, 3)
+	assert.Len(t, f.repo.groups, 1)
+	assert.Len(t, f.repo.messages, 6)
+	assert.Equal(t, 1, f.messages.replies[f.repo.messages[0].ID])
+}
+
+// 測試匯入到已有往來的對話時，只匯入晚於既有訊息的訊息，並清除該對話的訊息快取
+func TestImportUseCase_ExistingConversation(t *testing.T) {
+	f := newImportFixture()
+	ctx := context.Background()
+	f.repo.emails["alice@example.com"] = 7
+	f.repo.emails["bob@example.com"] = 8
+	live := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	f.repo.messages = append(f.repo.messages, &entities.Message{ID: 500, UserId: 8, TargetId: 7, Type: entities.MessageTypePrivate, Seq: 40, CreatedAt: live})
+
+	file := &entities.ImportFile{
+		Source: "slack-acme",
+		Users: []entities.ImportUser{
+			{ID: "U1", Email: "alice@example.com"},
+			{ID: "U2", Email: "bob@example.com"},
+		},
+		Messages: []entities.ImportMessage{
+			{ID: "M1", To: "U2", From: "U1", Text: "早於既有訊息", SentAt: live.Add(-time.Hour)},
+			{ID: "M2", To: "U1", From: "U2", Text: "晚於既有訊息", SentAt: live.Add(time.Hour)},
+		},
+	}
+
+	preview, err := f.useCase.Import(ctx, file, true)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.ImportCounts{Created: 1, Skipped: 1}, preview.Messages)
+
+	report, err := f.useCase.Import(ctx, file, false)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.ImportCounts{Created: 1, Skipped: 1}, report.Messages)
+	if assert.Len(t, report.Issues, 1) {
+		assert.Equal(t, "M1", report.Issues[0].ExternalID)
+		assert.Contains(t, report.Issues[0].Reason, "較新的訊息")
+	}
+
+	if assert.Len(t, f.repo.messages, 2) {
+		assert.Equal(t, "晚於既有訊息", f.repo.messages[1].Content)
+	}
+	assert.Equal(t, []string{"private:7:8"}, f.cache.invalidated)
+}
+
+// 測試缺少來源名稱時拒絕匯入
+func TestImportUseCase_RequiresSource(t *testing.T) {
+	f := newImportFixture()
+	file := newImportFile()
+	file.Source = "  "
+
+	_, err := f.useCase.Import(context.Background(), file, true)
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+}
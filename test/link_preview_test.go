@@ -0,0 +1,188 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 返回固定預覽的連結預覽產生器，零值不產生任何預覽
+type stubLinkPreviewer struct {
+	previews []entities.LinkPreview
+}
+
+func (p *stubLinkPreviewer) Preview(ctx context.Context, urls []string) []entities.LinkPreview {
+	return p.previews
+}
+
+// 依網址返回預設結果並記錄讀取次數的假讀取器
+type stubFetcher struct {
+	mu       sync.Mutex
+	previews map[string]*entities.LinkPreview
+	fetched  map[string]int
+}
+
+func (f *stubFetcher) Fetch(ctx context.Context, rawURL string) (*entities.LinkPreview, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.fetched[rawURL]++
+	preview, ok := f.previews[rawURL]
+	if !ok {
+		return nil, errors.New("unreachable")
+	}
+	return preview, nil
+}
+
+// 記憶體中的連結預覽快取，nil 表示快取的讀取失敗
+type memoryLinkPreviewCache struct {
+	mu       sync.Mutex
+	previews map[string]*entities.LinkPreview
+}
+
+func (c *memoryLinkPreviewCache) GetLinkPreview(ctx context.Context, url string) (*entities.LinkPreview, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	preview, found := c.previews[url]
+	return preview, found, nil
+}
+
+func (c *memoryLinkPreviewCache) StoreLinkPreview(ctx context.Context, url string, preview *entities.LinkPreview) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.previews[url] = preview
+	return nil
+}
+
+// 測試擷取網址時去除句尾標點並略過重複網址
+func TestExtractURLs(t *testing.T) {
+	urls := entities.ExtractURLs("看這個 https://example.com/a?b=1, 還有（https://example.org/x）與 https://example.com/a?b=1。ftp://no")
+	assert.Equal(t, []string{"https://example.com/a?b=1", "https://example.org/x"}, urls)
+
+	urls = entities.ExtractURLs("http://a.com http://b.com http://c.com http://d.com")
+	assert.Len(t, urls, entities.MaxLinkPreviews)
+	assert.Empty(t, entities.ExtractURLs("沒有網址"))
+}
+
+// 測試連結預覽依網址順序返回，並快取成功與失敗的結果
+func TestLinkPreviewer_UsesCache(t *testing.T) {
+	fetcher := &stubFetcher{
+		previews: map[string]*entities.LinkPreview{
+			"https://a.com": {URL: "https://a.com", Title: "A"},
+			"https://b.com": {URL: "https://b.com", Title: "B"},
+			"https://e.com": {URL: "https://e.com"},
+		},
+		fetched: make(map[string]int),
+	}
+	previewCache := &memoryLinkPreviewCache{previews: make(map[string]*entities.LinkPreview)}
+	previewer := chat.NewLinkPreviewer(fetcher, previewCache)
+	ctx := context.Background()
+	urls := []string{"https://b.com", "https://down.com", "https://a.com", "https://e.com"}
+
+	previews := previewer.Preview(ctx, urls)
+	if assert.Len(t, previews, 2) {
+		assert.Equal(t, "B", previews[0].Title)
+		assert.Equal(t, "A", previews[1].Title)
+	}
+
+	// 第二次全部由快取回答，包含讀取失敗與沒有內容的網址
+	assert.Len(t, previewer.Preview(ctx, urls), 2)
+	for _, url := range urls {
+		assert.Equal(t, 1, fetcher.fetched[url], url)
+	}
+	assert.Nil(t, previewCache.previews["https://down.com"])
+}
+
+// 測試發送含網址的訊息後在背景寫入預覽並通知雙方
+func TestMessageUseCase_SendPrivateMessage_AttachesLinkPreviews(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	publisher := newRecordingPublisher()
+	previewer := &stubLinkPreviewer{previews: []entities.LinkPreview{{URL: "https://example.com", Title: "Example"}}}
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.LinkPreviewer = previewer
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	mockRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 30
+	}).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+	mockRepo.On("UpdateMetadata", mock.Anything, mock.MatchedBy(func(m *entities.Message) bool {
+		return m.ID == 30 && len(m.LinkPreviews()) == 1
+	})).Return(true, nil)
+	mockCache.On("UpdateMessage", mock.Anything, mock.Anything).Return(nil)
+
+	msg := &entities.Message{UserId: 1, TargetId: 2, Content: "看看 https://example.com"}
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, msg))
+
+	assert.Eventually(t, func() bool {
+		return len(publisher.eventsOf(2, chat.EventMessagePreview)) == 1
+	}, time.Second, 10*time.Millisecond)
+	event := publisher.eventsOf(1, chat.EventMessagePreview)[0].Data.(*chat.LinkPreviewEvent)
+	assert.Equal(t, uint(30), event.MessageID)
+	assert.Equal(t, "Example", event.LinkPreviews[0].Title)
+	// 呼叫者持有的訊息不被背景工作修改
+	assert.Empty(t, msg.LinkPreviews())
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試訊息在產生預覽期間被編輯或撤回時不推送預覽
+func TestMessageUseCase_LinkPreviews_SkipChangedMessage(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	publisher := newRecordingPublisher()
+	previewer := &stubLinkPreviewer{previews: []entities.LinkPreview{{URL: "https://example.com", Title: "Example"}}}
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.LinkPreviewer = previewer
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	attempted := make(chan struct{})
+	mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+	mockRepo.On("UpdateMetadata", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		close(attempted)
+	}).Return(false, nil)
+
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Content: "https://example.com"}))
+
+	select {
+	case <-attempted:
+	case <-time.After(time.Second):
+		t.Fatal("未嘗試寫入連結預覽")
+	}
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, publisher.eventsOf(2, chat.EventMessagePreview))
+	mockCache.AssertNotCalled(t, "UpdateMessage", mock.Anything, mock.Anything)
+}
+
+// 測試編輯訊息時移除舊內容的預覽
+func TestMessageUseCase_EditMessage_ClearsLinkPreviews(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	useCase := newEditUseCase(mockRepo, mockCache, newRecordingPublisher())
+	ctx := context.Background()
+
+	original := &entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "https://old.com", CreatedAt: time.Now()}
+	assert.NoError(t, original.SetLinkPreviews([]entities.LinkPreview{{URL: "https://old.com", Title: "Old"}}))
+	mockRepo.On("FindByID", ctx, uint(7)).Return(original, nil)
+	mockRepo.On("UpdateContent", ctx, original, mock.Anything).Return(nil)
+	mockCache.On("UpdateMessage", ctx, original).Return(nil)
+	mockCache.On("InvalidatePins", ctx, mock.Anything).Return(nil)
+
+	edited, err := useCase.EditMessage(ctx, 1, 7, "沒有網址了")
+
+	assert.NoError(t, err)
+	assert.Empty(t, edited.LinkPreviews())
+	assert.Nil(t, edited.Metadata)
+}
@@ -0,0 +1,154 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試開啟自動消失的對話中新訊息會標記到期時間
+func TestMessageUseCase_SendPrivateMessage_StampsExpiry(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	conversationRepo := newRecordingConversationRepository()
+	conversationRepo.ttls[entities.PrivatePinScope(1, 2)] = time.Hour
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.ConversationRepo = conversationRepo
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+
+	msg := &entities.Message{UserId: 2, TargetId: 1, Content: "secret"}
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, msg))
+	if assert.NotNil(t, msg.ExpiresAt) {
+		assert.Equal(t, msg.CreatedAt.Add(time.Hour), *msg.ExpiresAt)
+	}
+
+	other := &entities.Message{UserId: 1, TargetId: 3, Content: "normal"}
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, other))
+	assert.Nil(t, other.ExpiresAt)
+}
+
+// 測試群組自動消失只有群主與管理員可以設定，變更時發出系統通知
+func TestMessageUseCase_SetDisappearing_Group(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 9}, admins: []uint{2}}
+	conversationRepo := newRecordingConversationRepository()
+	publisher := newRecordingPublisher()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.ConversationRepo = conversationRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	var notices []*entities.Message
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(m *entities.Message) bool {
+		return m.IsSystem() && m.RoomID == 5
+	})).Run(func(args mock.Arguments) {
+		notices = append(notices, args.Get(1).(*entities.Message))
+	}).Return(nil)
+	mockCache.On("StoreGroupMessage", ctx, mock.Anything).Return(nil)
+
+	err := useCase.SetDisappearing(ctx, 1, entities.ConversationTypeGroup, 5, time.Hour)
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+
+	err = useCase.SetDisappearing(ctx, 2, entities.ConversationTypeGroup, 5, 90*time.Minute)
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+
+	assert.NoError(t, useCase.SetDisappearing(ctx, 2, entities.ConversationTypeGroup, 5, 24*time.Hour))
+	// 設定未變更時不再通知
+	assert.NoError(t, useCase.SetDisappearing(ctx, 9, entities.ConversationTypeGroup, 5, 24*time.Hour))
+	assert.NoError(t, useCase.SetDisappearing(ctx, 9, entities.ConversationTypeGroup, 5, 0))
+
+	if assert.Len(t, notices, 2) {
+		assert.Equal(t, "已將訊息自動消失設為 1 天", notices[0].Content)
+		assert.JSONEq(t, `{"disappearing":{"ttl":86400}}`, string(notices[0].Metadata))
+		assert.Equal(t, "已關閉訊息自動消失", notices[1].Content)
+	}
+	assert.Len(t, publisher.published[1], 2)
+
+	ttl, err := useCase.GetDisappearing(ctx, 1, entities.ConversationTypeGroup, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), ttl)
+}
+
+// 測試清理過期訊息時刪除資料庫與快取中的訊息並通知對話參與者
+func TestMessageUseCase_PurgeExpiredMessages(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1, 2, 3}}
+	conversationRepo := newRecordingConversationRepository()
+	publisher := newRecordingPublisher()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.ConversationRepo = conversationRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+	now := time.Now()
+
+	expired := []*entities.Message{
+		{ID: 10, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate},
+		{ID: 11, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup},
+		{ID: 12, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate},
+	}
+	mockRepo.On("FindExpired", ctx, now, mock.Anything).Return(expired, nil).Once()
+	mockRepo.On("DeleteMessages", ctx, []uint{10, 11, 12}).Return(nil)
+	mockCache.On("RemoveMessages", ctx, expired).Return(nil)
+	mockCache.On("InvalidatePins", ctx, mock.Anything).Return(nil)
+
+	count, err := useCase.PurgeExpiredMessages(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	assert.Equal(t, []uint{10, 11, 12}, conversationRepo.cleared)
+	assert.ElementsMatch(t, []uint{10, 12, 11}, publisher.discarded[1])
+	assert.Equal(t, []uint{11}, publisher.discarded[3])
+
+	// 用戶 1 同時收到私聊與群組的過期通知，用戶 3 只收到群組的
+	if assert.Len(t, publisher.published[1], 2) {
+		assert.Equal(t, chat.EventMessageExpired, publisher.published[1][0].Type)
+		assert.Equal(t, []uint{10, 12}, publisher.published[1][0].Data.(*chat.ExpiredEvent).MessageIDs)
+	}
+	if assert.Len(t, publisher.published[3], 1) {
+		assert.Equal(t, uint(5), publisher.published[3][0].Data.(*chat.ExpiredEvent).RoomID)
+	}
+}
+
+// 測試已到自動消失時間、尚未被背景工作刪除的訊息不會出現在搜尋與討論串中
+func TestMessageUseCase_ExpiredMessagesHidden(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	index := newMemorySearchIndex()
+	deps := newMessageDeps(mockRepo, new(MockMessageCacheRepository))
+	deps.SearchIndex = index
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	expired := time.Now().Add(-time.Minute)
+	pending := time.Now().Add(time.Hour)
+	root := &entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "秘密計畫", ExpiresAt: &expired}
+	assert.NoError(t, index.Index(ctx, root))
+	assert.NoError(t, index.Index(ctx, &entities.Message{ID: 8, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "秘密計畫", ExpiresAt: &pending}))
+
+	result, err := chat.NewSearchUseCase(index, &stubGroupRepository{group: &entities.Group{ID: 5}}).SearchMessages(ctx, 1, chat.SearchQuery{Text: "秘密計畫"})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Hits, 1) {
+		assert.Equal(t, uint(8), result.Hits[0].Message.ID)
+	}
+
+	mockRepo.On("FindByID", ctx, uint(7)).Return(root, nil)
+	_, err = useCase.GetThread(ctx, 1, 7, entities.HistoryQuery{})
+	assertAppErrorKey(t, err, "MESSAGE_NOT_FOUND")
+}
@@ -0,0 +1,86 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newEditUseCase(mockRepo *MockMessageRepository, mockCache *MockMessageCacheRepository, publisher *recordingPublisher) chat.MessageUseCase {
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = &stubGroupRepository{}
+	deps.Publisher = publisher
+	deps.Settings = chat.MessageSettings{EditWindow: 10 * time.Minute}
+	return chat.NewMessageUseCase(deps)
+}
+
+// 測試編輯訊息會保存舊版本、更新快取並通知雙方
+func TestMessageUseCase_EditMessage(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	publisher := newRecordingPublisher()
+	useCase := newEditUseCase(mockRepo, mockCache, publisher)
+	ctx := context.Background()
+
+	original := &entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "helo", CreatedAt: time.Now()}
+	mockRepo.On("FindByID", ctx, uint(7)).Return(original, nil)
+	mockRepo.On("UpdateContent", ctx, original, mock.MatchedBy(func(revision *entities.MessageRevision) bool {
+		return revision.MessageID == 7 && revision.Content == "helo" && revision.EditedBy == 1
+	})).Return(nil)
+	mockCache.On("UpdateMessage", ctx, original).Return(nil)
+	mockCache.On("InvalidatePins", ctx, mock.Anything).Return(nil)
+
+	edited, err := useCase.EditMessage(ctx, 1, 7, "hello")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", edited.Content)
+	assert.True(t, edited.IsEdited())
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	for _, userID := range []uint{1, 2} {
+		if assert.Len(t, publisher.published[userID], 1) {
+			assert.Equal(t, chat.EventMessageEdited, publisher.published[userID][0].Type)
+		}
+	}
+}
+
+// 測試只能編輯自己發送的訊息
+func TestMessageUseCase_EditMessage_NotSender(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	useCase := newEditUseCase(mockRepo, new(MockMessageCacheRepository), newRecordingPublisher())
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, UserId: 1, TargetId: 2, Media: entities.MediaTypeText, CreatedAt: time.Now()}, nil)
+
+	_, err := useCase.EditMessage(ctx, 2, 7, "hello")
+
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "ACCESS_DENIED", appErr.Key())
+	}
+	mockRepo.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試超過可編輯時間的訊息無法編輯
+func TestMessageUseCase_EditMessage_WindowExpired(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	useCase := newEditUseCase(mockRepo, new(MockMessageCacheRepository), newRecordingPublisher())
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, UserId: 1, TargetId: 2, Media: entities.MediaTypeText, CreatedAt: time.Now().Add(-time.Hour)}, nil)
+
+	_, err := useCase.EditMessage(ctx, 1, 7, "hello")
+
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "MESSAGE_EDIT_EXPIRED", appErr.Key())
+	}
+	mockRepo.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything, mock.Anything)
+}
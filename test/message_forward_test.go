@@ -0,0 +1,108 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試多則訊息轉發到私聊與群組時依原始順序建立副本，並記錄來源、不帶入提及
+func TestMessageUseCase_ForwardMessages(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1, 2, 4}}
+	publisher := newRecordingPublisher()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	sentAt := time.Now().Add(-time.Hour)
+	image := &entities.Message{ID: 30, UserId: 4, RoomID: 5, Type: entities.MessageTypeGroup, Media: entities.MediaTypeImage,
+		Content: "https://cdn.example.com/a.png", Metadata: entities.JSON(`{"width":640,"mentions":[{"user_id":2,"offset":0,"length":2}]}`), CreatedAt: sentAt}
+	text := &entities.Message{ID: 20, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "@all 看這個", CreatedAt: sentAt}
+
+	mockRepo.On("FindByIDs", ctx, []uint{30, 20}).Return([]*entities.Message{image, text}, nil)
+	nextID := uint(100)
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*entities.Message")).Run(func(args mock.Arguments) {
+		nextID++
+		args.Get(1).(*entities.Message).ID = nextID
+	}).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+	mockCache.On("StoreGroupMessage", ctx, mock.Anything).Return(nil)
+
+	forwarded, err := useCase.ForwardMessages(ctx, 1, []uint{30, 20, 30}, []chat.ForwardTarget{
+		{Type: entities.ConversationTypePrivate, TargetID: 3},
+		{Type: entities.ConversationTypeGroup, TargetID: 5},
+	})
+
+	assert.NoError(t, err)
+	if assert.Len(t, forwarded, 4) {
+		first := forwarded[0]
+		assert.Equal(t, uint(1), first.UserId)
+		assert.Equal(t, uint(3), first.TargetId)
+		assert.Equal(t, "@all 看這個", first.Content)
+		origin := first.ForwardOrigin()
+		if assert.NotNil(t, origin) {
+			assert.Equal(t, uint(20), origin.MessageID)
+			assert.Equal(t, uint(2), origin.SenderID)
+			assert.Equal(t, entities.MessageTypePrivate, origin.Type)
+			assert.Equal(t, uint(1), origin.TargetID)
+		}
+
+		groupImage := forwarded[3]
+		assert.Equal(t, entities.MessageTypeGroup, groupImage.Type)
+		assert.Equal(t, uint(5), groupImage.RoomID)
+		assert.Equal(t, entities.MediaTypeImage, groupImage.Media)
+		assert.Empty(t, groupImage.Mentions())
+		assert.Contains(t, string(groupImage.Metadata), `"width":640`)
+		if origin := groupImage.ForwardOrigin(); assert.NotNil(t, origin) {
+			assert.Equal(t, uint(5), origin.RoomID)
+			assert.Equal(t, uint(4), origin.SenderID)
+		}
+
+		// 再次轉發時沿用最初的來源
+		again, err := groupImage.ForwardCopy(2)
+		assert.NoError(t, err)
+		assert.Equal(t, uint(30), again.ForwardOrigin().MessageID)
+	}
+	assert.Len(t, publisher.published[3], 2)
+	assert.Len(t, publisher.published[2], 2)
+}
+
+// 測試來源或目的對話的權限不足時不會發送任何副本
+func TestMessageUseCase_ForwardMessages_ChecksPermissions(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{2}}
+	deps := newMessageDeps(mockRepo, new(MockMessageCacheRepository))
+	deps.GroupRepo = groupRepo
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	mockRepo.On("FindByIDs", ctx, []uint{40}).Return([]*entities.Message{{ID: 40, UserId: 3, TargetId: 4, Type: entities.MessageTypePrivate}}, nil)
+	mockRepo.On("FindByIDs", ctx, []uint{41}).Return([]*entities.Message{{ID: 41, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate}}, nil)
+
+	_, err := useCase.ForwardMessages(ctx, 1, []uint{40}, []chat.ForwardTarget{{Type: entities.ConversationTypePrivate, TargetID: 2}})
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "ACCESS_DENIED", appErr.Key())
+	}
+
+	_, err = useCase.ForwardMessages(ctx, 1, []uint{41}, []chat.ForwardTarget{
+		{Type: entities.ConversationTypePrivate, TargetID: 3},
+		{Type: entities.ConversationTypeGroup, TargetID: 5},
+	})
+	appErr, ok = baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "NOT_GROUP_MEMBER", appErr.Key())
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
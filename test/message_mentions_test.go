@@ -0,0 +1,91 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試提及解析只接受位於開頭或空白後的 @用戶ID 與 @all，並去除重複
+func TestParseMentions(t *testing.T) {
+	mentions := entities.ParseMentions("@2 你好 @all，寄到 a@3 @2 @x @0")
+
+	assert.Equal(t, []entities.Mention{
+		{UserID: 2, Offset: 0, Length: 2},
+		{All: true, Offset: 6, Length: 4},
+	}, mentions)
+}
+
+// 測試群組訊息中的提及會過濾非成員，並通知被提及者與累加提及數
+func TestMessageUseCase_SendGroupMessage_Mentions(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 3}}
+	publisher := newRecordingPublisher()
+	conversationRepo := newRecordingConversationRepository()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.ConversationRepo = conversationRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	msg := &entities.Message{UserId: 1, RoomID: 5, Content: "@2 @8 @1 看一下", Metadata: entities.JSON(`{"color":"red"}`)}
+	mockRepo.On("Create", ctx, msg).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 40
+	}).Return(nil)
+	mockCache.On("StoreGroupMessage", ctx, msg).Return(nil)
+
+	err := useCase.SendGroupMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.Mention{{UserID: 2, Offset: 0, Length: 2}}, msg.Mentions())
+	assert.JSONEq(t, `{"color":"red","mentions":[{"user_id":2,"offset":0,"length":2}]}`, string(msg.Metadata))
+	assert.Equal(t, []uint{2}, conversationRepo.mentioned[40])
+
+	if assert.Len(t, publisher.published[2], 2) {
+		assert.Equal(t, chat.EventMessageMention, publisher.published[2][1].Type)
+	}
+	assert.Len(t, publisher.published[3], 1)
+}
+
+// 測試只有群主與管理員可以 @all
+func TestMessageUseCase_SendGroupMessage_MentionAll(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 3}, admins: []uint{2}}
+	publisher := newRecordingPublisher()
+	conversationRepo := newRecordingConversationRepository()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.ConversationRepo = conversationRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	err := useCase.SendGroupMessage(ctx, &entities.Message{UserId: 1, RoomID: 5, Content: "@all 開會"})
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "ACCESS_DENIED", appErr.Key())
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+
+	msg := &entities.Message{UserId: 2, RoomID: 5, Content: "@all 開會"}
+	mockRepo.On("Create", ctx, msg).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 41
+	}).Return(nil)
+	mockCache.On("StoreGroupMessage", ctx, msg).Return(nil)
+
+	err = useCase.SendGroupMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.True(t, msg.MentionsAll())
+	assert.Equal(t, []uint{1, 3}, conversationRepo.mentioned[41])
+	assert.Empty(t, publisher.published[2])
+}
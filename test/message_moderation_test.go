@@ -0,0 +1,318 @@
+package test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 以記憶體保存審核規則、審核佇列與稽核日誌的假儲存庫
+type memoryModerationRepository struct {
+	rules  []*entities.ModerationRule
+	held   []*entities.HeldMessage
+	logs   []*entities.ModerationLog
+	nextID uint
+}
+
+func newMemoryModerationRepository() *memoryModerationRepository {
+	return &memoryModerationRepository{}
+}
+
+func (r *memoryModerationRepository) ListRules(ctx context.Context, groupID uint) ([]*entities.ModerationRule, error) {
+	var rules []*entities.ModerationRule
+	for _, rule := range r.rules {
+		if rule.GroupID == groupID {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *memoryModerationRepository) CreateRule(ctx context.Context, rule *entities.ModerationRule) error {
+	r.nextID++
+	rule.ID = r.nextID
+	copied := *rule
+	r.rules = append(r.rules, &copied)
+	return nil
+}
+
+func (r *memoryModerationRepository) DeleteRule(ctx context.Context, groupID, ruleID uint) (bool, error) {
+	for i, rule := range r.rules {
+		if rule.ID == ruleID && rule.GroupID == groupID {
+			r.rules = append(r.rules[:i], r.rules[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryModerationRepository) CreateHeld(ctx context.Context, held *entities.HeldMessage) error {
+	r.nextID++
+	held.ID = r.nextID
+	copied := *held
+	r.held = append(r.held, &copied)
+	return nil
+}
+
+func (r *memoryModerationRepository) FindHeld(ctx context.Context, id uint) (*entities.HeldMessage, error) {
+	for _, held := range r.held {
+		if held.ID == id {
+			copied := *held
+			return &copied, nil
+		}
+	}
+	return nil, repositories.ErrHeldMessageNotFound
+}
+
+func (r *memoryModerationRepository) ListHeld(ctx context.Context, query entities.HeldQuery) ([]*entities.HeldMessage, error) {
+	var held []*entities.HeldMessage
+	for i := len(r.held) - 1; i >= 0 && len(held) <= query.Limit; i-- {
+		item := r.held[i]
+		if item.Status == query.Status && (query.GroupID == 0 || (item.IsGroup() && item.TargetID == query.GroupID)) &&
+			(query.BeforeID == 0 || item.ID < query.BeforeID) {
+			held = append(held, item)
+		}
+	}
+	return held, nil
+}
+
+func (r *memoryModerationRepository) UpdateHeld(ctx context.Context, held *entities.HeldMessage, from entities.HeldStatus) (bool, error) {
+	for _, existing := range r.held {
+		if existing.ID == held.ID && existing.Status == from {
+			*existing = *held
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryModerationRepository) CreateLog(ctx context.Context, log *entities.ModerationLog) error {
+	r.nextID++
+	log.ID = r.nextID
+	r.logs = append(r.logs, log)
+	return nil
+}
+
+func (r *memoryModerationRepository) ListLogs(ctx context.Context, query entities.ModerationLogQuery) ([]*entities.ModerationLog, error) {
+	var logs []*entities.ModerationLog
+	for i := len(r.logs) - 1; i >= 0 && len(logs) <= query.Limit; i-- {
+		log := r.logs[i]
+		if (query.Type == 0 || (log.Type == query.Type && log.TargetID == query.TargetID)) &&
+			(query.BeforeID == 0 || log.ID < query.BeforeID) {
+			logs = append(logs, log)
+		}
+	}
+	return logs, nil
+}
+
+func (r *memoryModerationRepository) logsOf(event string) []*entities.ModerationLog {
+	var logs []*entities.ModerationLog
+	for _, log := range r.logs {
+		if log.Event == event {
+			logs = append(logs, log)
+		}
+	}
+	return logs
+}
+
+type moderationFixture struct {
+	mockRepo   *MockMessageRepository
+	mockCache  *MockMessageCacheRepository
+	repo       *memoryModerationRepository
+	publisher  *recordingPublisher
+	messages   chat.MessageUseCase
+	moderation chat.ModerationUseCase
+}
+
+// newModerationFixture 群組 5 的群主為 9、管理員為 2，用戶 100 為全域審核者
+func newModerationFixture(t *testing.T, reviewers []uint, global ...entities.ModerationRule) *moderationFixture {
+	f := &moderationFixture{
+		mockRepo:  new(MockMessageRepository),
+		mockCache: new(MockMessageCacheRepository),
+		repo:      newMemoryModerationRepository(),
+		publisher: newRecordingPublisher(),
+	}
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 3, 9}, admins: []uint{2}}
+	moderator, err := chat.NewRuleModerator(global, f.repo)
+	assert.NoError(t, err)
+	stage := chat.NewModerationStage(moderator, f.repo, groupRepo, f.publisher, reviewers)
+	deps := newMessageDeps(f.mockRepo, f.mockCache)
+	deps.GroupRepo = groupRepo
+	deps.Moderation = stage
+	deps.Publisher = f.publisher
+	deps.Settings = chat.MessageSettings{EditWindow: time.Hour}
+	f.messages = chat.NewMessageUseCase(deps)
+	f.moderation = chat.NewModerationUseCase(f.repo, groupRepo, f.messages, f.publisher, reviewers)
+	return f
+}
+
+// 測試發送前的審核：命中的內容被遮蔽後發送，需審核的訊息進入佇列並通知審核者，拒絕的訊息不會寫入
+func TestModerationStage_SendPath(t *testing.T) {
+	f := newModerationFixture(t, nil,
+		entities.ModerationRule{Pattern: "Darn", Action: entities.ModerationMask},
+		entities.ModerationRule{Pattern: `bit\.ly/\S+`, IsRegex: true, Action: entities.ModerationHold},
+		entities.ModerationRule{Pattern: "scam", Action: entities.ModerationReject},
+	)
+	ctx := context.Background()
+	f.mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+	f.mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+	f.mockCache.On("StoreGroupMessage", ctx, mock.Anything).Return(nil)
+
+	masked := &entities.Message{UserId: 1, TargetId: 2, Content: "darn it"}
+	assert.NoError(t, f.messages.SendPrivateMessage(ctx, masked))
+	assert.Equal(t, "**** it", masked.Content)
+	if logs := f.repo.logsOf(entities.ModerationEventScreened); assert.Len(t, logs, 1) {
+		assert.Equal(t, entities.ModerationMask, logs[0].Action)
+		assert.Equal(t, "global:Darn", logs[0].Detail)
+	}
+
+	// 沒有全域審核者時私聊訊息無法暫扣，改為拒絕
+	err := f.messages.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Content: "see bit.ly/x"})
+	assertAppErrorKey(t, err, "MESSAGE_REJECTED")
+
+	err = f.messages.SendGroupMessage(ctx, &entities.Message{UserId: 3, RoomID: 5, Content: "see bit.ly/x"})
+	assertAppErrorKey(t, err, "MESSAGE_HELD")
+	if assert.Len(t, f.repo.held, 1) {
+		held := f.repo.held[0]
+		assert.Equal(t, entities.HeldPending, held.Status)
+		assert.Equal(t, uint(5), held.TargetID)
+		var payload entities.Message
+		assert.NoError(t, json.Unmarshal(held.Payload, &payload))
+		assert.Equal(t, "see bit.ly/x", payload.Content)
+	}
+	for _, reviewer := range []uint{2, 9} {
+		assert.Len(t, f.publisher.eventsOf(reviewer, chat.EventModerationHeld), 1)
+	}
+	assert.Empty(t, f.publisher.eventsOf(3, chat.EventModerationHeld))
+
+	err = f.messages.SendGroupMessage(ctx, &entities.Message{UserId: 3, RoomID: 5, Content: "SCAM bit.ly/x"})
+	assertAppErrorKey(t, err, "MESSAGE_REJECTED")
+	f.mockRepo.AssertNumberOfCalls(t, "Create", 1)
+
+	actions := make([]entities.ModerationAction, 0, len(f.repo.logs))
+	for _, log := range f.repo.logs {
+		actions = append(actions, log.Action)
+	}
+	assert.Equal(t, []entities.ModerationAction{entities.ModerationMask, entities.ModerationReject, entities.ModerationHold, entities.ModerationReject}, actions)
+
+	// 編輯無法暫扣，需審核的內容直接拒絕
+	f.mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "hi", CreatedAt: time.Now()}, nil)
+	_, err = f.messages.EditMessage(ctx, 1, 7, "bit.ly/y")
+	assertAppErrorKey(t, err, "MESSAGE_REJECTED")
+	f.mockRepo.AssertNotCalled(t, "UpdateContent", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試審核佇列：群組管理員可核准並以原內容發送，重複審核與無權限的審核者會被拒絕
+func TestModerationUseCase_ReviewHeld(t *testing.T) {
+	f := newModerationFixture(t, []uint{100}, entities.ModerationRule{Pattern: "promo", Action: entities.ModerationHold})
+	ctx := context.Background()
+	f.mockRepo.On("Create", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 50
+	}).Return(nil)
+	f.mockCache.On("StoreGroupMessage", mock.Anything, mock.Anything).Return(nil)
+	f.mockCache.On("StorePrivateMessage", mock.Anything, mock.Anything).Return(nil)
+
+	assertAppErrorKey(t, f.messages.SendGroupMessage(ctx, &entities.Message{UserId: 3, RoomID: 5, Content: "promo code"}), "MESSAGE_HELD")
+	assertAppErrorKey(t, f.messages.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Content: "promo"}), "MESSAGE_HELD")
+	groupHeld, privateHeld := f.repo.held[0].ID, f.repo.held[1].ID
+	// 私聊的待審核訊息只通知全域審核者
+	assert.Len(t, f.publisher.eventsOf(100, chat.EventModerationHeld), 2)
+	assert.Len(t, f.publisher.eventsOf(9, chat.EventModerationHeld), 1)
+
+	_, err := f.moderation.ListHeld(ctx, 3, entities.HeldQuery{GroupID: 5})
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+	_, err = f.moderation.ListHeld(ctx, 9, entities.HeldQuery{})
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+	page, err := f.moderation.ListHeld(ctx, 9, entities.HeldQuery{GroupID: 5})
+	assert.NoError(t, err)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, groupHeld, page.Items[0].ID)
+	}
+	page, err = f.moderation.ListHeld(ctx, 100, entities.HeldQuery{Limit: 1})
+	assert.NoError(t, err)
+	assert.True(t, page.HasMore)
+
+	// 群組管理員看不到私聊的待審核訊息
+	_, err = f.moderation.ReviewHeld(ctx, 9, privateHeld, true, "")
+	assertAppErrorKey(t, err, "HELD_MESSAGE_NOT_FOUND")
+
+	held, err := f.moderation.ReviewHeld(ctx, 2, groupHeld, true, " 活動公告 ")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.HeldApproved, held.Status)
+	assert.Equal(t, uint(50), held.MessageID)
+	assert.Equal(t, "活動公告", held.ReviewNote)
+	assert.Equal(t, uint(50), f.repo.held[0].MessageID)
+	f.mockRepo.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(m *entities.Message) bool {
+		return m.Content == "promo code" && m.UserId == 3 && m.RoomID == 5
+	}))
+	if events := f.publisher.eventsOf(3, chat.EventModerationReviewed); assert.Len(t, events, 1) {
+		assert.Equal(t, entities.HeldApproved, events[0].Data.(*entities.HeldMessage).Status)
+	}
+
+	_, err = f.moderation.ReviewHeld(ctx, 9, groupHeld, false, "")
+	assertAppErrorKey(t, err, "HELD_MESSAGE_REVIEWED")
+
+	held, err = f.moderation.ReviewHeld(ctx, 100, privateHeld, false, "廣告")
+	assert.NoError(t, err)
+	assert.Equal(t, entities.HeldRejected, held.Status)
+	f.mockRepo.AssertNumberOfCalls(t, "Create", 1)
+
+	logs, err := f.moderation.ListLogs(ctx, 100, entities.ModerationLogQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, logs.Items, 4) {
+		assert.Equal(t, entities.ModerationEventRejected, logs.Items[0].Event)
+		assert.Equal(t, uint(100), logs.Items[0].ActorID)
+		assert.Equal(t, entities.ModerationEventApproved, logs.Items[1].Event)
+		assert.Equal(t, uint(50), logs.Items[1].MessageID)
+	}
+	logs, err = f.moderation.ListLogs(ctx, 2, entities.ModerationLogQuery{Type: entities.ConversationTypeGroup, TargetID: 5})
+	assert.NoError(t, err)
+	assert.Len(t, logs.Items, 2)
+}
+
+// 測試群組規則只能由群主與管理員維護，新增後立即套用於群組訊息，變更會寫入稽核日誌
+func TestModerationUseCase_GroupRules(t *testing.T) {
+	f := newModerationFixture(t, nil)
+	ctx := context.Background()
+
+	err := f.moderation.CreateRule(ctx, &entities.ModerationRule{GroupID: 5, Pattern: "spoiler", Action: entities.ModerationReject, CreatedBy: 3})
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+	err = f.moderation.CreateRule(ctx, &entities.ModerationRule{GroupID: 5, Pattern: "(", IsRegex: true, Action: entities.ModerationReject, CreatedBy: 2})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+	err = f.moderation.CreateRule(ctx, &entities.ModerationRule{GroupID: 5, Pattern: "a*", IsRegex: true, Action: entities.ModerationReject, CreatedBy: 2})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+	err = f.moderation.CreateRule(ctx, &entities.ModerationRule{GroupID: 5, Pattern: "spoiler", Action: entities.ModerationAllow, CreatedBy: 2})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+
+	rule := &entities.ModerationRule{GroupID: 5, Pattern: " spoiler ", Action: entities.ModerationReject, CreatedBy: 2}
+	assert.NoError(t, f.moderation.CreateRule(ctx, rule))
+	assert.Equal(t, "spoiler", rule.Pattern)
+
+	rules, err := f.moderation.ListRules(ctx, 9, 5)
+	assert.NoError(t, err)
+	assert.Len(t, rules, 1)
+	_, err = f.moderation.ListRules(ctx, 1, 5)
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+
+	err = f.messages.SendGroupMessage(ctx, &entities.Message{UserId: 3, RoomID: 5, Content: "SPOILER: 結局"})
+	assertAppErrorKey(t, err, "MESSAGE_REJECTED")
+
+	assertAppErrorKey(t, f.moderation.DeleteRule(ctx, 3, 5, rule.ID), "ACCESS_DENIED")
+	assertAppErrorKey(t, f.moderation.DeleteRule(ctx, 9, 5, rule.ID+100), "INVALID_INPUT")
+	assert.NoError(t, f.moderation.DeleteRule(ctx, 9, 5, rule.ID))
+	assert.Empty(t, f.repo.rules)
+
+	assert.Len(t, f.repo.logsOf(entities.ModerationEventRuleCreated), 1)
+	if logs := f.repo.logsOf(entities.ModerationEventRuleDeleted); assert.Len(t, logs, 1) {
+		assert.Equal(t, uint(9), logs[0].ActorID)
+		assert.Equal(t, "keyword:spoiler", logs[0].Detail)
+	}
+}
@@ -0,0 +1,142 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 以記憶體保存釘選的假儲存庫
+type memoryPinRepository struct {
+	pins []*entities.MessagePin
+}
+
+func newMemoryPinRepository() *memoryPinRepository {
+	return &memoryPinRepository{}
+}
+
+func (r *memoryPinRepository) Pin(ctx context.Context, pin *entities.MessagePin, limit int) (bool, error) {
+	count := 0
+	for _, existing := range r.pins {
+		if existing.MessageID == pin.MessageID {
+			return false, nil
+		}
+		if existing.Scope == pin.Scope {
+			count++
+		}
+	}
+	if count >= limit {
+		return false, repositories.ErrPinLimitReached
+	}
+	pin.ID = uint(len(r.pins) + 1)
+	r.pins = append(r.pins, pin)
+	return true, nil
+}
+
+func (r *memoryPinRepository) Unpin(ctx context.Context, messageID uint) (bool, error) {
+	for i, existing := range r.pins {
+		if existing.MessageID == messageID {
+			r.pins = append(r.pins[:i], r.pins[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryPinRepository) ListByScope(ctx context.Context, scope string) ([]*entities.MessagePin, error) {
+	var pins []*entities.MessagePin
+	for i := len(r.pins) - 1; i >= 0; i-- {
+		if r.pins[i].Scope == scope {
+			pins = append(pins, r.pins[i])
+		}
+	}
+	return pins, nil
+}
+
+// 測試群組訊息只有群主與管理員可以釘選，釘選後發出系統通知並受數量上限限制
+func TestMessageUseCase_PinMessage_Group(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 9}, admins: []uint{2}}
+	publisher := newRecordingPublisher()
+	pinRepo := newMemoryPinRepository()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.PinRepo = pinRepo
+	deps.Publisher = publisher
+	deps.Settings = chat.MessageSettings{PinLimit: 1}
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup}, nil)
+	mockRepo.On("FindByID", ctx, uint(8)).Return(&entities.Message{ID: 8, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup}, nil)
+	mockRepo.On("Create", ctx, mock.MatchedBy(func(m *entities.Message) bool {
+		return m.IsSystem() && m.RoomID == 5 && m.UserId == 2
+	})).Return(nil)
+	mockCache.On("StoreGroupMessage", ctx, mock.Anything).Return(nil)
+	mockCache.On("InvalidatePins", ctx, "room:5").Return(nil)
+
+	_, err := useCase.PinMessage(ctx, 1, 7)
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "ACCESS_DENIED", appErr.Key())
+	}
+
+	pin, err := useCase.PinMessage(ctx, 2, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, "room:5", pin.Scope)
+	mockRepo.AssertNumberOfCalls(t, "Create", 1)
+	if assert.Len(t, publisher.published[1], 2) {
+		assert.Equal(t, chat.EventMessageNew, publisher.published[1][0].Type)
+		assert.Equal(t, chat.EventMessagePinned, publisher.published[1][1].Type)
+	}
+
+	// 重複釘選不再通知
+	_, err = useCase.PinMessage(ctx, 9, 7)
+	assert.NoError(t, err)
+	assert.Len(t, publisher.published[1], 2)
+
+	_, err = useCase.PinMessage(ctx, 2, 8)
+	appErr, ok = baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "PIN_LIMIT_REACHED", appErr.Key())
+	}
+}
+
+// 測試釘選列表未命中快取時從資料庫組裝並寫回快取
+func TestMessageUseCase_ListPinnedMessages(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	pinRepo := newMemoryPinRepository()
+	pinRepo.pins = []*entities.MessagePin{
+		{ID: 1, Scope: "private:1:2", MessageID: 3, PinnedBy: 1},
+		{ID: 2, Scope: "private:1:2", MessageID: 4, PinnedBy: 2},
+		{ID: 3, Scope: "private:1:3", MessageID: 5, PinnedBy: 1},
+	}
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = &stubGroupRepository{}
+	deps.PinRepo = pinRepo
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	messages := []*entities.Message{{ID: 3, UserId: 1, TargetId: 2}, {ID: 4, UserId: 2, TargetId: 1}}
+	mockCache.On("GetPins", ctx, "private:1:2").Return(nil, false, nil)
+	mockRepo.On("FindByIDs", ctx, []uint{4, 3}).Return(messages, nil)
+	mockCache.On("StorePins", ctx, "private:1:2", mock.Anything).Return(nil)
+
+	pinned, err := useCase.ListPinnedMessages(ctx, 2, entities.ConversationTypePrivate, 1)
+
+	assert.NoError(t, err)
+	if assert.Len(t, pinned, 2) {
+		assert.Equal(t, uint(4), pinned[0].Message.ID)
+		assert.Equal(t, uint(3), pinned[1].Message.ID)
+	}
+	mockCache.AssertExpectations(t)
+}
@@ -0,0 +1,240 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 以記憶體保存投票與選票的假儲存庫
+type memoryPollRepository struct {
+	polls map[uint]*entities.Poll
+	votes []*entities.PollVote
+}
+
+func newMemoryPollRepository() *memoryPollRepository {
+	return &memoryPollRepository{polls: make(map[uint]*entities.Poll)}
+}
+
+func (r *memoryPollRepository) Create(ctx context.Context, poll *entities.Poll) error {
+	r.polls[poll.MessageID] = poll
+	return nil
+}
+
+func (r *memoryPollRepository) FindByMessageID(ctx context.Context, messageID uint) (*entities.Poll, error) {
+	poll, ok := r.polls[messageID]
+	if !ok {
+		return nil, repositories.ErrPollNotFound
+	}
+	copied := *poll
+	return &copied, nil
+}
+
+func (r *memoryPollRepository) FindByMessageIDs(ctx context.Context, messageIDs []uint) ([]*entities.Poll, error) {
+	var polls []*entities.Poll
+	for _, id := range messageIDs {
+		if poll, ok := r.polls[id]; ok {
+			polls = append(polls, poll)
+		}
+	}
+	return polls, nil
+}
+
+func (r *memoryPollRepository) FindVotes(ctx context.Context, messageIDs []uint) ([]*entities.PollVote, error) {
+	var votes []*entities.PollVote
+	for _, vote := range r.votes {
+		for _, id := range messageIDs {
+			if vote.MessageID == id {
+				votes = append(votes, vote)
+			}
+		}
+	}
+	return votes, nil
+}
+
+func (r *memoryPollRepository) Vote(ctx context.Context, messageID, userID uint, options []int, now time.Time) error {
+	poll, ok := r.polls[messageID]
+	if !ok {
+		return repositories.ErrPollNotFound
+	}
+	if poll.IsClosed(now) {
+		return repositories.ErrPollClosed
+	}
+	kept := r.votes[:0]
+	for _, vote := range r.votes {
+		if vote.MessageID != messageID || vote.UserID != userID {
+			kept = append(kept, vote)
+		}
+	}
+	r.votes = kept
+	for _, option := range options {
+		r.votes = append(r.votes, &entities.PollVote{MessageID: messageID, UserID: userID, Option: option, CreatedAt: now})
+	}
+	return nil
+}
+
+func (r *memoryPollRepository) Close(ctx context.Context, messageID uint, at time.Time) (bool, error) {
+	poll, ok := r.polls[messageID]
+	if !ok || poll.ClosedAt != nil {
+		return false, nil
+	}
+	poll.ClosedAt = &at
+	return true, nil
+}
+
+func (r *memoryPollRepository) FindDue(ctx context.Context, now time.Time, limit int) ([]*entities.Poll, error) {
+	var polls []*entities.Poll
+	for _, poll := range r.polls {
+		if poll.ClosedAt == nil && !poll.ClosesAt.After(now) && len(polls) < limit {
+			copied := *poll
+			polls = append(polls, &copied)
+		}
+	}
+	return polls, nil
+}
+
+func newPollUseCase(mockRepo *MockMessageRepository, mockCache *MockMessageCacheRepository, pollRepo *memoryPollRepository, publisher *recordingPublisher) chat.MessageUseCase {
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 3, 9}}
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.PollRepo = pollRepo
+	deps.Publisher = publisher
+	return chat.NewMessageUseCase(deps)
+}
+
+// 測試建立投票時寫入投票訊息與投票，並推送帶有選項的新訊息
+func TestMessageUseCase_CreatePoll(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	pollRepo := newMemoryPollRepository()
+	publisher := newRecordingPublisher()
+	useCase := newPollUseCase(mockRepo, mockCache, pollRepo, publisher)
+	ctx := context.Background()
+
+	mockRepo.On("Create", ctx, mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 11
+	}).Return(nil)
+	mockCache.On("StoreGroupMessage", ctx, mock.Anything).Return(nil)
+
+	message, err := useCase.CreatePoll(ctx, &entities.Poll{
+		CreatorID: 1,
+		RoomID:    5,
+		Question:  " 午餐吃什麼？ ",
+		Options:   entities.StringList{"拉麵", " 咖哩 "},
+		ClosesAt:  time.Now().Add(time.Hour),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, entities.MediaTypePoll, message.Media)
+	assert.Equal(t, "午餐吃什麼？", message.Content)
+	assert.Equal(t, "[投票] 午餐吃什麼？", entities.MessagePreview(message))
+	if assert.Contains(t, pollRepo.polls, uint(11)) {
+		assert.Equal(t, entities.StringList{"拉麵", "咖哩"}, pollRepo.polls[11].Options)
+	}
+	events := publisher.eventsOf(2, chat.EventMessageNew)
+	if assert.Len(t, events, 1) {
+		poll := events[0].Data.(*entities.Message).Poll
+		if assert.NotNil(t, poll) {
+			assert.Equal(t, "咖哩", poll.Options[1].Text)
+		}
+	}
+
+	// 選項不足或一般發送流程不能建立投票
+	_, err = useCase.CreatePoll(ctx, &entities.Poll{CreatorID: 1, RoomID: 5, Question: "?", Options: entities.StringList{"A"}, ClosesAt: time.Now().Add(time.Hour)})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+	err = useCase.SendGroupMessage(ctx, &entities.Message{UserId: 1, RoomID: 5, Media: entities.MediaTypePoll, Content: "?"})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+}
+
+// 測試只有群組成員可以投票，重新投票取代原本的選擇，結果即時推送給成員
+func TestMessageUseCase_VotePoll(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	pollRepo := newMemoryPollRepository()
+	publisher := newRecordingPublisher()
+	useCase := newPollUseCase(mockRepo, mockCache, pollRepo, publisher)
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(11)).Return(&entities.Message{ID: 11, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup, Media: entities.MediaTypePoll}, nil)
+	pollRepo.polls[11] = &entities.Poll{MessageID: 11, RoomID: 5, CreatorID: 1, Question: "?", Options: entities.StringList{"A", "B", "C"}, ClosesAt: time.Now().Add(time.Hour)}
+
+	_, err := useCase.VotePoll(ctx, 7, 11, []int{0})
+	assertAppErrorKey(t, err, "NOT_GROUP_MEMBER")
+	_, err = useCase.VotePoll(ctx, 2, 11, []int{0, 1})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+	_, err = useCase.VotePoll(ctx, 2, 11, []int{3})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+
+	_, err = useCase.VotePoll(ctx, 2, 11, []int{0})
+	assert.NoError(t, err)
+	_, err = useCase.VotePoll(ctx, 3, 11, []int{1})
+	assert.NoError(t, err)
+	result, err := useCase.VotePoll(ctx, 2, 11, []int{1})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Options[0].Count)
+	assert.Equal(t, 2, result.Options[1].Count)
+	assert.Equal(t, []uint{3, 2}, result.Options[1].Voters)
+	assert.Equal(t, 2, result.TotalVoters)
+	assert.Equal(t, []int{1}, result.MyVotes)
+
+	events := publisher.eventsOf(9, chat.EventMessagePoll)
+	if assert.Len(t, events, 3) {
+		assert.True(t, events[2].Transient)
+		assert.Nil(t, events[2].Data.(*entities.PollResult).MyVotes)
+	}
+
+	// 匿名投票不公開投票者
+	pollRepo.polls[11].Anonymous = true
+	result, err = useCase.GetPoll(ctx, 3, 11)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Options[1].Count)
+	assert.Nil(t, result.Options[1].Voters)
+	assert.Equal(t, []int{1}, result.MyVotes)
+}
+
+// 測試到期的投票由背景工作截止並推送最終結果，截止後不能再投票
+func TestMessageUseCase_CloseDuePolls(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	pollRepo := newMemoryPollRepository()
+	publisher := newRecordingPublisher()
+	useCase := newPollUseCase(mockRepo, mockCache, pollRepo, publisher)
+	ctx := context.Background()
+
+	closesAt := time.Now().Add(time.Minute)
+	mockRepo.On("FindByID", ctx, uint(11)).Return(&entities.Message{ID: 11, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup, Media: entities.MediaTypePoll}, nil)
+	pollRepo.polls[11] = &entities.Poll{MessageID: 11, RoomID: 5, CreatorID: 1, Question: "?", Options: entities.StringList{"A", "B"}, ClosesAt: closesAt}
+
+	// 只有建立者、群主與管理員可以提前截止
+	_, err := useCase.ClosePoll(ctx, 2, 11)
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+
+	count, err := useCase.CloseDuePolls(ctx, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	count, err = useCase.CloseDuePolls(ctx, closesAt.Add(time.Second))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	if assert.NotNil(t, pollRepo.polls[11].ClosedAt) {
+		assert.Equal(t, closesAt, *pollRepo.polls[11].ClosedAt)
+	}
+	events := publisher.eventsOf(2, chat.EventMessagePoll)
+	if assert.Len(t, events, 1) {
+		assert.False(t, events[0].Transient)
+		assert.NotNil(t, events[0].Data.(*entities.PollResult).ClosedAt)
+	}
+
+	_, err = useCase.VotePoll(ctx, 2, 11, []int{0})
+	assertAppErrorKey(t, err, "POLL_CLOSED")
+	_, err = useCase.ClosePoll(ctx, 9, 11)
+	assertAppErrorKey(t, err, "POLL_CLOSED")
+}
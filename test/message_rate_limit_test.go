@@ -0,0 +1,214 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	appErrors "clean-architecture-gochat/internal/errors"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 以記憶體保存令牌數的假快取，不會隨時間補充令牌
+type memoryRateLimitCache struct {
+	tokens map[string]int
+	taken  [][]cache.TokenBucket
+	err    error
+}
+
+func newMemoryRateLimitCache() *memoryRateLimitCache {
+	return &memoryRateLimitCache{tokens: make(map[string]int)}
+}
+
+func (c *memoryRateLimitCache) Take(ctx context.Context, buckets []cache.TokenBucket, now time.Time) (time.Duration, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.taken = append(c.taken, buckets)
+	var wait time.Duration
+	for _, bucket := range buckets {
+		tokens, ok := c.tokens[bucket.Key]
+		if !ok {
+			tokens = bucket.Burst
+		}
+		if tokens < bucket.Cost {
+			wait = max(wait, time.Duration(bucket.Cost-tokens)*bucket.Interval)
+		}
+	}
+	if wait > 0 {
+		return wait, nil
+	}
+	for _, bucket := range buckets {
+		if _, ok := c.tokens[bucket.Key]; !ok {
+			c.tokens[bucket.Key] = bucket.Burst
+		}
+		c.tokens[bucket.Key] -= bucket.Cost
+	}
+	return 0, nil
+}
+
+// 以註冊時間區分新帳號的假用戶儲存庫
+type registeredUserRepository struct {
+	repositories.UserRepository
+	registered map[uint]time.Time
+	lookups    int
+}
+
+func (r *registeredUserRepository) FindByID(ctx context.Context, id uint) (*entities.User, error) {
+	r.lookups++
+	return &entities.User{ID: id, CreatedAt: r.registered[id]}, nil
+}
+
+// 只記錄轉發與投票呼叫的假訊息用例
+type forwardingMessageUseCase struct {
+	*recordingMessageUseCase
+	forwarded int
+	polls     int
+}
+
+func (uc *forwardingMessageUseCase) ForwardMessages(ctx context.Context, userID uint, messageIDs []uint, targets []chat.ForwardTarget) ([]*entities.Message, error) {
+	uc.forwarded++
+	return nil, nil
+}
+
+func (uc *forwardingMessageUseCase) CreatePoll(ctx context.Context, poll *entities.Poll) (*entities.Message, error) {
+	uc.polls++
+	return &entities.Message{}, nil
+}
+
+var testRateLimitSettings = chat.RateLimitSettings{
+	Text: chat.RateLimitTier{
+		User:         chat.RateLimit{Burst: 3, Interval: time.Second},
+		NewUser:      chat.RateLimit{Burst: 1, Interval: 10 * time.Second},
+		Conversation: chat.RateLimit{Burst: 5, Interval: time.Second},
+	},
+	Media: chat.RateLimitTier{
+		User:         chat.RateLimit{Burst: 4, Interval: 5 * time.Second},
+		Conversation: chat.RateLimit{Burst: 20, Interval: time.Second},
+	},
+	NewAccountAge: 24 * time.Hour,
+}
+
+// 測試文字與媒體訊息使用各自的令牌桶，新帳號套用較嚴格的限制，對話的額度由所有成員共用
+func TestRateLimitedMessageUseCase_Send(t *testing.T) {
+	limits := newMemoryRateLimitCache()
+	users := &registeredUserRepository{registered: map[uint]time.Time{
+		1: time.Now().AddDate(-1, 0, 0),
+		2: time.Now().AddDate(0, -1, 0),
+		4: time.Now().Add(-time.Hour),
+	}}
+	inner := &recordingMessageUseCase{}
+	useCase := chat.NewRateLimitedMessageUseCase(inner, chat.NewRateLimiter(limits, users, testRateLimitSettings), nil)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 1, RoomID: 5, Content: "hi"}))
+	}
+	err := useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Content: "hi"})
+	assertAppErrorKey(t, err, "RATE_LIMITED")
+	retryAfter, ok := chat.RetryAfter(err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, retryAfter)
+	status, _ := appErrors.ToResponse(err)
+	assert.Equal(t, http.StatusTooManyRequests, status)
+	assert.Len(t, inner.sent, 3)
+
+	// 媒體訊息不受文字訊息額度影響
+	assert.NoError(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 1, RoomID: 5, Media: entities.MediaTypeImage, Content: "a.png"}))
+
+	// 註冊未滿一天的帳號只能連續發送一則
+	assert.NoError(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 4, RoomID: 5, Content: "hi"}))
+	err = useCase.SendGroupMessage(ctx, &entities.Message{UserId: 4, RoomID: 5, Content: "hi"})
+	retryAfter, _ = chat.RetryAfter(err)
+	assert.Equal(t, 10, retryAfter)
+
+	// 對話的額度已用掉 4 則，其他成員也只能再發送一則
+	assert.NoError(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 2, RoomID: 5, Content: "hi"}))
+	assertAppErrorKey(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 2, RoomID: 5, Content: "hi"}), "RATE_LIMITED")
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 2, TargetId: 1, Content: "hi"}))
+	assert.Len(t, inner.sent, 7)
+
+	// 註冊時間只查詢一次
+	assert.Equal(t, 3, users.lookups)
+
+	// Redis 無法使用時不限制發送
+	limits.err = errors.New("redis down")
+	assert.NoError(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 4, RoomID: 5, Content: "hi"}))
+
+	_, ok = chat.RetryAfter(errors.New("other"))
+	assert.False(t, ok)
+}
+
+// 測試轉發依不重複的目標對話數計算用戶額度，超過容量時以整個桶計算，投票以媒體訊息的限制計算
+func TestRateLimitedMessageUseCase_ForwardAndPoll(t *testing.T) {
+	limits := newMemoryRateLimitCache()
+	inner := &forwardingMessageUseCase{recordingMessageUseCase: &recordingMessageUseCase{}}
+	useCase := chat.NewRateLimitedMessageUseCase(inner, chat.NewRateLimiter(limits, nil, testRateLimitSettings), nil)
+	ctx := context.Background()
+
+	targets := []chat.ForwardTarget{
+		{Type: entities.ConversationTypeGroup, TargetID: 5},
+		{Type: entities.ConversationTypePrivate, TargetID: 2},
+		{Type: entities.ConversationTypeGroup, TargetID: 5},
+	}
+	_, err := useCase.ForwardMessages(ctx, 1, []uint{10}, targets)
+	assert.NoError(t, err)
+	if assert.Len(t, limits.taken, 1) {
+		buckets := limits.taken[0]
+		if assert.Len(t, buckets, 3) {
+			assert.Equal(t, cache.TokenBucket{Key: "media:user:1", Burst: 4, Interval: 5 * time.Second, Cost: 2}, buckets[0])
+			assert.Equal(t, "media:room:5", buckets[1].Key)
+			assert.Equal(t, "media:private:1:2", buckets[2].Key)
+		}
+	}
+
+	// 用戶的媒體額度剩下 2 則
+	_, err = useCase.ForwardMessages(ctx, 1, []uint{10, 11, 12}, targets[:1])
+	assertAppErrorKey(t, err, "RATE_LIMITED")
+	assert.Equal(t, 1, inner.forwarded)
+
+	for i := 0; i < 2; i++ {
+		_, err = useCase.CreatePoll(ctx, &entities.Poll{CreatorID: 1, RoomID: 5})
+		assert.NoError(t, err)
+	}
+	_, err = useCase.CreatePoll(ctx, &entities.Poll{CreatorID: 1, RoomID: 5})
+	assertAppErrorKey(t, err, "RATE_LIMITED")
+	assert.Equal(t, 2, inner.polls)
+
+	// 一次轉發超過容量時以整個桶計算，額度補滿後仍可發送
+	limits.tokens = make(map[string]int)
+	_, err = useCase.ForwardMessages(ctx, 3, []uint{1, 2, 3, 4, 5, 6}, targets[:2])
+	assert.NoError(t, err)
+	assert.Equal(t, 4, limits.taken[len(limits.taken)-1][0].Cost)
+}
+
+// 測試 client_msg_id 已處理過的重試不消耗發送額度，仍交由內層用例返回原訊息
+func TestRateLimitedMessageUseCase_RetryNotCharged(t *testing.T) {
+	limits := newMemoryRateLimitCache()
+	mockCache := new(MockMessageCacheRepository)
+	inner := &recordingMessageUseCase{}
+	useCase := chat.NewRateLimitedMessageUseCase(inner, chat.NewRateLimiter(limits, nil, testRateLimitSettings), mockCache)
+	ctx := context.Background()
+
+	sent, fresh := "c-1", "c-2"
+	mockCache.On("GetClientMessageID", ctx, uint(1), sent).Return(uint(10), nil)
+	mockCache.On("GetClientMessageID", ctx, uint(1), fresh).Return(uint(0), nil)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 1, RoomID: 5, Content: "hi", ClientMsgID: &fresh}))
+	}
+	assertAppErrorKey(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 1, RoomID: 5, Content: "hi", ClientMsgID: &fresh}), "RATE_LIMITED")
+
+	// 額度用完後，已送出訊息的重試仍會送達內層用例
+	assert.NoError(t, useCase.SendGroupMessage(ctx, &entities.Message{UserId: 1, RoomID: 5, Content: "hi", ClientMsgID: &sent}))
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Content: "hi", ClientMsgID: &sent}))
+	assert.Len(t, inner.sent, 5)
+	assert.Len(t, limits.taken, 4)
+}
@@ -0,0 +1,148 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 以記憶體保存表情回應的假儲存庫，並記錄統計查詢次數
+type memoryReactionRepository struct {
+	reactions  []*entities.MessageReaction
+	countCalls int
+}
+
+func newMemoryReactionRepository() *memoryReactionRepository {
+	return &memoryReactionRepository{}
+}
+
+func (r *memoryReactionRepository) Add(ctx context.Context, reaction *entities.MessageReaction) (bool, error) {
+	for _, existing := range r.reactions {
+		if existing.MessageID == reaction.MessageID && existing.UserID == reaction.UserID && existing.Emoji == reaction.Emoji {
+			return false, nil
+		}
+	}
+	r.reactions = append(r.reactions, reaction)
+	return true, nil
+}
+
+func (r *memoryReactionRepository) Remove(ctx context.Context, messageID, userID uint, emoji string) (bool, error) {
+	for i, existing := range r.reactions {
+		if existing.MessageID == messageID && existing.UserID == userID && existing.Emoji == emoji {
+			r.reactions = append(r.reactions[:i], r.reactions[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryReactionRepository) CountByMessageIDs(ctx context.Context, messageIDs []uint) (map[uint][]entities.ReactionCount, error) {
+	r.countCalls++
+	counts := make(map[uint][]entities.ReactionCount)
+	for _, id := range messageIDs {
+		for _, reaction := range r.reactions {
+			if reaction.MessageID != id {
+				continue
+			}
+			found := false
+			for i := range counts[id] {
+				if counts[id][i].Emoji == reaction.Emoji {
+					counts[id][i].Count++
+					found = true
+				}
+			}
+			if !found {
+				counts[id] = append(counts[id], entities.ReactionCount{Emoji: reaction.Emoji, Count: 1})
+			}
+		}
+	}
+	return counts, nil
+}
+
+// 測試新增表情回應會通知對話雙方，重複新增不再推送
+func TestMessageUseCase_AddReaction(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	publisher := newRecordingPublisher()
+	reactionRepo := newMemoryReactionRepository()
+	deps := newMessageDeps(mockRepo, new(MockMessageCacheRepository))
+	deps.GroupRepo = &stubGroupRepository{}
+	deps.ReactionRepo = reactionRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate}, nil)
+
+	reactions, err := useCase.AddReaction(ctx, 2, 7, "👍")
+	assert.NoError(t, err)
+	assert.Equal(t, []entities.ReactionCount{{Emoji: "👍", Count: 1}}, reactions)
+
+	_, err = useCase.AddReaction(ctx, 2, 7, "👍")
+	assert.NoError(t, err)
+
+	for _, userID := range []uint{1, 2} {
+		if assert.Len(t, publisher.published[userID], 1) {
+			assert.Equal(t, chat.EventMessageReaction, publisher.published[userID][0].Type)
+		}
+	}
+
+	reactions, err = useCase.RemoveReaction(ctx, 2, 7, "👍")
+	assert.NoError(t, err)
+	assert.Empty(t, reactions)
+	assert.Len(t, publisher.published[1], 2)
+}
+
+// 測試群組限制允許的表情
+func TestMessageUseCase_AddReaction_GroupRestricted(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, AllowedReactions: "👍,❤️"}, members: []uint{1, 2}}
+	deps := newMessageDeps(mockRepo, new(MockMessageCacheRepository))
+	deps.GroupRepo = groupRepo
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(8)).Return(&entities.Message{ID: 8, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup}, nil)
+
+	_, err := useCase.AddReaction(ctx, 2, 8, "😡")
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "REACTION_NOT_ALLOWED", appErr.Key())
+	}
+
+	_, err = useCase.AddReaction(ctx, 2, 8, "❤️")
+	assert.NoError(t, err)
+}
+
+// 測試歷史訊息以單一查詢附加表情回應
+func TestMessageUseCase_History_AttachesReactionsInOneQuery(t *testing.T) {
+	mockCache := new(MockMessageCacheRepository)
+	reactionRepo := newMemoryReactionRepository()
+	deps := newMessageDeps(new(MockMessageRepository), mockCache)
+	deps.GroupRepo = &stubGroupRepository{}
+	deps.ReactionRepo = reactionRepo
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	messages := []*entities.Message{{ID: 1, RoomID: 5}, {ID: 2, RoomID: 5}, {ID: 3, RoomID: 5}}
+	reactionRepo.reactions = []*entities.MessageReaction{
+		{MessageID: 1, UserID: 1, Emoji: "👍", CreatedAt: time.Now()},
+		{MessageID: 1, UserID: 2, Emoji: "👍", CreatedAt: time.Now()},
+		{MessageID: 3, UserID: 2, Emoji: "🎉", CreatedAt: time.Now()},
+	}
+	mockCache.On("GetGroupMessagesPage", ctx, uint(5), mock.Anything).Return(&entities.MessagePage{Messages: messages}, true, nil)
+
+	page, err := useCase.GetGroupMessageHistory(ctx, 5, entities.HistoryQuery{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, reactionRepo.countCalls)
+	assert.Equal(t, []entities.ReactionCount{{Emoji: "👍", Count: 2}}, page.Messages[0].Reactions)
+	assert.Empty(t, page.Messages[1].Reactions)
+	assert.Equal(t, []entities.ReactionCount{{Emoji: "🎉", Count: 1}}, page.Messages[2].Reactions)
+}
@@ -0,0 +1,103 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func newRecallUseCase(mockRepo *MockMessageRepository, mockCache *MockMessageCacheRepository, groupRepo *stubGroupRepository, publisher *recordingPublisher) chat.MessageUseCase {
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.Publisher = publisher
+	deps.Settings = chat.MessageSettings{RecallWindow: 2 * time.Minute}
+	return chat.NewMessageUseCase(deps)
+}
+
+// 測試發送者在時限內撤回訊息，內容被清除並通知雙方
+func TestMessageUseCase_RecallMessage_BySender(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	publisher := newRecordingPublisher()
+	useCase := newRecallUseCase(mockRepo, mockCache, &stubGroupRepository{}, publisher)
+	ctx := context.Background()
+
+	msg := &entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeImage, Content: "secret.png", CreatedAt: time.Now()}
+	mockRepo.On("FindByID", ctx, uint(7)).Return(msg, nil)
+	mockRepo.On("Recall", ctx, msg).Return(nil)
+	mockCache.On("UpdateMessage", ctx, msg).Return(nil)
+	mockCache.On("InvalidatePins", ctx, mock.Anything).Return(nil)
+
+	recalled, err := useCase.RecallMessage(ctx, 1, 7)
+
+	assert.NoError(t, err)
+	assert.True(t, recalled.IsRecalled())
+	assert.Equal(t, entities.RecalledMessageContent, recalled.Content)
+	assert.Equal(t, entities.MediaTypeText, recalled.Media)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	for _, userID := range []uint{1, 2} {
+		if assert.Len(t, publisher.published[userID], 1) {
+			assert.Equal(t, chat.EventMessageRecalled, publisher.published[userID][0].Type)
+		}
+	}
+}
+
+// 測試發送者超過時限後無法撤回
+func TestMessageUseCase_RecallMessage_SenderWindowExpired(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	useCase := newRecallUseCase(mockRepo, new(MockMessageCacheRepository), &stubGroupRepository{}, newRecordingPublisher())
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, CreatedAt: time.Now().Add(-time.Hour)}, nil)
+
+	_, err := useCase.RecallMessage(ctx, 1, 7)
+
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "MESSAGE_RECALL_EXPIRED", appErr.Key())
+	}
+	mockRepo.AssertNotCalled(t, "Recall", mock.Anything, mock.Anything)
+}
+
+// 測試群組管理員可不限時撤回他人訊息，一般成員則不行
+func TestMessageUseCase_RecallMessage_GroupAdmin(t *testing.T) {
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 3, 9}, admins: []uint{3}}
+	old := time.Now().Add(-24 * time.Hour)
+	ctx := context.Background()
+
+	// 一般成員無法撤回他人訊息
+	mockRepo := new(MockMessageRepository)
+	useCase := newRecallUseCase(mockRepo, new(MockMessageCacheRepository), groupRepo, newRecordingPublisher())
+	mockRepo.On("FindByID", ctx, uint(8)).Return(&entities.Message{ID: 8, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup, CreatedAt: old}, nil)
+
+	_, err := useCase.RecallMessage(ctx, 2, 8)
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Recall", mock.Anything, mock.Anything)
+
+	// 管理員與群主可撤回
+	for _, managerID := range []uint{3, 9} {
+		mockRepo := new(MockMessageRepository)
+		mockCache := new(MockMessageCacheRepository)
+		publisher := newRecordingPublisher()
+		useCase := newRecallUseCase(mockRepo, mockCache, groupRepo, publisher)
+		msg := &entities.Message{ID: 8, UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup, Content: "oops", CreatedAt: old}
+		mockRepo.On("FindByID", ctx, uint(8)).Return(msg, nil)
+		mockRepo.On("Recall", ctx, msg).Return(nil)
+		mockCache.On("UpdateMessage", ctx, msg).Return(nil)
+		mockCache.On("InvalidatePins", ctx, mock.Anything).Return(nil)
+
+		recalled, err := useCase.RecallMessage(ctx, managerID, 8)
+
+		assert.NoError(t, err)
+		assert.Equal(t, managerID, recalled.RecalledBy)
+		assert.Len(t, publisher.published, 4)
+	}
+}
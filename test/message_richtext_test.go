@@ -0,0 +1,82 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/search"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 測試發送富文本時保存清理後的 HTML 與純文字，會話預覽與搜尋使用純文字
+func TestMessageUseCase_SendPrivateMessage_RichText(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	searchIndex := newMemorySearchIndex()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.SearchIndex = searchIndex
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+
+	msg := &entities.Message{UserId: 1, TargetId: 2, Media: entities.MediaTypeRichText, Content: "**重要** <b>會議</b>"}
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, msg))
+
+	assert.Equal(t, "<p><strong>重要</strong> &lt;b&gt;會議&lt;/b&gt;</p>", msg.RichTextHTML())
+	assert.Equal(t, "重要 <b>會議</b>", msg.PlainText)
+	assert.Equal(t, "重要 <b>會議</b>", entities.MessagePreview(msg))
+	result, err := searchIndex.Search(ctx, search.Query{ViewerID: 2, Terms: search.Tokenize("重要 會議")})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Hits, 1) {
+		assert.Equal(t, "<mark>重要</mark> &lt;b&gt;<mark>會議</mark>&lt;/b&gt;", result.Hits[0].Snippet)
+	}
+
+	// 不安全的連結直接拒絕
+	err = useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Media: entities.MediaTypeRichText, Content: "[點我](javascript:alert(1))"})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+}
+
+// 測試一般文字訊息不可夾帶自行產生的富文本 HTML
+func TestMessageUseCase_SendPrivateMessage_StripsForgedHTML(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	useCase := chat.NewMessageUseCase(newMessageDeps(mockRepo, mockCache))
+	ctx := context.Background()
+
+	mockRepo.On("Create", ctx, mock.Anything).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, mock.Anything).Return(nil)
+
+	msg := &entities.Message{UserId: 1, TargetId: 2, Content: "hi", PlainText: "forged", Metadata: entities.JSON(`{"html":"<img src=x onerror=alert(1)>"}`)}
+	assert.NoError(t, useCase.SendPrivateMessage(ctx, msg))
+
+	assert.Nil(t, msg.Metadata)
+	assert.Empty(t, msg.PlainText)
+}
+
+// 測試編輯富文本時重新產生 HTML 與純文字
+func TestMessageUseCase_EditMessage_RichText(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	useCase := newEditUseCase(mockRepo, mockCache, newRecordingPublisher())
+	ctx := context.Background()
+
+	original := &entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeRichText, Content: "*old*", CreatedAt: time.Now()}
+	assert.NoError(t, original.SetRichText("<p><em>old</em></p>", "old"))
+	mockRepo.On("FindByID", ctx, uint(7)).Return(original, nil)
+	mockRepo.On("UpdateContent", ctx, original, mock.Anything).Return(nil)
+	mockCache.On("UpdateMessage", ctx, original).Return(nil)
+	mockCache.On("InvalidatePins", ctx, mock.Anything).Return(nil)
+
+	edited, err := useCase.EditMessage(ctx, 1, 7, "- **new**")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<ul><li><strong>new</strong></li></ul>", edited.RichTextHTML())
+	assert.Equal(t, "- new", edited.PlainText)
+}
@@ -0,0 +1,242 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 以記憶體保存排程的假儲存庫，認領邏輯與資料庫實作相同
+type memoryScheduleRepository struct {
+	items  map[uint]*entities.ScheduledMessage
+	nextID uint
+	claims int
+}
+
+func newMemoryScheduleRepository() *memoryScheduleRepository {
+	return &memoryScheduleRepository{items: make(map[uint]*entities.ScheduledMessage)}
+}
+
+func (r *memoryScheduleRepository) Create(ctx context.Context, scheduled *entities.ScheduledMessage) error {
+	r.nextID++
+	scheduled.ID = r.nextID
+	copied := *scheduled
+	r.items[scheduled.ID] = &copied
+	return nil
+}
+
+func (r *memoryScheduleRepository) FindByID(ctx context.Context, id uint) (*entities.ScheduledMessage, error) {
+	scheduled, ok := r.items[id]
+	if !ok {
+		return nil, repositories.ErrScheduledMessageNotFound
+	}
+	copied := *scheduled
+	return &copied, nil
+}
+
+func (r *memoryScheduleRepository) ListPending(ctx context.Context, userID uint) ([]*entities.ScheduledMessage, error) {
+	var result []*entities.ScheduledMessage
+	for _, scheduled := range r.items {
+		if scheduled.UserID == userID && scheduled.Status == entities.SchedulePending {
+			copied := *scheduled
+			result = append(result, &copied)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].SendAt.Before(result[j].SendAt) })
+	return result, nil
+}
+
+func (r *memoryScheduleRepository) UpdatePending(ctx context.Context, scheduled *entities.ScheduledMessage) error {
+	stored, ok := r.items[scheduled.ID]
+	if !ok || stored.Status != entities.SchedulePending {
+		return repositories.ErrScheduleNotPending
+	}
+	stored.Content = scheduled.Content
+	stored.SendAt = scheduled.SendAt
+	return nil
+}
+
+func (r *memoryScheduleRepository) Cancel(ctx context.Context, id uint) error {
+	stored, ok := r.items[id]
+	if !ok || stored.Status != entities.SchedulePending {
+		return repositories.ErrScheduleNotPending
+	}
+	stored.Status = entities.ScheduleCanceled
+	return nil
+}
+
+func (r *memoryScheduleRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]*entities.ScheduledMessage, error) {
+	r.claims++
+	token := string(rune('a' + r.claims))
+	until := now.Add(lease)
+
+	var claimed []*entities.ScheduledMessage
+	for _, scheduled := range r.items {
+		due := scheduled.Status == entities.SchedulePending && !scheduled.SendAt.After(now)
+		expired := scheduled.Status == entities.ScheduleSending && scheduled.ClaimedUntil.Before(now)
+		if !due && !expired || len(claimed) >= limit {
+			continue
+		}
+		scheduled.Status = entities.ScheduleSending
+		scheduled.ClaimToken = token
+		scheduled.ClaimedUntil = &until
+		scheduled.Attempts++
+		copied := *scheduled
+		claimed = append(claimed, &copied)
+	}
+	sort.Slice(claimed, func(i, j int) bool { return claimed[i].ID < claimed[j].ID })
+	return claimed, nil
+}
+
+func (r *memoryScheduleRepository) MarkSent(ctx context.Context, scheduled *entities.ScheduledMessage, messageID uint) error {
+	if stored := r.items[scheduled.ID]; stored.ClaimToken == scheduled.ClaimToken {
+		stored.Status = entities.ScheduleSent
+		stored.MessageID = messageID
+		stored.ClaimToken = ""
+	}
+	return nil
+}
+
+func (r *memoryScheduleRepository) MarkFailed(ctx context.Context, scheduled *entities.ScheduledMessage, reason string, retry bool) error {
+	if stored := r.items[scheduled.ID]; stored.ClaimToken == scheduled.ClaimToken {
+		stored.Status = entities.ScheduleFailed
+		if retry {
+			stored.Status = entities.SchedulePending
+		}
+		stored.LastError = reason
+		stored.ClaimToken = ""
+	}
+	return nil
+}
+
+// 記錄經由一般發送流程送出訊息的假訊息用例
+type recordingMessageUseCase struct {
+	chat.MessageUseCase
+	sent   []*entities.Message
+	failed error
+}
+
+func (uc *recordingMessageUseCase) send(message *entities.Message) error {
+	if uc.failed != nil {
+		return uc.failed
+	}
+	message.ID = uint(100 + len(uc.sent))
+	uc.sent = append(uc.sent, message)
+	return nil
+}
+
+func (uc *recordingMessageUseCase) SendPrivateMessage(ctx context.Context, message *entities.Message) error {
+	message.Type = entities.MessageTypePrivate
+	return uc.send(message)
+}
+
+func (uc *recordingMessageUseCase) SendGroupMessage(ctx context.Context, message *entities.Message) error {
+	message.Type = entities.MessageTypeGroup
+	return uc.send(message)
+}
+
+// 測試建立排程時驗證發送時間與群組成員身份，並只有擁有者能修改或取消待發送的排程
+func TestScheduleUseCase_ScheduleUpdateCancel(t *testing.T) {
+	repo := newMemoryScheduleRepository()
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1}}
+	useCase := chat.NewScheduleUseCase(repo, &recordingMessageUseCase{}, groupRepo)
+	ctx := context.Background()
+	later := time.Now().Add(time.Hour)
+
+	err := useCase.ScheduleMessage(ctx, &entities.ScheduledMessage{UserID: 1, Type: entities.MessageTypePrivate, TargetID: 2, Content: "hi", SendAt: time.Now().Add(-time.Minute)})
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+
+	err = useCase.ScheduleMessage(ctx, &entities.ScheduledMessage{UserID: 2, Type: entities.MessageTypeGroup, TargetID: 5, Content: "hi", SendAt: later})
+	assertAppErrorKey(t, err, "NOT_GROUP_MEMBER")
+
+	scheduled := &entities.ScheduledMessage{UserID: 1, Type: entities.MessageTypeGroup, TargetID: 5, Content: "早安", SendAt: later}
+	assert.NoError(t, useCase.ScheduleMessage(ctx, scheduled))
+	assert.Equal(t, entities.SchedulePending, scheduled.Status)
+	assert.Equal(t, entities.MediaTypeText, scheduled.Media)
+
+	_, err = useCase.UpdateScheduled(ctx, 2, scheduled.ID, "改", later)
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+
+	updated, err := useCase.UpdateScheduled(ctx, 1, scheduled.ID, "午安", later.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, "午安", updated.Content)
+
+	pending, err := useCase.ListScheduled(ctx, 1)
+	assert.NoError(t, err)
+	if assert.Len(t, pending, 1) {
+		assert.Equal(t, "午安", pending[0].Content)
+	}
+
+	assert.NoError(t, useCase.CancelScheduled(ctx, 1, scheduled.ID))
+	_, err = useCase.UpdateScheduled(ctx, 1, scheduled.ID, "晚安", later)
+	assertAppErrorKey(t, err, "SCHEDULE_NOT_PENDING")
+	assertAppErrorKey(t, useCase.CancelScheduled(ctx, 1, 99), "SCHEDULE_NOT_FOUND")
+}
+
+// 測試到期的排程經由一般發送流程送出，且以排程ID作為 client_msg_id 去重
+func TestScheduleUseCase_DispatchDue(t *testing.T) {
+	repo := newMemoryScheduleRepository()
+	messages := &recordingMessageUseCase{}
+	useCase := chat.NewScheduleUseCase(repo, messages, &stubGroupRepository{})
+	ctx := context.Background()
+	now := time.Now()
+
+	repo.items[1] = &entities.ScheduledMessage{ID: 1, UserID: 1, Type: entities.MessageTypePrivate, TargetID: 2, Content: "a", SendAt: now.Add(-time.Second), Status: entities.SchedulePending}
+	repo.items[2] = &entities.ScheduledMessage{ID: 2, UserID: 1, Type: entities.MessageTypeGroup, TargetID: 5, Content: "b", SendAt: now.Add(-time.Second), Status: entities.SchedulePending}
+	repo.items[3] = &entities.ScheduledMessage{ID: 3, UserID: 1, Type: entities.MessageTypePrivate, TargetID: 2, Content: "c", SendAt: now.Add(time.Hour), Status: entities.SchedulePending}
+
+	count, err := useCase.DispatchDue(ctx, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	if assert.Len(t, messages.sent, 2) {
+		assert.Equal(t, uint(2), messages.sent[0].TargetId)
+		assert.Equal(t, "scheduled:1", *messages.sent[0].ClientMsgID)
+		assert.Equal(t, uint(5), messages.sent[1].RoomID)
+	}
+	assert.Equal(t, entities.ScheduleSent, repo.items[1].Status)
+	assert.Equal(t, uint(100), repo.items[1].MessageID)
+	assert.Equal(t, entities.SchedulePending, repo.items[3].Status)
+
+	count, err = useCase.DispatchDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+// 測試發送失敗時在重試次數內放回待發送，超過後標記為失敗
+func TestScheduleUseCase_DispatchDue_RetriesThenFails(t *testing.T) {
+	repo := newMemoryScheduleRepository()
+	messages := &recordingMessageUseCase{failed: errors.New("db down")}
+	useCase := chat.NewScheduleUseCase(repo, messages, &stubGroupRepository{})
+	ctx := context.Background()
+	now := time.Now()
+
+	repo.items[1] = &entities.ScheduledMessage{ID: 1, UserID: 1, Type: entities.MessageTypePrivate, TargetID: 2, Content: "a", SendAt: now, Status: entities.SchedulePending}
+
+	for i := 1; i < entities.MaxScheduleAttempts; i++ {
+		_, err := useCase.DispatchDue(ctx, now)
+		assert.NoError(t, err)
+		assert.Equal(t, entities.SchedulePending, repo.items[1].Status)
+	}
+	_, err := useCase.DispatchDue(ctx, now)
+	assert.NoError(t, err)
+	assert.Equal(t, entities.ScheduleFailed, repo.items[1].Status)
+	assert.Equal(t, "db down", repo.items[1].LastError)
+}
+
+func assertAppErrorKey(t *testing.T, err error, key string) {
+	t.Helper()
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok, "expected app error %s, got %v", key, err) {
+		assert.Equal(t, key, appErr.Key())
+	}
+}
@@ -0,0 +1,77 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	searchInfra "clean-architecture-gochat/infrastructure/search"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/search"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newMemorySearchIndex() search.MessageIndex {
+	return searchInfra.NewMemoryIndex()
+}
+
+// 測試英數字以單字、中文以相鄰兩字切詞，並在片段中跳脫 HTML 後標示搜尋詞
+func TestSearchTokenizeAndSnippet(t *testing.T) {
+	assert.Equal(t, []string{"hello", "世界", "界和", "和平"}, search.Tokenize("Hello 世界和平, hello!"))
+	assert.Equal(t, []string{"好"}, search.Tokenize("好"))
+
+	snippet := search.Snippet("<b>Go</b> 語言", search.Tokenize("go"))
+	assert.Equal(t, "&lt;b&gt;<mark>Go</mark>&lt;/b&gt; 語言", snippet)
+}
+
+// 測試搜尋只返回用戶的私聊與所屬群組中未撤回的訊息，並支援過濾與分頁
+func TestSearchUseCase_SearchMessages(t *testing.T) {
+	index := newMemorySearchIndex()
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1, 3}}
+	useCase := chat.NewSearchUseCase(index, groupRepo)
+	ctx := context.Background()
+
+	now := time.Now()
+	recalledAt := now
+	for _, msg := range []*entities.Message{
+		{ID: 1, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "專案會議改到明天", CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: 2, UserId: 3, TargetId: 4, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "專案會議", CreatedAt: now},
+		{ID: 3, UserId: 3, RoomID: 5, Type: entities.MessageTypeGroup, Media: entities.MediaTypeText, Content: "下週的專案會議", CreatedAt: now},
+		{ID: 4, UserId: 3, RoomID: 6, Type: entities.MessageTypeGroup, Media: entities.MediaTypeText, Content: "專案會議", CreatedAt: now},
+		{ID: 5, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Media: entities.MediaTypeText, Content: "專案會議", CreatedAt: now, RecalledAt: &recalledAt},
+	} {
+		assert.NoError(t, index.Index(ctx, msg))
+	}
+
+	result, err := useCase.SearchMessages(ctx, 1, chat.SearchQuery{Text: "專案會議"})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Hits, 2) {
+		assert.Equal(t, uint(3), result.Hits[0].Message.ID)
+		assert.Equal(t, uint(1), result.Hits[1].Message.ID)
+		assert.Equal(t, "<mark>專案會議</mark>改到明天", result.Hits[1].Snippet)
+	}
+
+	result, err = useCase.SearchMessages(ctx, 1, chat.SearchQuery{Text: "專案", SenderID: 1})
+	assert.NoError(t, err)
+	assert.Len(t, result.Hits, 1)
+
+	result, err = useCase.SearchMessages(ctx, 1, chat.SearchQuery{Text: "專案", From: now.Add(-time.Hour)})
+	assert.NoError(t, err)
+	if assert.Len(t, result.Hits, 1) {
+		assert.Equal(t, uint(3), result.Hits[0].Message.ID)
+	}
+
+	result, err = useCase.SearchMessages(ctx, 1, chat.SearchQuery{Text: "專案", Limit: 1})
+	assert.NoError(t, err)
+	assert.Len(t, result.Hits, 1)
+	assert.True(t, result.HasMore)
+
+	_, err = useCase.SearchMessages(ctx, 1, chat.SearchQuery{Text: "專案", ConversationType: entities.ConversationTypeGroup, TargetID: 6})
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "NOT_GROUP_MEMBER", appErr.Key())
+	}
+}
@@ -0,0 +1,224 @@
+package test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// memorySequenceCache 以記憶體模擬 Redis 序號計數器，failing 為 true 時模擬 Redis 不可用
+type memorySequenceCache struct {
+	mu       sync.Mutex
+	counters map[string]uint64
+	failing  bool
+}
+
+func newMemorySequenceCache() *memorySequenceCache {
+	return &memorySequenceCache{counters: make(map[string]uint64)}
+}
+
+func (c *memorySequenceCache) NextSeq(ctx context.Context, scope string) (uint64, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failing {
+		return 0, false, errors.New("redis unavailable")
+	}
+	if _, ok := c.counters[scope]; !ok {
+		return 0, false, nil
+	}
+	c.counters[scope]++
+	return c.counters[scope], true, nil
+}
+
+func (c *memorySequenceCache) EnsureSeq(ctx context.Context, scope string, seq uint64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.failing {
+		return errors.New("redis unavailable")
+	}
+	if current, ok := c.counters[scope]; !ok || current < seq {
+		c.counters[scope] = seq
+	}
+	return nil
+}
+
+func (c *memorySequenceCache) setFailing(failing bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.failing = failing
+}
+
+// memorySequenceRepository 以記憶體模擬資料庫中各對話的最大序號
+type memorySequenceRepository struct {
+	mu     sync.Mutex
+	latest map[string]uint64
+}
+
+func newMemorySequenceRepository() *memorySequenceRepository {
+	return &memorySequenceRepository{latest: make(map[string]uint64)}
+}
+
+func (r *memorySequenceRepository) Current(ctx context.Context, message *entities.Message) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.latest[entities.PinScopeOf(message)], nil
+}
+
+func (r *memorySequenceRepository) Next(ctx context.Context, message *entities.Message) (uint64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	scope := entities.PinScopeOf(message)
+	r.latest[scope]++
+	return r.latest[scope], nil
+}
+
+func newMemorySequencer() chat.Sequencer {
+	return chat.NewSequencer(newMemorySequenceCache(), newMemorySequenceRepository())
+}
+
+// 測試私聊雙方共用一組序號，群組各自獨立，討論串回覆不配發序號
+func TestSequencer_AssignsPerConversation(t *testing.T) {
+	sequencer := newMemorySequencer()
+	ctx := context.Background()
+
+	assign := func(message *entities.Message) uint64 {
+		assert.NoError(t, sequencer.Assign(ctx, message))
+		return message.Seq
+	}
+
+	assert.Equal(t, uint64(1), assign(&entities.Message{UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate}))
+	assert.Equal(t, uint64(2), assign(&entities.Message{UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate}))
+	assert.Equal(t, uint64(1), assign(&entities.Message{UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup}))
+	assert.Equal(t, uint64(2), assign(&entities.Message{UserId: 3, RoomID: 5, Type: entities.MessageTypeSystem}))
+
+	rootID := uint(9)
+	assert.Equal(t, uint64(0), assign(&entities.Message{UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup, ThreadRootID: &rootID}))
+	assert.Equal(t, uint64(3), assign(&entities.Message{UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup}))
+}
+
+// 測試 Redis 計數器遺失時以資料庫中的最大序號初始化，Redis 失敗時改由資料庫配發，恢復後不會倒退
+func TestSequencer_FallsBackToDatabase(t *testing.T) {
+	seqCache := newMemorySequenceCache()
+	repo := newMemorySequenceRepository()
+	repo.latest["room:5"] = 10
+	sequencer := chat.NewSequencer(seqCache, repo)
+	ctx := context.Background()
+
+	next := func() uint64 {
+		message := &entities.Message{UserId: 1, RoomID: 5, Type: entities.MessageTypeGroup}
+		assert.NoError(t, sequencer.Assign(ctx, message))
+		return message.Seq
+	}
+
+	assert.Equal(t, uint64(11), next())
+	assert.Equal(t, uint64(12), next())
+
+	// Redis 不可用，資料庫從訊息中的最大序號之後配發
+	seqCache.setFailing(true)
+	repo.latest["room:5"] = 12
+	assert.Equal(t, uint64(13), next())
+	assert.Equal(t, uint64(14), next())
+
+	// Redis 恢復後先將計數器提高到資料庫配發過的序號之上，保留其他副本由資料庫配發的空間
+	seqCache.setFailing(false)
+	assert.Equal(t, uint64(14+1000+1), next())
+}
+
+// 測試 Redis 主從切換後計數器倒退時，寫入因序號唯一索引被拒絕，以資料庫的最大序號為準重新配發
+func TestMessageUseCase_SendPrivateMessage_SeqConflict(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	seqCache := newMemorySequenceCache()
+	seqRepo := newMemorySequenceRepository()
+	seqCache.counters["private:1:2"] = 5
+	seqRepo.latest["private:1:2"] = 8
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.Sequencer = chat.NewSequencer(seqCache, seqRepo)
+	useCase := chat.NewMessageUseCase(deps)
+
+	ctx := context.Background()
+	msg := &entities.Message{UserId: 1, TargetId: 2, Content: "hi"}
+
+	var attempts []uint64
+	mockRepo.On("Create", ctx, msg).Run(func(args mock.Arguments) {
+		attempts = append(attempts, args.Get(1).(*entities.Message).Seq)
+	}).Return(repositories.ErrDuplicateSeq).Once()
+	mockRepo.On("Create", ctx, msg).Run(func(args mock.Arguments) {
+		message := args.Get(1).(*entities.Message)
+		attempts = append(attempts, message.Seq)
+		message.ID = 10
+	}).Return(nil).Once()
+	mockCache.On("StorePrivateMessage", ctx, msg).Return(nil)
+
+	err := useCase.SendPrivateMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{6, 8 + 1000 + 1}, attempts)
+	if assert.NotNil(t, msg.SeqScope) {
+		assert.Equal(t, "private:1:2", *msg.SeqScope)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+// 測試對話歷史的 ID 游標換算為序號後查詢
+func TestMessageUseCase_GetGroupMessageHistory_SeqCursor(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	useCase := chat.NewMessageUseCase(newMessageDeps(mockRepo, mockCache))
+	ctx := context.Background()
+
+	expected := entities.HistoryQuery{BeforeSeq: 42, Limit: 20}
+	mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, RoomID: 5, Type: entities.MessageTypeGroup, Seq: 42}, nil)
+	mockCache.On("GetGroupMessagesPage", ctx, uint(5), expected).Return(nil, false, nil)
+	mockRepo.On("FindMessagesByRoomIDPage", ctx, uint(5), expected).Return(&entities.MessagePage{Messages: []*entities.Message{
+		{ID: 6, RoomID: 5, Type: entities.MessageTypeGroup, Seq: 41},
+	}}, nil)
+
+	page, err := useCase.GetGroupMessageHistory(ctx, 5, entities.HistoryQuery{BeforeID: 7, Limit: 20})
+
+	assert.NoError(t, err)
+	if assert.Len(t, page.Messages, 1) {
+		assert.Equal(t, uint64(41), page.Messages[0].Seq)
+	}
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "WarmGroupMessages", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// 測試最新一頁回源資料庫時多取一筆寫入快取，返回的分頁仍只有 limit 則
+func TestMessageUseCase_GetGroupMessageHistory_WarmsExtraRow(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	useCase := chat.NewMessageUseCase(newMessageDeps(mockRepo, mockCache))
+	ctx := context.Background()
+
+	rows := []*entities.Message{
+		{ID: 1, RoomID: 5, Type: entities.MessageTypeGroup, Seq: 1},
+		{ID: 2, RoomID: 5, Type: entities.MessageTypeGroup, Seq: 2},
+		{ID: 3, RoomID: 5, Type: entities.MessageTypeGroup, Seq: 3},
+	}
+	warmed := make(chan []*entities.Message, 1)
+	mockCache.On("GetGroupMessagesPage", ctx, uint(5), entities.HistoryQuery{Limit: 2}).Return(nil, false, nil)
+	mockRepo.On("FindMessagesByRoomIDPage", ctx, uint(5), entities.HistoryQuery{Limit: 3}).Return(&entities.MessagePage{Messages: rows}, nil)
+	mockCache.On("WarmGroupMessages", mock.Anything, uint(5), mock.Anything).Run(func(args mock.Arguments) {
+		warmed <- args.Get(2).([]*entities.Message)
+	}).Return(nil)
+
+	page, err := useCase.GetGroupMessageHistory(ctx, 5, entities.HistoryQuery{Limit: 2})
+
+	assert.NoError(t, err)
+	assert.True(t, page.HasMore)
+	if assert.Len(t, page.Messages, 2) {
+		assert.Equal(t, uint(2), page.Messages[0].ID)
+		assert.Equal(t, uint(3), page.Messages[1].ID)
+	}
+	// 快取保存多取的一筆，下一次查詢最新一頁時即可命中
+	assert.Len(t, <-warmed, 3)
+}
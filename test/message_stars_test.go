@@ -0,0 +1,185 @@
+package test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// 以記憶體保存收藏的假儲存庫
+type memoryStarRepository struct {
+	stars  []*entities.MessageStar
+	nextID uint
+}
+
+func newMemoryStarRepository() *memoryStarRepository {
+	return &memoryStarRepository{}
+}
+
+func (r *memoryStarRepository) Star(ctx context.Context, star *entities.MessageStar) (bool, error) {
+	for _, existing := range r.stars {
+		if existing.UserID == star.UserID && existing.MessageID == star.MessageID {
+			existing.Note = star.Note
+			existing.UpdatedAt = star.UpdatedAt
+			*star = *existing
+			return false, nil
+		}
+	}
+	r.nextID++
+	star.ID = r.nextID
+	copied := *star
+	r.stars = append(r.stars, &copied)
+	return true, nil
+}
+
+func (r *memoryStarRepository) Unstar(ctx context.Context, userID, messageID uint) (bool, error) {
+	for i, existing := range r.stars {
+		if existing.UserID == userID && existing.MessageID == messageID {
+			r.stars = append(r.stars[:i], r.stars[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryStarRepository) ListByUser(ctx context.Context, userID uint, query entities.StarQuery) ([]*entities.MessageStar, error) {
+	query = query.Normalize()
+	var stars []*entities.MessageStar
+	for i := len(r.stars) - 1; i >= 0 && len(stars) <= query.Limit; i-- {
+		star := r.stars[i]
+		if star.UserID == userID && (query.BeforeID == 0 || star.ID < query.BeforeID) {
+			stars = append(stars, star)
+		}
+	}
+	return stars, nil
+}
+
+func (r *memoryStarRepository) DeleteByMessageIDs(ctx context.Context, messageIDs []uint) error {
+	kept := r.stars[:0]
+	for _, star := range r.stars {
+		if !containsUint(messageIDs, star.MessageID) {
+			kept = append(kept, star)
+		}
+	}
+	r.stars = kept
+	return nil
+}
+
+func containsUint(ids []uint, id uint) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func newStarUseCase(mockRepo *MockMessageRepository, starRepo *memoryStarRepository, publisher *recordingPublisher) chat.MessageUseCase {
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5, OwnerId: 9}, members: []uint{1, 2, 9}}
+	deps := newMessageDeps(mockRepo, new(MockMessageCacheRepository))
+	deps.GroupRepo = groupRepo
+	deps.StarRepo = starRepo
+	deps.Publisher = publisher
+	return chat.NewMessageUseCase(deps)
+}
+
+// 測試收藏需可存取訊息，重複收藏只更新備註，並同步到用戶的其他裝置
+func TestMessageUseCase_StarMessage(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	starRepo := newMemoryStarRepository()
+	publisher := newRecordingPublisher()
+	useCase := newStarUseCase(mockRepo, starRepo, publisher)
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(7)).Return(&entities.Message{ID: 7, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Content: "地址"}, nil)
+	mockRepo.On("FindByID", ctx, uint(8)).Return(&entities.Message{ID: 8, UserId: 9, RoomID: 5, Type: entities.MessageTypeGroup, Content: "會議"}, nil)
+
+	_, err := useCase.StarMessage(ctx, 3, 7, "")
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+	_, err = useCase.StarMessage(ctx, 3, 8, "")
+	assertAppErrorKey(t, err, "ACCESS_DENIED")
+	_, err = useCase.StarMessage(ctx, 2, 7, strings.Repeat("字", entities.MaxStarNoteLength+1))
+	assertAppErrorKey(t, err, "INVALID_INPUT")
+
+	star, err := useCase.StarMessage(ctx, 2, 7, " 週末要去 ")
+	assert.NoError(t, err)
+	assert.Equal(t, "週末要去", star.Note)
+
+	again, err := useCase.StarMessage(ctx, 2, 7, "改到週日")
+	assert.NoError(t, err)
+	assert.Equal(t, star.ID, again.ID)
+	if assert.Len(t, starRepo.stars, 1) {
+		assert.Equal(t, "改到週日", starRepo.stars[0].Note)
+	}
+
+	_, err = useCase.StarMessage(ctx, 2, 8, "")
+	assert.NoError(t, err)
+	events := publisher.eventsOf(2, chat.EventMessageStarred)
+	if assert.Len(t, events, 3) {
+		assert.True(t, events[0].Transient)
+	}
+	assert.Empty(t, publisher.eventsOf(1, chat.EventMessageStarred))
+
+	assert.NoError(t, useCase.UnstarMessage(ctx, 2, 7))
+	assert.NoError(t, useCase.UnstarMessage(ctx, 2, 7))
+	events = publisher.eventsOf(2, chat.EventMessageStarred)
+	if assert.Len(t, events, 4) {
+		assert.False(t, events[3].Data.(*chat.StarEvent).Starred)
+	}
+}
+
+// 測試收藏列表從資料庫讀取訊息並分頁，已撤回或已刪除的訊息會被略過並清除
+func TestMessageUseCase_ListStarredMessages(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	starRepo := newMemoryStarRepository()
+	useCase := newStarUseCase(mockRepo, starRepo, newRecordingPublisher())
+	ctx := context.Background()
+
+	now := time.Now()
+	for _, id := range []uint{11, 12, 13, 14} {
+		_, err := starRepo.Star(ctx, &entities.MessageStar{UserID: 2, MessageID: id, CreatedAt: now})
+		assert.NoError(t, err)
+	}
+	_, err := starRepo.Star(ctx, &entities.MessageStar{UserID: 1, MessageID: 11, CreatedAt: now})
+	assert.NoError(t, err)
+
+	recalled := &entities.Message{ID: 13, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate}
+	recalled.Recall(1, now)
+	mockRepo.On("FindByIDs", ctx, []uint{14, 13}).Return([]*entities.Message{
+		{ID: 14, UserId: 9, RoomID: 5, Type: entities.MessageTypeGroup, Content: "群組"},
+		recalled,
+	}, nil)
+	mockRepo.On("FindByIDs", ctx, []uint{12, 11}).Return([]*entities.Message{
+		{ID: 11, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, Content: "私聊"},
+	}, nil)
+
+	page, err := useCase.ListStarredMessages(ctx, 2, entities.StarQuery{Limit: 2})
+	assert.NoError(t, err)
+	assert.True(t, page.HasMore)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, uint(14), page.Items[0].Message.ID)
+	}
+
+	page, err = useCase.ListStarredMessages(ctx, 2, entities.StarQuery{BeforeID: page.NextCursor, Limit: 2})
+	assert.NoError(t, err)
+	assert.False(t, page.HasMore)
+	assert.Zero(t, page.NextCursor)
+	if assert.Len(t, page.Items, 1) {
+		assert.Equal(t, "私聊", page.Items[0].Message.Content)
+	}
+
+	// 已撤回與不存在的訊息的收藏被清除，其他用戶的收藏不受影響
+	var remaining []uint
+	for _, star := range starRepo.stars {
+		remaining = append(remaining, star.MessageID)
+	}
+	assert.Equal(t, []uint{11, 14, 11}, remaining)
+	mockRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,118 @@
+package test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/usecases/chat"
+	baseErrors "clean-architecture-gochat/pkg/errors"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func uintPtr(v uint) *uint {
+	return &v
+}
+
+// 測試在討論串回覆中再回覆時歸到最上層根訊息，且不進入主對話快取與會話
+func TestMessageUseCase_SendGroupMessage_ThreadReply(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1, 2, 3}}
+	publisher := newRecordingPublisher()
+	conversationRepo := newRecordingConversationRepository()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.ConversationRepo = conversationRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+	ctx := context.Background()
+
+	root := &entities.Message{ID: 10, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup, Content: "root"}
+	earlierReply := &entities.Message{ID: 11, UserId: 3, RoomID: 5, Type: entities.MessageTypeGroup, ThreadRootID: uintPtr(10)}
+	updatedRoot := &entities.Message{ID: 10, UserId: 2, RoomID: 5, Type: entities.MessageTypeGroup, ThreadReplyCount: 2, ThreadParticipants: entities.UintList{3, 1}}
+	msg := &entities.Message{UserId: 1, RoomID: 5, Content: "reply", ThreadRootID: uintPtr(11), ReplyToID: uintPtr(10)}
+
+	mockRepo.On("FindByID", ctx, uint(11)).Return(earlierReply, nil)
+	mockRepo.On("FindByID", ctx, uint(10)).Return(root, nil)
+	mockRepo.On("Create", ctx, msg).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 12
+	}).Return(nil)
+	mockRepo.On("AddThreadReply", ctx, uint(10), uint(1), mock.AnythingOfType("time.Time")).Return(updatedRoot, nil)
+	mockCache.On("UpdateMessage", ctx, updatedRoot).Return(nil)
+
+	err := useCase.SendGroupMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(10), *msg.ThreadRootID)
+	if assert.NotNil(t, msg.ReplyTo) {
+		assert.Equal(t, "root", msg.ReplyTo.Preview)
+	}
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertNotCalled(t, "StoreGroupMessage", mock.Anything, mock.Anything)
+	assert.Empty(t, conversationRepo.recorded)
+
+	if assert.Len(t, publisher.published[1], 1) {
+		assert.Equal(t, chat.EventThreadUpdated, publisher.published[1][0].Type)
+	}
+	if assert.Len(t, publisher.published[2], 2) {
+		assert.Equal(t, chat.EventMessageNew, publisher.published[2][0].Type)
+		event := publisher.published[2][1].Data.(*chat.ThreadEvent)
+		assert.Equal(t, 2, event.ReplyCount)
+		assert.Equal(t, []uint{3, 1}, event.Participants)
+	}
+}
+
+// 測試無法引用其他對話的訊息
+func TestMessageUseCase_SendPrivateMessage_ReplyToOtherConversation(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	useCase := chat.NewMessageUseCase(newMessageDeps(mockRepo, new(MockMessageCacheRepository)))
+	ctx := context.Background()
+
+	mockRepo.On("FindByID", ctx, uint(40)).Return(&entities.Message{ID: 40, UserId: 3, TargetId: 4, Type: entities.MessageTypePrivate}, nil)
+
+	err := useCase.SendPrivateMessage(ctx, &entities.Message{UserId: 1, TargetId: 2, Content: "hi", ReplyToID: uintPtr(40)})
+
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "INVALID_INPUT", appErr.Key())
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// 測試討論串查詢會以單一查詢附加引用預覽，並拒絕非參與者
+func TestMessageUseCase_GetThread(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	useCase := chat.NewMessageUseCase(newMessageDeps(mockRepo, new(MockMessageCacheRepository)))
+	ctx := context.Background()
+
+	root := &entities.Message{ID: 10, UserId: 1, TargetId: 2, Type: entities.MessageTypePrivate, ThreadReplyCount: 2}
+	quoted := &entities.Message{ID: 3, UserId: 2, TargetId: 1, Type: entities.MessageTypePrivate, Media: entities.MediaTypeImage}
+	replies := []*entities.Message{
+		{ID: 11, UserId: 2, TargetId: 1, ThreadRootID: uintPtr(10), CreatedAt: time.Now()},
+		{ID: 12, UserId: 1, TargetId: 2, ThreadRootID: uintPtr(10), ReplyToID: uintPtr(3), CreatedAt: time.Now()},
+	}
+	mockRepo.On("FindByID", ctx, uint(10)).Return(root, nil)
+	mockRepo.On("FindThreadRepliesPage", ctx, uint(10), entities.HistoryQuery{Limit: entities.DefaultHistoryLimit}).
+		Return(&entities.MessagePage{Messages: replies, HasMore: false}, nil)
+	mockRepo.On("FindByIDs", ctx, []uint{3}).Return([]*entities.Message{quoted}, nil).Once()
+
+	thread, err := useCase.GetThread(ctx, 2, 10, entities.HistoryQuery{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, root, thread.Root)
+	assert.Len(t, thread.Replies, 2)
+	assert.Nil(t, thread.Replies[0].ReplyTo)
+	if assert.NotNil(t, thread.Replies[1].ReplyTo) {
+		assert.Equal(t, "[圖片]", thread.Replies[1].ReplyTo.Preview)
+	}
+
+	_, err = useCase.GetThread(ctx, 9, 10, entities.HistoryQuery{})
+	appErr, ok := baseErrors.GetAppError(err)
+	if assert.True(t, ok) {
+		assert.Equal(t, "ACCESS_DENIED", appErr.Key())
+	}
+}
@@ -0,0 +1,303 @@
+package test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"clean-architecture-gochat/internal/domain/cache"
+	"clean-architecture-gochat/internal/domain/entities"
+	"clean-architecture-gochat/internal/domain/repositories"
+	"clean-architecture-gochat/internal/usecases/chat"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newMessageDeps 以記憶體假物件建立訊息用例的依賴，測試只需覆寫用到的欄位，不設定群組儲存庫與審核
+func newMessageDeps(messageRepo repositories.MessageRepository, messageCache cache.MessageCacheRepository) chat.MessageDeps {
+	return chat.MessageDeps{
+		MessageRepo:      messageRepo,
+		MessageCache:     messageCache,
+		UnreadCounter:    newMemoryUnreadCounter(),
+		ConversationRepo: newRecordingConversationRepository(),
+		ReactionRepo:     newMemoryReactionRepository(),
+		PinRepo:          newMemoryPinRepository(),
+		PollRepo:         newMemoryPollRepository(),
+		StarRepo:         newMemoryStarRepository(),
+		SearchIndex:      newMemorySearchIndex(),
+		LinkPreviewer:    &stubLinkPreviewer{},
+		Sequencer:        newMemorySequencer(),
+		Publisher:        newRecordingPublisher(),
+	}
+}
+
+// 記錄推送事件的假推送器
+type recordingPublisher struct {
+	mu        sync.Mutex // 連結預覽在背景發布事件
+	published map[uint][]*chat.Event
+	discarded map[uint][]uint // userID -> 被移除離線事件的訊息ID
+}
+
+func newRecordingPublisher() *recordingPublisher {
+	return &recordingPublisher{published: make(map[uint][]*chat.Event), discarded: make(map[uint][]uint)}
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, userIDs []uint, event *chat.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, userID := range userIDs {
+		p.published[userID] = append(p.published[userID], event)
+	}
+	return nil
+}
+
+// eventsOf 返回用戶收到的指定類型事件
+func (p *recordingPublisher) eventsOf(userID uint, eventType chat.EventType) []*chat.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var events []*chat.Event
+	for _, event := range p.published[userID] {
+		if event.Type == eventType {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+func (p *recordingPublisher) DeliverPending(ctx context.Context, userID uint) error {
+	return nil
+}
+
+func (p *recordingPublisher) DiscardPending(ctx context.Context, userIDs []uint, messageIDs ...uint) error {
+	for _, userID := range userIDs {
+		p.discarded[userID] = append(p.discarded[userID], messageIDs...)
+	}
+	return nil
+}
+
+// 只實作測試所需方法的群組儲存庫
+type stubGroupRepository struct {
+	repositories.GroupRepository
+	group   *entities.Group
+	members []uint
+	admins  []uint
+}
+
+func (r *stubGroupRepository) FindByID(ctx context.Context, id uint) (*entities.Group, error) {
+	return r.group, nil
+}
+
+func (r *stubGroupRepository) IsMember(ctx context.Context, groupID, userID uint) (bool, error) {
+	for _, member := range r.members {
+		if member == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *stubGroupRepository) FindJoinedGroups(ctx context.Context, userID uint) ([]*entities.Group, error) {
+	if ok, _ := r.IsMember(ctx, r.group.ID, userID); ok {
+		return []*entities.Group{r.group}, nil
+	}
+	return nil, nil
+}
+
+func (r *stubGroupRepository) GetMembers(ctx context.Context, groupID uint) ([]uint, error) {
+	return r.members, nil
+}
+
+func (r *stubGroupRepository) GetMemberRole(ctx context.Context, groupID, userID uint) (entities.GroupRole, error) {
+	for _, admin := range r.admins {
+		if admin == userID {
+			return entities.GroupRoleAdmin, nil
+		}
+	}
+	if ok, _ := r.IsMember(ctx, groupID, userID); ok {
+		return entities.GroupRoleMember, nil
+	}
+	return "", nil
+}
+
+func (r *stubGroupRepository) GetMembersByRole(ctx context.Context, groupID uint, role entities.GroupRole) ([]uint, error) {
+	if role == entities.GroupRoleAdmin {
+		return r.admins, nil
+	}
+	return nil, nil
+}
+
+// 記錄會話更新的假會話儲存庫
+type recordingConversationRepository struct {
+	repositories.ConversationRepository
+	recorded  map[uint][]uint // messageID -> recipients
+	mentioned map[uint][]uint // messageID -> 被提及的用戶
+	ttls      map[string]time.Duration
+	cleared   []uint
+}
+
+func newRecordingConversationRepository() *recordingConversationRepository {
+	return &recordingConversationRepository{recorded: make(map[uint][]uint), mentioned: make(map[uint][]uint), ttls: make(map[string]time.Duration)}
+}
+
+func (r *recordingConversationRepository) FindDisappearingTTL(ctx context.Context, scope string) (time.Duration, error) {
+	return r.ttls[scope], nil
+}
+
+func (r *recordingConversationRepository) SaveDisappearingSetting(ctx context.Context, setting *entities.DisappearingSetting) error {
+	r.ttls[setting.Scope] = time.Duration(setting.TTL) * time.Second
+	return nil
+}
+
+func (r *recordingConversationRepository) ClearPreview(ctx context.Context, messageIDs []uint) error {
+	r.cleared = append(r.cleared, messageIDs...)
+	return nil
+}
+
+func (r *recordingConversationRepository) RecordMentions(ctx context.Context, message *entities.Message, userIDs []uint) error {
+	r.mentioned[message.ID] = userIDs
+	return nil
+}
+
+func (r *recordingConversationRepository) RecordMessage(ctx context.Context, message *entities.Message, recipients []uint) error {
+	r.recorded[message.ID] = recipients
+	return nil
+}
+
+func (r *recordingConversationRepository) RefreshPreview(ctx context.Context, message *entities.Message) error {
+	return nil
+}
+
+// 測試帶有新 client_msg_id 的訊息會寫入資料庫、記錄去重資訊並推送給接收者
+func TestMessageUseCase_SendPrivateMessage_NewClientMsgID(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	publisher := newRecordingPublisher()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+
+	ctx := context.Background()
+	clientMsgID := "c-1"
+	msg := &entities.Message{UserId: 1, TargetId: 2, Content: "hi", ClientMsgID: &clientMsgID}
+
+	mockCache.On("GetClientMessageID", ctx, uint(1), clientMsgID).Return(uint(0), nil)
+	mockRepo.On("Create", ctx, msg).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 10
+	}).Return(nil)
+	mockCache.On("StoreClientMessageID", ctx, uint(1), clientMsgID, uint(10)).Return(nil)
+	mockCache.On("StorePrivateMessage", ctx, msg).Return(nil)
+
+	err := useCase.SendPrivateMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(10), msg.ID)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	if assert.Len(t, publisher.published[2], 1) {
+		assert.Equal(t, chat.EventMessageNew, publisher.published[2][0].Type)
+	}
+	assert.Empty(t, publisher.published[1])
+}
+
+// 測試 Redis 命中去重記錄時返回原訊息，且不再寫入或推送
+func TestMessageUseCase_SendPrivateMessage_DuplicateFromCache(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	publisher := newRecordingPublisher()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+
+	ctx := context.Background()
+	clientMsgID := "c-1"
+	msg := &entities.Message{UserId: 1, TargetId: 2, Content: "hi", ClientMsgID: &clientMsgID}
+	original := &entities.Message{ID: 10, UserId: 1, TargetId: 2, Content: "hi", ClientMsgID: &clientMsgID}
+
+	mockCache.On("GetClientMessageID", ctx, uint(1), clientMsgID).Return(uint(10), nil)
+	mockRepo.On("FindByID", ctx, uint(10)).Return(original, nil)
+
+	err := useCase.SendPrivateMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(10), msg.ID)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	assert.Empty(t, publisher.published)
+}
+
+// 測試併發重試時由唯一索引判定重複並返回原訊息
+func TestMessageUseCase_SendGroupMessage_DuplicateFromUniqueIndex(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1, 2, 3}}
+	publisher := newRecordingPublisher()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+
+	ctx := context.Background()
+	clientMsgID := "c-2"
+	msg := &entities.Message{UserId: 1, RoomID: 5, Content: "hello", ClientMsgID: &clientMsgID}
+	original := &entities.Message{ID: 20, UserId: 1, RoomID: 5, Content: "hello", ClientMsgID: &clientMsgID}
+
+	mockCache.On("GetClientMessageID", ctx, uint(1), clientMsgID).Return(uint(0), nil)
+	mockRepo.On("Create", ctx, msg).Return(repositories.ErrDuplicateMessage)
+	mockRepo.On("FindByClientMsgID", ctx, uint(1), clientMsgID).Return(original, nil)
+	mockCache.On("StoreClientMessageID", ctx, uint(1), clientMsgID, uint(20)).Return(nil)
+
+	err := useCase.SendGroupMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(20), msg.ID)
+	mockRepo.AssertExpectations(t)
+	mockCache.AssertExpectations(t)
+	assert.Empty(t, publisher.published)
+}
+
+// 測試群組訊息推送給發送者以外的所有成員
+func TestMessageUseCase_SendGroupMessage_FansOutToMembers(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{1, 2, 3}}
+	publisher := newRecordingPublisher()
+	conversationRepo := newRecordingConversationRepository()
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	deps.ConversationRepo = conversationRepo
+	deps.Publisher = publisher
+	useCase := chat.NewMessageUseCase(deps)
+
+	ctx := context.Background()
+	msg := &entities.Message{UserId: 1, RoomID: 5, Content: "hello"}
+
+	mockRepo.On("Create", ctx, msg).Run(func(args mock.Arguments) {
+		args.Get(1).(*entities.Message).ID = 30
+	}).Return(nil)
+	mockCache.On("StoreGroupMessage", ctx, msg).Return(nil)
+
+	err := useCase.SendGroupMessage(ctx, msg)
+
+	assert.NoError(t, err)
+	assert.Equal(t, entities.MessageTypeGroup, msg.Type)
+	assert.Len(t, publisher.published[2], 1)
+	assert.Len(t, publisher.published[3], 1)
+	assert.Empty(t, publisher.published[1])
+	assert.Equal(t, []uint{1, 2, 3}, conversationRepo.recorded[30])
+}
+
+// 測試非群組成員無法發送群組訊息
+func TestMessageUseCase_SendGroupMessage_NotMember(t *testing.T) {
+	mockRepo := new(MockMessageRepository)
+	mockCache := new(MockMessageCacheRepository)
+	groupRepo := &stubGroupRepository{group: &entities.Group{ID: 5}, members: []uint{2, 3}}
+	deps := newMessageDeps(mockRepo, mockCache)
+	deps.GroupRepo = groupRepo
+	useCase := chat.NewMessageUseCase(deps)
+
+	err := useCase.SendGroupMessage(context.Background(), &entities.Message{UserId: 1, RoomID: 5, Content: "hello"})
+
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
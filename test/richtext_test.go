@@ -0,0 +1,58 @@
+package test
+
+import (
+	"strings"
+	"testing"
+
+	"clean-architecture-gochat/pkg/richtext"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// 測試支援的行內語法轉為對應的 HTML 與純文字
+func TestRichText_Inline(t *testing.T) {
+	doc, err := richtext.Parse("**粗體** *斜體* __b__ _i_ ***both*** `a<b>` [官網](https://example.com/a?x=1&y=2) snake_case \\*literal\\*")
+
+	assert.NoError(t, err)
+	assert.Equal(t, `<p><strong>粗體</strong> <em>斜體</em> <strong>b</strong> <em>i</em> <strong><em>both</em></strong> <code>a&lt;b&gt;</code> `+
+		`<a href="https://example.com/a?x=1&amp;y=2" rel="nofollow noopener noreferrer" target="_blank">官網</a> snake_case *literal*</p>`, doc.HTML())
+	assert.Equal(t, "粗體 斜體 b i both a<b> 官網 (https://example.com/a?x=1&y=2) snake_case *literal*", doc.PlainText())
+}
+
+// 測試清單、引用與程式碼區塊
+func TestRichText_Blocks(t *testing.T) {
+	doc, err := richtext.Parse("- one\n- **two**\n\n3. c\n4. d\n> quote\n> line\n\n```\n<b>code</b>\n```\nafter\nnext")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "<ul><li>one</li><li><strong>two</strong></li></ul>"+
+		`<ol start="3"><li>c</li><li>d</li></ol>`+
+		"<blockquote>quote<br>line</blockquote>"+
+		"<pre><code>&lt;b&gt;code&lt;/b&gt;</code></pre>"+
+		"<p>after<br>next</p>", doc.HTML())
+	assert.Equal(t, "- one\n- two\n3. c\n4. d\nquote\nline\n<b>code</b>\nafter\nnext", doc.PlainText())
+}
+
+// 測試原始內容中的 HTML 一律跳脫，連結只允許安全的網址
+func TestRichText_Sanitizes(t *testing.T) {
+	doc, err := richtext.Parse(`<img src=x onerror="alert(1)"> **<script>alert(1)</script>**`)
+	assert.NoError(t, err)
+	assert.NotContains(t, doc.HTML(), "<img")
+	assert.NotContains(t, doc.HTML(), "<script")
+	assert.Contains(t, doc.HTML(), "&lt;script&gt;")
+
+	for _, source := range []string{
+		"[x](javascript:alert(1))",
+		"[x](JaVaScRiPt:alert(1))",
+		"[x](data:text/html;base64,PHNjcmlwdD4=)",
+		`[x](https://example.com/" onclick="alert(1))`,
+		"[x](//example.com)",
+	} {
+		_, err := richtext.Parse(source)
+		assert.ErrorIs(t, err, richtext.ErrUnsafeLink, source)
+	}
+
+	_, err = richtext.Parse("  \n ")
+	assert.ErrorIs(t, err, richtext.ErrEmpty)
+	_, err = richtext.Parse(strings.Repeat("字", richtext.MaxSourceLength+1))
+	assert.ErrorIs(t, err, richtext.ErrTooLong)
+}